@@ -0,0 +1,303 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/valyala/fastjson"
+)
+
+// ContractABISection is the name of the wasm custom section a smart contract may optionally ship its ABI in.
+const ContractABISection = "wavelet_abi"
+
+// ABIType is the type of a parameter or result of a smart contract function.
+type ABIType string
+
+// Types supported by smart contract ABIs. All integers are little-endian encoded, strings are null-terminated,
+// byte arrays are prefixed with their length as a 32-bit little-endian integer, and addresses are 32 bytes long.
+const (
+	ABITypeU8      ABIType = "u8"
+	ABITypeU16     ABIType = "u16"
+	ABITypeU32     ABIType = "u32"
+	ABITypeU64     ABIType = "u64"
+	ABITypeString  ABIType = "string"
+	ABITypeBytes   ABIType = "bytes"
+	ABITypeAddress ABIType = "address"
+)
+
+var (
+	ErrInvalidABI = errors.New("contract: invalid abi")
+	ErrNoABI      = errors.New("contract: smart contract does not have an abi")
+)
+
+func (t ABIType) valid() bool {
+	switch t {
+	case ABITypeU8, ABITypeU16, ABITypeU32, ABITypeU64, ABITypeString, ABITypeBytes, ABITypeAddress:
+		return true
+	}
+
+	return false
+}
+
+// ABIValue describes a single named and typed parameter or result of a smart contract function.
+type ABIValue struct {
+	Name string
+	Type ABIType
+}
+
+// ABIFunction describes a function exported by a smart contract.
+type ABIFunction struct {
+	Name    string
+	Params  []ABIValue
+	Results []ABIValue
+}
+
+// ContractABI describes the functions exported by a smart contract, alongside their parameters and results.
+type ContractABI struct {
+	Functions []ABIFunction
+}
+
+// Function looks up a function described by the ABI by its name.
+func (abi ContractABI) Function(name string) (ABIFunction, bool) {
+	for _, fn := range abi.Functions {
+		if fn.Name == name {
+			return fn, true
+		}
+	}
+
+	return ABIFunction{}, false
+}
+
+// ParseContractABI parses and performs sanity checks on a JSON-encoded ABI, which is expected to be of the form:
+//
+//	{"functions": [{"name": "transfer", "params": [{"name": "to", "type": "address"}], "results": []}]}
+func ParseContractABI(raw []byte) (ContractABI, error) {
+	var abi ContractABI
+
+	var p fastjson.Parser
+
+	v, err := p.ParseBytes(raw)
+	if err != nil {
+		return abi, errors.Wrap(ErrInvalidABI, err.Error())
+	}
+
+	seen := make(map[string]struct{})
+
+	for _, f := range v.GetArray("functions") {
+		fn := ABIFunction{Name: string(f.GetStringBytes("name"))}
+
+		if len(fn.Name) == 0 {
+			return abi, errors.Wrap(ErrInvalidABI, "function is missing a name")
+		}
+
+		if _, exists := seen[fn.Name]; exists {
+			return abi, errors.Wrapf(ErrInvalidABI, "function %q is described more than once", fn.Name)
+		}
+
+		seen[fn.Name] = struct{}{}
+
+		if fn.Params, err = parseABIValues(f.GetArray("params")); err != nil {
+			return abi, errors.Wrapf(err, "invalid params for function %q", fn.Name)
+		}
+
+		if fn.Results, err = parseABIValues(f.GetArray("results")); err != nil {
+			return abi, errors.Wrapf(err, "invalid results for function %q", fn.Name)
+		}
+
+		abi.Functions = append(abi.Functions, fn)
+	}
+
+	return abi, nil
+}
+
+func parseABIValues(values []*fastjson.Value) ([]ABIValue, error) {
+	parsed := make([]ABIValue, 0, len(values))
+
+	for _, v := range values {
+		value := ABIValue{
+			Name: string(v.GetStringBytes("name")),
+			Type: ABIType(v.GetStringBytes("type")),
+		}
+
+		if !value.Type.valid() {
+			return nil, errors.Wrapf(ErrInvalidABI, "unknown type %q", value.Type)
+		}
+
+		parsed = append(parsed, value)
+	}
+
+	return parsed, nil
+}
+
+// MarshalJSON encodes the ABI into JSON, in the same format expected by ParseContractABI.
+func (abi ContractABI) MarshalJSON() ([]byte, error) {
+	var arena fastjson.Arena
+
+	return abi.getObject(&arena).MarshalTo(nil), nil
+}
+
+func (abi ContractABI) getObject(arena *fastjson.Arena) *fastjson.Value {
+	values := func(list []ABIValue) *fastjson.Value {
+		arr := arena.NewArray()
+
+		for i, value := range list {
+			o := arena.NewObject()
+			o.Set("name", arena.NewString(value.Name))
+			o.Set("type", arena.NewString(string(value.Type)))
+
+			arr.SetArrayItem(i, o)
+		}
+
+		return arr
+	}
+
+	functions := arena.NewArray()
+
+	for i, fn := range abi.Functions {
+		o := arena.NewObject()
+		o.Set("name", arena.NewString(fn.Name))
+		o.Set("params", values(fn.Params))
+		o.Set("results", values(fn.Results))
+
+		functions.SetArrayItem(i, o)
+	}
+
+	o := arena.NewObject()
+	o.Set("functions", functions)
+
+	return o
+}
+
+// ReadContractABI reads and parses the ABI a smart contract ships in the custom section of its wasm code
+// named by ContractABISection. ErrNoABI is returned should the smart contract not have an ABI.
+func ReadContractABI(code []byte) (ContractABI, error) {
+	raw, exists, err := readWasmCustomSection(code, ContractABISection)
+	if err != nil {
+		return ContractABI{}, err
+	}
+
+	if !exists {
+		return ContractABI{}, ErrNoABI
+	}
+
+	return ParseContractABI(raw)
+}
+
+// readWasmCustomSection looks up the contents of a custom section by its name in wasm code.
+func readWasmCustomSection(code []byte, name string) ([]byte, bool, error) {
+	header := []byte{0x00, 0x61, 0x73, 0x6d}
+
+	if len(code) < 8 || !bytes.Equal(code[:4], header) {
+		return nil, false, errors.New("wasm: invalid module header")
+	}
+
+	buf := code[8:]
+
+	for len(buf) > 0 {
+		id := buf[0]
+
+		size, n := binary.Uvarint(buf[1:])
+		if n <= 0 || uint64(len(buf)-1-n) < size {
+			return nil, false, errors.New("wasm: invalid section size")
+		}
+
+		section := buf[1+n : 1+n+int(size)]
+		buf = buf[1+n+int(size):]
+
+		if id != 0 {
+			continue
+		}
+
+		nameLen, n := binary.Uvarint(section)
+		if n <= 0 || uint64(len(section)-n) < nameLen {
+			return nil, false, errors.New("wasm: invalid custom section name")
+		}
+
+		if string(section[n:n+int(nameLen)]) == name {
+			return section[n+int(nameLen):], true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// readWasmFunctionExport returns whether or not wasm code exports a function by name, by looking it up in the export
+// section of the code.
+func readWasmFunctionExport(code []byte, name string) (bool, error) {
+	header := []byte{0x00, 0x61, 0x73, 0x6d}
+
+	if len(code) < 8 || !bytes.Equal(code[:4], header) {
+		return false, errors.New("wasm: invalid module header")
+	}
+
+	buf := code[8:]
+
+	for len(buf) > 0 {
+		id := buf[0]
+
+		size, n := binary.Uvarint(buf[1:])
+		if n <= 0 || uint64(len(buf)-1-n) < size {
+			return false, errors.New("wasm: invalid section size")
+		}
+
+		section := buf[1+n : 1+n+int(size)]
+		buf = buf[1+n+int(size):]
+
+		if id != 7 {
+			continue
+		}
+
+		count, n := binary.Uvarint(section)
+		if n <= 0 {
+			return false, errors.New("wasm: invalid number of exports")
+		}
+
+		section = section[n:]
+
+		for i := uint64(0); i < count; i++ {
+			nameLen, n := binary.Uvarint(section)
+			if n <= 0 || uint64(len(section)-n) <= nameLen {
+				return false, errors.New("wasm: invalid export name")
+			}
+
+			exported := string(section[n : n+int(nameLen)])
+			kind := section[n+int(nameLen)]
+			section = section[n+int(nameLen)+1:]
+
+			if _, n = binary.Uvarint(section); n <= 0 {
+				return false, errors.New("wasm: invalid export index")
+			}
+
+			section = section[n:]
+
+			// Functions are exported under a kind of 0.
+			if kind == 0 && exported == name {
+				return true, nil
+			}
+		}
+
+		return false, nil
+	}
+
+	return false, nil
+}
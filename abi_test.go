@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// +build unit
+
+package wavelet
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const testABI = `{"functions":[{"name":"balance","params":[{"name":"owner","type":"address"}],` +
+	`"results":[{"name":"amount","type":"u64"}]},{"name":"greet","params":[],"results":[]}]}`
+
+func appendWasmCustomSection(code []byte, name string, content []byte) []byte {
+	var buf [binary.MaxVarintLen64]byte
+
+	section := make([]byte, 0, len(name)+len(content)+binary.MaxVarintLen64)
+	section = append(section, buf[:binary.PutUvarint(buf[:], uint64(len(name)))]...)
+	section = append(section, name...)
+	section = append(section, content...)
+
+	code = append(append([]byte{}, code...), 0)
+	code = append(code, buf[:binary.PutUvarint(buf[:], uint64(len(section)))]...)
+
+	return append(code, section...)
+}
+
+func TestParseContractABI(t *testing.T) {
+	abi, err := ParseContractABI([]byte(testABI))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	fn, exists := abi.Function("balance")
+	assert.True(t, exists)
+	assert.Equal(t, []ABIValue{{Name: "owner", Type: ABITypeAddress}}, fn.Params)
+	assert.Equal(t, []ABIValue{{Name: "amount", Type: ABITypeU64}}, fn.Results)
+
+	_, exists = abi.Function("missing")
+	assert.False(t, exists)
+
+	marshaled, err := abi.MarshalJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, testABI, string(marshaled))
+
+	_, err = ParseContractABI([]byte(`{"functions":[{"name":"a","params":[{"name":"x","type":"f32"}]}]}`))
+	assert.Equal(t, ErrInvalidABI, errors.Cause(err))
+
+	_, err = ParseContractABI([]byte(`{"functions":[{"name":"a"},{"name":"a"}]}`))
+	assert.Equal(t, ErrInvalidABI, errors.Cause(err))
+
+	_, err = ParseContractABI([]byte(`{"functions":[`))
+	assert.Equal(t, ErrInvalidABI, errors.Cause(err))
+}
+
+func TestReadContractABI(t *testing.T) {
+	code, err := ioutil.ReadFile("testdata/transfer_back.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = ReadContractABI(code)
+	assert.Equal(t, ErrNoABI, err)
+
+	withABI := appendWasmCustomSection(code, ContractABISection, []byte(testABI))
+
+	abi, err := ReadContractABI(withABI)
+	if assert.NoError(t, err) {
+		assert.Len(t, abi.Functions, 2)
+	}
+
+	// Custom sections with other names must be skipped.
+	withOther := appendWasmCustomSection(code, "name_other", []byte("garbage"))
+
+	_, err = ReadContractABI(withOther)
+	assert.Equal(t, ErrNoABI, err)
+
+	_, err = ReadContractABI(withABI[:len(withABI)-1])
+	assert.Error(t, err)
+
+	_, err = ReadContractABI([]byte("not wasm"))
+	assert.Error(t, err)
+}
@@ -73,6 +73,20 @@ func (a *Accounts) Snapshot() *avl.Tree {
 	return snapshot
 }
 
+// SnapshotAt returns a snapshot of the accounts as they were when their Merkle root was root. Only the state as of
+// the latest committed block and the block prior to it are guaranteed to not have been garbage collected.
+func (a *Accounts) SnapshotAt(root [avl.MerkleHashSize]byte) (*avl.Tree, error) {
+	a.RLock()
+	snapshot, err := a.tree.LoadSnapshot(root)
+	a.RUnlock()
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "accounts: state at merkle root %x is not available", root)
+	}
+
+	return snapshot, nil
+}
+
 func (a *Accounts) Commit(new *avl.Tree) error {
 	a.Lock()
 	defer a.Unlock()
@@ -26,6 +26,7 @@ import (
 	"testing"
 	"testing/quick"
 
+	"github.com/perlin-network/wavelet/avl"
 	"github.com/perlin-network/wavelet/store"
 	"github.com/stretchr/testify/assert"
 )
@@ -53,6 +54,88 @@ func TestSmartContract(t *testing.T) {
 	assert.NoError(t, quick.Check(fn, nil))
 }
 
+func TestSmartContractCodeDeduplication(t *testing.T) {
+	tree := NewAccounts(store.NewInmem()).Snapshot()
+
+	code := []byte("loremipsumdolorsitamet")
+
+	WriteAccountContractCode(tree, TransactionID{1}, code)
+	WriteAccountContractCode(tree, TransactionID{2}, code)
+
+	codeID1, exists := ReadAccountContractCodeID(tree, TransactionID{1})
+	assert.True(t, exists)
+
+	codeID2, exists := ReadAccountContractCodeID(tree, TransactionID{2})
+	assert.True(t, exists)
+
+	assert.Equal(t, HashContractCode(code), codeID1)
+	assert.Equal(t, codeID1, codeID2)
+	assert.Equal(t, 1, countContractCode(tree))
+
+	// Code is kept upon a contract referencing it being deleted, so that contracts may still be spawned from it.
+	DeleteAccountContract(tree, TransactionID{1})
+	DeleteAccountContract(tree, TransactionID{2})
+
+	_, exists = ReadAccountContractCode(tree, TransactionID{1})
+	assert.False(t, exists)
+
+	stored, exists := ReadContractCode(tree, codeID1)
+	assert.True(t, exists)
+	assert.Equal(t, code, stored)
+}
+
+func TestMigrateContractCode(t *testing.T) {
+	tree := NewAccounts(store.NewInmem()).Snapshot()
+
+	code := []byte("loremipsumdolorsitamet")
+	other := []byte("consecteturadipiscingelit")
+
+	// Lay code out the way it was stored prior to being stored by its code ID.
+	writeUnderAccounts(tree, TransactionID{1}, keyAccountContractCode[:], code)
+	writeUnderAccounts(tree, TransactionID{2}, keyAccountContractCode[:], code)
+	writeUnderAccounts(tree, TransactionID{3}, keyAccountContractCode[:], other)
+
+	stored, exists := ReadAccountContractCode(tree, TransactionID{3})
+	assert.True(t, exists)
+	assert.Equal(t, other, stored)
+
+	assert.Equal(t, 3, MigrateContractCode(tree))
+	assert.Equal(t, 0, MigrateContractCode(tree))
+
+	// Once run, the migration is not run again, as code is never stored under contracts anymore.
+	writeUnderAccounts(tree, TransactionID{4}, keyAccountContractCode[:], other)
+	assert.Equal(t, 0, MigrateContractCode(tree))
+
+	_, exists = ReadAccountContractCodeID(tree, TransactionID{4})
+	assert.False(t, exists)
+
+	for id, expected := range map[TransactionID][]byte{{1}: code, {2}: code, {3}: other} {
+		_, exists := readUnderAccounts(tree, id, keyAccountContractCode[:])
+		assert.False(t, exists)
+
+		codeID, exists := ReadAccountContractCodeID(tree, id)
+		assert.True(t, exists)
+		assert.Equal(t, HashContractCode(expected), codeID)
+
+		stored, exists := ReadAccountContractCode(tree, id)
+		assert.True(t, exists)
+		assert.Equal(t, expected, stored)
+	}
+
+	assert.Equal(t, 2, countContractCode(tree))
+}
+
+func countContractCode(tree *avl.Tree) int {
+	var count int
+
+	tree.IteratePrefix(keyContractCode[:], func(_, _ []byte) bool {
+		count++
+		return true
+	})
+
+	return count
+}
+
 //func BenchmarkAccountsCommit(b *testing.B) {
 //	dbs := []string{"level"}
 //
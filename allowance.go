@@ -0,0 +1,64 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Allowance is an amount of PERLs of an account which Spender is approved to transfer out of it. An allowance may
+// only be spent at block heights below Expiry, unless Expiry is zero.
+type Allowance struct {
+	Spender AccountID
+	Amount  uint64
+	Expiry  uint64
+}
+
+// Expired returns whether or not the allowance may no longer be spent at the block height.
+func (a Allowance) Expired(height uint64) bool {
+	return a.Expiry != 0 && height >= a.Expiry
+}
+
+func (a Allowance) Marshal() []byte {
+	buf := make([]byte, 8+8)
+
+	binary.LittleEndian.PutUint64(buf[0:8], a.Amount)
+	binary.LittleEndian.PutUint64(buf[8:16], a.Expiry)
+
+	return buf
+}
+
+// UnmarshalAllowance unmarshals the amount and expiry of an allowance approved to spender.
+func UnmarshalAllowance(spender AccountID, r io.Reader) (Allowance, error) {
+	var buf [8 + 8]byte
+
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return Allowance{}, errors.Wrap(err, "failed to decode allowance")
+	}
+
+	return Allowance{
+		Spender: spender,
+		Amount:  binary.LittleEndian.Uint64(buf[0:8]),
+		Expiry:  binary.LittleEndian.Uint64(buf[8:16]),
+	}, nil
+}
@@ -119,14 +119,22 @@ func (g *Gateway) setup() {
 
 	// Account endpoints.
 	r.GET("/accounts/:id", g.applyMiddleware(g.getAccount, ""))
+	r.GET("/accounts/:id/schedules", g.applyMiddleware(g.getAccountSchedules, ""))
+
+	// Hash time-locked transfer endpoints.
+	r.GET("/htlc/:id", g.applyMiddleware(g.getHTLC, ""))
 
 	// Contract endpoints.
 	r.GET("/contract/:id/page/:index", g.applyMiddleware(g.getContractPages, "/contract/:id/page/:index", g.contractScope))
 	r.GET("/contract/:id/page", g.applyMiddleware(g.getContractPages, "/contract/:id/page", g.contractScope))
+	r.GET("/contract/:id/storage/:key", g.applyMiddleware(g.getContractStorage, "/contract/:id/storage/:key", g.contractScope))
+	r.GET("/contract/:id/abi", g.applyMiddleware(g.getContractABI, "/contract/:id/abi", g.contractScope))
 	r.GET("/contract/:id", g.applyMiddleware(g.getContractCode, "/contract/:id", g.contractScope))
 
 	// Transaction endpoints.
 	r.POST("/tx/send", g.applyMiddleware(g.sendTransaction, ""))
+	r.POST("/tx/simulate", g.applyMiddleware(g.simulateTransaction, ""))
+	r.GET("/tx/:id/trace", g.applyMiddleware(g.traceTransaction, ""))
 	r.GET("/tx/:id", g.applyMiddleware(g.getTransaction, ""))
 	r.GET("/tx", g.applyMiddleware(g.listTransactions, "/tx"))
 
@@ -328,10 +336,7 @@ func (g *Gateway) sendTransaction(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	tx := wavelet.NewSignedTransaction(
-		req.sender, req.Nonce, req.Block,
-		sys.Tag(req.Tag), req.payload, req.signature,
-	)
+	tx := req.transaction()
 
 	snapshot := g.ledger.Snapshot()
 
@@ -346,6 +351,62 @@ func (g *Gateway) sendTransaction(ctx *fasthttp.RequestCtx) {
 	g.render(ctx, &sendTransactionResponse{ledger: g.ledger, tx: &tx})
 }
 
+// simulateTransaction applies a signed transaction against the latest state of the ledger without persisting or
+// broadcasting it, and renders a trace of all smart contract invocations made while applying it.
+func (g *Gateway) simulateTransaction(ctx *fasthttp.RequestCtx) {
+	req := &sendTransactionRequest{}
+
+	parser := g.parserPool.Get()
+	defer g.parserPool.Put(parser)
+
+	if err := req.bind(parser, ctx.PostBody()); err != nil {
+		g.renderError(ctx, ErrBadRequest(err))
+		return
+	}
+
+	tx := req.transaction()
+
+	if err := wavelet.ValidateTransaction(g.ledger.Snapshot(), tx); err != nil {
+		g.renderError(ctx, ErrBadRequest(err))
+		return
+	}
+
+	g.render(ctx, &contractTrace{trace: g.ledger.SimulateTransaction(&tx)})
+}
+
+// traceTransaction replays a finalized transaction against the state of the block prior to the block it was
+// finalized in, and renders a trace of all smart contract invocations made while applying it.
+func (g *Gateway) traceTransaction(ctx *fasthttp.RequestCtx) {
+	param, ok := ctx.UserValue("id").(string)
+	if !ok {
+		g.renderError(ctx, ErrBadRequest(errors.New("id must be a string")))
+		return
+	}
+
+	slice, err := hex.DecodeString(param)
+	if err != nil {
+		g.renderError(ctx, ErrBadRequest(errors.Wrap(err, "transaction ID must be presented as valid hex")))
+		return
+	}
+
+	if len(slice) != wavelet.SizeTransactionID {
+		g.renderError(ctx, ErrBadRequest(errors.Errorf("transaction ID must be %d bytes long", wavelet.SizeTransactionID)))
+		return
+	}
+
+	var id wavelet.TransactionID
+
+	copy(id[:], slice)
+
+	trace, err := g.ledger.TraceTransaction(id)
+	if err != nil {
+		g.renderError(ctx, ErrNotFound(err))
+		return
+	}
+
+	g.render(ctx, &contractTrace{trace: trace})
+}
+
 func (g *Gateway) ledgerStatus(ctx *fasthttp.RequestCtx) {
 	g.render(ctx, &ledgerStatusResponse{client: g.client, ledger: g.ledger, publicKey: g.keys.PublicKey()})
 }
@@ -464,6 +525,10 @@ func (g *Gateway) getTransaction(ctx *fasthttp.RequestCtx) {
 		res.status = statusReceived
 	}
 
+	if tx.Tag == sys.TagBatch {
+		res.batch, _ = wavelet.ReadBatchResults(g.ledger.Snapshot(), tx.ID)
+	}
+
 	g.render(ctx, res)
 }
 
@@ -494,9 +559,31 @@ func (g *Gateway) getAccount(ctx *fasthttp.RequestCtx) {
 	gasBalance, _ := wavelet.ReadAccountContractGasBalance(snapshot, id)
 	stake, _ := wavelet.ReadAccountStake(snapshot, id)
 	reward, _ := wavelet.ReadAccountReward(snapshot, id)
-	_, isContract := wavelet.ReadAccountContractCode(snapshot, id)
+	code, isContract := wavelet.ReadAccountContractCode(snapshot, id)
 	numPages, _ := wavelet.ReadAccountContractNumPages(snapshot, id)
 
+	var codeID wavelet.ContractCodeID
+	if isContract {
+		codeID = wavelet.HashContractCode(code)
+	}
+
+	var multisig *wavelet.Multisig
+	if m, isMultisig := wavelet.ReadAccountMultisig(snapshot, id); isMultisig {
+		multisig = &m
+	}
+
+	// The key that signs transactions on behalf of an account is only reported should it have been rotated.
+	var signingKey wavelet.AccountID
+	if key, rotated := wavelet.ReadAccountSigningKey(snapshot, id); rotated {
+		signingKey = key
+	}
+
+	var assets []accountAsset
+	for _, balance := range wavelet.ReadAccountAssetBalances(snapshot, id) {
+		info, _ := wavelet.ReadAsset(snapshot, balance.Asset)
+		assets = append(assets, accountAsset{id: balance.Asset, info: info, balance: balance.Balance})
+	}
+
 	g.render(ctx, &account{
 		ledger:     g.ledger,
 		id:         id,
@@ -505,10 +592,80 @@ func (g *Gateway) getAccount(ctx *fasthttp.RequestCtx) {
 		stake:      stake,
 		reward:     reward,
 		isContract: isContract,
+		codeID:     codeID,
 		numPages:   numPages,
+		multisig:   multisig,
+		signingKey: signingKey,
+		assets:     assets,
+		allowances: wavelet.ReadAccountAllowances(snapshot, id),
 	})
 }
 
+func (g *Gateway) getAccountSchedules(ctx *fasthttp.RequestCtx) {
+	param, ok := ctx.UserValue("id").(string)
+	if !ok {
+		g.renderError(ctx, ErrBadRequest(errors.New("id must be a string")))
+		return
+	}
+
+	slice, err := hex.DecodeString(param)
+	if err != nil {
+		g.renderError(ctx, ErrBadRequest(errors.Wrap(err, "account ID must be presented as valid hex")))
+		return
+	}
+
+	if len(slice) != wavelet.SizeAccountID {
+		g.renderError(ctx, ErrBadRequest(errors.Errorf("account ID must be %d bytes long", wavelet.SizeAccountID)))
+		return
+	}
+
+	var id wavelet.AccountID
+
+	copy(id[:], slice)
+
+	snapshot := g.ledger.Snapshot()
+
+	list := make(scheduleList, 0)
+	for _, scheduleID := range wavelet.ReadAccountSchedules(snapshot, id) {
+		if info, exists := wavelet.ReadSchedule(snapshot, scheduleID); exists {
+			list = append(list, &schedule{id: scheduleID, info: info})
+		}
+	}
+
+	g.render(ctx, list)
+}
+
+func (g *Gateway) getHTLC(ctx *fasthttp.RequestCtx) {
+	param, ok := ctx.UserValue("id").(string)
+	if !ok {
+		g.renderError(ctx, ErrBadRequest(errors.New("id must be a string")))
+		return
+	}
+
+	slice, err := hex.DecodeString(param)
+	if err != nil {
+		g.renderError(ctx, ErrBadRequest(errors.Wrap(err, "htlc ID must be presented as valid hex")))
+		return
+	}
+
+	if len(slice) != wavelet.SizeTransactionID {
+		g.renderError(ctx, ErrBadRequest(errors.Errorf("htlc ID must be %d bytes long", wavelet.SizeTransactionID)))
+		return
+	}
+
+	var id wavelet.TransactionID
+
+	copy(id[:], slice)
+
+	info, exists := wavelet.ReadHTLC(g.ledger.Snapshot(), id)
+	if !exists {
+		g.renderError(ctx, ErrNotFound(errors.Errorf("could not find htlc with ID %x", id)))
+		return
+	}
+
+	g.render(ctx, &htlc{id: id, info: info})
+}
+
 func (g *Gateway) getContractCode(ctx *fasthttp.RequestCtx) {
 	id, ok := ctx.UserValue("contract_id").(wavelet.TransactionID)
 	if !ok {
@@ -530,6 +687,34 @@ func (g *Gateway) getContractCode(ctx *fasthttp.RequestCtx) {
 	_, _ = io.Copy(ctx, bytes.NewReader(code))
 }
 
+func (g *Gateway) getContractABI(ctx *fasthttp.RequestCtx) {
+	id, ok := ctx.UserValue("contract_id").(wavelet.TransactionID)
+	if !ok {
+		g.renderError(ctx, ErrBadRequest(errors.New("id must be a TransactionID")))
+		return
+	}
+
+	code, available := wavelet.ReadAccountContractCode(g.ledger.Snapshot(), id)
+
+	if len(code) == 0 || !available {
+		g.renderError(ctx, ErrNotFound(errors.Errorf("could not find contract with ID %x", id)))
+		return
+	}
+
+	abi, err := wavelet.ReadContractABI(code)
+	if err != nil {
+		if errors.Cause(err) == wavelet.ErrNoABI {
+			g.renderError(ctx, ErrNotFound(errors.Errorf("contract with ID %x does not have an abi", id)))
+		} else {
+			g.renderError(ctx, ErrInternal(errors.Wrap(err, "could not read contract abi")))
+		}
+
+		return
+	}
+
+	g.render(ctx, &contractABI{abi: abi})
+}
+
 func (g *Gateway) getContractPages(ctx *fasthttp.RequestCtx) {
 	id, ok := ctx.UserValue("contract_id").(wavelet.TransactionID)
 	if !ok {
@@ -583,6 +768,41 @@ func (g *Gateway) getContractPages(ctx *fasthttp.RequestCtx) {
 	_, _ = ctx.Write(page)
 }
 
+func (g *Gateway) getContractStorage(ctx *fasthttp.RequestCtx) {
+	id, ok := ctx.UserValue("contract_id").(wavelet.TransactionID)
+	if !ok {
+		g.renderError(ctx, ErrBadRequest(errors.New("id must be a TransactionID")))
+		return
+	}
+
+	rawKey, ok := ctx.UserValue("key").(string)
+	if !ok {
+		g.renderError(ctx, ErrBadRequest(errors.New("could not cast key into string")))
+		return
+	}
+
+	key, err := hex.DecodeString(rawKey)
+	if err != nil {
+		g.renderError(ctx, ErrBadRequest(errors.Wrap(err, "storage key must be presented as valid hex")))
+		return
+	}
+
+	if len(key) > sys.ContractMaxStorageKeySize {
+		g.renderError(ctx, ErrBadRequest(errors.Errorf("storage key must be at most %d bytes long",
+			sys.ContractMaxStorageKeySize)))
+		return
+	}
+
+	value, available := wavelet.ReadAccountContractStorage(g.ledger.Snapshot(), id, key)
+
+	if !available {
+		g.renderError(ctx, ErrNotFound(errors.Errorf("could not find storage key %x for contract with ID %x", key, id)))
+		return
+	}
+
+	_, _ = ctx.Write(value)
+}
+
 func (g *Gateway) connect(ctx *fasthttp.RequestCtx) {
 	parser := g.parserPool.Get()
 	v, err := parser.ParseBytes(ctx.PostBody())
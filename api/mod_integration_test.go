@@ -444,6 +444,108 @@ func TestGetAccount(t *testing.T) {
 	}
 }
 
+func TestGetHTLC(t *testing.T) {
+	gateway := New()
+	gateway.setup()
+
+	gateway.ledger = createLedger(t)
+
+	tests := []struct {
+		name     string
+		url      string
+		wantCode int
+	}{
+		{
+			name:     "id not hex",
+			url:      "/htlc/-----",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid id length",
+			url:      "/htlc/1c331c1d",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "id not found",
+			url:      "/htlc/" + strings.Repeat("00", wavelet.SizeTransactionID),
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "http://localhost"+tc.url, nil)
+
+			w, err := serve(gateway.router, request)
+			if !assert.NoError(t, err) || !assert.NotNil(t, w) {
+				return
+			}
+
+			defer func() {
+				_ = w.Body.Close()
+			}()
+
+			assert.Equal(t, tc.wantCode, w.StatusCode, "status code")
+		})
+	}
+}
+
+func TestGetAccountSchedules(t *testing.T) {
+	gateway := New()
+	gateway.setup()
+
+	gateway.ledger = createLedger(t)
+
+	tests := []struct {
+		name         string
+		url          string
+		wantCode     int
+		wantResponse string
+	}{
+		{
+			name:     "id not hex",
+			url:      "/accounts/-----/schedules",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid id length",
+			url:      "/accounts/1c331c1d/schedules",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:         "no schedules",
+			url:          "/accounts/" + strings.Repeat("00", wavelet.SizeAccountID) + "/schedules",
+			wantCode:     http.StatusOK,
+			wantResponse: "[]",
+		},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "http://localhost"+tc.url, nil)
+
+			w, err := serve(gateway.router, request)
+			if !assert.NoError(t, err) || !assert.NotNil(t, w) {
+				return
+			}
+
+			defer func() {
+				_ = w.Body.Close()
+			}()
+
+			assert.Equal(t, tc.wantCode, w.StatusCode, "status code")
+
+			if tc.wantResponse != "" {
+				response, err := ioutil.ReadAll(w.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.wantResponse, string(bytes.TrimSpace(response)))
+			}
+		})
+	}
+}
+
 func TestGetContractCode(t *testing.T) {
 	gateway := New()
 	gateway.setup()
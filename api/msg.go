@@ -47,6 +47,10 @@ var (
 	_ marshalableJSON = (*account)(nil)
 
 	_ marshalableJSON = (*msgResponse)(nil)
+
+	_ marshalableJSON = (*contractABI)(nil)
+
+	_ marshalableJSON = (*contractTrace)(nil)
 )
 
 type msgResponse struct {
@@ -69,9 +73,24 @@ type sendTransactionRequest struct {
 	Payload   string `json:"payload"`
 	Signature string `json:"signature"`
 
-	sender    edwards25519.PublicKey
-	payload   []byte
-	signature edwards25519.Signature
+	// Signatures, should the sender be a multisig account, are sent in place of Signature.
+	Signatures []multisigSignature `json:"signatures"`
+
+	// FeePayer and FeePayerSignature are only sent should the transaction be sponsored by a fee payer.
+	FeePayer          string `json:"fee_payer"`
+	FeePayerSignature string `json:"fee_payer_signature"`
+
+	sender            edwards25519.PublicKey
+	payload           []byte
+	signature         edwards25519.Signature
+	signatures        []wavelet.MultisigSignature
+	feePayer          wavelet.AccountID
+	feePayerSignature wavelet.Signature
+}
+
+type multisigSignature struct {
+	Key       uint8  `json:"key"`
+	Signature string `json:"signature"`
 }
 
 func (s *sendTransactionRequest) bind(parser *fastjson.Parser, body []byte) error {
@@ -134,14 +153,21 @@ func (s *sendTransactionRequest) bind(parser *fastjson.Parser, body []byte) erro
 		return errors.Wrap(err, "invalid payload")
 	}
 
-	signatureVal := v.Get("signature")
-	if signatureVal == nil {
-		return errors.New("missing signature")
-	}
+	// Transactions sent from multisig accounts carry signatures in place of a signature.
+	signaturesVal := v.Get("signatures")
 
-	signature, err := signatureVal.StringBytes()
-	if err != nil {
-		return errors.Wrap(err, "invalid signature")
+	var signature []byte
+
+	if signaturesVal == nil {
+		signatureVal := v.Get("signature")
+		if signatureVal == nil {
+			return errors.New("missing signature")
+		}
+
+		signature, err = signatureVal.StringBytes()
+		if err != nil {
+			return errors.Wrap(err, "invalid signature")
+		}
 	}
 
 	s.Sender = string(sender)
@@ -162,7 +188,7 @@ func (s *sendTransactionRequest) bind(parser *fastjson.Parser, body []byte) erro
 
 	copy(s.sender[:], senderBuf)
 
-	if sys.Tag(s.Tag) > sys.TagBatch {
+	if sys.Tag(s.Tag) > sys.TagSchedule {
 		return errors.New("unknown transaction tag specified")
 	}
 
@@ -171,6 +197,16 @@ func (s *sendTransactionRequest) bind(parser *fastjson.Parser, body []byte) erro
 		return errors.Wrap(err, "payload provided is not hex-formatted")
 	}
 
+	if feePayerVal := v.Get("fee_payer"); feePayerVal != nil {
+		if err := s.bindFeePayer(feePayerVal, v.Get("fee_payer_signature")); err != nil {
+			return err
+		}
+	}
+
+	if signaturesVal != nil {
+		return s.bindSignatures(signaturesVal)
+	}
+
 	signatureBuf, err := hex.DecodeString(s.Signature)
 	if err != nil {
 		return errors.Wrap(err, "signature provided is not hex-formatted")
@@ -185,6 +221,117 @@ func (s *sendTransactionRequest) bind(parser *fastjson.Parser, body []byte) erro
 	return nil
 }
 
+func (s *sendTransactionRequest) bindFeePayer(feePayerVal *fastjson.Value, signatureVal *fastjson.Value) error {
+	feePayer, err := feePayerVal.StringBytes()
+	if err != nil {
+		return errors.Wrap(err, "invalid fee payer")
+	}
+
+	feePayerBuf, err := hex.DecodeString(string(feePayer))
+	if err != nil {
+		return errors.Wrap(err, "fee payer provided is not hex-formatted")
+	}
+
+	if len(feePayerBuf) != wavelet.SizeAccountID {
+		return errors.Errorf("fee payer must be size %d", wavelet.SizeAccountID)
+	}
+
+	if signatureVal == nil {
+		return errors.New("missing fee payer signature")
+	}
+
+	signature, err := signatureVal.StringBytes()
+	if err != nil {
+		return errors.Wrap(err, "invalid fee payer signature")
+	}
+
+	signatureBuf, err := hex.DecodeString(string(signature))
+	if err != nil {
+		return errors.Wrap(err, "fee payer signature provided is not hex-formatted")
+	}
+
+	if len(signatureBuf) != wavelet.SizeSignature {
+		return errors.Errorf("fee payer signature must be size %d", wavelet.SizeSignature)
+	}
+
+	s.FeePayer = string(feePayer)
+	s.FeePayerSignature = string(signature)
+
+	copy(s.feePayer[:], feePayerBuf)
+	copy(s.feePayerSignature[:], signatureBuf)
+
+	return nil
+}
+
+func (s *sendTransactionRequest) bindSignatures(v *fastjson.Value) error {
+	signaturesVal, err := v.Array()
+	if err != nil {
+		return errors.Wrap(err, "invalid signatures")
+	}
+
+	if len(signaturesVal) == 0 || len(signaturesVal) > sys.MultisigMaxKeys {
+		return errors.Errorf("number of signatures must be between 1 and %d", sys.MultisigMaxKeys)
+	}
+
+	s.Signatures = make([]multisigSignature, 0, len(signaturesVal))
+	s.signatures = make([]wavelet.MultisigSignature, 0, len(signaturesVal))
+
+	for _, signatureVal := range signaturesVal {
+		keyVal := signatureVal.Get("key")
+		if keyVal == nil {
+			return errors.New("missing signature key")
+		}
+
+		key, err := keyVal.Uint()
+		if err != nil || key > 255 {
+			return errors.New("invalid signature key")
+		}
+
+		sigVal := signatureVal.Get("signature")
+		if sigVal == nil {
+			return errors.New("missing signature")
+		}
+
+		signature, err := sigVal.StringBytes()
+		if err != nil {
+			return errors.Wrap(err, "invalid signature")
+		}
+
+		signatureBuf, err := hex.DecodeString(string(signature))
+		if err != nil {
+			return errors.Wrap(err, "signature provided is not hex-formatted")
+		}
+
+		if len(signatureBuf) != wavelet.SizeSignature {
+			return errors.Errorf("signature must be size %d", wavelet.SizeSignature)
+		}
+
+		multisig := wavelet.MultisigSignature{Key: uint8(key)}
+		copy(multisig.Signature[:], signatureBuf)
+
+		s.Signatures = append(s.Signatures, multisigSignature{Key: uint8(key), Signature: string(signature)})
+		s.signatures = append(s.signatures, multisig)
+	}
+
+	return nil
+}
+
+// transaction returns the transaction denoted by the request.
+func (s *sendTransactionRequest) transaction() wavelet.Transaction {
+	tx := wavelet.NewSignedTransaction(s.sender, s.Nonce, s.Block, sys.Tag(s.Tag), s.payload, s.signature)
+
+	if len(s.signatures) > 0 {
+		tx = wavelet.CombineMultisigSignatures(tx, s.signatures...)
+	}
+
+	if s.feePayer != wavelet.ZeroAccountID {
+		tx.FeePayer = s.feePayer
+		tx = wavelet.SponsorTransaction(tx, s.feePayerSignature)
+	}
+
+	return tx
+}
+
 type sendTransactionResponse struct {
 	// Internal fields.
 	ledger *wavelet.Ledger
@@ -300,6 +447,9 @@ type transaction struct {
 	// Internal fields.
 	tx     *wavelet.Transaction
 	status string
+
+	// batch holds the outcome of each entry of a best-effort batch transaction once it is applied.
+	batch []wavelet.BatchEntryResult
 }
 
 func (s *transaction) marshalJSON(arena *fastjson.Arena) ([]byte, error) {
@@ -327,6 +477,42 @@ func (s *transaction) getObject(arena *fastjson.Arena) (*fastjson.Value, error)
 	o.Set("payload", arena.NewString(base64.StdEncoding.EncodeToString(s.tx.Payload)))
 	o.Set("signature", arena.NewString(hex.EncodeToString(s.tx.Signature[:])))
 
+	if len(s.tx.Signatures) > 0 {
+		signatures := arena.NewArray()
+
+		for i, signature := range s.tx.Signatures {
+			v := arena.NewObject()
+			v.Set("key", arena.NewNumberInt(int(signature.Key)))
+			v.Set("signature", arena.NewString(hex.EncodeToString(signature.Signature[:])))
+
+			signatures.SetArrayItem(i, v)
+		}
+
+		o.Set("signatures", signatures)
+	}
+
+	if s.tx.IsSponsored() {
+		o.Set("fee_payer", arena.NewString(hex.EncodeToString(s.tx.FeePayer[:])))
+		o.Set("fee_payer_signature", arena.NewString(hex.EncodeToString(s.tx.FeePayerSignature[:])))
+	}
+
+	if len(s.batch) > 0 {
+		batch := arena.NewArray()
+
+		for i, entry := range s.batch {
+			v := arena.NewObject()
+			v.Set("tag", arena.NewNumberInt(int(entry.Tag)))
+
+			if entry.Error != "" {
+				v.Set("error", arena.NewString(entry.Error))
+			}
+
+			batch.SetArrayItem(i, v)
+		}
+
+		o.Set("batch", batch)
+	}
+
 	return o, nil
 }
 
@@ -357,7 +543,19 @@ type account struct {
 	stake      uint64
 	reward     uint64
 	isContract bool
+	codeID     wavelet.ContractCodeID
 	numPages   uint64
+	multisig   *wavelet.Multisig
+	signingKey wavelet.AccountID
+	assets     []accountAsset
+	allowances []wavelet.Allowance
+}
+
+// accountAsset is the balance of a native asset held by an account, along with the symbol and decimals of the asset.
+type accountAsset struct {
+	id      wavelet.AssetID
+	info    wavelet.AssetInfo
+	balance uint64
 }
 
 func (s *account) marshalJSON(arena *fastjson.Arena) ([]byte, error) {
@@ -375,6 +573,7 @@ func (s *account) marshalJSON(arena *fastjson.Arena) ([]byte, error) {
 
 	if s.isContract {
 		o.Set("is_contract", arena.NewTrue())
+		o.Set("code_id", arena.NewString(hex.EncodeToString(s.codeID[:])))
 	} else {
 		o.Set("is_contract", arena.NewFalse())
 	}
@@ -383,9 +582,134 @@ func (s *account) marshalJSON(arena *fastjson.Arena) ([]byte, error) {
 		o.Set("num_mem_pages", arena.NewNumberString(strconv.FormatUint(s.numPages, 10)))
 	}
 
+	if s.signingKey != wavelet.ZeroAccountID {
+		o.Set("signing_key", arena.NewString(hex.EncodeToString(s.signingKey[:])))
+	}
+
+	if s.multisig != nil {
+		keys := arena.NewArray()
+
+		for i, key := range s.multisig.Keys {
+			keys.SetArrayItem(i, arena.NewString(hex.EncodeToString(key[:])))
+		}
+
+		multisig := arena.NewObject()
+		multisig.Set("threshold", arena.NewNumberInt(int(s.multisig.Threshold)))
+		multisig.Set("keys", keys)
+
+		o.Set("multisig", multisig)
+	}
+
+	if len(s.assets) > 0 {
+		assets := arena.NewArray()
+
+		for i, asset := range s.assets {
+			v := arena.NewObject()
+			v.Set("id", arena.NewString(hex.EncodeToString(asset.id[:])))
+			v.Set("symbol", arena.NewString(asset.info.Symbol))
+			v.Set("decimals", arena.NewNumberInt(int(asset.info.Decimals)))
+			v.Set("balance", arena.NewNumberString(strconv.FormatUint(asset.balance, 10)))
+
+			assets.SetArrayItem(i, v)
+		}
+
+		o.Set("assets", assets)
+	}
+
+	if len(s.allowances) > 0 {
+		allowances := arena.NewArray()
+
+		for i, allowance := range s.allowances {
+			v := arena.NewObject()
+			v.Set("spender", arena.NewString(hex.EncodeToString(allowance.Spender[:])))
+			v.Set("amount", arena.NewNumberString(strconv.FormatUint(allowance.Amount, 10)))
+			v.Set("expiry", arena.NewNumberString(strconv.FormatUint(allowance.Expiry, 10)))
+
+			allowances.SetArrayItem(i, v)
+		}
+
+		o.Set("allowances", allowances)
+	}
+
 	return o.MarshalTo(nil), nil
 }
 
+// htlcStatuses are the labels of the statuses of a hash time-locked transfer.
+var htlcStatuses = map[byte]string{
+	wavelet.HTLCLocked:   "locked",
+	wavelet.HTLCClaimed:  "claimed",
+	wavelet.HTLCRefunded: "refunded",
+}
+
+type htlc struct {
+	id   wavelet.TransactionID
+	info wavelet.HTLCInfo
+}
+
+func (s *htlc) marshalJSON(arena *fastjson.Arena) ([]byte, error) {
+	o := arena.NewObject()
+
+	o.Set("id", arena.NewString(hex.EncodeToString(s.id[:])))
+	o.Set("sender", arena.NewString(hex.EncodeToString(s.info.Sender[:])))
+	o.Set("recipient", arena.NewString(hex.EncodeToString(s.info.Recipient[:])))
+	o.Set("amount", arena.NewNumberString(strconv.FormatUint(s.info.Amount, 10)))
+	o.Set("hash_lock", arena.NewString(hex.EncodeToString(s.info.HashLock[:])))
+	o.Set("expiry", arena.NewNumberString(strconv.FormatUint(s.info.Expiry, 10)))
+	o.Set("status", arena.NewString(htlcStatuses[s.info.Status]))
+
+	if s.info.Preimage != nil {
+		o.Set("preimage", arena.NewString(hex.EncodeToString(s.info.Preimage)))
+	}
+
+	return o.MarshalTo(nil), nil
+}
+
+type schedule struct {
+	id   wavelet.TransactionID
+	info wavelet.ScheduleInfo
+}
+
+func (s *schedule) getObject(arena *fastjson.Arena) *fastjson.Value {
+	o := arena.NewObject()
+
+	o.Set("id", arena.NewString(hex.EncodeToString(s.id[:])))
+	o.Set("recipient", arena.NewString(hex.EncodeToString(s.info.Recipient[:])))
+	o.Set("amount", arena.NewNumberString(strconv.FormatUint(s.info.Amount, 10)))
+	o.Set("next", arena.NewNumberString(strconv.FormatUint(s.info.Next, 10)))
+	o.Set("interval", arena.NewNumberString(strconv.FormatUint(s.info.Interval, 10)))
+	o.Set("remaining", arena.NewNumberString(strconv.FormatUint(s.info.Remaining, 10)))
+
+	return o
+}
+
+type scheduleList []*schedule
+
+func (s scheduleList) marshalJSON(arena *fastjson.Arena) ([]byte, error) {
+	list := arena.NewArray()
+
+	for i, v := range s {
+		list.SetArrayItem(i, v.getObject(arena))
+	}
+
+	return list.MarshalTo(nil), nil
+}
+
+type contractABI struct {
+	abi wavelet.ContractABI
+}
+
+func (s *contractABI) marshalJSON(arena *fastjson.Arena) ([]byte, error) {
+	return s.abi.MarshalJSON()
+}
+
+type contractTrace struct {
+	trace *wavelet.ContractTrace
+}
+
+func (s *contractTrace) marshalJSON(arena *fastjson.Arena) ([]byte, error) {
+	return s.trace.MarshalJSON()
+}
+
 type errResponse struct {
 	Err            error `json:"-"` // low-level runtime error
 	HTTPStatusCode int   `json:"-"` // http response status code
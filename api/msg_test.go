@@ -66,7 +66,7 @@ func TestSendTransactionRequestTag(t *testing.T) {
 	// test send invalid tag
 	typeInvalid := `
 		{
-			"tag": 10,
+			"tag": 255,
 			"sender": "3132333435363738393031323334353637383930313233343536373839303132",
 			"payload": "7061796C6F6164",
 			"signature": "31323334353637383930313233343536373839303132333435363738393031323132333435363738393031323334353637383930313233343536373839303132"
@@ -108,3 +108,117 @@ func TestSendTransactionRequestMissingFields(t *testing.T) {
 	`
 	assert.Error(t, req.bind(&fastjson.Parser{}, []byte(missingSignature)))
 }
+
+func TestSendTransactionRequestSignatures(t *testing.T) {
+	req := new(sendTransactionRequest)
+
+	// test multisig signatures in place of a signature
+	multisig := `
+		{
+			"sender": "3132333435363738393031323334353637383930313233343536373839303132",
+			"nonce": 1,
+			"block": 1,
+			"tag": 1,
+			"payload": "7061796C6F6164",
+			"signatures": [
+				{
+					"key": 2,
+					"signature": "31323334353637383930313233343536373839303132333435363738393031323132333435363738393031323334353637383930313233343536373839303132"
+				},
+				{
+					"key": 0,
+					"signature": "31323334353637383930313233343536373839303132333435363738393031323132333435363738393031323334353637383930313233343536373839303132"
+				}
+			]
+		}
+	`
+	if !assert.NoError(t, req.bind(&fastjson.Parser{}, []byte(multisig))) {
+		return
+	}
+
+	tx := req.transaction()
+	if assert.Len(t, tx.Signatures, 2) {
+		assert.EqualValues(t, 0, tx.Signatures[0].Key)
+		assert.EqualValues(t, 2, tx.Signatures[1].Key)
+	}
+
+	// test empty signatures
+	empty := `
+		{
+			"sender": "3132333435363738393031323334353637383930313233343536373839303132",
+			"nonce": 1,
+			"block": 1,
+			"tag": 1,
+			"payload": "7061796C6F6164",
+			"signatures": []
+		}
+	`
+	assert.Error(t, new(sendTransactionRequest).bind(&fastjson.Parser{}, []byte(empty)))
+
+	// test signature of invalid size
+	invalid := `
+		{
+			"sender": "3132333435363738393031323334353637383930313233343536373839303132",
+			"nonce": 1,
+			"block": 1,
+			"tag": 1,
+			"payload": "7061796C6F6164",
+			"signatures": [{"key": 0, "signature": "3132"}]
+		}
+	`
+	assert.Error(t, new(sendTransactionRequest).bind(&fastjson.Parser{}, []byte(invalid)))
+}
+
+func TestSendTransactionRequestFeePayer(t *testing.T) {
+	req := new(sendTransactionRequest)
+
+	sponsored := `
+		{
+			"sender": "3132333435363738393031323334353637383930313233343536373839303132",
+			"nonce": 1,
+			"block": 1,
+			"tag": 1,
+			"payload": "7061796C6F6164",
+			"signature": "31323334353637383930313233343536373839303132333435363738393031323132333435363738393031323334353637383930313233343536373839303132",
+			"fee_payer": "3231333435363738393031323334353637383930313233343536373839303132",
+			"fee_payer_signature": "32313334353637383930313233343536373839303132333435363738393031323132333435363738393031323334353637383930313233343536373839303132"
+		}
+	`
+	if !assert.NoError(t, req.bind(&fastjson.Parser{}, []byte(sponsored))) {
+		return
+	}
+
+	tx := req.transaction()
+	assert.True(t, tx.IsSponsored())
+	assert.Equal(t, "21", string(tx.FeePayer[:2]))
+	assert.Equal(t, "21", string(tx.FeePayerSignature[:2]))
+
+	// test missing fee payer signature
+	missing := `
+		{
+			"sender": "3132333435363738393031323334353637383930313233343536373839303132",
+			"nonce": 1,
+			"block": 1,
+			"tag": 1,
+			"payload": "7061796C6F6164",
+			"signature": "31323334353637383930313233343536373839303132333435363738393031323132333435363738393031323334353637383930313233343536373839303132",
+			"fee_payer": "3231333435363738393031323334353637383930313233343536373839303132"
+		}
+	`
+	assert.Error(t, new(sendTransactionRequest).bind(&fastjson.Parser{}, []byte(missing)))
+
+	// test fee payer of invalid size
+	invalid := `
+		{
+			"sender": "3132333435363738393031323334353637383930313233343536373839303132",
+			"nonce": 1,
+			"block": 1,
+			"tag": 1,
+			"payload": "7061796C6F6164",
+			"signature": "31323334353637383930313233343536373839303132333435363738393031323132333435363738393031323334353637383930313233343536373839303132",
+			"fee_payer": "3132",
+			"fee_payer_signature": "32313334353637383930313233343536373839303132333435363738393031323132333435363738393031323334353637383930313233343536373839303132"
+		}
+	`
+	assert.Error(t, new(sendTransactionRequest).bind(&fastjson.Parser{}, []byte(invalid)))
+}
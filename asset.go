@@ -0,0 +1,101 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/perlin-network/wavelet/sys"
+	"github.com/pkg/errors"
+)
+
+// AssetInfo describes a native asset. The ID of an asset is the ID of the transaction that created it.
+type AssetInfo struct {
+	Issuer   AccountID
+	Symbol   string
+	Decimals uint8
+	Supply   uint64
+}
+
+// AssetBalance is the balance of a native asset held by an account.
+type AssetBalance struct {
+	Asset   AssetID
+	Balance uint64
+}
+
+func (a AssetInfo) Marshal() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, SizeAccountID+1+8+1+len(a.Symbol)))
+
+	buf.Write(a.Issuer[:])
+	buf.WriteByte(a.Decimals)
+
+	var b [8]byte
+
+	binary.LittleEndian.PutUint64(b[:], a.Supply)
+	buf.Write(b[:])
+
+	buf.WriteByte(uint8(len(a.Symbol)))
+	buf.WriteString(a.Symbol)
+
+	return buf.Bytes()
+}
+
+func UnmarshalAssetInfo(r io.Reader) (AssetInfo, error) {
+	var (
+		info AssetInfo
+		b    [8]byte
+	)
+
+	if _, err := io.ReadFull(r, info.Issuer[:]); err != nil {
+		return info, errors.Wrap(err, "failed to decode asset issuer")
+	}
+
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return info, errors.Wrap(err, "failed to decode asset decimals")
+	}
+
+	info.Decimals = b[0]
+
+	if _, err := io.ReadFull(r, b[:8]); err != nil {
+		return info, errors.Wrap(err, "failed to decode asset supply")
+	}
+
+	info.Supply = binary.LittleEndian.Uint64(b[:8])
+
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return info, errors.Wrap(err, "failed to decode size of asset symbol")
+	}
+
+	if int(b[0]) > sys.AssetMaxSymbolSize {
+		return info, errors.Errorf("asset symbol must not exceed %d characters", sys.AssetMaxSymbolSize)
+	}
+
+	symbol := make([]byte, b[0])
+
+	if _, err := io.ReadFull(r, symbol); err != nil {
+		return info, errors.Wrap(err, "failed to decode asset symbol")
+	}
+
+	info.Symbol = string(symbol)
+
+	return info, nil
+}
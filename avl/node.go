@@ -119,13 +119,13 @@ func (n *node) leftRotate(t *Tree) *node {
 
 	n = n.update(t, func(node *node) {
 		node.right = right.left
-		node.rightObj = right.leftObj
+		storeChild(&node.rightObj, loadChild(&right.leftObj))
 		node.sync(t, nil, nil)
 	})
 
 	right = right.update(t, func(node *node) {
 		node.left = n.id
-		node.leftObj = n
+		storeChild(&node.leftObj, n)
 		node.sync(t, nil, nil)
 	})
 
@@ -137,13 +137,13 @@ func (n *node) rightRotate(t *Tree) *node {
 
 	n = n.update(t, func(node *node) {
 		node.left = left.right
-		node.leftObj = left.rightObj
+		storeChild(&node.leftObj, loadChild(&left.rightObj))
 		node.sync(t, nil, nil)
 	})
 
 	left = left.update(t, func(node *node) {
 		node.right = n.id
-		node.rightObj = n
+		storeChild(&node.rightObj, n)
 		node.sync(t, nil, nil)
 	})
 
@@ -161,7 +161,7 @@ func (n *node) rebalance(t *Tree) *node {
 			n = n.update(t, func(node *node) {
 				newLeft := left.leftRotate(t)
 				node.left = newLeft.id
-				node.leftObj = newLeft
+				storeChild(&node.leftObj, newLeft)
 			})
 		}
 
@@ -171,7 +171,7 @@ func (n *node) rebalance(t *Tree) *node {
 			n = n.update(t, func(node *node) {
 				newRight := right.rightRotate(t)
 				node.right = newRight.id
-				node.rightObj = newRight
+				storeChild(&node.rightObj, newRight)
 			})
 		}
 
@@ -191,7 +191,7 @@ func (n *node) insert(t *Tree, key, value []byte) *node {
 				left = left.insert(t, key, value)
 
 				node.left = left.id
-				node.leftObj = left
+				storeChild(&node.leftObj, left)
 				node.sync(t, left, right)
 			}).rebalance(t)
 		}
@@ -200,7 +200,7 @@ func (n *node) insert(t *Tree, key, value []byte) *node {
 			right = right.insert(t, key, value)
 
 			node.right = right.id
-			node.rightObj = right
+			storeChild(&node.rightObj, right)
 			node.sync(t, left, right)
 		}).rebalance(t)
 	} else if n.kind == NodeLeafValue {
@@ -216,15 +216,15 @@ func (n *node) insert(t *Tree, key, value []byte) *node {
 			if bytes.Compare(key, n.key) < 0 {
 				newLeft := newLeafNode(t, key, value)
 				node.left = newLeft.id
-				node.leftObj = newLeft
+				storeChild(&node.leftObj, newLeft)
 				node.right = n.id
-				node.rightObj = n
+				storeChild(&node.rightObj, n)
 			} else {
 				node.left = n.id
-				node.leftObj = n
+				storeChild(&node.leftObj, n)
 				newRight := newLeafNode(t, key, value)
 				node.right = newRight.id
-				node.rightObj = newRight
+				storeChild(&node.rightObj, newRight)
 			}
 
 			node.sync(t, nil, nil)
@@ -301,7 +301,7 @@ func (n *node) delete(t *Tree, key []byte) (*node, bool) {
 			if deleted {
 				return n.update(t, func(node *node) {
 					node.left = left.id
-					node.leftObj = left
+					storeChild(&node.leftObj, left)
 					node.sync(t, left, right)
 				}).rebalance(t), deleted
 			}
@@ -318,7 +318,7 @@ func (n *node) delete(t *Tree, key []byte) (*node, bool) {
 		if deleted {
 			return n.update(t, func(node *node) {
 				node.right = right.id
-				node.rightObj = right
+				storeChild(&node.rightObj, right)
 				node.sync(t, left, right)
 			}).rebalance(t), deleted
 		}
@@ -346,9 +346,25 @@ func (n *node) rehashNoWrite() [MerkleHashSize]byte {
 	return hash
 }
 
+// clone copies the node field by field, as its children may be loaded into it concurrently.
 func (n *node) clone() *node {
-	cloned := *n
-	return &cloned
+	return &node{
+		wroteBack: n.wroteBack,
+		depth:     n.depth,
+
+		key:   n.key,
+		value: n.value,
+		id:    n.id,
+		left:  n.left,
+		right: n.right,
+
+		viewID: n.viewID,
+		size:   n.size,
+
+		kind:     n.kind,
+		leftObj:  loadChild(&n.leftObj),
+		rightObj: loadChild(&n.rightObj),
+	}
 }
 
 func (n *node) update(t *Tree, fn func(node *node)) *node {
@@ -801,8 +817,8 @@ func populateDiffs( // nolint:gocognit
 			return nil, errors.New("hash mismatch")
 		}
 
-		n.leftObj = leftNode
-		n.rightObj = rightNode
+		storeChild(&n.leftObj, leftNode)
+		storeChild(&n.rightObj, rightNode)
 
 		return n, nil
 	default:
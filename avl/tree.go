@@ -26,6 +26,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"sync/atomic"
+	"unsafe"
 
 	"github.com/perlin-network/wavelet/store"
 	"github.com/phf/go-queue/queue"
@@ -116,6 +118,26 @@ func (t *Tree) Snapshot() *Tree {
 	return &Tree{kv: t.kv, cache: t.cache, maxWriteBatchSize: t.maxWriteBatchSize, root: t.root}
 }
 
+// LoadSnapshot returns a snapshot of the tree as it was when its root had the Merkle root hash root. An error is
+// returned should any of the nodes of the tree at that root no longer be persisted, which is the case for roots
+// that have been garbage collected.
+func (t *Tree) LoadSnapshot(root [MerkleHashSize]byte) (*Tree, error) {
+	snapshot := &Tree{kv: t.kv, cache: t.cache, maxWriteBatchSize: t.maxWriteBatchSize}
+
+	if root == ([MerkleHashSize]byte{}) {
+		return snapshot, nil
+	}
+
+	n, err := t.loadNode(root)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot.root = n
+
+	return snapshot, nil
+}
+
 func (t *Tree) Revert(snapshot *Tree) {
 	t.root = snapshot.root
 }
@@ -315,8 +337,8 @@ func (t *Tree) mustLoadNode(id [MerkleHashSize]byte) *node {
 }
 
 func (t *Tree) loadLeft(n *node) (*node, error) {
-	if n.leftObj != nil {
-		return n.leftObj, nil
+	if left := loadChild(&n.leftObj); left != nil {
+		return left, nil
 	}
 
 	ret, err := t.loadNode(n.left)
@@ -324,14 +346,14 @@ func (t *Tree) loadLeft(n *node) (*node, error) {
 		return nil, err
 	}
 
-	n.leftObj = ret
+	storeChild(&n.leftObj, ret)
 
 	return ret, nil
 }
 
 func (t *Tree) loadRight(n *node) (*node, error) {
-	if n.rightObj != nil {
-		return n.rightObj, nil
+	if right := loadChild(&n.rightObj); right != nil {
+		return right, nil
 	}
 
 	ret, err := t.loadNode(n.right)
@@ -339,33 +361,43 @@ func (t *Tree) loadRight(n *node) (*node, error) {
 		return nil, err
 	}
 
-	n.rightObj = ret
+	storeChild(&n.rightObj, ret)
 
 	return ret, nil
 }
 
 func (t *Tree) mustLoadLeft(n *node) *node {
-	if n.leftObj != nil {
-		return n.leftObj
+	if left := loadChild(&n.leftObj); left != nil {
+		return left
 	}
 
 	ret := t.mustLoadNode(n.left)
-	n.leftObj = ret
+	storeChild(&n.leftObj, ret)
 
 	return ret
 }
 
 func (t *Tree) mustLoadRight(n *node) *node {
-	if n.rightObj != nil {
-		return n.rightObj
+	if right := loadChild(&n.rightObj); right != nil {
+		return right
 	}
 
 	ret := t.mustLoadNode(n.right)
-	n.rightObj = ret
+	storeChild(&n.rightObj, ret)
 
 	return ret
 }
 
+// Children of nodes are loaded lazily, and may be loaded by several readers of a tree or its snapshots at once. All
+// accesses to the leftObj and rightObj fields of a node must therefore go through loadChild and storeChild.
+func loadChild(child **node) *node {
+	return (*node)(atomic.LoadPointer((*unsafe.Pointer)(unsafe.Pointer(child))))
+}
+
+func storeChild(child **node, n *node) {
+	atomic.StorePointer((*unsafe.Pointer)(unsafe.Pointer(child)), unsafe.Pointer(n))
+}
+
 func (t *Tree) deleteNodeAndMetadata(id [MerkleHashSize]byte) {
 	t.cache.Remove(id)
 	_ = t.kv.Delete(append(NodeKeyPrefix, id[:]...))
@@ -26,6 +26,7 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	mrand "math/rand"
+	"sync"
 	"testing"
 	"testing/quick"
 
@@ -160,6 +161,110 @@ func TestTree_Snapshot(t *testing.T) {
 	assert.False(t, ok)
 }
 
+// TestTree_SnapshotConcurrency has nodes shared between a tree and its snapshot be lazily loaded by readers of the
+// tree while the snapshot is modified, such that the race detector catches children of nodes accessed unsafely.
+func TestTree_SnapshotConcurrency(t *testing.T) {
+	kv := store.NewInmem()
+
+	const numKeys = 1000
+
+	key := func(i int) []byte {
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], uint64(i))
+
+		return buf[:]
+	}
+
+	tree := New(kv)
+	for i := 0; i < numKeys; i++ {
+		tree.Insert(key(i), []byte("old"))
+	}
+
+	if !assert.NoError(t, tree.Commit()) {
+		return
+	}
+
+	for round := 0; round < 10; round++ {
+		// Reload the tree, such that the children of its nodes are yet to be loaded.
+		tree := New(kv)
+		snapshot := tree.Snapshot()
+
+		var wg sync.WaitGroup
+
+		wg.Add(4)
+
+		for w := 0; w < 4; w++ {
+			order := mrand.Perm(numKeys)
+
+			go func() {
+				defer wg.Done()
+
+				for _, i := range order {
+					value, exists := tree.Lookup(key(i))
+					if !exists || !bytes.Equal(value, []byte("old")) {
+						t.Errorf("expected key %d to be found in the tree", i)
+						return
+					}
+				}
+			}()
+		}
+
+		for _, i := range mrand.Perm(numKeys) {
+			snapshot.Insert(key(i), []byte("new"))
+		}
+
+		wg.Wait()
+
+		for i := 0; i < numKeys; i++ {
+			value, exists := snapshot.Lookup(key(i))
+			assert.True(t, exists)
+			assert.EqualValues(t, "new", value)
+		}
+	}
+}
+
+func TestTree_LoadSnapshot(t *testing.T) {
+	kv, cleanup, err := store.NewTestKV("level", "db")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer cleanup()
+
+	tree := New(kv)
+	tree.Insert([]byte("k1"), []byte("1"))
+	assert.NoError(t, tree.Commit())
+
+	root := tree.Checksum()
+
+	tree.Insert([]byte("k1"), []byte("2"))
+	tree.Insert([]byte("k2"), []byte("2"))
+	assert.NoError(t, tree.Commit())
+
+	ss, err := tree.LoadSnapshot(root)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	v, ok := ss.Lookup([]byte("k1"))
+	assert.True(t, ok)
+	assert.EqualValues(t, []byte("1"), v)
+
+	_, ok = ss.Lookup([]byte("k2"))
+	assert.False(t, ok)
+
+	ss, err = tree.LoadSnapshot([MerkleHashSize]byte{})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, ok = ss.Lookup([]byte("k1"))
+	assert.False(t, ok)
+
+	_, err = tree.LoadSnapshot([MerkleHashSize]byte{1})
+	assert.Error(t, err)
+}
+
 func TestTree_Diff_Randomized(t *testing.T) {
 	kv, cleanup, err := store.NewTestKV("level", "db")
 	if !assert.NoError(t, err) {
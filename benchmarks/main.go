@@ -37,5 +37,7 @@ func main() {
 		runAccountsBenchmark()
 	case "tree":
 		runTreeBenchmark()
+	case "signatures":
+		runSignaturesBenchmark()
 	}
 }
@@ -0,0 +1,104 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/perlin-network/noise/skademlia"
+	"github.com/perlin-network/wavelet"
+	"github.com/perlin-network/wavelet/avl"
+	"github.com/perlin-network/wavelet/internal/worker"
+	"github.com/perlin-network/wavelet/store"
+	"github.com/perlin-network/wavelet/sys"
+)
+
+func runSignaturesBenchmark() {
+	sizes := []int{1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096}
+
+	workers := map[string]int{
+		"individual": 0,
+		"batch":      1,
+		"parallel":   runtime.NumCPU(),
+	}
+
+	// Generate a CSV
+	fmt.Println("size,mode,workers,time")
+
+	for _, size := range sizes {
+		for _, mode := range []string{"individual", "batch", "parallel"} {
+			result := testing.Benchmark(benchmarkValidateTransactions(size, workers[mode]))
+			fmt.Printf("%d,%s,%d,%d\n", size, mode, workers[mode], result.NsPerOp())
+		}
+	}
+}
+
+// benchmarkValidateTransactions benchmarks validating size transfer transactions. Transactions are validated one by
+// one should there be no workers, and otherwise have their signatures verified in batches spread across the workers.
+func benchmarkValidateTransactions(size int, workers int) func(b *testing.B) {
+	snapshot := avl.New(store.NewInmem())
+
+	payload, err := wavelet.Transfer{Recipient: wavelet.AccountID{0x01}, Amount: 1}.Marshal()
+	if err != nil {
+		panic(err)
+	}
+
+	txs := make([]wavelet.Transaction, size)
+
+	for i := range txs {
+		keys, err := skademlia.NewKeys(1, 1)
+		if err != nil {
+			panic(err)
+		}
+
+		wavelet.WriteAccountBalance(snapshot, keys.PublicKey(), 1000000)
+
+		txs[i] = wavelet.NewTransaction(keys, uint64(i), 0, sys.TagTransfer, payload)
+	}
+
+	return func(b *testing.B) {
+		if workers == 0 {
+			for n := 0; n < b.N; n++ {
+				for _, tx := range txs {
+					if err := wavelet.ValidateTransaction(snapshot, tx); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+
+			return
+		}
+
+		pool := worker.NewWorkerPool()
+		pool.Start(workers)
+
+		defer pool.Stop()
+
+		for n := 0; n < b.N; n++ {
+			for _, err := range wavelet.ValidateTransactions(snapshot, txs, pool) {
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	}
+}
@@ -25,6 +25,8 @@ import (
 	"github.com/perlin-network/wavelet/sys"
 	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/perlin-network/wavelet"
 	"gopkg.in/urfave/cli.v1"
@@ -112,7 +114,8 @@ func (cli *CLI) call(ctx *cli.Context) {
 
 	if len(cmd) < 4 {
 		cli.logger.Error().
-			Msg("Invalid usage: call <smart-contract-address> <amount> <gas-limit> <function> [function parameters]")
+			Msg("Invalid usage: call <smart-contract-address> <amount> <gas-limit> <function> [function parameters]" +
+				" or [<name>=<value> ...] for functions described by the contract's abi")
 		return
 	}
 
@@ -137,6 +140,14 @@ func (cli *CLI) call(ctx *cli.Context) {
 		GasLimit: gasLimit,
 	}
 
+	// Should the contract describe the function in its ABI, arguments are to be given by name.
+	if abi, err := cli.client.GetContractABI(cmd[0]); err == nil {
+		if desc, exists := abi.Function(fn.Name); exists {
+			cli.callTyped(cmd[0], recipient, fn, desc, cmd[4:])
+			return
+		}
+	}
+
 	for i := 4; i < len(cmd); i++ {
 		arg := cmd[i]
 
@@ -193,6 +204,104 @@ func (cli *CLI) call(ctx *cli.Context) {
 		Msgf("Smart contract function called.")
 }
 
+func (cli *CLI) callTyped(
+	contract string, recipient [wavelet.SizeAccountID]byte, fn wctl.FunctionCall, desc wavelet.ABIFunction,
+	args []string,
+) {
+	named := make(map[string]string, len(args))
+
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			cli.logger.Error().
+				Msgf("Invalid argument %q: arguments to %s must be given as <name>=<value>", arg, fn.Name)
+			return
+		}
+
+		named[kv[0]] = kv[1]
+	}
+
+	ordered := make([]interface{}, 0, len(desc.Params))
+
+	for _, param := range desc.Params {
+		value, exists := named[param.Name]
+		if !exists {
+			cli.logger.Error().
+				Str("type", string(param.Type)).
+				Msgf("Missing argument %q", param.Name)
+			return
+		}
+
+		delete(named, param.Name)
+		ordered = append(ordered, value)
+	}
+
+	for name := range named {
+		cli.logger.Error().
+			Msgf("Function %s does not have a parameter named %q", fn.Name, name)
+		return
+	}
+
+	params, err := wctl.EncodeABIValues(desc.Params, ordered...)
+	if err != nil {
+		cli.logger.Error().Err(err).Msg("Failed to encode arguments.")
+		return
+	}
+
+	fn.AddParams(params...)
+
+	tx, err := cli.client.Call(recipient, fn)
+	if err != nil {
+		cli.logger.Err(err).Msg("Failed to call function.")
+		return
+	}
+
+	if len(desc.Results) > 0 {
+		cli.pendingResults.Store(tx.ID, desc)
+	}
+
+	cli.logger.Info().
+		Str("recipient", contract).
+		Hex("tx_id", tx.ID[:]).
+		Msgf("Smart contract function called.")
+}
+
+func (cli *CLI) onContractResult(u wctl.ContractResult) {
+	v, exists := cli.pendingResults.Load(u.TxID)
+	if !exists {
+		return
+	}
+
+	cli.pendingResults.Delete(u.TxID)
+
+	desc := v.(wavelet.ABIFunction)
+
+	results, err := wctl.DecodeABIValues(desc.Results, u.Result)
+	if err != nil {
+		cli.logger.Error().Err(err).
+			Hex("tx_id", u.TxID[:]).
+			Hex("result", u.Result).
+			Msg("Failed to decode smart contract function result.")
+
+		return
+	}
+
+	ev := cli.logger.Info().
+		Hex("contract_id", u.ContractID[:]).
+		Hex("tx_id", u.TxID[:])
+
+	for i, result := range desc.Results {
+		name := result.Name
+		if len(name) == 0 {
+			name = fmt.Sprintf("result_%d", i)
+		}
+
+		ev = ev.Interface(name, results[i])
+	}
+
+	ev.Msgf("Smart contract function %s returned.", desc.Name)
+}
+
 func (cli *CLI) find(ctx *cli.Context) {
 	cmd := ctx.Args()
 
@@ -222,8 +331,18 @@ func (cli *CLI) find(ctx *cli.Context) {
 			Uint64("stake", account.Stake).
 			Uint64("reward", account.Reward).
 			Bool("is_contract", account.IsContract).
+			Hex("code_id", account.CodeID[:]).
 			Uint64("num_pages", account.NumPages).
 			Msgf("Account: %s", cmd[0])
+
+		for _, asset := range account.Assets {
+			cli.logger.Info().
+				Hex("asset_id", asset.ID[:]).
+				Str("symbol", asset.Symbol).
+				Uint8("decimals", asset.Decimals).
+				Uint64("balance", asset.Balance).
+				Msgf("Asset: %s", asset.Symbol)
+		}
 	case tx != nil:
 		cli.logger.Info().
 			Hex("sender", tx.Sender[:]).
@@ -233,6 +352,71 @@ func (cli *CLI) find(ctx *cli.Context) {
 	}
 }
 
+func (cli *CLI) trace(ctx *cli.Context) {
+	cmd := ctx.Args()
+
+	if len(cmd) < 1 {
+		cli.logger.Error().Msg("Invalid usage: trace <tx-id>")
+		return
+	}
+
+	txID, ok := cli.parseRecipient(cmd[0])
+	if !ok {
+		return
+	}
+
+	trace, err := cli.client.TraceTransaction(txID)
+	if err != nil {
+		cli.logger.Error().Err(err).
+			Msg("Failed to trace transaction.")
+		return
+	}
+
+	for _, invocation := range trace.Invocations {
+		for _, event := range invocation.Events {
+			ev := cli.logger.Info().
+				Int("depth", event.Depth).
+				Str("function", event.Function)
+
+			switch event.Kind {
+			case wavelet.ContractTraceEnter:
+				ev = ev.Ints64("params", event.Params)
+			case wavelet.ContractTraceHostCall:
+				ev = ev.Ints64("params", event.Params).Int64("result", event.Result).Uint64("gas", event.Gas)
+			case wavelet.ContractTraceExit:
+				ev = ev.Uint64("gas", event.Gas)
+			}
+
+			ev.Msg(string(event.Kind))
+		}
+
+		ev := cli.logger.Info()
+		if invocation.Error != "" {
+			ev = cli.logger.Error().Str("error", invocation.Error)
+		}
+
+		if invocation.Trap != nil {
+			ev = ev.Str("trap_function", invocation.Trap.Function).
+				Int("trap_ip", invocation.Trap.IP).
+				Strs("stack", invocation.Trap.Stack)
+		}
+
+		ev.Hex("contract_id", invocation.ContractID[:]).
+			Str("function", invocation.Function).
+			Uint64("gas", invocation.Gas).
+			Uint64("gas_limit", invocation.GasLimit).
+			Interface("pages_written", invocation.PagesWritten).
+			Msg("Smart contract function invoked.")
+	}
+
+	if trace.Error != "" {
+		cli.logger.Error().Str("error", trace.Error).Msgf("Transaction %s was rejected.", cmd[0])
+		return
+	}
+
+	cli.logger.Info().Int("invocations", len(trace.Invocations)).Msgf("Transaction %s traced.", cmd[0])
+}
+
 func (cli *CLI) spawn(ctx *cli.Context) {
 	cmd := ctx.Args()
 
@@ -263,6 +447,99 @@ func (cli *CLI) spawn(ctx *cli.Context) {
 		Msgf("Smart contract spawned.")
 }
 
+func (cli *CLI) spawnFromCode(ctx *cli.Context) {
+	cmd := ctx.Args()
+
+	if len(cmd) < 1 {
+		cli.logger.Error().
+			Msg("Invalid usage: spawn-from-code <code-id>")
+		return
+	}
+
+	codeID, ok := cli.parseRecipient(cmd[0])
+	if !ok {
+		return
+	}
+
+	tx, err := cli.client.SpawnFromCodeID(codeID, 100000000)
+	if err != nil {
+		cli.logger.Err(err).Msg("Failed to spawn smart contract.")
+		return
+	}
+
+	cli.logger.Info().
+		Hex("tx_id", tx.ID[:]).
+		Msgf("Smart contract spawned.")
+}
+
+func (cli *CLI) upgradeContract(ctx *cli.Context) {
+	cmd := ctx.Args()
+
+	if len(cmd) < 2 {
+		cli.logger.Error().
+			Msg("Invalid usage: upgrade-contract <contract-id> <path-to-smart-contract> [migrate gas limit]")
+		return
+	}
+
+	contract, ok := cli.parseRecipient(cmd[0])
+	if !ok {
+		return
+	}
+
+	code, err := ioutil.ReadFile(cmd[1])
+	if err != nil {
+		cli.logger.Error().
+			Err(err).
+			Str("path", cmd[1]).
+			Msg("Failed to find/load the smart contract code from the given path.")
+
+		return
+	}
+
+	var gasLimit uint64 = 100000000
+
+	if len(cmd) > 2 {
+		if gasLimit, ok = cli.parseAmount(cmd[2]); !ok {
+			return
+		}
+	}
+
+	tx, err := cli.client.UpgradeContract(contract, code, gasLimit, nil)
+	if err != nil {
+		cli.logger.Err(err).Msg("Failed to upgrade smart contract.")
+		return
+	}
+
+	cli.logger.Info().
+		Hex("tx_id", tx.ID[:]).
+		Msgf("Smart contract upgrade requested.")
+}
+
+func (cli *CLI) destroyContract(ctx *cli.Context) {
+	cmd := ctx.Args()
+
+	if len(cmd) < 1 {
+		cli.logger.Error().
+			Msg("Invalid usage: destroy-contract <contract-id>")
+		return
+	}
+
+	contract, ok := cli.parseRecipient(cmd[0])
+	if !ok {
+		return
+	}
+
+	tx, err := cli.client.DestroyContract(contract)
+	if err != nil {
+		cli.logger.Err(err).Msg("Failed to destroy smart contract.")
+		return
+	}
+
+	cli.logger.Info().
+		Hex("tx_id", tx.ID[:]).
+		Msgf("Smart contract destruction requested.")
+}
+
 func (cli *CLI) depositGas(ctx *cli.Context) {
 	cmd := ctx.Args()
 
@@ -294,6 +571,45 @@ func (cli *CLI) depositGas(ctx *cli.Context) {
 		Msgf("Gas deposited.")
 }
 
+func (cli *CLI) withdrawGas(ctx *cli.Context) {
+	cmd := ctx.Args()
+
+	if len(cmd) < 2 {
+		cli.logger.Error().
+			Msg("Invalid usage: withdraw-gas <contract-id> <amount> [recipient]")
+		return
+	}
+
+	contract, ok := cli.parseRecipient(cmd[0])
+	if !ok {
+		return
+	}
+
+	amount, ok := cli.parseAmount(cmd[1])
+	if !ok {
+		return
+	}
+
+	recipient := cli.client.PublicKey
+
+	if len(cmd) > 2 {
+		if recipient, ok = cli.parseRecipient(cmd[2]); !ok {
+			return
+		}
+	}
+
+	tx, err := cli.client.WithdrawGas(contract, recipient, amount)
+	if err != nil {
+		cli.logger.Err(err).
+			Msg("Failed to withdraw gas.")
+		return
+	}
+
+	cli.logger.Info().
+		Hex("tx_id", tx.ID[:]).
+		Msgf("Gas withdrawn.")
+}
+
 func (cli *CLI) placeStake(ctx *cli.Context) {
 	cmd := ctx.Args()
 
@@ -372,6 +688,137 @@ func (cli *CLI) withdrawReward(ctx *cli.Context) {
 		Msgf("Reward withdrew.")
 }
 
+func (cli *CLI) createAsset(ctx *cli.Context) {
+	cmd := ctx.Args()
+
+	if len(cmd) < 3 {
+		cli.logger.Error().
+			Msg("Invalid usage: create-asset <symbol> <decimals> <supply>")
+		return
+	}
+
+	decimals, err := strconv.ParseUint(cmd[1], 10, 8)
+	if err != nil {
+		cli.logger.Error().Err(err).
+			Msg("Failed to convert decimals to a uint8.")
+		return
+	}
+
+	supply, ok := cli.parseAmount(cmd[2])
+	if !ok {
+		return
+	}
+
+	tx, err := cli.client.CreateAsset(cmd[0], uint8(decimals), supply)
+	if err != nil {
+		cli.logger.Err(err).
+			Msg("Failed to create asset.")
+		return
+	}
+
+	cli.logger.Info().
+		Hex("tx_id", tx.ID[:]).
+		Msgf("Asset created.")
+}
+
+func (cli *CLI) transferAsset(ctx *cli.Context) {
+	cmd := ctx.Args()
+
+	if len(cmd) < 3 {
+		cli.logger.Error().
+			Msg("Invalid usage: transfer-asset <asset-id> <recipient> <amount>")
+		return
+	}
+
+	asset, recipient, amount, ok := cli.parseAssetArgs(cmd)
+	if !ok {
+		return
+	}
+
+	tx, err := cli.client.TransferAsset(asset, recipient, amount)
+	if err != nil {
+		cli.logger.Err(err).
+			Msg("Failed to transfer asset.")
+		return
+	}
+
+	cli.logger.Info().
+		Hex("tx_id", tx.ID[:]).
+		Msgf("Asset transferred.")
+}
+
+func (cli *CLI) mintAsset(ctx *cli.Context) {
+	cmd := ctx.Args()
+
+	if len(cmd) < 3 {
+		cli.logger.Error().
+			Msg("Invalid usage: mint-asset <asset-id> <recipient> <amount>")
+		return
+	}
+
+	asset, recipient, amount, ok := cli.parseAssetArgs(cmd)
+	if !ok {
+		return
+	}
+
+	tx, err := cli.client.MintAsset(asset, recipient, amount)
+	if err != nil {
+		cli.logger.Err(err).
+			Msg("Failed to mint asset.")
+		return
+	}
+
+	cli.logger.Info().
+		Hex("tx_id", tx.ID[:]).
+		Msgf("Asset minted.")
+}
+
+func (cli *CLI) burnAsset(ctx *cli.Context) {
+	cmd := ctx.Args()
+
+	if len(cmd) < 2 {
+		cli.logger.Error().
+			Msg("Invalid usage: burn-asset <asset-id> <amount>")
+		return
+	}
+
+	asset, ok := cli.parseRecipient(cmd[0])
+	if !ok {
+		return
+	}
+
+	amount, ok := cli.parseAmount(cmd[1])
+	if !ok {
+		return
+	}
+
+	tx, err := cli.client.BurnAsset(asset, amount)
+	if err != nil {
+		cli.logger.Err(err).
+			Msg("Failed to burn asset.")
+		return
+	}
+
+	cli.logger.Info().
+		Hex("tx_id", tx.ID[:]).
+		Msgf("Asset burned.")
+}
+
+// parseAssetArgs parses the asset ID, recipient and amount arguments shared by transfer-asset and mint-asset.
+func (cli *CLI) parseAssetArgs(cmd []string) (asset [32]byte, recipient [32]byte, amount uint64, ok bool) {
+	if asset, ok = cli.parseRecipient(cmd[0]); !ok {
+		return
+	}
+
+	if recipient, ok = cli.parseRecipient(cmd[1]); !ok {
+		return
+	}
+
+	amount, ok = cli.parseAmount(cmd[2])
+
+	return
+}
+
 func (cli *CLI) connect(ctx *cli.Context) {
 	cmd := ctx.Args()
 
@@ -27,6 +27,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"text/tabwriter"
 
 	"gopkg.in/urfave/cli.v1"
@@ -59,6 +60,10 @@ type CLI struct {
 	nocolor bool
 
 	cleanup func()
+
+	// ABI descriptions of functions called with typed arguments, keyed by transaction ID,
+	// used to decode their results.
+	pendingResults sync.Map
 }
 
 type CLIOption func(cli *CLI)
@@ -82,6 +87,16 @@ func CLIWithNoColor(b bool) CLIOption {
 }
 
 func NewCLI(client *wctl.Client, opts ...CLIOption) (*CLI, error) {
+	c := &CLI{
+		client: client,
+		logger: log.Node(),
+		app:    cli.NewApp(),
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+	}
+
+	client.OnContractResult = c.onContractResult
+
 	// Set CLI callbacks, mainly loggers
 	cleanup, err := setEvents(client)
 	if err != nil {
@@ -89,14 +104,7 @@ func NewCLI(client *wctl.Client, opts ...CLIOption) (*CLI, error) {
 		return nil, fmt.Errorf("failed to start websockets to the server: %v", err)
 	}
 
-	c := &CLI{
-		client:  client,
-		logger:  log.Node(),
-		app:     cli.NewApp(),
-		stdin:   os.Stdin,
-		stdout:  os.Stdout,
-		cleanup: cleanup,
-	}
+	c.cleanup = cleanup
 
 	for _, o := range opts {
 		o(c)
@@ -136,18 +144,48 @@ func NewCLI(client *wctl.Client, opts ...CLIOption) (*CLI, error) {
 			Action:      a(c.find),
 			Description: "search for any wallet/smart contract/transaction",
 		},
+		{
+			Name:        "trace",
+			Aliases:     []string{"t"},
+			Action:      a(c.trace),
+			Description: "replay a finalized transaction, tracing all smart contract functions it invoked",
+		},
 		{
 			Name:        "spawn",
 			Aliases:     []string{"s"},
 			Action:      a(c.spawn),
 			Description: "test deploy a smart contract",
 		},
+		{
+			Name:        "spawn-from-code",
+			Aliases:     []string{"sfc"},
+			Action:      a(c.spawnFromCode),
+			Description: "deploy a smart contract from code previously uploaded, referenced by its code ID",
+		},
 		{
 			Name:        "deposit-gas",
 			Aliases:     []string{"g"},
 			Action:      a(c.depositGas),
 			Description: "deposit gas to a smart contract",
 		},
+		{
+			Name:        "withdraw-gas",
+			Aliases:     []string{"wg"},
+			Action:      a(c.withdrawGas),
+			Description: "withdraw unused gas from a smart contract you own",
+		},
+		{
+			Name:        "upgrade-contract",
+			Aliases:     []string{"uc"},
+			Action:      a(c.upgradeContract),
+			Description: "upgrade the code of a smart contract you own",
+		},
+		{
+			Name:        "destroy-contract",
+			Aliases:     []string{"xc"},
+			Action:      a(c.destroyContract),
+			Description: "destroy a smart contract you own, refunding its gas balance",
+		},
 		{
 			Name:        "place-stake",
 			Aliases:     []string{"ps"},
@@ -166,6 +204,30 @@ func NewCLI(client *wctl.Client, opts ...CLIOption) (*CLI, error) {
 			Action:      a(c.withdrawReward),
 			Description: "withdraw rewards into PERLs",
 		},
+		{
+			Name:        "create-asset",
+			Aliases:     []string{"ca"},
+			Action:      a(c.createAsset),
+			Description: "create a native asset issued by you, crediting its initial supply to you",
+		},
+		{
+			Name:        "transfer-asset",
+			Aliases:     []string{"ta"},
+			Action:      a(c.transferAsset),
+			Description: "transfer an amount of a native asset to the address",
+		},
+		{
+			Name:        "mint-asset",
+			Aliases:     []string{"ma"},
+			Action:      a(c.mintAsset),
+			Description: "mint an amount of a native asset you issued to the address",
+		},
+		{
+			Name:        "burn-asset",
+			Aliases:     []string{"ba"},
+			Action:      a(c.burnAsset),
+			Description: "burn an amount of a native asset you issued out of your balance",
+		},
 		{
 			Name:        "connect",
 			Aliases:     []string{"cc"},
@@ -174,6 +174,24 @@ func Run(args []string, stdin io.ReadCloser, stdout io.Writer, disableGC bool) {
 			Usage:  "Maximum memory in MB allowed to be used by wavelet.",
 			EnvVar: "WAVELET_MEMORY_MAX",
 		}),
+		altsrc.NewIntFlag(cli.IntFlag{
+			Name:   "contract.module_cache.size",
+			Value:  wavelet.DefaultModuleCacheSize,
+			Usage:  "Maximum number of compiled smart contracts persisted to disk. Set to 0 to disable.",
+			EnvVar: "WAVELET_CONTRACT_MODULE_CACHE_SIZE",
+		}),
+		altsrc.NewStringFlag(cli.StringFlag{
+			Name:   "contract.module_cache.eviction",
+			Value:  "lru",
+			Usage:  "Policy for evicting compiled smart contracts once the cache is full: lru or lfu.",
+			EnvVar: "WAVELET_CONTRACT_MODULE_CACHE_EVICTION",
+		}),
+		altsrc.NewIntFlag(cli.IntFlag{
+			Name:   "sys.collapse_workers",
+			Value:  conf.GetCollapseWorkers(),
+			Usage:  "Number of workers to apply the transactions of a block across. Set to 1 to apply them in order.",
+			EnvVar: "WAVELET_COLLAPSE_WORKERS",
+		}),
 		altsrc.NewDurationFlag(cli.DurationFlag{
 			Name:  "sys.query_timeout",
 			Value: conf.GetQueryTimeout(),
@@ -276,6 +294,7 @@ func start(c *cli.Context, stdin io.ReadCloser, stdout io.Writer, disableGC bool
 		conf.WithSnowballK(c.Int("sys.snowball.k")),
 		conf.WithSnowballBeta(c.Int("sys.snowball.beta")),
 		conf.WithQueryTimeout(c.Duration("sys.query_timeout")),
+		conf.WithCollapseWorkers(c.Int("sys.collapse_workers")),
 		conf.WithSecret(secret),
 	)
 
@@ -296,6 +315,9 @@ func start(c *cli.Context, stdin io.ReadCloser, stdout io.Writer, disableGC bool
 			Peers:       c.Args(),
 			Database:    c.String("db"),
 			MaxMemoryMB: c.Uint64("memory.max"),
+			// Compiled smart contract code cache
+			ModuleCacheSize:     c.Int("contract.module_cache.size"),
+			ModuleCacheEviction: c.String("contract.module_cache.eviction"),
 			// HTTPS
 			APIHost:       c.String("api.host"),
 			APICertsCache: c.String("api.certs"),
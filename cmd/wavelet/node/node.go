@@ -37,6 +37,10 @@ type Config struct {
 	Database    string
 	MaxMemoryMB uint64
 
+	// Compiled smart contract code cache
+	ModuleCacheSize     int
+	ModuleCacheEviction string
+
 	// HTTPS
 	APIHost       string
 	APICertsCache string
@@ -53,6 +57,9 @@ var DefaultConfig = Config{
 	APIPort:  9000,
 	Peers:    []string{},
 	Database: "",
+
+	ModuleCacheSize:     wavelet.DefaultModuleCacheSize,
+	ModuleCacheEviction: "lru",
 }
 
 var (
@@ -196,6 +203,16 @@ func New(cfg *Config) (*Wavelet, error) {
 		opts = append(opts, wavelet.WithMaxMemoryMB(cfg.MaxMemoryMB))
 	}
 
+	policy := wavelet.ModuleCacheEvictLRU
+
+	if cfg.ModuleCacheEviction != "" {
+		if policy, err = wavelet.ParseModuleCacheEvictionPolicy(cfg.ModuleCacheEviction); err != nil {
+			return nil, err
+		}
+	}
+
+	opts = append(opts, wavelet.WithModuleCache(cfg.ModuleCacheSize, policy))
+
 	ledger, err := wavelet.NewLedger(kv, client, opts...)
 	if err != nil {
 		return nil, errors.Wrap(err, "error creating ledger")
@@ -21,18 +21,34 @@ package wavelet
 
 import (
 	"encoding/hex"
+
 	"github.com/perlin-network/wavelet/avl"
+	"github.com/perlin-network/wavelet/conf"
 	"github.com/perlin-network/wavelet/log"
 	"github.com/perlin-network/wavelet/sys"
 	"github.com/pkg/errors"
 )
 
 func collapseTransactions(
-	height uint64, txs []*Transaction, block *Block, accounts *Accounts,
+	height uint64, txs []*Transaction, block *Block, accounts *Accounts, engine ContractEngine,
+) (*collapseResults, error) {
+	return collapseTransactionsWithWorkers(height, txs, block, accounts, engine, conf.GetCollapseWorkers())
+}
+
+// collapseTransactionsWithWorkers collapses transactions just like collapseTransactions, applying them in parallel
+// across the given number of workers should there be more than one. The resulting state is always identical to that
+// of applying the transactions one after another.
+func collapseTransactionsWithWorkers(
+	height uint64, txs []*Transaction, block *Block, accounts *Accounts, engine ContractEngine, workers int,
 ) (*collapseResults, error) {
 	snapshot := accounts.Snapshot()
 	snapshot.SetViewID(height)
 
+	// Contract code stored under contracts themselves is migrated to be stored by its code ID as part of the
+	// first block collapsed since, so that all nodes derive the same Merkle root. Later blocks find the migration
+	// already marked as run.
+	MigrateContractCode(snapshot)
+
 	res := &collapseResults{
 		snapshot: snapshot,
 		ctx:      NewCollapseContext(snapshot),
@@ -42,6 +58,8 @@ func collapseTransactions(
 		rejectedErrors: make([]error, 0, len(txs)),
 	}
 
+	res.ctx.Engine = engine
+
 	var (
 		totalStake uint64
 		totalFee   uint64
@@ -49,51 +67,32 @@ func collapseTransactions(
 
 	stakes := make(map[AccountID]uint64)
 
-	// Apply transactions in reverse order from the end of the round
-	// all the way down to the beginning of the round.
-	for _, tx := range txs {
-		if hex.EncodeToString(tx.Sender[:]) != sys.FaucetAddress {
-			fee := tx.Fee()
-
-			senderBalance, _ := res.ctx.ReadAccountBalance(tx.Sender)
-			if senderBalance < fee {
-				res.rejected = append(res.rejected, tx)
-				res.rejectedErrors = append(
-					res.rejectedErrors,
-					errors.Errorf(
-						"stake: sender %x does not have enough PERLs to pay transaction fees (comprised of %d PERLs)",
-						tx.Sender, fee,
-					),
-				)
-				res.rejectedCount += tx.LogicalUnits()
-
-				continue
-			}
-
-			res.ctx.WriteAccountBalance(tx.Sender, senderBalance-fee)
-			totalFee += fee
+	record := func(tx *Transaction, outcome blockTxOutcome) {
+		if outcome.charged && hex.EncodeToString(tx.Sender[:]) != sys.FaucetAddress {
+			totalFee += tx.Fee()
 
-			stake, _ := res.ctx.ReadAccountStake(tx.Sender)
-			if stake >= sys.MinimumStake {
+			if outcome.stake >= sys.MinimumStake {
 				if _, ok := stakes[tx.Sender]; !ok {
-					stakes[tx.Sender] = stake
+					stakes[tx.Sender] = outcome.stake
 				} else {
-					stakes[tx.Sender] += stake
+					stakes[tx.Sender] += outcome.stake
 				}
 
-				totalStake += stake
+				totalStake += outcome.stake
 			}
 		}
 
-		if err := res.ctx.ApplyTransaction(block, tx); err != nil {
+		if outcome.err != nil {
 			res.rejected = append(res.rejected, tx)
-			res.rejectedErrors = append(res.rejectedErrors, err)
+			res.rejectedErrors = append(res.rejectedErrors, outcome.err)
 			res.rejectedCount += tx.LogicalUnits()
 
-			logger := log.Node()
-			logger.Error().Err(err).Msg("error applying transaction")
+			if outcome.charged {
+				logger := log.Node()
+				logger.Error().Err(outcome.err).Msg("error applying transaction")
+			}
 
-			continue
+			return
 		}
 
 		// Update statistics.
@@ -102,6 +101,14 @@ func collapseTransactions(
 		res.appliedCount += tx.LogicalUnits()
 	}
 
+	if workers > 1 && len(txs) > 1 {
+		applyBlockTransactionsParallel(res.ctx, block, txs, workers, record)
+	} else {
+		for _, tx := range txs {
+			record(tx, applyBlockTransaction(res.ctx, block, tx, nil))
+		}
+	}
+
 	if totalStake > 0 {
 		for sender, stake := range stakes {
 			rewardeeBalance, _ := res.ctx.ReadAccountReward(sender)
@@ -112,6 +119,7 @@ func collapseTransactions(
 	}
 
 	res.ctx.processRewardWithdrawals(block.Index)
+	res.ctx.processSchedules(block.Index)
 
 	if err := res.ctx.Flush(); err != nil {
 		return res, err
@@ -122,6 +130,73 @@ func collapseTransactions(
 	return res, nil
 }
 
+// blockTxOutcome is the outcome of applying a transaction of a block. Charged is set should the fee of the
+// transaction have been charged, in which case stake is the stake of its sender prior to it being applied.
+type blockTxOutcome struct {
+	err     error
+	charged bool
+	stake   uint64
+}
+
+// applyBlockTransaction authorizes a transaction of a block, charges its fee, and applies it. Should trace be set, a
+// trace of every smart contract invocation made while applying the transaction is recorded into it.
+//
+// Transactions are applied the exact same way when collapsing a block, and when simulating or tracing a transaction.
+// The stake of the sender is only reported, as rewards are only distributed once all transactions of a block have
+// been applied.
+func applyBlockTransaction(ctx *CollapseContext, block *Block, tx *Transaction, trace *ContractTrace) blockTxOutcome {
+	if err := authorizeSender(ctx, block, tx); err != nil {
+		return blockTxOutcome{err: err}
+	}
+
+	if err := chargeTransactionFee(ctx, tx); err != nil {
+		return blockTxOutcome{err: err}
+	}
+
+	outcome := blockTxOutcome{charged: true}
+
+	if hex.EncodeToString(tx.Sender[:]) != sys.FaucetAddress {
+		outcome.stake, _ = ctx.ReadAccountStake(tx.Sender)
+	}
+
+	outcome.err = applyTransaction(block, ctx, tx, &contractExecutorState{GasPayer: tx.Payer(), Trace: trace})
+
+	return outcome
+}
+
+// chargeTransactionFee deducts the fee of a transaction from the balance of its payer, which is its fee payer should
+// the transaction be sponsored and its sender otherwise, unless the transaction was sent by the faucet.
+func chargeTransactionFee(ctx *CollapseContext, tx *Transaction) error {
+	if hex.EncodeToString(tx.Sender[:]) == sys.FaucetAddress {
+		return nil
+	}
+
+	fee := tx.Fee()
+	payer := tx.Payer()
+
+	payerBalance, _ := ctx.ReadAccountBalance(payer)
+	if payerBalance < fee {
+		return errors.Errorf(
+			"stake: payer %x does not have enough PERLs to pay transaction fees (comprised of %d PERLs)",
+			payer, fee,
+		)
+	}
+
+	ctx.WriteAccountBalance(payer, payerBalance-fee)
+
+	return nil
+}
+
+type scheduleEntry struct {
+	info    ScheduleInfo
+	deleted bool
+}
+
+type contractStorageEntry struct {
+	value   []byte
+	deleted bool
+}
+
 // WARNING: While using this, the tree must not be modified.
 type CollapseContext struct {
 	tree     *avl.Tree
@@ -139,10 +214,62 @@ type CollapseContext struct {
 	contracts           map[TransactionID][]byte
 	contractGasBalances map[TransactionID]uint64
 	contractVMs         map[AccountID]*VMState
+	contractOwners      map[TransactionID]AccountID
+	multisigs           map[AccountID]Multisig
+	signingKeys         map[AccountID]AccountID
+
+	// Code uploaded within this context, along with the order in which it was uploaded.
+	contractCode    map[ContractCodeID][]byte
+	contractCodeIDs []ContractCodeID
+
+	// Smart contracts destroyed within this context, whose state is to be deleted from the tree upon flushing.
+	destroyedContracts map[TransactionID]struct{}
+
+	// Key-value storage of contracts, along with the order in which keys were written per contract.
+	contractStorage     map[AccountID]map[string]contractStorageEntry
+	contractStorageKeys map[AccountID][]string
+
+	// Native assets created or whose supply changed within this context, along with the order in which they were
+	// written.
+	assets   map[AssetID]AssetInfo
+	assetIDs []AssetID
+
+	// Balances of native assets held by accounts, along with the order in which they were written per account.
+	assetBalances   map[AccountID]map[AssetID]uint64
+	assetBalanceIDs map[AccountID][]AssetID
+
+	allowances   map[AccountID]map[AccountID]Allowance
+	allowanceIDs map[AccountID][]AccountID
+
+	// Hash time-locked transfers locked, claimed or refunded within this context, along with the order in which they
+	// were written.
+	htlcs   map[TransactionID]HTLCInfo
+	htlcIDs []TransactionID
+
+	// Outcomes of the entries of best-effort batch transactions applied within this context, along with the order in
+	// which they were written.
+	batchResults   map[TransactionID][]BatchEntryResult
+	batchResultIDs []TransactionID
+
+	// Scheduled transfers registered, paid or cancelled within this context, along with the order in which they were
+	// written.
+	schedules   map[TransactionID]scheduleEntry
+	scheduleIDs []TransactionID
 
 	rewardWithdrawalRequests []RewardWithdrawalRequest
 
+	// Changes made within the context since the earliest checkpoint still open, which may be reverted.
+	journal journal
+
+	// State read from and written into the context, recorded only should reads or writes be set so that transactions
+	// applied in parallel may be checked for conflicts.
+	reads  map[stateKey]struct{}
+	writes map[stateKey]struct{}
+
 	VMCache *VMLRU
+
+	// Engine, if set, is the engine smart contracts are executed with.
+	Engine ContractEngine
 }
 
 func NewCollapseContext(tree *avl.Tree) *CollapseContext {
@@ -165,21 +292,48 @@ func (c *CollapseContext) init() {
 	c.stakes = make(map[AccountID]uint64)
 	c.rewards = make(map[AccountID]uint64)
 	c.contracts = make(map[TransactionID][]byte)
+	c.contractCode = make(map[ContractCodeID][]byte)
 	c.contractGasBalances = make(map[TransactionID]uint64)
 	c.contractVMs = make(map[AccountID]*VMState)
+	c.contractOwners = make(map[TransactionID]AccountID)
+	c.multisigs = make(map[AccountID]Multisig)
+	c.signingKeys = make(map[AccountID]AccountID)
+	c.destroyedContracts = make(map[TransactionID]struct{})
+	c.contractStorage = make(map[AccountID]map[string]contractStorageEntry)
+	c.contractStorageKeys = make(map[AccountID][]string)
+	c.assets = make(map[AssetID]AssetInfo)
+	c.assetBalances = make(map[AccountID]map[AssetID]uint64)
+	c.assetBalanceIDs = make(map[AccountID][]AssetID)
+	c.allowances = make(map[AccountID]map[AccountID]Allowance)
+	c.allowanceIDs = make(map[AccountID][]AccountID)
+	c.htlcs = make(map[TransactionID]HTLCInfo)
+	c.batchResults = make(map[TransactionID][]BatchEntryResult)
+	c.schedules = make(map[TransactionID]scheduleEntry)
 
 	c.VMCache = NewVMLRU(4)
 }
 
+// engine returns the engine smart contracts are executed with.
+func (c *CollapseContext) engine() ContractEngine {
+	if c.Engine != nil {
+		return c.Engine
+	}
+
+	return NewLifeEngine(nil)
+}
+
 func (c *CollapseContext) ReadAccountsLen() uint64 {
 	return c.accountLen
 }
 
 func (c *CollapseContext) WriteAccountsLen(size uint64) {
+	c.journalAccountsLen()
 	c.accountLen = size
 }
 
 func (c *CollapseContext) ReadAccountBalance(id AccountID) (uint64, bool) {
+	c.recordRead(stateAccount, id)
+
 	if balance, ok := c.balances[id]; ok {
 		return balance, true
 	}
@@ -193,6 +347,8 @@ func (c *CollapseContext) ReadAccountBalance(id AccountID) (uint64, bool) {
 }
 
 func (c *CollapseContext) ReadAccountStake(id AccountID) (uint64, bool) {
+	c.recordRead(stateAccount, id)
+
 	if stake, ok := c.stakes[id]; ok {
 		return stake, true
 	}
@@ -206,6 +362,8 @@ func (c *CollapseContext) ReadAccountStake(id AccountID) (uint64, bool) {
 }
 
 func (c *CollapseContext) ReadAccountReward(id AccountID) (uint64, bool) {
+	c.recordRead(stateAccount, id)
+
 	if reward, ok := c.rewards[id]; ok {
 		return reward, true
 	}
@@ -219,6 +377,12 @@ func (c *CollapseContext) ReadAccountReward(id AccountID) (uint64, bool) {
 }
 
 func (c *CollapseContext) ReadAccountContractGasBalance(id TransactionID) (uint64, bool) {
+	c.recordRead(stateAccount, id)
+
+	if _, destroyed := c.destroyedContracts[id]; destroyed {
+		return 0, false
+	}
+
 	if gasBalance, ok := c.contractGasBalances[id]; ok {
 		return gasBalance, true
 	}
@@ -232,6 +396,12 @@ func (c *CollapseContext) ReadAccountContractGasBalance(id TransactionID) (uint6
 }
 
 func (c *CollapseContext) ReadAccountContractCode(id TransactionID) ([]byte, bool) {
+	c.recordRead(stateAccount, id)
+
+	if _, destroyed := c.destroyedContracts[id]; destroyed {
+		return nil, false
+	}
+
 	if code, ok := c.contracts[id]; ok {
 		return code, true
 	}
@@ -244,51 +414,357 @@ func (c *CollapseContext) ReadAccountContractCode(id TransactionID) ([]byte, boo
 	return code, exists
 }
 
+// ReadContractCode returns code by its code ID, be it code stored in the tree or code uploaded within this context.
+func (c *CollapseContext) ReadContractCode(codeID ContractCodeID) ([]byte, bool) {
+	c.recordRead(stateContractCode, codeID)
+
+	if code, ok := c.contractCode[codeID]; ok {
+		return code, true
+	}
+
+	return ReadContractCode(c.tree, codeID)
+}
+
+func (c *CollapseContext) ReadAccountContractStorage(id TransactionID, key []byte) ([]byte, bool) {
+	c.recordRead(stateAccount, id)
+
+	if _, destroyed := c.destroyedContracts[id]; destroyed {
+		return nil, false
+	}
+
+	if entry, ok := c.contractStorage[id][string(key)]; ok {
+		return entry.value, !entry.deleted
+	}
+
+	return ReadAccountContractStorage(c.tree, id, key)
+}
+
+func (c *CollapseContext) ReadAccountContractOwner(id TransactionID) (AccountID, bool) {
+	c.recordRead(stateAccount, id)
+
+	if _, destroyed := c.destroyedContracts[id]; destroyed {
+		return AccountID{}, false
+	}
+
+	if owner, ok := c.contractOwners[id]; ok {
+		return owner, true
+	}
+
+	owner, exists := ReadAccountContractOwner(c.tree, id)
+	if exists {
+		c.contractOwners[id] = owner
+	}
+
+	return owner, exists
+}
+
+func (c *CollapseContext) ReadAccountMultisig(id AccountID) (Multisig, bool) {
+	c.recordRead(stateAccount, id)
+
+	if multisig, ok := c.multisigs[id]; ok {
+		return multisig, true
+	}
+
+	multisig, exists := ReadAccountMultisig(c.tree, id)
+	if exists {
+		c.multisigs[id] = multisig
+	}
+
+	return multisig, exists
+}
+
+func (c *CollapseContext) ReadAccountSigningKey(id AccountID) (AccountID, bool) {
+	c.recordRead(stateAccount, id)
+
+	if key, ok := c.signingKeys[id]; ok {
+		return key, true
+	}
+
+	key, exists := ReadAccountSigningKey(c.tree, id)
+	if exists {
+		c.signingKeys[id] = key
+	}
+
+	return key, exists
+}
+
+func (c *CollapseContext) ReadAsset(id AssetID) (AssetInfo, bool) {
+	c.recordRead(stateAsset, id)
+
+	if info, ok := c.assets[id]; ok {
+		return info, true
+	}
+
+	return ReadAsset(c.tree, id)
+}
+
+func (c *CollapseContext) ReadAccountAssetBalance(id AccountID, asset AssetID) (uint64, bool) {
+	c.recordRead(stateAccount, id)
+
+	if balance, ok := c.assetBalances[id][asset]; ok {
+		return balance, true
+	}
+
+	return ReadAccountAssetBalance(c.tree, id, asset)
+}
+
+func (c *CollapseContext) ReadAccountAllowance(owner AccountID, spender AccountID) (Allowance, bool) {
+	c.recordRead(stateAccount, owner)
+
+	if allowance, ok := c.allowances[owner][spender]; ok {
+		return allowance, allowance.Amount > 0
+	}
+
+	return ReadAccountAllowance(c.tree, owner, spender)
+}
+
+func (c *CollapseContext) ReadHTLC(id TransactionID) (HTLCInfo, bool) {
+	c.recordRead(stateHTLC, id)
+
+	if info, ok := c.htlcs[id]; ok {
+		return info, true
+	}
+
+	return ReadHTLC(c.tree, id)
+}
+
+func (c *CollapseContext) ReadBatchResults(id TransactionID) ([]BatchEntryResult, bool) {
+	c.recordRead(stateBatchResults, id)
+
+	if results, ok := c.batchResults[id]; ok {
+		return results, true
+	}
+
+	return ReadBatchResults(c.tree, id)
+}
+
+func (c *CollapseContext) ReadSchedule(id TransactionID) (ScheduleInfo, bool) {
+	c.recordRead(stateSchedule, id)
+
+	if entry, ok := c.schedules[id]; ok {
+		return entry.info, !entry.deleted
+	}
+
+	return ReadSchedule(c.tree, id)
+}
+
 func (c *CollapseContext) GetContractState(id AccountID) (*VMState, bool) {
+	c.recordRead(stateAccount, id)
+
 	vm, exists := c.contractVMs[id]
 	return vm, exists
 }
 
 func (c *CollapseContext) addAccount(id AccountID) {
+	c.recordWrite(stateAccount, id)
+
 	if _, ok := c.accounts[id]; ok {
 		return
 	}
 
+	c.journalNewAccount(id)
 	c.accounts[id] = struct{}{}
 	c.accountIDs = append(c.accountIDs, id)
 }
 
 func (c *CollapseContext) WriteAccountBalance(id AccountID, balance uint64) {
 	c.addAccount(id)
+	c.journalUint64(c.balances, id)
 	c.balances[id] = balance
 }
 
 func (c *CollapseContext) WriteAccountStake(id AccountID, stake uint64) {
 	c.addAccount(id)
+	c.journalUint64(c.stakes, id)
 	c.stakes[id] = stake
 }
 
 func (c *CollapseContext) WriteAccountReward(id AccountID, reward uint64) {
 	c.addAccount(id)
+	c.journalUint64(c.rewards, id)
 	c.rewards[id] = reward
 }
 
 func (c *CollapseContext) WriteAccountContractGasBalance(id TransactionID, gasBalance uint64) {
 	c.addAccount(id)
+	c.journalUint64(c.contractGasBalances, id)
 	c.contractGasBalances[id] = gasBalance
 }
 
+// WriteAccountContractCode sets the code of a contract. The code is uploaded such that it is stored upon
+// flushing under its code ID, even should the contract be destroyed within this context.
 func (c *CollapseContext) WriteAccountContractCode(id TransactionID, code []byte) {
 	c.addAccount(id)
+
+	codeID := HashContractCode(code)
+	c.recordWrite(stateContractCode, codeID)
+	c.journalContractCode(id, codeID)
+
 	c.contracts[id] = code
+
+	if _, ok := c.contractCode[codeID]; !ok {
+		c.contractCode[codeID] = code
+		c.contractCodeIDs = append(c.contractCodeIDs, codeID)
+	}
+}
+
+func (c *CollapseContext) WriteAccountContractOwner(id TransactionID, owner AccountID) {
+	c.addAccount(id)
+	c.journalID(c.contractOwners, id)
+	c.contractOwners[id] = owner
+}
+
+func (c *CollapseContext) WriteAccountMultisig(id AccountID, multisig Multisig) {
+	c.addAccount(id)
+	c.journalMultisig(id)
+	c.multisigs[id] = multisig
+}
+
+func (c *CollapseContext) WriteAccountSigningKey(id AccountID, key AccountID) {
+	c.addAccount(id)
+	c.journalID(c.signingKeys, id)
+	c.signingKeys[id] = key
+}
+
+func (c *CollapseContext) WriteAsset(id AssetID, info AssetInfo) {
+	c.recordWrite(stateAsset, id)
+	c.journalAsset(id)
+
+	if _, ok := c.assets[id]; !ok {
+		c.assetIDs = append(c.assetIDs, id)
+	}
+
+	c.assets[id] = info
+}
+
+func (c *CollapseContext) WriteAccountAssetBalance(id AccountID, asset AssetID, balance uint64) {
+	c.addAccount(id)
+
+	balances, ok := c.assetBalances[id]
+	if !ok {
+		balances = make(map[AssetID]uint64)
+		c.assetBalances[id] = balances
+	}
+
+	c.journalAssetBalance(id, asset)
+
+	if _, exists := balances[asset]; !exists {
+		c.assetBalanceIDs[id] = append(c.assetBalanceIDs[id], asset)
+	}
+
+	balances[asset] = balance
+}
+
+func (c *CollapseContext) WriteAccountAllowance(owner AccountID, allowance Allowance) {
+	c.addAccount(owner)
+
+	allowances, ok := c.allowances[owner]
+	if !ok {
+		allowances = make(map[AccountID]Allowance)
+		c.allowances[owner] = allowances
+	}
+
+	c.journalAllowance(owner, allowance.Spender)
+
+	if _, exists := allowances[allowance.Spender]; !exists {
+		c.allowanceIDs[owner] = append(c.allowanceIDs[owner], allowance.Spender)
+	}
+
+	allowances[allowance.Spender] = allowance
+}
+
+func (c *CollapseContext) WriteHTLC(id TransactionID, info HTLCInfo) {
+	c.recordWrite(stateHTLC, id)
+	c.journalHTLC(id)
+
+	if _, ok := c.htlcs[id]; !ok {
+		c.htlcIDs = append(c.htlcIDs, id)
+	}
+
+	c.htlcs[id] = info
+}
+
+func (c *CollapseContext) WriteBatchResults(id TransactionID, results []BatchEntryResult) {
+	c.recordWrite(stateBatchResults, id)
+	c.journalBatchResults(id)
+
+	if _, ok := c.batchResults[id]; !ok {
+		c.batchResultIDs = append(c.batchResultIDs, id)
+	}
+
+	c.batchResults[id] = results
+}
+
+func (c *CollapseContext) WriteSchedule(id TransactionID, info ScheduleInfo) {
+	c.putSchedule(id, scheduleEntry{info: info})
+}
+
+func (c *CollapseContext) DeleteSchedule(id TransactionID) {
+	c.putSchedule(id, scheduleEntry{deleted: true})
+}
+
+func (c *CollapseContext) putSchedule(id TransactionID, entry scheduleEntry) {
+	c.recordWrite(stateSchedule, id)
+	c.journalSchedule(id)
+
+	if _, ok := c.schedules[id]; !ok {
+		c.scheduleIDs = append(c.scheduleIDs, id)
+	}
+
+	c.schedules[id] = entry
+}
+
+// DestroyAccountContract marks a smart contract as destroyed, discarding all pending changes to its code,
+// memory, gas balance, owner and key-value storage. Its state is deleted from the tree upon flushing.
+func (c *CollapseContext) DestroyAccountContract(id TransactionID) {
+	c.addAccount(id)
+	c.journalDestroyedContract(id)
+	c.destroyedContracts[id] = struct{}{}
+
+	delete(c.contracts, id)
+	delete(c.contractGasBalances, id)
+	delete(c.contractVMs, id)
+	delete(c.contractOwners, id)
+	delete(c.contractStorage, id)
+	delete(c.contractStorageKeys, id)
+
+	c.VMCache.Remove(id)
+}
+
+func (c *CollapseContext) WriteAccountContractStorage(id TransactionID, key, value []byte) {
+	c.addAccount(id)
+	c.putContractStorage(id, key, contractStorageEntry{value: value})
+}
+
+func (c *CollapseContext) DeleteAccountContractStorage(id TransactionID, key []byte) {
+	c.addAccount(id)
+	c.putContractStorage(id, key, contractStorageEntry{deleted: true})
+}
+
+func (c *CollapseContext) putContractStorage(id TransactionID, key []byte, entry contractStorageEntry) {
+	entries, ok := c.contractStorage[id]
+	if !ok {
+		entries = make(map[string]contractStorageEntry)
+		c.contractStorage[id] = entries
+	}
+
+	c.journalContractStorage(id, string(key))
+
+	if _, exists := entries[string(key)]; !exists {
+		c.contractStorageKeys[id] = append(c.contractStorageKeys[id], string(key))
+	}
+
+	entries[string(key)] = entry
 }
 
 func (c *CollapseContext) SetContractState(id AccountID, state *VMState) {
 	c.addAccount(id)
+	c.journalContractState(id)
 	c.contractVMs[id] = state
 }
 
 func (c *CollapseContext) StoreRewardWithdrawalRequest(rw RewardWithdrawalRequest) {
+	c.journalRewardWithdrawalRequests()
 	c.rewardWithdrawalRequests = append(c.rewardWithdrawalRequests, rw)
 }
 
@@ -311,9 +787,45 @@ func (c *CollapseContext) processRewardWithdrawals(blockIndex uint64) {
 		c.WriteAccountBalance(rw.account, balance+rw.amount)
 	}
 
+	c.journalRewardWithdrawalRequests()
 	c.rewardWithdrawalRequests = leftovers
 }
 
+// processSchedules pays the transfers of all scheduled transfers due at or before the block height, be they stored in
+// the tree or registered within this context. Scheduled transfers are processed in the order in which they are due,
+// followed by those registered within this context in the order in which they were registered. Scheduled transfers
+// are deleted once all of their transfers have been paid.
+func (c *CollapseContext) processSchedules(blockIndex uint64) {
+	due := ReadDueSchedules(c.tree, blockIndex)
+
+	for _, id := range c.scheduleIDs {
+		if _, stored := ReadSchedule(c.tree, id); !stored {
+			due = append(due, id)
+		}
+	}
+
+	for _, id := range due {
+		info, exists := c.ReadSchedule(id)
+		if !exists || info.Next > blockIndex {
+			continue
+		}
+
+		for info.Remaining > 0 && info.Next <= blockIndex {
+			balance, _ := c.ReadAccountBalance(info.Recipient)
+			c.WriteAccountBalance(info.Recipient, balance+info.Amount)
+
+			info.Remaining--
+			info.Next += info.Interval
+		}
+
+		if info.Remaining == 0 {
+			c.DeleteSchedule(id)
+		} else {
+			c.WriteSchedule(id, info)
+		}
+	}
+}
+
 //func (c *CollapseContext) processFinalizedTransactions(height uint64, finalized []*Transaction) {
 //	pruningLimit := uint64(conf.GetPruningLimit())
 //
@@ -350,6 +862,32 @@ func (c *CollapseContext) Flush() error {
 
 	WriteAccountsLen(c.tree, c.accountLen)
 
+	for _, codeID := range c.contractCodeIDs {
+		WriteContractCode(c.tree, c.contractCode[codeID])
+	}
+
+	for _, id := range c.assetIDs {
+		WriteAsset(c.tree, id, c.assets[id])
+	}
+
+	for _, id := range c.htlcIDs {
+		WriteHTLC(c.tree, id, c.htlcs[id])
+	}
+
+	for _, id := range c.batchResultIDs {
+		WriteBatchResults(c.tree, id, c.batchResults[id])
+	}
+
+	for _, id := range c.scheduleIDs {
+		if old, exists := ReadSchedule(c.tree, id); exists {
+			DeleteSchedule(c.tree, id, old)
+		}
+
+		if entry := c.schedules[id]; !entry.deleted {
+			WriteSchedule(c.tree, id, entry.info)
+		}
+	}
+
 	for _, id := range c.accountIDs {
 		if bal, ok := c.balances[id]; ok {
 			WriteAccountBalance(c.tree, id, bal)
@@ -363,6 +901,27 @@ func (c *CollapseContext) Flush() error {
 			WriteAccountReward(c.tree, id, reward)
 		}
 
+		if multisig, ok := c.multisigs[id]; ok {
+			WriteAccountMultisig(c.tree, id, multisig)
+		}
+
+		if key, ok := c.signingKeys[id]; ok {
+			WriteAccountSigningKey(c.tree, id, key)
+		}
+
+		for _, asset := range c.assetBalanceIDs[id] {
+			WriteAccountAssetBalance(c.tree, id, asset, c.assetBalances[id][asset])
+		}
+
+		for _, spender := range c.allowanceIDs[id] {
+			WriteAccountAllowance(c.tree, id, c.allowances[id][spender])
+		}
+
+		if _, destroyed := c.destroyedContracts[id]; destroyed {
+			DeleteAccountContract(c.tree, id)
+			continue
+		}
+
 		if gasBal, ok := c.contractGasBalances[id]; ok {
 			WriteAccountContractGasBalance(c.tree, id, gasBal)
 		}
@@ -371,10 +930,22 @@ func (c *CollapseContext) Flush() error {
 			WriteAccountContractCode(c.tree, id, code)
 		}
 
+		if owner, ok := c.contractOwners[id]; ok {
+			WriteAccountContractOwner(c.tree, id, owner)
+		}
+
 		if vm, ok := c.contractVMs[id]; ok {
-			SaveContractMemorySnapshot(c.tree, id, vm.Memory)
+			SaveContractMemorySnapshot(c.tree, id, vm.Memory, vm.Dirty)
 			SaveContractGlobals(c.tree, id, vm.Globals)
 		}
+
+		for _, key := range c.contractStorageKeys[id] {
+			if entry := c.contractStorage[id][key]; entry.deleted {
+				DeleteAccountContractStorage(c.tree, id, []byte(key))
+			} else {
+				WriteAccountContractStorage(c.tree, id, []byte(key), entry.value)
+			}
+		}
 	}
 
 	return nil
@@ -384,10 +955,19 @@ func (c *CollapseContext) Flush() error {
 // After you've finished, you MUST call CollapseContext.Flush() to actually write the states into the tree.
 func (c *CollapseContext) ApplyTransaction(block *Block, tx *Transaction) error {
 	if err := applyTransaction(block, c, tx, &contractExecutorState{
-		GasPayer: tx.Sender,
+		GasPayer: tx.Payer(),
 	}); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// TraceTransaction applies a transaction just like ApplyTransaction, recording a trace of every smart contract
+// invocation made while applying it into trace.
+func (c *CollapseContext) TraceTransaction(block *Block, tx *Transaction, trace *ContractTrace) error {
+	return applyTransaction(block, c, tx, &contractExecutorState{
+		GasPayer: tx.Payer(),
+		Trace:    trace,
+	})
+}
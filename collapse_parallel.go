@@ -0,0 +1,234 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// stateKind denotes the kind of state a stateKey refers to.
+type stateKind uint8
+
+const (
+	stateAccount stateKind = iota
+	stateContractCode
+	stateAsset
+	stateHTLC
+	stateSchedule
+	stateBatchResults
+)
+
+// stateKey identifies a piece of state read from or written into a CollapseContext. All state of an account, be it
+// its balance, stake, smart contract or anything else stored under it, is identified by the ID of the account.
+type stateKey struct {
+	kind stateKind
+	id   [32]byte
+}
+
+func (c *CollapseContext) recordRead(kind stateKind, id [32]byte) {
+	if c.reads != nil {
+		c.reads[stateKey{kind: kind, id: id}] = struct{}{}
+	}
+}
+
+func (c *CollapseContext) recordWrite(kind stateKind, id [32]byte) {
+	if c.writes != nil {
+		c.writes[stateKey{kind: kind, id: id}] = struct{}{}
+	}
+}
+
+// speculation is the outcome of optimistically applying a transaction of a block against the state prior to the
+// block, in a context of its own.
+type speculation struct {
+	ctx     *CollapseContext
+	outcome blockTxOutcome
+}
+
+// applyBlockTransactionsParallel applies the transactions of a block into ctx, which must hold no changes, with the
+// exact same outcome as applying them one after another through applyBlockTransaction. The outcome of each
+// transaction is passed to record in the order in which the transactions are given.
+//
+// Each transaction is first optimistically applied by one of the workers against the state prior to the block, in a
+// context of its own that records all state the transaction read. The changes made by each transaction are then
+// merged into ctx in order, unless the transaction read state written by any transaction before it, in which case the
+// transaction is applied once more into ctx directly.
+func applyBlockTransactionsParallel(
+	ctx *CollapseContext, block *Block, txs []*Transaction, workers int, record func(*Transaction, blockTxOutcome),
+) {
+	speculations := make([]speculation, len(txs))
+
+	// VMs instantiated by any worker are shared with all others, such that each smart contract invoked throughout
+	// the block is only instantiated once per version of its code.
+	codeVMs := NewVMLRU(4 * workers)
+
+	var (
+		next uint64
+		wg   sync.WaitGroup
+	)
+
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for {
+				idx := atomic.AddUint64(&next, 1) - 1
+				if idx >= uint64(len(txs)) {
+					return
+				}
+
+				spec := NewCollapseContext(ctx.tree)
+				spec.Engine = ctx.Engine
+				spec.VMCache.code = codeVMs
+				spec.reads = make(map[stateKey]struct{})
+
+				speculations[idx] = speculation{ctx: spec, outcome: applyBlockTransaction(spec, block, txs[idx], nil)}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	ctx.writes = make(map[stateKey]struct{})
+	defer func() { ctx.writes = nil }()
+
+	for i, tx := range txs {
+		spec := speculations[i]
+		speculations[i] = speculation{}
+
+		if spec.ctx.conflicts(ctx.writes) {
+			record(tx, applyBlockTransaction(ctx, block, tx, nil))
+			continue
+		}
+
+		ctx.merge(spec.ctx)
+		record(tx, spec.outcome)
+	}
+}
+
+// conflicts returns true if the changes made within a speculative context may not be merged, as it read state that
+// was written. Contexts in which smart contracts were destroyed are never merged.
+func (c *CollapseContext) conflicts(writes map[stateKey]struct{}) bool {
+	if len(c.destroyedContracts) > 0 {
+		return true
+	}
+
+	for key := range c.reads {
+		if _, written := writes[key]; written {
+			return true
+		}
+	}
+
+	return false
+}
+
+// merge applies all changes made within a speculative context into c, in the same order in which they were made.
+// Values the speculative context cached out of the tree are merged as well, as they are identical to those c would
+// read given that the speculative context does not conflict with c.
+func (c *CollapseContext) merge(spec *CollapseContext) {
+	for _, id := range spec.accountIDs {
+		c.addAccount(id)
+	}
+
+	for id, balance := range spec.balances {
+		c.balances[id] = balance
+	}
+
+	for id, stake := range spec.stakes {
+		c.stakes[id] = stake
+	}
+
+	for id, reward := range spec.rewards {
+		c.rewards[id] = reward
+	}
+
+	for id, gasBalance := range spec.contractGasBalances {
+		c.contractGasBalances[id] = gasBalance
+	}
+
+	for id, multisig := range spec.multisigs {
+		c.multisigs[id] = multisig
+	}
+
+	for id, key := range spec.signingKeys {
+		c.signingKeys[id] = key
+	}
+
+	for id, owner := range spec.contractOwners {
+		c.contractOwners[id] = owner
+	}
+
+	for id, vm := range spec.contractVMs {
+		c.contractVMs[id] = vm
+	}
+
+	// VMs cached for smart contracts whose code may have changed are invalidated.
+	for id, code := range spec.contracts {
+		c.contracts[id] = code
+		c.VMCache.Remove(id)
+	}
+
+	for _, codeID := range spec.contractCodeIDs {
+		c.recordWrite(stateContractCode, codeID)
+
+		if _, ok := c.contractCode[codeID]; !ok {
+			c.contractCode[codeID] = spec.contractCode[codeID]
+			c.contractCodeIDs = append(c.contractCodeIDs, codeID)
+		}
+	}
+
+	for id, keys := range spec.contractStorageKeys {
+		for _, key := range keys {
+			c.putContractStorage(id, []byte(key), spec.contractStorage[id][key])
+		}
+	}
+
+	for _, id := range spec.assetIDs {
+		c.WriteAsset(id, spec.assets[id])
+	}
+
+	for id, assets := range spec.assetBalanceIDs {
+		for _, asset := range assets {
+			c.WriteAccountAssetBalance(id, asset, spec.assetBalances[id][asset])
+		}
+	}
+
+	for owner, spenders := range spec.allowanceIDs {
+		for _, spender := range spenders {
+			c.WriteAccountAllowance(owner, spec.allowances[owner][spender])
+		}
+	}
+
+	for _, id := range spec.htlcIDs {
+		c.WriteHTLC(id, spec.htlcs[id])
+	}
+
+	for _, id := range spec.batchResultIDs {
+		c.WriteBatchResults(id, spec.batchResults[id])
+	}
+
+	for _, id := range spec.scheduleIDs {
+		c.putSchedule(id, spec.schedules[id])
+	}
+
+	c.rewardWithdrawalRequests = append(c.rewardWithdrawalRequests, spec.rewardWithdrawalRequests...)
+}
@@ -27,6 +27,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/perlin-network/noise/edwards25519"
 	"github.com/perlin-network/noise/skademlia"
 	"github.com/perlin-network/wavelet/avl"
 	"github.com/perlin-network/wavelet/store"
@@ -180,6 +181,316 @@ func TestCollapseContext(t *testing.T) {
 	f(true)
 }
 
+func TestCollapseContextContractStorage(t *testing.T) {
+	state := avl.New(store.NewInmem())
+
+	var id AccountID
+	_, err := rand.Read(id[:])
+	assert.NoError(t, err)
+
+	WriteAccountContractStorage(state, id, []byte("a"), []byte("1"))
+	WriteAccountContractStorage(state, id, []byte("b"), []byte("2"))
+
+	ctx := NewCollapseContext(state)
+
+	value, exists := ctx.ReadAccountContractStorage(id, []byte("a"))
+	assert.True(t, exists)
+	assert.Equal(t, []byte("1"), value)
+
+	ctx.WriteAccountContractStorage(id, []byte("a"), []byte("3"))
+	ctx.DeleteAccountContractStorage(id, []byte("b"))
+	ctx.WriteAccountContractStorage(id, []byte("c"), []byte("4"))
+
+	value, exists = ctx.ReadAccountContractStorage(id, []byte("a"))
+	assert.True(t, exists)
+	assert.Equal(t, []byte("3"), value)
+
+	_, exists = ctx.ReadAccountContractStorage(id, []byte("b"))
+	assert.False(t, exists)
+
+	// Nothing is written into the tree until the context is flushed.
+	_, exists = ReadAccountContractStorage(state, id, []byte("c"))
+	assert.False(t, exists)
+
+	assert.NoError(t, ctx.Flush())
+
+	value, exists = ReadAccountContractStorage(state, id, []byte("a"))
+	assert.True(t, exists)
+	assert.Equal(t, []byte("3"), value)
+
+	_, exists = ReadAccountContractStorage(state, id, []byte("b"))
+	assert.False(t, exists)
+
+	value, exists = ReadAccountContractStorage(state, id, []byte("c"))
+	assert.True(t, exists)
+	assert.Equal(t, []byte("4"), value)
+}
+
+func TestCollapseContextCheckpoint(t *testing.T) {
+	build := func() *avl.Tree {
+		state := avl.New(store.NewInmem())
+
+		WriteAccountBalance(state, AccountID{1}, 100)
+		WriteAccountContractCode(state, AccountID{2}, []byte("code"))
+		WriteAccountContractStorage(state, AccountID{2}, []byte("a"), []byte("1"))
+
+		return state
+	}
+
+	// The same changes are made to both contexts, apart from those made to the second context that are reverted.
+	write := func(ctx *CollapseContext) {
+		ctx.WriteAccountBalance(AccountID{1}, 50)
+		ctx.WriteAccountContractStorage(AccountID{2}, []byte("b"), []byte("2"))
+		ctx.WriteAccountStake(AccountID{3}, 10)
+	}
+
+	expected := build()
+	expectedCtx := NewCollapseContext(expected)
+	write(expectedCtx)
+
+	state := build()
+	ctx := NewCollapseContext(state)
+
+	outer := ctx.Checkpoint()
+	write(ctx)
+
+	inner := ctx.Checkpoint()
+
+	ctx.WriteAccountBalance(AccountID{1}, 10)
+	ctx.WriteAccountBalance(AccountID{4}, 10)
+	ctx.WriteAccountsLen(10)
+	ctx.WriteAccountContractStorage(AccountID{2}, []byte("a"), []byte("3"))
+	ctx.WriteAccountContractStorage(AccountID{2}, []byte("c"), []byte("4"))
+	ctx.DestroyAccountContract(AccountID{2})
+	ctx.WriteAccountContractCode(AccountID{5}, []byte("other code"))
+	ctx.WriteAsset(AssetID{6}, AssetInfo{Issuer: AccountID{1}, Symbol: "TST", Supply: 100})
+	ctx.WriteAccountAssetBalance(AccountID{1}, AssetID{6}, 100)
+	ctx.WriteAccountAllowance(AccountID{1}, Allowance{Spender: AccountID{4}, Amount: 10})
+	ctx.WriteHTLC(TransactionID{7}, HTLCInfo{Sender: AccountID{1}, Amount: 10})
+	ctx.WriteSchedule(TransactionID{8}, ScheduleInfo{Sender: AccountID{1}, Amount: 10, Remaining: 1})
+	ctx.StoreRewardWithdrawalRequest(RewardWithdrawalRequest{account: AccountID{1}, amount: 10})
+
+	// Reverting to the inner checkpoint keeps the changes made prior to it.
+	ctx.RevertToCheckpoint(inner)
+
+	balance, _ := ctx.ReadAccountBalance(AccountID{1})
+	assert.EqualValues(t, 50, balance)
+
+	_, exists := ctx.ReadAccountContractCode(AccountID{2})
+	assert.True(t, exists)
+
+	value, _ := ctx.ReadAccountContractStorage(AccountID{2}, []byte("a"))
+	assert.Equal(t, []byte("1"), value)
+
+	_, exists = ctx.ReadAccountContractStorage(AccountID{2}, []byte("c"))
+	assert.False(t, exists)
+
+	assert.Equal(t, expectedCtx.accountIDs, ctx.accountIDs)
+	assert.Empty(t, ctx.rewardWithdrawalRequests)
+
+	ctx.DiscardCheckpoint(outer)
+
+	assert.NoError(t, expectedCtx.Flush())
+	assert.NoError(t, ctx.Flush())
+
+	assert.Equal(t, expected.Checksum(), state.Checksum())
+
+	// Reverting to the outer checkpoint reverts all changes.
+	ctx = NewCollapseContext(state)
+
+	outer = ctx.Checkpoint()
+	write(ctx)
+	ctx.WriteAccountBalance(AccountID{1}, 20)
+
+	inner = ctx.Checkpoint()
+	ctx.WriteAccountBalance(AccountID{1}, 30)
+	ctx.DiscardCheckpoint(inner)
+
+	ctx.RevertToCheckpoint(outer)
+
+	balance, _ = ctx.ReadAccountBalance(AccountID{1})
+	assert.EqualValues(t, 50, balance)
+	assert.Empty(t, ctx.accountIDs)
+	assert.Empty(t, ctx.journal.undo)
+}
+
+// TestCollapseTransactionsParallel checks that applying random workloads of transactions in parallel yields the exact
+// same state as applying them one after another.
+func TestCollapseTransactionsParallel(t *testing.T) {
+	transferBack, err := ioutil.ReadFile("testdata/transfer_back.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	dummy, err := ioutil.ReadFile("testdata/dummy.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	keys := make([]*skademlia.Keypair, 6)
+
+	for i := range keys {
+		keys[i], err = skademlia.NewKeys(1, 1)
+		if !assert.NoError(t, err) {
+			return
+		}
+	}
+
+	spawnPayload, err := buildContractSpawnPayload(1000000, 0, transferBack).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	spawn := buildSignedTransaction(keys[0], sys.TagContract, 1, 0, spawnPayload)
+	contractID := spawn.ID
+
+	setup := func() *Accounts {
+		accounts := NewAccounts(store.NewInmem())
+		snapshot := accounts.Snapshot()
+
+		for i, key := range keys {
+			WriteAccountBalance(snapshot, key.PublicKey(), 1000000000+uint64(i)*1000)
+			WriteAccountStake(snapshot, key.PublicKey(), sys.MinimumStake*uint64(i))
+		}
+
+		genesis := NewBlock(0, snapshot.Checksum())
+		if !assert.NoError(t, ApplyTransaction(snapshot, &genesis, &spawn)) {
+			t.FailNow()
+		}
+
+		if !assert.NoError(t, accounts.Commit(snapshot)) {
+			t.FailNow()
+		}
+
+		return accounts
+	}
+
+	for seed := int64(0); seed < 4; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+
+		var (
+			nonce  uint64 = 1
+			assets []AssetID
+			txs    []*Transaction
+		)
+
+		account := func() AccountID {
+			return keys[rng.Intn(len(keys))].PublicKey()
+		}
+
+		for i := 0; i < 300; i++ {
+			sender := keys[rng.Intn(len(keys))]
+			recipient := account()
+
+			// A few fresh accounts are also sent PERLs.
+			if rng.Intn(4) == 0 {
+				recipient = AccountID{0xFF, byte(rng.Intn(4))}
+			}
+
+			var (
+				tag     sys.Tag
+				payload []byte
+			)
+
+			switch rng.Intn(11) {
+			case 0, 1:
+				tag = sys.TagTransfer
+				payload, err = buildTransferPayload(recipient, uint64(rng.Intn(600000000))).Marshal()
+			case 2:
+				tag = sys.TagStake
+				payload, err = buildPlaceStakePayload(uint64(rng.Intn(1000)) * sys.MinimumStake).Marshal()
+			case 3:
+				tag = sys.TagStake
+				payload, err = buildWithdrawStakePayload(uint64(rng.Intn(1000)) * sys.MinimumStake).Marshal()
+			case 4:
+				batch := Batch{BestEffort: rng.Intn(2) == 0}
+
+				for j := 0; j < 3; j++ {
+					assert.NoError(t, batch.AddTransfer(buildTransferPayload(account(), uint64(rng.Intn(400000000)))))
+				}
+
+				tag = sys.TagBatch
+				payload, err = batch.Marshal()
+			case 5:
+				tag = sys.TagTransfer
+				payload, err = buildTransferWithInvocationPayload(
+					contractID, uint64(rng.Intn(1000)), 500000, []byte("on_money_received"), nil, 0,
+				).Marshal()
+			case 6:
+				tag = sys.TagContract
+				payload, err = buildContractSpawnPayload(100000, 0, dummy).Marshal()
+			case 7:
+				tag = sys.TagTransfer
+				payload, err = Transfer{
+					Opcode: sys.ApproveAllowance, Recipient: account(), Amount: uint64(rng.Intn(1000)),
+				}.Marshal()
+			case 8:
+				tag = sys.TagTransfer
+				payload, err = Transfer{
+					Opcode: sys.TransferFromAllowance, Owner: account(), Recipient: recipient, Amount: 1 + uint64(rng.Intn(1000)),
+				}.Marshal()
+			case 9:
+				tag = sys.TagAsset
+
+				if len(assets) == 0 || rng.Intn(3) == 0 {
+					payload, err = Asset{Opcode: sys.CreateAsset, Symbol: "TST", Supply: 1000}.Marshal()
+				} else {
+					payload, err = Asset{
+						Opcode: sys.TransferAsset, AssetID: assets[rng.Intn(len(assets))], Recipient: recipient,
+						Amount: uint64(rng.Intn(600)),
+					}.Marshal()
+				}
+			case 10:
+				tag = sys.TagSchedule
+				payload, err = Schedule{
+					Opcode: sys.CreateSchedule, Recipient: recipient, Amount: uint64(rng.Intn(1000)), Start: 1,
+					Interval: 1, Count: 1 + uint64(rng.Intn(3)),
+				}.Marshal()
+			}
+
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			nonce++
+			tx := buildSignedTransaction(sender, tag, nonce, 1, payload)
+			txs = append(txs, &tx)
+
+			if tag == sys.TagAsset && len(payload) > 0 && payload[0] == sys.CreateAsset {
+				assets = append(assets, tx.ID)
+			}
+		}
+
+		accounts := setup()
+		block := NewBlock(1, accounts.tree.Checksum())
+
+		serial, err := collapseTransactionsWithWorkers(block.Index, txs, &block, accounts, nil, 1)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		accounts = setup()
+
+		parallel, err := collapseTransactionsWithWorkers(block.Index, txs, &block, accounts, nil, 4)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.NotEmpty(t, serial.applied)
+		assert.NotEmpty(t, serial.rejected)
+
+		assert.Equal(t, serial.snapshot.Checksum(), parallel.snapshot.Checksum(), "seed %d", seed)
+		assert.Equal(t, serial.applied, parallel.applied, "seed %d", seed)
+		assert.Equal(t, serial.rejected, parallel.rejected, "seed %d", seed)
+		if assert.Len(t, parallel.rejectedErrors, len(serial.rejectedErrors), "seed %d", seed) {
+			for i := range serial.rejectedErrors {
+				assert.EqualError(t, parallel.rejectedErrors[i], serial.rejectedErrors[i].Error(), "seed %d", seed)
+			}
+		}
+		assert.Equal(t, serial.appliedCount, parallel.appliedCount, "seed %d", seed)
+	}
+}
+
 type collapseTestContainer struct {
 	accounts   map[AccountID]*skademlia.Keypair
 	accountIDs []AccountID
@@ -248,7 +559,7 @@ func (g *collapseTestContainer) applyContract(b *testing.B, code []byte) (Transa
 	nonce := uint64(time.Now().UnixNano())
 	tx := NewTransaction(sender, nonce+1, g.block.Index, sys.TagContract, payload)
 
-	results, err := collapseTransactions(g.block.Index, []*Transaction{&tx}, g.block, g.accountState)
+	results, err := collapseTransactions(g.block.Index, []*Transaction{&tx}, g.block, g.accountState, nil)
 	if err != nil {
 		return Transaction{}, err
 	}
@@ -280,7 +591,7 @@ func (g *collapseTestContainer) collapseTransactionsNewState(b *testing.B) (*col
 
 	b.StartTimer()
 
-	results, err := collapseTransactions(g.block.Index, g.txs, g.block, accountState)
+	results, err := collapseTransactions(g.block.Index, g.txs, g.block, accountState, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -688,3 +999,301 @@ func BenchmarkCollapseTransactionsMixed(b *testing.B) {
 		}
 	}
 }
+
+func TestCollapseContractSender(t *testing.T) {
+	accounts := NewAccounts(store.NewInmem())
+
+	code, err := ioutil.ReadFile("testdata/validator.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	contractID := AccountID{0xAA}
+	recipientID := AccountID{0xBB}
+
+	snapshot := accounts.Snapshot()
+	WriteAccountContractCode(snapshot, contractID, code)
+	WriteAccountBalance(snapshot, contractID, 1000000)
+	WriteAccountContractGasBalance(snapshot, contractID, 1000000)
+
+	if !assert.NoError(t, accounts.Commit(snapshot)) {
+		return
+	}
+
+	transfer, err := buildTransferPayload(recipientID, 100).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	stake, err := buildPlaceStakePayload(100).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	txs := []*Transaction{}
+
+	for nonce := uint64(1); nonce <= 3; nonce++ {
+		tx := NewSignedTransaction(contractID, nonce, 1, sys.TagTransfer, transfer, edwards25519.Signature{})
+		txs = append(txs, &tx)
+	}
+
+	tx := NewSignedTransaction(contractID, 4, 1, sys.TagStake, stake, edwards25519.Signature{})
+	txs = append(txs, &tx)
+
+	block := NewBlock(1, accounts.tree.Checksum())
+
+	results, err := collapseTransactions(block.Index, txs, &block, accounts, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The validate function of the contract only accepts two transfers, and rejects stakes.
+	assert.Equal(t, []*Transaction{txs[0], txs[1]}, results.applied)
+	assert.Equal(t, []*Transaction{txs[2], txs[3]}, results.rejected)
+
+	balance, _ := ReadAccountBalance(results.snapshot, recipientID)
+	assert.EqualValues(t, 200, balance)
+
+	balance, _ = ReadAccountBalance(results.snapshot, contractID)
+	assert.EqualValues(t, 1000000-2*(100+txs[0].Fee()), balance)
+
+	gasBalance, _ := ReadAccountContractGasBalance(results.snapshot, contractID)
+	assert.True(t, gasBalance < 1000000)
+
+	// Gas spent validating the transactions the contract rejected is paid for all the same.
+	accepted, err := collapseTransactions(block.Index, txs[:2], &block, accounts, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	acceptedGasBalance, _ := ReadAccountContractGasBalance(accepted.snapshot, contractID)
+	assert.True(t, gasBalance < acceptedGasBalance)
+}
+
+func TestCollapseKeyRotation(t *testing.T) {
+	accounts := NewAccounts(store.NewInmem())
+
+	oldKeys, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	newKeys, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	accountID := oldKeys.PublicKey()
+	recipientID := AccountID{0xBB}
+
+	snapshot := accounts.Snapshot()
+	WriteAccountBalance(snapshot, accountID, 1000000)
+
+	if !assert.NoError(t, accounts.Commit(snapshot)) {
+		return
+	}
+
+	signedBy := func(keys *skademlia.Keypair, nonce uint64, tag sys.Tag, payload []byte) *Transaction {
+		tx := NewTransaction(keys, nonce, 1, tag, payload)
+		tx = NewSignedTransaction(accountID, tx.Nonce, tx.Block, tx.Tag, tx.Payload, tx.Signature)
+
+		return &tx
+	}
+
+	rotation, err := KeyRotation{Key: newKeys.PublicKey()}.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	transfer, err := buildTransferPayload(recipientID, 100).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// A transaction signed by the old key that is accepted into the graph before the key is rotated is rejected
+	// should it be applied after the key is rotated.
+	txs := []*Transaction{
+		signedBy(oldKeys, 1, sys.TagKeyRotation, rotation),
+		signedBy(oldKeys, 2, sys.TagTransfer, transfer),
+		signedBy(newKeys, 3, sys.TagTransfer, transfer),
+	}
+
+	block := NewBlock(1, accounts.tree.Checksum())
+
+	results, err := collapseTransactions(block.Index, txs, &block, accounts, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []*Transaction{txs[0], txs[2]}, results.applied)
+	assert.Equal(t, []*Transaction{txs[1]}, results.rejected)
+
+	balance, _ := ReadAccountBalance(results.snapshot, recipientID)
+	assert.EqualValues(t, 100, balance)
+}
+
+func TestCollapseSponsoredTransaction(t *testing.T) {
+	accounts := NewAccounts(store.NewInmem())
+
+	sender, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	feePayer, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	recipientID := AccountID{0xBB}
+
+	transfer, err := buildTransferPayload(recipientID, 100).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	txs := []*Transaction{
+		func() *Transaction {
+			tx := NewSponsoredTransaction(sender, feePayer, 1, 1, sys.TagTransfer, transfer)
+			return &tx
+		}(),
+		func() *Transaction {
+			tx := NewSponsoredTransaction(sender, feePayer, 2, 1, sys.TagTransfer, transfer)
+			return &tx
+		}(),
+	}
+
+	// The fee payer is only able to pay for the fee of the first transaction, while the sender is only able to
+	// afford the amount sent by it.
+	snapshot := accounts.Snapshot()
+	WriteAccountBalance(snapshot, sender.PublicKey(), 100)
+	WriteAccountBalance(snapshot, feePayer.PublicKey(), txs[0].Fee())
+
+	if !assert.NoError(t, accounts.Commit(snapshot)) {
+		return
+	}
+
+	block := NewBlock(1, accounts.tree.Checksum())
+
+	results, err := collapseTransactions(block.Index, txs, &block, accounts, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, []*Transaction{txs[0]}, results.applied)
+	assert.Equal(t, []*Transaction{txs[1]}, results.rejected)
+
+	balance, _ := ReadAccountBalance(results.snapshot, recipientID)
+	assert.EqualValues(t, 100, balance)
+
+	balance, _ = ReadAccountBalance(results.snapshot, sender.PublicKey())
+	assert.EqualValues(t, 0, balance)
+
+	balance, _ = ReadAccountBalance(results.snapshot, feePayer.PublicKey())
+	assert.EqualValues(t, 0, balance)
+
+	// Gas spent by smart contracts invoked by a sponsored transaction is paid for by the fee payer.
+	code, err := ioutil.ReadFile("testdata/dummy.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	spawn, err := Contract{GasLimit: 100000000, Code: code}.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := NewSponsoredTransaction(sender, feePayer, 3, 2, sys.TagContract, spawn)
+
+	if !assert.NoError(t, accounts.Commit(results.snapshot)) {
+		return
+	}
+
+	snapshot = accounts.Snapshot()
+	WriteAccountBalance(snapshot, feePayer.PublicKey(), tx.Fee()+100000000)
+
+	if !assert.NoError(t, accounts.Commit(snapshot)) {
+		return
+	}
+
+	block = NewBlock(2, accounts.tree.Checksum())
+
+	results, err = collapseTransactions(block.Index, []*Transaction{&tx}, &block, accounts, nil)
+	if !assert.NoError(t, err) || !assert.Equal(t, []*Transaction{&tx}, results.applied) {
+		return
+	}
+
+	balance, _ = ReadAccountBalance(results.snapshot, sender.PublicKey())
+	assert.EqualValues(t, 0, balance)
+
+	balance, _ = ReadAccountBalance(results.snapshot, feePayer.PublicKey())
+	assert.True(t, balance < 100000000)
+}
+
+func TestApplyBlockTransactionTrace(t *testing.T) {
+	state := avl.New(store.NewInmem())
+	block := NewBlock(1, state.Checksum())
+
+	keys, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	code, err := ioutil.ReadFile("testdata/forward.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	contractID := AccountID{0xAA}
+	WriteAccountContractCode(state, contractID, code)
+
+	var forwarded Batch
+
+	marshaled, err := forwarded.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	invoke := buildTransferPayload(contractID, 1)
+	invoke.GasLimit = 100000
+	invoke.FuncName = []byte("forward")
+	invoke.FuncParams = append([]byte{byte(sys.TagBatch)}, marshaled...)
+
+	payload, err := invoke.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := buildSignedTransaction(keys, sys.TagTransfer, 1, block.Index, payload)
+	WriteAccountBalance(state, keys.PublicKey(), tx.Fee()+100001)
+
+	// Tracing a transaction authorizes it, charges its fee and applies it just like collapsing it does.
+	applied := NewCollapseContext(state)
+	outcome := applyBlockTransaction(applied, &block, &tx, nil)
+
+	trace := new(ContractTrace)
+
+	traced := NewCollapseContext(state)
+	assert.Equal(t, outcome, applyBlockTransaction(traced, &block, &tx, trace))
+
+	assert.NoError(t, outcome.err)
+	assert.True(t, outcome.charged)
+
+	if assert.Len(t, trace.Invocations, 1) {
+		assert.Equal(t, contractID, trace.Invocations[0].ContractID)
+		assert.Equal(t, "forward", trace.Invocations[0].Function)
+	}
+
+	appliedBalance, _ := applied.ReadAccountBalance(keys.PublicKey())
+	tracedBalance, _ := traced.ReadAccountBalance(keys.PublicKey())
+	assert.Equal(t, appliedBalance, tracedBalance)
+	assert.True(t, tracedBalance < 100001)
+
+	// A transaction whose fee may not be paid is not applied, nor traced.
+	trace = new(ContractTrace)
+
+	outcome = applyBlockTransaction(NewCollapseContext(avl.New(store.NewInmem())), &block, &tx, trace)
+	assert.Error(t, outcome.err)
+	assert.False(t, outcome.charged)
+	assert.Empty(t, trace.Invocations)
+}
@@ -33,6 +33,8 @@ const (
 	SizeMerkleNodeID    = md5.Size
 	SizeAccountID       = 32
 	SizeSignature       = 64
+	SizeContractCodeID  = blake2b.Size256
+	SizeAssetID         = blake2b.Size256
 )
 
 type TransactionID = [SizeTransactionID]byte
@@ -41,14 +43,18 @@ type BlockID = [SizeBlockID]byte
 type MerkleNodeID = [SizeMerkleNodeID]byte
 type AccountID = [SizeAccountID]byte
 type Signature = [SizeSignature]byte
+type ContractCodeID = [SizeContractCodeID]byte
+type AssetID = [SizeAssetID]byte
 
 var (
-	ZeroTransactionID TransactionID
-	ZeroBlockID       BlockID
-	ZeroMerkleNodeID  MerkleNodeID
-	ZeroAccountID     AccountID
-	ZeroSignature     Signature
-	ZeroBlockPtr      = &Block{}
+	ZeroTransactionID  TransactionID
+	ZeroBlockID        BlockID
+	ZeroMerkleNodeID   MerkleNodeID
+	ZeroAccountID      AccountID
+	ZeroSignature      Signature
+	ZeroContractCodeID ContractCodeID
+	ZeroAssetID        AssetID
+	ZeroBlockPtr       = &Block{}
 
 	ZeroPage = make([]byte, PageSize)
 	CacheKey = make([]byte, 32)
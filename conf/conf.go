@@ -45,6 +45,10 @@ type config struct {
 	// Max number of transactions within the block
 	blockTxLimit uint64
 
+	// Number of workers transactions of a block are applied in parallel across while collapsing, where 1 applies them
+	// one after another
+	collapseWorkers int
+
 	// shared secret for http api authorization
 	secret string
 }
@@ -82,6 +86,8 @@ func defaultConfig() config {
 		pruningLimit: 30,
 
 		blockTxLimit: 1 << 16,
+
+		collapseWorkers: 1,
 	}
 
 	if sys.VersionMeta == "testnet" {
@@ -202,6 +208,12 @@ func WithBlockTXLimit(n uint64) Option {
 	}
 }
 
+func WithCollapseWorkers(n int) Option {
+	return func(c *config) {
+		c.collapseWorkers = n
+	}
+}
+
 func WithMissingTxPullLimit(n uint64) Option {
 	return func(c *config) {
 		c.missingTxPullLimit = n
@@ -352,6 +364,14 @@ func GetBlockTXLimit() uint64 {
 	return t
 }
 
+func GetCollapseWorkers() int {
+	l.RLock()
+	t := c.collapseWorkers
+	l.RUnlock()
+
+	return t
+}
+
 func GetMissingTxPullLimit() uint64 {
 	l.RLock()
 	t := c.missingTxPullLimit
@@ -27,6 +27,7 @@ func TestGet(t *testing.T) {
 	assert.EqualValues(t, uint64(5), GetSyncIfBlockIndicesDifferBy())
 	assert.EqualValues(t, 30, GetPruningLimit())
 	assert.EqualValues(t, "", GetSecret())
+	assert.EqualValues(t, 1, GetCollapseWorkers())
 }
 
 func TestUpdate(t *testing.T) {
@@ -50,6 +51,7 @@ func TestUpdate(t *testing.T) {
 		WithSyncIfBlockIndicesDifferBy(7),
 		WithPruningLimit(13),
 		WithSecret("shambles"),
+		WithCollapseWorkers(4),
 	)
 
 	assert.EqualValues(t, 10, GetSnowballK())
@@ -69,6 +71,7 @@ func TestUpdate(t *testing.T) {
 	assert.EqualValues(t, 7, GetSyncIfBlockIndicesDifferBy())
 	assert.EqualValues(t, 13, GetPruningLimit())
 	assert.EqualValues(t, "shambles", GetSecret())
+	assert.EqualValues(t, 4, GetCollapseWorkers())
 }
 
 func resetConfig() {
@@ -24,25 +24,23 @@ import (
 	"crypto/sha256"
 	"crypto/sha512"
 	"encoding/binary"
-	"fmt"
+	"math/big"
 	"reflect"
 	"unsafe"
 
-	"github.com/perlin-network/life/compiler"
-	"github.com/perlin-network/life/exec"
-	"github.com/perlin-network/life/utils"
+	"github.com/decred/dcrd/dcrec/secp256k1/v2"
 	"github.com/perlin-network/noise/edwards25519"
 	"github.com/perlin-network/wavelet/avl"
 	"github.com/perlin-network/wavelet/sys"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
 )
 
 var (
 	ErrContractFunctionNotFound = errors.New("contract: smart contract func not found")
 
-	_ exec.ImportResolver = (*ContractExecutor)(nil)
-	_ compiler.GasPolicy  = (*ContractExecutor)(nil)
+	_ ContractHost = (*ContractExecutor)(nil)
 )
 
 const (
@@ -58,77 +56,167 @@ type ContractExecutor struct {
 	Payload []byte
 	Error   []byte
 
+	// Logs are the messages logged by the contract through `_log` throughout an invocation. They are only
+	// recorded while tracing.
+	Logs [][]byte
+
 	Queue []*Transaction
+
+	// Context, if set, is used to read the key-value storage of the contract. Otherwise, storage is read from
+	// the tree passed to Execute.
+	Context *CollapseContext
+
+	// StorageWrites are writes made into the key-value storage of the contract throughout an invocation,
+	// in the order they were made. They are only to be persisted should the invocation succeed.
+	StorageWrites []ContractStorageWrite
+
+	// Trace, if set, records a trace of every invocation made by the executor.
+	Trace *ContractTrace
+
+	// Engine, if set, is the engine smart contracts are executed with. Otherwise, they are executed with a
+	// LifeEngine that does not cache compiled code.
+	Engine ContractEngine
+
+	storage map[string]int
+	tree    *avl.Tree
+
+	block *Block
+	tx    *Transaction
+}
+
+type ContractStorageWrite struct {
+	Key     []byte
+	Value   []byte
+	Deleted bool
 }
 
 type VMState struct {
 	Globals []int64
 	Memory  []byte
+
+	// Dirty marks the pages of Memory that may differ from those last persisted. Pages at or beyond len(Dirty)
+	// are always considered to be dirty, such that a nil Dirty marks every page as dirty.
+	Dirty []bool
 }
 
-func (state VMState) Apply(
-	vm *exec.VirtualMachine, gasPolicy compiler.GasPolicy, importResolver exec.ImportResolver, move bool,
-) (*exec.VirtualMachine, error) {
-	if len(vm.Globals) != len(state.Globals) {
-		return nil, errors.New("global count mismatch")
-	}
-
-	// safe as `state` is passed by value
-	if !move {
-		state.Globals = append([]int64{}, state.Globals...)
-		state.Memory = append([]byte{}, state.Memory...)
-	}
-
-	return &exec.VirtualMachine{
-		Config:          vm.Config,
-		Module:          vm.Module,
-		FunctionCode:    vm.FunctionCode,
-		FunctionImports: vm.FunctionImports,
-		CallStack:       make([]exec.Frame, exec.DefaultCallStackSize),
-		CurrentFrame:    -1,
-		Table:           vm.Table,
-		Globals:         state.Globals,
-		Memory:          state.Memory,
-		Exited:          true,
-		GasPolicy:       gasPolicy,
-		ImportResolver:  importResolver,
-	}, nil
+// GetCost prices the WebAssembly instruction named key when a contract is compiled. Host functions are instead
+// priced by hostCost.
+func (e *ContractExecutor) GetCost(key string) int64 {
+	return 1 // FIXME(kenta): Remove for testnet.
 }
 
-func CloneVM(
-	vm *exec.VirtualMachine, gasPolicy compiler.GasPolicy, importResolver exec.ImportResolver,
-) (*exec.VirtualMachine, error) {
-	return SnapshotVMState(vm).Apply(vm, gasPolicy, importResolver, false)
+func (e *ContractExecutor) readStorage(key []byte) ([]byte, bool) {
+	if idx, ok := e.storage[string(key)]; ok {
+		return e.StorageWrites[idx].Value, !e.StorageWrites[idx].Deleted
+	}
+
+	if e.Context != nil {
+		return e.Context.ReadAccountContractStorage(e.ID, key)
+	}
+
+	if e.tree != nil {
+		return ReadAccountContractStorage(e.tree, e.ID, key)
+	}
+
+	return nil, false
 }
 
-func SnapshotVMState(vm *exec.VirtualMachine) VMState {
-	return VMState{
-		Globals: vm.Globals,
-		Memory:  vm.Memory,
+func (e *ContractExecutor) writeStorage(write ContractStorageWrite) {
+	if e.storage == nil {
+		e.storage = make(map[string]int)
 	}
+
+	if idx, ok := e.storage[string(write.Key)]; ok {
+		e.StorageWrites[idx] = write
+		return
+	}
+
+	e.storage[string(write.Key)] = len(e.StorageWrites)
+	e.StorageWrites = append(e.StorageWrites, write)
 }
 
-func (e *ContractExecutor) GetCost(key string) int64 {
-	return 1 // FIXME(kenta): Remove for testnet.
+func (e *ContractExecutor) readAccountValue(
+	id AccountID, fromContext func(AccountID) (uint64, bool), fromTree func(*avl.Tree, AccountID) (uint64, bool),
+) uint64 {
+	if e.Context != nil {
+		value, _ := fromContext(id)
+		return value
+	}
+
+	if e.tree != nil {
+		value, _ := fromTree(e.tree, id)
+		return value
+	}
+
+	return 0
 }
 
-func (e *ContractExecutor) ResolveFunc(module, field string) exec.FunctionImport {
+func (e *ContractExecutor) readAccountBalance(id AccountID) uint64 {
+	return e.readAccountValue(id, e.Context.ReadAccountBalance, ReadAccountBalance)
+}
+
+func (e *ContractExecutor) readAccountStake(id AccountID) uint64 {
+	return e.readAccountValue(id, e.Context.ReadAccountStake, ReadAccountStake)
+}
+
+func (e *ContractExecutor) readAccountContractGasBalance(id AccountID) uint64 {
+	return e.readAccountValue(id, e.Context.ReadAccountContractGasBalance, ReadAccountContractGasBalance)
+}
+
+// caller returns the ID of the account that invoked the contract.
+func (e *ContractExecutor) caller() AccountID {
+	if e.tx == nil {
+		return ZeroAccountID
+	}
+
+	return e.tx.Sender
+}
+
+// seed returns a seed that is deterministic given the block the contract is invoked in, and the contract's ID.
+// As the ID of a block is known ahead of time by its proposer, the seed must not be relied upon as a source of
+// secure randomness.
+func (e *ContractExecutor) seed() [blake2b.Size256]byte {
+	buf := make([]byte, 0, SizeBlockID+SizeAccountID)
+
+	if e.block != nil {
+		buf = append(buf, e.block.ID[:]...)
+	} else {
+		buf = append(buf, ZeroBlockID[:]...)
+	}
+
+	buf = append(buf, e.ID[:]...)
+
+	return blake2b.Sum256(buf)
+}
+
+// hostCost returns the amount of gas charged for calling the host function priced under key in the gas table.
+func (e *ContractExecutor) hostCost(key string) uint64 {
+	return sys.GasTable[key]
+}
+
+// storageCost prices accessing numBytes bytes of contract storage through the host function priced under key, such
+// that contracts pay for every byte they keep in the ledger state.
+func (e *ContractExecutor) storageCost(key string, numBytes int) uint64 {
+	return e.hostCost(key) + e.hostCost("wavelet.storage.byte")*uint64(numBytes)
+}
+
+func (e *ContractExecutor) ResolveHostFunc(module, field string) ContractHostFunc {
 	switch module {
 	case "env":
 		switch field {
 		case "abort":
-			return func(vm *exec.VirtualMachine) int64 {
+			return func(call ContractHostCall) int64 {
 				panic(errors.New("abort called"))
 			}
 		case "_send_transaction":
-			return func(vm *exec.VirtualMachine) int64 {
-				frame := vm.GetCurrentFrame()
+			return func(call ContractHostCall) int64 {
+				params := call.Params()
 
-				tag := byte(uint32(frame.Locals[0]))
-				payloadPtr := int(uint32(frame.Locals[1]))
-				payloadLen := int(uint32(frame.Locals[2]))
+				tag := byte(uint32(params[0]))
+				payloadPtr := int(uint32(params[1]))
+				payloadLen := int(uint32(params[2]))
 
-				payloadRef := vm.Memory[payloadPtr : payloadPtr+payloadLen]
+				payloadRef := call.Memory()[payloadPtr : payloadPtr+payloadLen]
 				payload := make([]byte, len(payloadRef))
 				copy(payload, payloadRef)
 
@@ -138,59 +226,89 @@ func (e *ContractExecutor) ResolveFunc(module, field string) exec.FunctionImport
 					Payload: payload,
 				})
 
+				return 0
+			}
+		case "_withdraw_gas":
+			// Withdrawals are queued as sub-transactions, such that they are applied only after gas for the
+			// invocation has been deducted from the contracts gas balance.
+			return func(call ContractHostCall) int64 {
+				params := call.Params()
+
+				amount := uint64(params[0])
+				recipientPtr, recipientLen := int(uint32(params[1])), int(uint32(params[2]))
+
+				if amount == 0 || recipientLen != SizeAccountID {
+					return 1
+				}
+
+				withdrawal := ContractLifecycle{Opcode: sys.WithdrawContractGas, ContractID: e.ID, Amount: amount}
+				copy(withdrawal.Recipient[:], call.Memory()[recipientPtr:recipientPtr+recipientLen])
+
+				payload, err := withdrawal.Marshal()
+				if err != nil {
+					panic(err)
+				}
+
+				e.Queue = append(e.Queue, &Transaction{
+					Sender:  e.ID,
+					Tag:     sys.TagContractLifecycle,
+					Payload: payload,
+				})
+
 				return 0
 			}
 		case "_payload_len":
-			return func(vm *exec.VirtualMachine) int64 {
+			return func(call ContractHostCall) int64 {
 				return int64(len(e.Payload))
 			}
 		case "_payload":
-			return func(vm *exec.VirtualMachine) int64 {
-				frame := vm.GetCurrentFrame()
+			return func(call ContractHostCall) int64 {
+				params := call.Params()
 
-				outPtr := int(uint32(frame.Locals[0]))
-				copy(vm.Memory[outPtr:], e.Payload)
+				outPtr := int(uint32(params[0]))
+				copy(call.Memory()[outPtr:], e.Payload)
 				return 0
 			}
 		case "_result":
-			return func(vm *exec.VirtualMachine) int64 {
-				frame := vm.GetCurrentFrame()
-				dataPtr := int(uint32(frame.Locals[0]))
-				dataLen := int(uint32(frame.Locals[1]))
+			return func(call ContractHostCall) int64 {
+				params := call.Params()
+				dataPtr := int(uint32(params[0]))
+				dataLen := int(uint32(params[1]))
 
 				e.Error = make([]byte, dataLen)
-				copy(e.Error, vm.Memory[dataPtr:dataPtr+dataLen])
+				copy(e.Error, call.Memory()[dataPtr:dataPtr+dataLen])
 				return 0
 			}
 		case "_log":
-			return func(vm *exec.VirtualMachine) int64 {
-				//frame := vm.GetCurrentFrame()
-				//dataPtr := int(uint32(frame.Locals[0]))
-				//dataLen := int(uint32(frame.Locals[1]))
-				//
-				//logger := log.Contracts("log")
-				//logger.Debug().
-				//	Hex("contract_id", e.ID[:]).
-				//	Msg(string(vm.Memory[dataPtr : dataPtr+dataLen]))
+			return func(call ContractHostCall) int64 {
+				if e.Trace == nil {
+					return 0
+				}
+
+				params := call.Params()
+				dataPtr := int(uint32(params[0]))
+				dataLen := int(uint32(params[1]))
+
+				e.Logs = append(e.Logs, append([]byte{}, call.Memory()[dataPtr:dataPtr+dataLen]...))
 
 				return 0
 			}
 		case "_verify_ed25519":
-			return func(vm *exec.VirtualMachine) int64 {
-				vm.Gas += uint64(e.GetCost("wavelet.verify.ed25519"))
+			return func(call ContractHostCall) int64 {
+				call.AddGas(e.hostCost("wavelet.verify.ed25519"))
 
-				frame := vm.GetCurrentFrame()
-				keyPtr, keyLen := int(uint32(frame.Locals[0])), int(uint32(frame.Locals[1]))
-				dataPtr, dataLen := int(uint32(frame.Locals[2])), int(uint32(frame.Locals[3]))
-				sigPtr, sigLen := int(uint32(frame.Locals[4])), int(uint32(frame.Locals[5]))
+				params := call.Params()
+				keyPtr, keyLen := int(uint32(params[0])), int(uint32(params[1]))
+				dataPtr, dataLen := int(uint32(params[2])), int(uint32(params[3]))
+				sigPtr, sigLen := int(uint32(params[4])), int(uint32(params[5]))
 
 				if keyLen != edwards25519.SizePublicKey || sigLen != edwards25519.SizeSignature {
 					return 1
 				}
 
-				key := vm.Memory[keyPtr : keyPtr+keyLen]
-				data := vm.Memory[dataPtr : dataPtr+dataLen]
-				sig := vm.Memory[sigPtr : sigPtr+sigLen]
+				key := call.Memory()[keyPtr : keyPtr+keyLen]
+				data := call.Memory()[dataPtr : dataPtr+dataLen]
+				sig := call.Memory()[sigPtr : sigPtr+sigLen]
 
 				var pub edwards25519.PublicKey
 				var edSig edwards25519.Signature
@@ -205,9 +323,222 @@ func (e *ContractExecutor) ResolveFunc(module, field string) exec.FunctionImport
 
 				return 1
 			}
+		case "_verify_ed25519_batch":
+			return func(call ContractHostCall) int64 {
+				params := call.Params()
+				batchPtr, batchLen := int(uint32(params[0])), int(uint32(params[1]))
+
+				entries, ok := parseEd25519Batch(call.Memory()[batchPtr : batchPtr+batchLen])
+				if !ok {
+					return 1
+				}
+
+				call.AddAndCheckGas(uint64(len(entries)) * e.hostCost("wavelet.verify.ed25519.batch"))
+
+				for _, entry := range entries {
+					if !edwards25519.Verify(entry.key, entry.data, entry.sig) {
+						return 1
+					}
+				}
+
+				return 0
+			}
+		case "_verify_secp256k1":
+			return func(call ContractHostCall) int64 {
+				call.AddGas(e.hostCost("wavelet.verify.secp256k1"))
+
+				params := call.Params()
+				keyPtr, keyLen := int(uint32(params[0])), int(uint32(params[1]))
+				hashPtr, hashLen := int(uint32(params[2])), int(uint32(params[3]))
+				sigPtr, sigLen := int(uint32(params[4])), int(uint32(params[5]))
+
+				if hashLen != secp256k1HashSize || sigLen != secp256k1SignatureSize {
+					return 1
+				}
+
+				key := call.Memory()[keyPtr : keyPtr+keyLen]
+				hash := call.Memory()[hashPtr : hashPtr+hashLen]
+				sig := call.Memory()[sigPtr : sigPtr+sigLen]
+
+				if verifySecp256k1(key, hash, sig) {
+					return 0
+				}
+
+				return 1
+			}
+		case "_recover_secp256k1":
+			return func(call ContractHostCall) int64 {
+				call.AddGas(e.hostCost("wavelet.recover.secp256k1"))
+
+				params := call.Params()
+				hashPtr, hashLen := int(uint32(params[0])), int(uint32(params[1]))
+				sigPtr, sigLen := int(uint32(params[2])), int(uint32(params[3]))
+				outPtr, outLen := int(uint32(params[4])), int(uint32(params[5]))
+
+				if hashLen != secp256k1HashSize || sigLen != secp256k1SignatureSize+1 {
+					return 1
+				}
+
+				if outLen != secp256k1.PubKeyBytesLenCompressed && outLen != secp256k1.PubKeyBytesLenUncompressed {
+					return 1
+				}
+
+				hash := call.Memory()[hashPtr : hashPtr+hashLen]
+				sig := call.Memory()[sigPtr : sigPtr+sigLen]
+
+				key, ok := recoverSecp256k1(hash, sig, outLen == secp256k1.PubKeyBytesLenCompressed)
+				if !ok {
+					return 1
+				}
+
+				copy(call.Memory()[outPtr:outPtr+outLen], key)
+
+				return 0
+			}
+		case "_storage_get":
+			return func(call ContractHostCall) int64 {
+				params := call.Params()
+				keyPtr, keyLen := int(uint32(params[0])), int(uint32(params[1]))
+				outPtr, outLen := int(uint32(params[2])), int(uint32(params[3]))
+
+				if keyLen > sys.ContractMaxStorageKeySize {
+					panic(errors.Errorf("storage key exceeds %d bytes", sys.ContractMaxStorageKeySize))
+				}
+
+				value, exists := e.readStorage(call.Memory()[keyPtr : keyPtr+keyLen])
+
+				call.AddAndCheckGas(e.storageCost("wavelet.storage.get", keyLen+len(value)))
+
+				if !exists {
+					return -1
+				}
+
+				copy(call.Memory()[outPtr:outPtr+outLen], value)
+
+				return int64(len(value))
+			}
+		case "_storage_set":
+			return func(call ContractHostCall) int64 {
+				params := call.Params()
+				keyPtr, keyLen := int(uint32(params[0])), int(uint32(params[1]))
+				valuePtr, valueLen := int(uint32(params[2])), int(uint32(params[3]))
+
+				if keyLen > sys.ContractMaxStorageKeySize {
+					panic(errors.Errorf("storage key exceeds %d bytes", sys.ContractMaxStorageKeySize))
+				}
+
+				if valueLen > sys.ContractMaxStorageValueSize {
+					panic(errors.Errorf("storage value exceeds %d bytes", sys.ContractMaxStorageValueSize))
+				}
+
+				call.AddAndCheckGas(e.storageCost("wavelet.storage.set", keyLen+valueLen))
+
+				write := ContractStorageWrite{
+					Key:   make([]byte, keyLen),
+					Value: make([]byte, valueLen),
+				}
+
+				copy(write.Key, call.Memory()[keyPtr:keyPtr+keyLen])
+				copy(write.Value, call.Memory()[valuePtr:valuePtr+valueLen])
+
+				e.writeStorage(write)
+
+				return 0
+			}
+		case "_storage_delete":
+			return func(call ContractHostCall) int64 {
+				params := call.Params()
+				keyPtr, keyLen := int(uint32(params[0])), int(uint32(params[1]))
+
+				if keyLen > sys.ContractMaxStorageKeySize {
+					panic(errors.Errorf("storage key exceeds %d bytes", sys.ContractMaxStorageKeySize))
+				}
+
+				call.AddAndCheckGas(e.storageCost("wavelet.storage.delete", keyLen))
+
+				key := make([]byte, keyLen)
+				copy(key, call.Memory()[keyPtr:keyPtr+keyLen])
+
+				if _, exists := e.readStorage(key); !exists {
+					return 1
+				}
+
+				e.writeStorage(ContractStorageWrite{Key: key, Deleted: true})
+
+				return 0
+			}
+		case "_block_height":
+			return func(call ContractHostCall) int64 {
+				call.AddAndCheckGas(e.hostCost("wavelet.chain.block"))
+
+				if e.block == nil {
+					return 0
+				}
+
+				return int64(e.block.Index)
+			}
+		case "_block_id":
+			return func(call ContractHostCall) int64 {
+				call.AddAndCheckGas(e.hostCost("wavelet.chain.block"))
+
+				params := call.Params()
+				outPtr, outLen := int(uint32(params[0])), int(uint32(params[1]))
+
+				if outLen != SizeBlockID {
+					return 1
+				}
+
+				if e.block != nil {
+					copy(call.Memory()[outPtr:outPtr+outLen], e.block.ID[:])
+				} else {
+					copy(call.Memory()[outPtr:outPtr+outLen], ZeroBlockID[:])
+				}
+
+				return 0
+			}
+		case "_block_seed":
+			return func(call ContractHostCall) int64 {
+				call.AddAndCheckGas(e.hostCost("wavelet.chain.seed"))
+
+				params := call.Params()
+				outPtr, outLen := int(uint32(params[0])), int(uint32(params[1]))
+
+				if outLen != blake2b.Size256 {
+					return 1
+				}
+
+				seed := e.seed()
+				copy(call.Memory()[outPtr:outPtr+outLen], seed[:])
+
+				return 0
+			}
+		case "_caller_balance":
+			return func(call ContractHostCall) int64 {
+				call.AddAndCheckGas(e.hostCost("wavelet.chain.account"))
+				return int64(e.readAccountBalance(e.caller()))
+			}
+		case "_caller_stake":
+			return func(call ContractHostCall) int64 {
+				call.AddAndCheckGas(e.hostCost("wavelet.chain.account"))
+				return int64(e.readAccountStake(e.caller()))
+			}
+		case "_account_balance":
+			return buildAccountImpl(e.hostCost("wavelet.chain.account"), e.readAccountBalance)
+		case "_account_stake":
+			return buildAccountImpl(e.hostCost("wavelet.chain.account"), e.readAccountStake)
+		case "_self_balance":
+			return func(call ContractHostCall) int64 {
+				call.AddAndCheckGas(e.hostCost("wavelet.chain.account"))
+				return int64(e.readAccountBalance(e.ID))
+			}
+		case "_self_gas_balance":
+			return func(call ContractHostCall) int64 {
+				call.AddAndCheckGas(e.hostCost("wavelet.chain.account"))
+				return int64(e.readAccountContractGasBalance(e.ID))
+			}
 		case "_hash_blake2b_256":
 			return buildHashImpl(
-				uint64(e.GetCost("wavelet.hash.blake2b256")),
+				e.hostCost("wavelet.hash.blake2b256"),
 				blake2b.Size256,
 				func(data, out []byte) {
 					b := blake2b.Sum256(data)
@@ -216,7 +547,7 @@ func (e *ContractExecutor) ResolveFunc(module, field string) exec.FunctionImport
 			)
 		case "_hash_blake2b_512":
 			return buildHashImpl(
-				uint64(e.GetCost("wavelet.hash.blake2b512")),
+				e.hostCost("wavelet.hash.blake2b512"),
 				blake2b.Size,
 				func(data, out []byte) {
 					b := blake2b.Sum512(data)
@@ -225,7 +556,7 @@ func (e *ContractExecutor) ResolveFunc(module, field string) exec.FunctionImport
 			)
 		case "_hash_sha256":
 			return buildHashImpl(
-				uint64(e.GetCost("wavelet.hash.sha256")),
+				e.hostCost("wavelet.hash.sha256"),
 				sha256.Size,
 				func(data, out []byte) {
 					b := sha256.Sum256(data)
@@ -234,13 +565,23 @@ func (e *ContractExecutor) ResolveFunc(module, field string) exec.FunctionImport
 			)
 		case "_hash_sha512":
 			return buildHashImpl(
-				uint64(e.GetCost("wavelet.hash.sha512")),
+				e.hostCost("wavelet.hash.sha512"),
 				sha512.Size,
 				func(data, out []byte) {
 					b := sha512.Sum512(data)
 					copy(out, b[:])
 				},
 			)
+		case "_hash_keccak256":
+			return buildHashImpl(
+				e.hostCost("wavelet.hash.keccak256"),
+				secp256k1HashSize,
+				func(data, out []byte) {
+					h := sha3.NewLegacyKeccak256()
+					_, _ = h.Write(data)
+					copy(out, h.Sum(nil))
+				},
+			)
 		default:
 			panic("unknown field")
 		}
@@ -249,8 +590,41 @@ func (e *ContractExecutor) ResolveFunc(module, field string) exec.FunctionImport
 	}
 }
 
-func (e *ContractExecutor) ResolveGlobal(module, field string) int64 {
-	panic("global variables are disallowed in smart contracts")
+// instantiate instantiates a VM out of code, cloning it out of the VMs cached by their code in codeCache should
+// codeCache be set.
+func (e *ContractExecutor) instantiate(code []byte, gasLimit uint64, codeCache *VMLRU) (ContractInstance, error) {
+	var codeID ContractCodeID
+
+	if codeCache != nil {
+		codeID = HashContractCode(code)
+
+		if cached, ok := codeCache.Load(codeID); ok {
+			instance, err := cached.Clone(e)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot clone vm")
+			}
+
+			instance.SetGasLimit(gasLimit)
+
+			return instance, nil
+		}
+	}
+
+	instance, err := e.engine().Instantiate(code, gasLimit, e)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot initialize vm")
+	}
+
+	if codeCache != nil {
+		cloned, err := instance.Clone(nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot clone vm")
+		}
+
+		codeCache.Put(codeID, cloned)
+	}
+
+	return instance, nil
 }
 
 // contractState is an optional parameter that is used to pass the VMState of the contract.
@@ -263,36 +637,24 @@ func (e *ContractExecutor) Execute( // nolint:gocognit
 	tree *avl.Tree, vmCache *VMLRU, contractState *VMState,
 ) (*VMState, error) {
 	var (
-		vm  *exec.VirtualMachine
-		err error
+		instance ContractInstance
+		err      error
 	)
 
 	if cached, ok := vmCache.Load(id); ok {
-		vm, err = CloneVM(cached, e, e)
+		instance, err = cached.Clone(e)
 		if err != nil {
 			return nil, errors.Wrap(err, "cannot clone vm")
 		}
 
-		vm.Config.GasLimit = gasLimit
+		instance.SetGasLimit(gasLimit)
 	} else {
-		config := exec.VMConfig{
-			DefaultMemoryPages: sys.ContractDefaultMemoryPages,
-			MaxMemoryPages:     sys.ContractMaxMemoryPages,
-
-			DefaultTableSize: sys.ContractTableSize,
-			MaxTableSize:     sys.ContractTableSize,
-
-			MaxValueSlots:     sys.ContractMaxValueSlots,
-			MaxCallStackDepth: sys.ContractMaxCallStackDepth,
-			GasLimit:          gasLimit,
-		}
-
-		vm, err = exec.NewVirtualMachine(code, config, e, e)
+		instance, err = e.instantiate(code, gasLimit, vmCache.code)
 		if err != nil {
-			return nil, errors.Wrap(err, "cannot initialize vm")
+			return nil, err
 		}
 
-		cloned, err := CloneVM(vm, nil, nil)
+		cloned, err := instance.Clone(nil)
 		if err != nil {
 			return nil, errors.Wrap(err, "cannot clone vm")
 		}
@@ -302,92 +664,159 @@ func (e *ContractExecutor) Execute( // nolint:gocognit
 
 	// We can safely initialize the VM first before checking this because the size of the global slice
 	// is proportional to the size of the contract's global section.
-	if len(vm.Globals) > sys.ContractMaxGlobals {
+	if len(instance.Globals()) > sys.ContractMaxGlobals {
 		return nil, errors.New("too many globals")
 	}
 
-	var firstRun bool
+	var (
+		firstRun bool
+
+		// Pages that were dirty before the invocation. All other pages are identical to those persisted in tree.
+		dirty []bool
+	)
 
 	// If state cache is enabled and we have a valid state previously.
 	if contractState != nil {
-		vm, err = contractState.Apply(vm, e, e, true)
-		if err != nil {
+		dirty = contractState.Dirty
+
+		if err := instance.Restore(*contractState); err != nil {
 			return nil, errors.New("unable to apply state")
 		}
 	} else if mem := LoadContractMemorySnapshot(tree, id); mem != nil {
-		vm.Memory = mem
+		dirty = make([]bool, len(mem)/PageSize)
+
+		state := VMState{Globals: instance.Globals(), Memory: mem}
+
 		if globals, exists := LoadContractGlobals(tree, id); exists {
-			if len(globals) == len(vm.Globals) {
-				vm.Globals = globals
+			if len(globals) == len(state.Globals) {
+				state.Globals = globals
 			}
 		}
+
+		if err := instance.Restore(state); err != nil {
+			return nil, errors.New("unable to apply state")
+		}
 	} else {
 		firstRun = true
 	}
 
 	e.ID = id
+	e.tree = tree
+	e.block = block
+	e.tx = tx
 
 	e.Payload = buildContractPayload(block, tx, amount, params)
 
-	entry, exists := vm.GetFunctionExport("_contract_" + name)
+	numParams, exists := instance.Export("_contract_" + name)
 	if !exists {
-		return nil, errors.Wrapf(ErrContractFunctionNotFound, `fn "_contract_%s" does not exist`, name)
+		err = errors.Wrapf(ErrContractFunctionNotFound, `fn "_contract_%s" does not exist`, name)
+	} else if numParams != 0 {
+		err = errors.New("entry function must not have parameters")
 	}
 
-	if vm.FunctionCode[entry].NumParams != 0 {
-		return nil, errors.New("entry function must not have parameters")
+	if err != nil {
+		// The invocation is still traced, so that traces account for functions that could not be invoked.
+		if e.Trace != nil {
+			e.Trace.add(&ContractInvocationTrace{ContractID: id, Function: name, GasLimit: gasLimit, Error: err.Error()})
+		}
+
+		return nil, err
+	}
+
+	tracing, traced := instance.(ContractTracingInstance)
+	traced = traced && e.Trace != nil
+
+	if traced {
+		tracing.StartTrace(id, name, gasLimit)
 	}
 
 	if firstRun {
-		if vm.Module.Base.Start != nil {
-			startID := int(vm.Module.Base.Start.Index)
+		err = instance.Start()
+	}
+
+	if err == nil {
+		err = instance.Call("_contract_" + name)
+	}
 
-			vm.Ignite(startID)
+	if errors.Cause(err) == ErrContractGasLimitExceeded {
+		e.Gas = gasLimit
+		e.GasLimitExceeded = true
+	} else {
+		e.Gas = instance.Gas()
+		e.GasLimitExceeded = false
+	}
 
-			for !vm.Exited {
-				vm.Execute()
+	if traced {
+		invocation := tracing.FinishTrace(e.Gas)
+		invocation.Result = e.Error
+		invocation.Logs = e.Logs
 
-				if vm.Delegate != nil {
-					vm.Delegate()
-					vm.Delegate = nil
-				}
-			}
+		e.Trace.add(invocation)
+	}
+
+	if err != nil {
+		// The invocation may have written into the memory of contractState, which remains to be persisted.
+		if contractState != nil {
+			contractState.Dirty = markDirtyPages(tree, id, contractState.Memory, dirty)
 		}
+
+		return nil, err
 	}
 
-	if vm.ExitError == nil {
-		vm.Ignite(entry)
+	return &VMState{
+		Globals: instance.Globals(),
+		Memory:  instance.Memory(),
+		Dirty:   markDirtyPages(tree, id, instance.Memory(), dirty),
+	}, nil
+}
 
-		for !vm.Exited {
-			vm.Execute()
+// engine returns the engine smart contracts are executed with.
+func (e *ContractExecutor) engine() ContractEngine {
+	if e.Engine != nil {
+		return e.Engine
+	}
 
-			if vm.Delegate != nil {
-				vm.Delegate()
-				vm.Delegate = nil
-			}
-		}
+	return NewLifeEngine(nil)
+}
+
+// UpgradeContractState carries the memory and globals of a smart contract over to a virtual machine freshly
+// instantiated with the smart contracts new code. The data segments of the new code are laid over the carried
+// over memory so that its static data remains addressable, and globals are only carried over should the new
+// code declare as many globals as the old code did.
+func UpgradeContractState(instance ContractInstance, old *VMState) (*VMState, error) {
+	upgraded := VMState{
+		Globals: append([]int64{}, instance.Globals()...),
+		Memory:  append([]byte{}, instance.Memory()...),
 	}
 
-	if vm.ExitError != nil {
-		fmt.Println("error: ", utils.UnifyError(vm.ExitError))
-		vm.PrintStackTrace()
+	if old == nil {
+		return &upgraded, nil
 	}
 
-	if vm.ExitError != nil && utils.UnifyError(vm.ExitError).Error() == "gas limit exceeded" {
-		e.Gas = gasLimit
-		e.GasLimitExceeded = true
-	} else {
-		e.Gas = vm.Gas
-		e.GasLimitExceeded = false
+	if len(old.Globals) == len(upgraded.Globals) {
+		copy(upgraded.Globals, old.Globals)
 	}
 
-	if vm.ExitError != nil {
-		return nil, utils.UnifyError(vm.ExitError)
+	if len(old.Memory) > len(upgraded.Memory) {
+		upgraded.Memory = append(upgraded.Memory, make([]byte, len(old.Memory)-len(upgraded.Memory))...)
 	}
 
-	vmState := SnapshotVMState(vm)
+	copy(upgraded.Memory, old.Memory)
+
+	segments, err := instance.DataSegments()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, segment := range segments {
+		if segment.Offset < 0 || segment.Offset+len(segment.Data) > len(upgraded.Memory) {
+			return nil, errors.New("data segment is out of bounds")
+		}
+
+		copy(upgraded.Memory[segment.Offset:], segment.Data)
+	}
 
-	return &vmState, nil
+	return &upgraded, nil
 }
 
 func LoadContractGlobals(snapshot *avl.Tree, id AccountID) ([]int64, bool) {
@@ -439,39 +868,59 @@ func LoadContractMemorySnapshot(snapshot *avl.Tree, id AccountID) []byte {
 	return mem
 }
 
-func SaveContractMemorySnapshot(snapshot *avl.Tree, id AccountID, mem []byte) {
+// SaveContractMemorySnapshot persists the pages of mem marked as dirty that differ from those persisted. Pages at
+// or beyond len(dirty) are always considered to be dirty. Pages are persisted uncompressed.
+func SaveContractMemorySnapshot(snapshot *avl.Tree, id AccountID, mem []byte, dirty []bool) {
 	numPages := uint64(len(mem) / PageSize)
 
 	WriteAccountContractNumPages(snapshot, id, numPages)
 
-	var (
-		identical, allZero bool
-		pageStart          uint64
-	)
-
 	for pageIdx := uint64(0); pageIdx < numPages; pageIdx++ {
-		old, _ := ReadAccountContractPage(snapshot, id, pageIdx)
-		allZero = false
-		pageStart = pageIdx * PageSize
-
-		if len(old) == 0 {
-			allZero = bytes.Equal(ZeroPage, mem[pageStart:pageStart+PageSize])
-			identical = allZero
-		} else {
-			identical = bytes.Equal(old, mem[pageStart:pageStart+PageSize])
+		if pageIdx < uint64(len(dirty)) && !dirty[pageIdx] {
+			continue
 		}
 
-		if !identical {
-			// If the page is empty, save an empty byte array. Otherwise, save the pages content.
-			if allZero {
-				WriteAccountContractPage(snapshot, id, pageIdx, []byte{})
-			} else {
-				WriteAccountContractPage(snapshot, id, pageIdx, mem[pageStart:pageStart+PageSize])
-			}
+		page := mem[pageIdx*PageSize : (pageIdx+1)*PageSize]
+
+		if !isContractPagePersisted(snapshot, id, pageIdx, page) {
+			WriteAccountContractPage(snapshot, id, pageIdx, page)
 		}
 	}
 }
 
+// isContractPagePersisted returns whether page is identical to the page at pageIdx persisted in snapshot. Pages
+// that were never persisted, or that were persisted empty, are made up of zeroes.
+func isContractPagePersisted(snapshot *avl.Tree, id AccountID, pageIdx uint64, page []byte) bool {
+	old, _ := ReadAccountContractPage(snapshot, id, pageIdx)
+	if len(old) == 0 {
+		return bytes.Equal(ZeroPage, page)
+	}
+
+	return bytes.Equal(old, page)
+}
+
+// markDirtyPages marks the pages of memory that were either dirty before an invocation, or that differ from those
+// persisted in snapshot. As pages not marked as dirty before the invocation are identical to those persisted, they
+// are compared against snapshot in place rather than against copies taken before the invocation. A nil dirty marks
+// every page as dirty.
+//
+// The life interpreter exposes no hook on stores into memory, and instrumenting the code of smart contracts to
+// track them would alter the gas they consume. Pages written into are hence detected by comparison.
+func markDirtyPages(snapshot *avl.Tree, id AccountID, memory []byte, dirty []bool) []bool {
+	if dirty == nil {
+		return nil
+	}
+
+	marked := make([]bool, len(memory)/PageSize)
+
+	for i := range marked {
+		marked[i] = i >= len(dirty) || dirty[i] ||
+			!isContractPagePersisted(snapshot, id, uint64(i), memory[i*PageSize:(i+1)*PageSize])
+	}
+
+	return marked
+}
+
 func buildContractPayload(block *Block, tx *Transaction, amount uint64, params []byte) []byte {
 	p := make([]byte, 0)
 	b := make([]byte, 8)
@@ -511,20 +960,127 @@ func buildContractPayload(block *Block, tx *Transaction, amount uint64, params [
 	return p
 }
 
-func buildHashImpl(gas uint64, size int, f func(data, out []byte)) func(vm *exec.VirtualMachine) int64 {
-	return func(vm *exec.VirtualMachine) int64 {
-		vm.Gas += gas
+func buildHashImpl(gas uint64, size int, f func(data, out []byte)) ContractHostFunc {
+	return func(call ContractHostCall) int64 {
+		call.AddGas(gas)
 
-		frame := vm.GetCurrentFrame()
-		dataPtr, dataLen := int(uint32(frame.Locals[0])), int(uint32(frame.Locals[1]))
-		outPtr, outLen := int(uint32(frame.Locals[2])), int(uint32(frame.Locals[3]))
+		params := call.Params()
+		dataPtr, dataLen := int(uint32(params[0])), int(uint32(params[1]))
+		outPtr, outLen := int(uint32(params[2])), int(uint32(params[3]))
 		if outLen != size {
 			return 1
 		}
 
-		data := vm.Memory[dataPtr : dataPtr+dataLen]
-		out := vm.Memory[outPtr : outPtr+outLen]
+		data := call.Memory()[dataPtr : dataPtr+dataLen]
+		out := call.Memory()[outPtr : outPtr+outLen]
 		f(data, out)
 		return 0
 	}
 }
+
+// ed25519BatchEntry is a single signature to verify within a batch passed to `_verify_ed25519_batch`.
+type ed25519BatchEntry struct {
+	key  edwards25519.PublicKey
+	sig  edwards25519.Signature
+	data []byte
+}
+
+// parseEd25519Batch parses a batch of signatures to verify, which is laid out as a sequence of entries of the form
+// [256-bit public key | 512-bit signature | 32-bit little-endian length of the message | message].
+func parseEd25519Batch(batch []byte) ([]ed25519BatchEntry, bool) {
+	var entries []ed25519BatchEntry
+
+	for len(batch) > 0 {
+		if len(batch) < edwards25519.SizePublicKey+edwards25519.SizeSignature+4 {
+			return nil, false
+		}
+
+		var entry ed25519BatchEntry
+
+		batch = batch[copy(entry.key[:], batch):]
+		batch = batch[copy(entry.sig[:], batch):]
+
+		size := binary.LittleEndian.Uint32(batch[:4])
+		batch = batch[4:]
+
+		if uint64(size) > uint64(len(batch)) {
+			return nil, false
+		}
+
+		entry.data, batch = batch[:size], batch[size:]
+		entries = append(entries, entry)
+	}
+
+	return entries, true
+}
+
+const (
+	// secp256k1HashSize is the size of the message hashes secp256k1 signatures are made over.
+	secp256k1HashSize = 32
+
+	// secp256k1SignatureSize is the size of a secp256k1 signature, laid out as [256-bit R | 256-bit S]. Recoverable
+	// signatures are suffixed with a single byte recovery ID, which is either 0, 1, 27 or 28.
+	secp256k1SignatureSize = 64
+)
+
+// verifySecp256k1 verifies a secp256k1 signature over a message hash, against a public key that is either SEC1
+// compressed or uncompressed.
+func verifySecp256k1(key, hash, sig []byte) bool {
+	pub, err := secp256k1.ParsePubKey(key)
+	if err != nil {
+		return false
+	}
+
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+
+	return secp256k1.NewSignature(r, s).Verify(hash, pub)
+}
+
+// recoverSecp256k1 recovers the public key a recoverable secp256k1 signature over a message hash was made with,
+// and returns it SEC1 encoded.
+func recoverSecp256k1(hash, sig []byte, compressed bool) ([]byte, bool) {
+	recovery := sig[secp256k1SignatureSize]
+	if recovery >= 27 {
+		recovery -= 27
+	}
+
+	if recovery > 1 {
+		return nil, false
+	}
+
+	compact := make([]byte, 0, secp256k1SignatureSize+1)
+	compact = append(compact, 27+recovery)
+	compact = append(compact, sig[:secp256k1SignatureSize]...)
+
+	pub, _, err := secp256k1.RecoverCompact(compact, hash)
+	if err != nil {
+		return nil, false
+	}
+
+	if compressed {
+		return pub.SerializeCompressed(), true
+	}
+
+	return pub.SerializeUncompressed(), true
+}
+
+// buildAccountImpl builds a host function that reads a value associated to an account, whose 256-bit ID is
+// located in memory. If the ID is not exactly 32 bytes, -1 is returned.
+func buildAccountImpl(gas uint64, read func(id AccountID) uint64) ContractHostFunc {
+	return func(call ContractHostCall) int64 {
+		call.AddAndCheckGas(gas)
+
+		params := call.Params()
+		idPtr, idLen := int(uint32(params[0])), int(uint32(params[1]))
+
+		if idLen != SizeAccountID {
+			return -1
+		}
+
+		var id AccountID
+		copy(id[:], call.Memory()[idPtr:idPtr+idLen])
+
+		return int64(read(id))
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// +build unit
+
+package wavelet
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+
+	"github.com/perlin-network/noise/skademlia"
+	"github.com/perlin-network/wavelet/avl"
+	"github.com/perlin-network/wavelet/store"
+	"github.com/perlin-network/wavelet/sys"
+	"github.com/stretchr/testify/assert"
+)
+
+// buildMemoryContract assembles a smart contract with numPages pages of memory, whose functions `init` and
+// `touch` both increment the 32-bit integer at the start of its memory. Its function `fail` increments the 32-bit
+// integer at the start of its second page, and traps right after.
+func buildMemoryContract(numPages uint64) []byte {
+	leb := func(x uint64) []byte {
+		buf := make([]byte, binary.MaxVarintLen64)
+		return buf[:binary.PutUvarint(buf, x)]
+	}
+
+	section := func(id byte, contents ...byte) []byte {
+		return append(append([]byte{id}, leb(uint64(len(contents)))...), contents...)
+	}
+
+	export := func(name string, index byte) []byte {
+		return append(append(leb(uint64(len(name))), name...), 0x00, index)
+	}
+
+	// (i32.store (i32.const 0) (i32.add (i32.load (i32.const 0)) (i32.const 1)))
+	touch := []byte{0x00, 0x41, 0x00, 0x41, 0x00, 0x28, 0x02, 0x00, 0x41, 0x01, 0x6a, 0x36, 0x02, 0x00, 0x0b}
+
+	// (i32.store (i32.const 65536) (i32.add (i32.load (i32.const 65536)) (i32.const 1))) (unreachable)
+	fail := []byte{
+		0x00, 0x41, 0x80, 0x80, 0x04, 0x41, 0x80, 0x80, 0x04, 0x28, 0x02, 0x00, 0x41, 0x01, 0x6a, 0x36, 0x02, 0x00,
+		0x00, 0x0b,
+	}
+
+	exports := []byte{0x03}
+	exports = append(exports, export("_contract_init", 0)...)
+	exports = append(exports, export("_contract_touch", 0)...)
+	exports = append(exports, export("_contract_fail", 1)...)
+
+	bodies := []byte{0x02, byte(len(touch))}
+	bodies = append(bodies, touch...)
+	bodies = append(bodies, byte(len(fail)))
+	bodies = append(bodies, fail...)
+
+	code := []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+	code = append(code, section(0x01, 0x01, 0x60, 0x00, 0x00)...)
+	code = append(code, section(0x03, 0x02, 0x00, 0x00)...)
+	code = append(code, section(0x05, append([]byte{0x01, 0x00}, leb(numPages)...)...)...)
+	code = append(code, section(0x07, exports...)...)
+	code = append(code, section(0x0a, bodies...)...)
+
+	return code
+}
+
+func TestSaveContractMemorySnapshotDirtyPages(t *testing.T) {
+	t.Parallel()
+
+	var id AccountID
+	id[0] = 1
+
+	mem := make([]byte, 4*PageSize)
+	mem[0], mem[PageSize] = 1, 2
+
+	tree := avl.New(store.NewInmem())
+	SaveContractMemorySnapshot(tree, id, mem, nil)
+
+	assert.Equal(t, mem, LoadContractMemorySnapshot(tree, id))
+
+	// Only pages marked as dirty are persisted.
+	mem[PageSize], mem[3*PageSize] = 3, 4
+
+	SaveContractMemorySnapshot(tree, id, mem, []bool{false, true, false, false})
+
+	loaded := LoadContractMemorySnapshot(tree, id)
+	assert.EqualValues(t, 3, loaded[PageSize])
+	assert.EqualValues(t, 0, loaded[3*PageSize])
+
+	// Pages that differ from those persisted are marked as dirty, along with pages that were already dirty.
+	dirty := markDirtyPages(tree, id, mem, []bool{true, false, false, false})
+	assert.Equal(t, []bool{true, false, false, true}, dirty)
+
+	SaveContractMemorySnapshot(tree, id, mem, dirty)
+	assert.Equal(t, mem, LoadContractMemorySnapshot(tree, id))
+	assert.Equal(t, make([]bool, 4), markDirtyPages(tree, id, mem, make([]bool, 4)))
+
+	// Pages persisted as zeroes are not marked as dirty unless written into.
+	mem[3*PageSize] = 0
+	SaveContractMemorySnapshot(tree, id, mem, nil)
+
+	assert.Equal(t, make([]bool, 4), markDirtyPages(tree, id, mem, make([]bool, 4)))
+
+	// Pages that memory has grown by are always dirty.
+	grown := append(mem, make([]byte, PageSize)...)
+
+	assert.Equal(t, []bool{false, false, false, false, true}, markDirtyPages(tree, id, grown, make([]bool, 4)))
+	assert.Nil(t, markDirtyPages(tree, id, grown, nil))
+}
+
+// TestContractMemoryDirtyPages checks that persisting only the pages of smart contracts marked as dirty yields
+// the same state as persisting every page.
+func TestContractMemoryDirtyPages(t *testing.T) {
+	t.Parallel()
+
+	keys, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	transferBack, err := ioutil.ReadFile("testdata/transfer_back.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	contracts := []struct {
+		code     []byte
+		function string
+		fail     string
+	}{
+		{code: transferBack, function: "on_money_received", fail: "on_money_received"},
+		{code: buildMemoryContract(16), function: "touch", fail: "fail"},
+	}
+
+	for _, contract := range contracts {
+		state := avl.New(store.NewInmem())
+		WriteAccountBalance(state, keys.PublicKey(), 1000000000)
+
+		block := NewBlock(0, state.Checksum())
+
+		var nonce uint64
+
+		sign := func(tag sys.Tag, payload []byte) *Transaction {
+			nonce++
+			tx := buildSignedTransaction(keys, tag, nonce, block.Index+1, payload)
+
+			return &tx
+		}
+
+		payload, err := buildContractSpawnPayload(100000, 0, contract.code).Marshal()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		spawn := sign(sys.TagContract, payload)
+		contractID := spawn.ID
+
+		ctx := NewCollapseContext(state)
+		assert.NoError(t, ctx.ApplyTransaction(&block, spawn))
+		assert.NoError(t, ctx.Flush())
+
+		invoke, err := buildTransferWithInvocationPayload(
+			contractID, 200, 100000, []byte(contract.function), nil, 0,
+		).Marshal()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		outOfGas, err := buildTransferWithInvocationPayload(
+			contractID, 200, 1, []byte(contract.function), nil, 0,
+		).Marshal()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		fail, err := buildTransferWithInvocationPayload(contractID, 200, 100000, []byte(contract.fail), nil, 0).Marshal()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		// Invoke the contract several times within a single context, some of which fail, and compare persisting
+		// only dirty pages against persisting every page.
+		dirty, full := NewCollapseContext(state.Snapshot()), NewCollapseContext(state.Snapshot())
+
+		txs := []*Transaction{
+			sign(sys.TagTransfer, invoke),
+			sign(sys.TagTransfer, outOfGas),
+			sign(sys.TagTransfer, invoke),
+			sign(sys.TagTransfer, fail),
+		}
+
+		for _, ctx := range []*CollapseContext{dirty, full} {
+			for _, tx := range txs {
+				assert.NoError(t, ctx.ApplyTransaction(&block, tx))
+			}
+		}
+
+		numPages, _ := ReadAccountContractNumPages(state, contractID)
+
+		if vm, ok := dirty.GetContractState(contractID); assert.True(t, ok) && assert.NotNil(t, vm.Dirty) {
+			marked := 0
+
+			for _, d := range vm.Dirty {
+				if d {
+					marked++
+				}
+			}
+
+			assert.True(t, marked > 0)
+			assert.True(t, uint64(marked) < numPages)
+		}
+
+		for _, vm := range full.contractVMs {
+			vm.Dirty = nil
+		}
+
+		assert.NoError(t, dirty.Flush())
+		assert.NoError(t, full.Flush())
+
+		assert.Equal(t, full.tree.Checksum(), dirty.tree.Checksum())
+		assert.Equal(t, LoadContractMemorySnapshot(full.tree, contractID), LoadContractMemorySnapshot(dirty.tree, contractID))
+	}
+}
+
+func benchmarkSaveContractMemorySnapshot(b *testing.B, numPages int) {
+	var id AccountID
+	id[0] = 1
+
+	mem := make([]byte, numPages*PageSize)
+
+	for i := range mem {
+		mem[i] = byte(i)
+	}
+
+	tree := avl.New(store.NewInmem())
+	SaveContractMemorySnapshot(tree, id, mem, nil)
+
+	dirty := make([]bool, numPages)
+	dirty[0] = true
+
+	for _, bench := range []struct {
+		name  string
+		dirty []bool
+	}{{name: "all", dirty: nil}, {name: "dirty", dirty: dirty}} {
+		bench := bench
+
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for n := 0; n < b.N; n++ {
+				mem[0]++
+				SaveContractMemorySnapshot(tree, id, mem, bench.dirty)
+			}
+		})
+	}
+}
+
+func BenchmarkSaveContractMemorySnapshot1Page(b *testing.B) {
+	benchmarkSaveContractMemorySnapshot(b, 1)
+}
+
+func BenchmarkSaveContractMemorySnapshot1000Pages(b *testing.B) {
+	benchmarkSaveContractMemorySnapshot(b, 1000)
+}
+
+func benchmarkContractInvocation(b *testing.B, numPages uint64) {
+	keys, err := skademlia.NewKeys(1, 1)
+	assert.NoError(b, err)
+
+	state := avl.New(store.NewInmem())
+	WriteAccountBalance(state, keys.PublicKey(), 1<<60)
+
+	block := NewBlock(0, state.Checksum())
+
+	var nonce uint64
+
+	apply := func(tag sys.Tag, payload []byte) *Transaction {
+		nonce++
+		tx := buildSignedTransaction(keys, tag, nonce, block.Index+1, payload)
+
+		ctx := NewCollapseContext(state)
+
+		if err := ctx.ApplyTransaction(&block, &tx); err != nil {
+			b.Fatal(err)
+		}
+
+		if err := ctx.Flush(); err != nil {
+			b.Fatal(err)
+		}
+
+		return &tx
+	}
+
+	payload, err := buildContractSpawnPayload(100000, 0, buildMemoryContract(numPages)).Marshal()
+	assert.NoError(b, err)
+
+	contractID := apply(sys.TagContract, payload).ID
+
+	payload, err = buildTransferWithInvocationPayload(contractID, 1, 100000, []byte("touch"), nil, 0).Marshal()
+	assert.NoError(b, err)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		apply(sys.TagTransfer, payload)
+	}
+
+	b.StopTimer()
+
+	mem := LoadContractMemorySnapshot(state, contractID)
+	assert.Len(b, mem, int(numPages)*PageSize)
+	assert.EqualValues(b, b.N+1, binary.LittleEndian.Uint32(mem))
+}
+
+func BenchmarkContractInvocation1Page(b *testing.B) {
+	benchmarkContractInvocation(b, 1)
+}
+
+func BenchmarkContractInvocation1000Pages(b *testing.B) {
+	benchmarkContractInvocation(b, 1000)
+}
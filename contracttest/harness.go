@@ -0,0 +1,354 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package contracttest provides a lightweight harness for testing smart contracts with plain `go test`. Contracts are
+// deployed and invoked by applying signed transactions against an in-memory ledger state, through the exact same
+// path transactions finalized by the ledger are applied through.
+package contracttest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/perlin-network/noise/edwards25519"
+	"github.com/perlin-network/noise/skademlia"
+	"github.com/perlin-network/wavelet"
+	"github.com/perlin-network/wavelet/avl"
+	"github.com/perlin-network/wavelet/store"
+	"github.com/perlin-network/wavelet/sys"
+	"golang.org/x/crypto/blake2b"
+)
+
+// DefaultGasLimit is the gas limit contracts are deployed and invoked with should no gas limit be specified.
+const DefaultGasLimit = 100000000
+
+// Harness holds the state of an in-memory ledger, along with the block transactions are applied within. Blocks only
+// advance when explicitly asked to, so that contracts observe the same block heights on every run.
+//
+// Transaction fees are not charged, so that balances only change by the amounts transferred and the gas consumed.
+type Harness struct {
+	// Engine, if set, is the engine smart contracts are executed with.
+	Engine wavelet.ContractEngine
+
+	tree  *avl.Tree
+	block wavelet.Block
+
+	accounts uint64
+	nonces   map[wavelet.AccountID]uint64
+}
+
+// Account is an account whose keys are held by the harness, such that it may sign transactions.
+type Account struct {
+	ID wavelet.AccountID
+
+	keys *skademlia.Keypair
+}
+
+// New returns a harness over an empty ledger state at block height zero.
+func New() *Harness {
+	tree := avl.New(store.NewInmem())
+
+	return &Harness{
+		tree:   tree,
+		block:  wavelet.NewBlock(0, tree.Checksum()),
+		nonces: make(map[wavelet.AccountID]uint64),
+	}
+}
+
+// Tree returns the ledger state the harness applies transactions against.
+func (h *Harness) Tree() *avl.Tree {
+	return h.tree
+}
+
+// Height returns the height of the block transactions are currently applied within.
+func (h *Harness) Height() uint64 {
+	return h.block.Index
+}
+
+// Block returns the block transactions are currently applied within.
+func (h *Harness) Block() wavelet.Block {
+	return h.block
+}
+
+// AdvanceBlocks advances the block transactions are applied within by n blocks. The Merkle root of every block is
+// the Merkle root of the ledger state at the time it is advanced to.
+func (h *Harness) AdvanceBlocks(n uint64) {
+	h.block = wavelet.NewBlock(h.block.Index+n, h.tree.Checksum())
+}
+
+// AdvanceBlock advances the block transactions are applied within by a single block.
+func (h *Harness) AdvanceBlock() {
+	h.AdvanceBlocks(1)
+}
+
+// NewAccount creates an account funded with balance PERLs. Accounts are derived deterministically from the order
+// in which they are created.
+func (h *Harness) NewAccount(balance uint64) *Account {
+	for {
+		h.accounts++
+
+		var buf [8]byte
+		binary.BigEndian.PutUint64(buf[:], h.accounts)
+
+		seed := blake2b.Sum256(append([]byte("contracttest"), buf[:]...))
+
+		_, privateKey, err := edwards25519.GenerateKey(bytes.NewReader(seed[:]))
+		if err != nil {
+			panic(err)
+		}
+
+		// Not every private key passes the S/Kademlia crypto puzzle, in which case the next one is tried.
+		keys, err := skademlia.LoadKeys(privateKey, 1, 1)
+		if err != nil {
+			continue
+		}
+
+		account := &Account{ID: keys.PublicKey(), keys: keys}
+		h.Fund(account.ID, balance)
+
+		return account
+	}
+}
+
+// Fund adds amount PERLs to the balance of an account.
+func (h *Harness) Fund(id wavelet.AccountID, amount uint64) {
+	balance, _ := wavelet.ReadAccountBalance(h.tree, id)
+	wavelet.WriteAccountBalance(h.tree, id, balance+amount)
+}
+
+// Balance returns the balance of an account.
+func (h *Harness) Balance(id wavelet.AccountID) uint64 {
+	balance, _ := wavelet.ReadAccountBalance(h.tree, id)
+	return balance
+}
+
+// GasBalance returns the gas balance of a contract.
+func (h *Harness) GasBalance(id wavelet.TransactionID) uint64 {
+	balance, _ := wavelet.ReadAccountContractGasBalance(h.tree, id)
+	return balance
+}
+
+// Storage returns the value of a key in the key-value storage of a contract.
+func (h *Harness) Storage(id wavelet.TransactionID, key []byte) ([]byte, bool) {
+	return wavelet.ReadAccountContractStorage(h.tree, id, key)
+}
+
+// Apply signs a transaction on behalf of an account and applies it within the current block. The ledger state is
+// only changed should the transaction not be rejected.
+func (h *Harness) Apply(from *Account, tag sys.Tag, payload []byte) *Receipt {
+	h.nonces[from.ID]++
+
+	tx := wavelet.NewTransaction(from.keys, h.nonces[from.ID], h.block.Index+1, tag, payload)
+	receipt := &Receipt{TxID: tx.ID, Trace: new(wavelet.ContractTrace)}
+
+	ctx := wavelet.NewCollapseContext(h.tree)
+	ctx.Engine = h.Engine
+
+	if receipt.Err = ctx.TraceTransaction(&h.block, &tx, receipt.Trace); receipt.Err != nil {
+		return receipt
+	}
+
+	receipt.Err = ctx.Flush()
+
+	return receipt
+}
+
+// Deploy spawns a contract from code on behalf of an account, invoking its `init` function with params. The ID of
+// the contract is the ID of the transaction that spawned it.
+func (h *Harness) Deploy(from *Account, code []byte, params []byte, gasLimit uint64) *Receipt {
+	if gasLimit == 0 {
+		gasLimit = DefaultGasLimit
+	}
+
+	payload, err := wavelet.Contract{GasLimit: gasLimit, Params: params, Code: code}.Marshal()
+	if err != nil {
+		return &Receipt{Err: err}
+	}
+
+	return h.Apply(from, sys.TagContract, payload)
+}
+
+// Call invokes the function fn of a contract with params on behalf of an account, sending along amount PERLs.
+func (h *Harness) Call(
+	from *Account, contract wavelet.TransactionID, fn string, params []byte, amount, gasLimit uint64,
+) *Receipt {
+	if gasLimit == 0 {
+		gasLimit = DefaultGasLimit
+	}
+
+	payload, err := wavelet.Transfer{
+		Recipient:  contract,
+		Amount:     amount,
+		GasLimit:   gasLimit,
+		FuncName:   []byte(fn),
+		FuncParams: params,
+	}.Marshal()
+	if err != nil {
+		return &Receipt{Err: err}
+	}
+
+	return h.Apply(from, sys.TagTransfer, payload)
+}
+
+// Receipt is the outcome of applying a transaction.
+type Receipt struct {
+	TxID wavelet.TransactionID
+
+	// Err is the error the transaction was rejected with, if it was rejected. A transaction is not rejected should
+	// a contract function it invoked fail; see InvocationErr.
+	Err error
+
+	// Trace is a trace of every contract invocation made while applying the transaction.
+	Trace *wavelet.ContractTrace
+}
+
+// ContractID returns the ID of the contract spawned by the transaction, should the transaction have spawned one.
+func (r *Receipt) ContractID() wavelet.TransactionID {
+	return r.TxID
+}
+
+// Gas returns the total gas consumed by all contract invocations made while applying the transaction.
+func (r *Receipt) Gas() uint64 {
+	var gas uint64
+
+	if r.Trace != nil {
+		for _, invocation := range r.Trace.Invocations {
+			gas += invocation.Gas
+		}
+	}
+
+	return gas
+}
+
+// Result returns the result returned by the first contract invocation made while applying the transaction, which is
+// the invocation of the function the transaction called.
+func (r *Receipt) Result() []byte {
+	if r.Trace == nil || len(r.Trace.Invocations) == 0 {
+		return nil
+	}
+
+	return r.Trace.Invocations[0].Result
+}
+
+// Logs returns the messages logged by all contract invocations made while applying the transaction, in order.
+func (r *Receipt) Logs() []string {
+	var logs []string
+
+	if r.Trace != nil {
+		for _, invocation := range r.Trace.Invocations {
+			for _, msg := range invocation.Logs {
+				logs = append(logs, string(msg))
+			}
+		}
+	}
+
+	return logs
+}
+
+// InvocationErr returns an error describing the first contract invocation that failed while applying the
+// transaction, should any have failed.
+func (r *Receipt) InvocationErr() error {
+	if r.Trace == nil {
+		return nil
+	}
+
+	for _, invocation := range r.Trace.Invocations {
+		if invocation.Error != "" {
+			return fmt.Errorf("contract %x failed to invoke %q: %s", invocation.ContractID, invocation.Function,
+				invocation.Error)
+		}
+	}
+
+	return nil
+}
+
+// AssertSuccess asserts that the transaction was not rejected, and that no contract invocation made while
+// applying it failed.
+func (r *Receipt) AssertSuccess(t testing.TB) {
+	t.Helper()
+
+	if r.Err != nil {
+		t.Errorf("transaction %x was rejected: %v", r.TxID, r.Err)
+	}
+
+	if err := r.InvocationErr(); err != nil {
+		t.Errorf("transaction %x: %v", r.TxID, err)
+	}
+}
+
+// AssertFailure asserts that the transaction was either rejected, or that some contract invocation made while
+// applying it failed.
+func (r *Receipt) AssertFailure(t testing.TB) {
+	t.Helper()
+
+	if r.Err == nil && r.InvocationErr() == nil {
+		t.Errorf("expected transaction %x to fail, but it succeeded", r.TxID)
+	}
+}
+
+// AssertResult asserts that the function the transaction called returned expected as its result.
+func (r *Receipt) AssertResult(t testing.TB, expected []byte) {
+	t.Helper()
+
+	if result := r.Result(); !bytes.Equal(result, expected) {
+		t.Errorf("expected transaction %x to result in %q, but got %q", r.TxID, expected, result)
+	}
+}
+
+// AssertLogged asserts that some contract invocation made while applying the transaction logged msg.
+func (r *Receipt) AssertLogged(t testing.TB, msg string) {
+	t.Helper()
+
+	for _, logged := range r.Logs() {
+		if logged == msg {
+			return
+		}
+	}
+
+	t.Errorf("expected transaction %x to log %q, but got %q", r.TxID, msg, r.Logs())
+}
+
+// AssertGasAtMost asserts that the transaction consumed at most limit gas.
+func (r *Receipt) AssertGasAtMost(t testing.TB, limit uint64) {
+	t.Helper()
+
+	if gas := r.Gas(); gas > limit {
+		t.Errorf("expected transaction %x to consume at most %d gas, but it consumed %d", r.TxID, limit, gas)
+	}
+}
+
+// AssertBalance asserts that the balance of an account is expected.
+func (h *Harness) AssertBalance(t testing.TB, id wavelet.AccountID, expected uint64) {
+	t.Helper()
+
+	if balance := h.Balance(id); balance != expected {
+		t.Errorf("expected the balance of %x to be %d, but it is %d", id, expected, balance)
+	}
+}
+
+// AssertGasBalance asserts that the gas balance of a contract is expected.
+func (h *Harness) AssertGasBalance(t testing.TB, id wavelet.TransactionID, expected uint64) {
+	t.Helper()
+
+	if balance := h.GasBalance(id); balance != expected {
+		t.Errorf("expected the gas balance of %x to be %d, but it is %d", id, expected, balance)
+	}
+}
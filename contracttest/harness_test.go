@@ -0,0 +1,128 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// +build unit
+
+package contracttest
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// greeter is a contract whose function `greet` logs and returns "hello" by calling `_log` and `_result`.
+var greeter = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// (type (func)) (type (func (param i32 i32)))
+	0x01, 0x09, 0x02, 0x60, 0x00, 0x00, 0x60, 0x02, 0x7f, 0x7f, 0x00,
+	// (import "env" "_log" (func (type 1))) (import "env" "_result" (func (type 1)))
+	0x02, 0x1a, 0x02,
+	0x03, 'e', 'n', 'v', 0x04, '_', 'l', 'o', 'g', 0x00, 0x01,
+	0x03, 'e', 'n', 'v', 0x07, '_', 'r', 'e', 's', 'u', 'l', 't', 0x00, 0x01,
+	// (func (type 0)) (func (type 0))
+	0x03, 0x03, 0x02, 0x00, 0x00,
+	// (memory 1)
+	0x05, 0x03, 0x01, 0x00, 0x01,
+	// (export "_contract_init" (func 2)) (export "_contract_greet" (func 3))
+	0x07, 0x24, 0x02,
+	0x0e, '_', 'c', 'o', 'n', 't', 'r', 'a', 'c', 't', '_', 'i', 'n', 'i', 't', 0x00, 0x02,
+	0x0f, '_', 'c', 'o', 'n', 't', 'r', 'a', 'c', 't', '_', 'g', 'r', 'e', 'e', 't', 0x00, 0x03,
+	// (func) (func (call 0 (i32.const 0) (i32.const 5)) (call 1 (i32.const 0) (i32.const 5)))
+	0x0a, 0x13, 0x02,
+	0x02, 0x00, 0x0b,
+	0x0e, 0x00, 0x41, 0x00, 0x41, 0x05, 0x10, 0x00, 0x41, 0x00, 0x41, 0x05, 0x10, 0x01, 0x0b,
+	// (data (i32.const 0) "hello")
+	0x0b, 0x0b, 0x01, 0x00, 0x41, 0x00, 0x0b, 0x05, 'h', 'e', 'l', 'l', 'o',
+}
+
+func TestHarnessResultsAndLogs(t *testing.T) {
+	h := New()
+	alice := h.NewAccount(1000000000)
+
+	deploy := h.Deploy(alice, greeter, nil, 0)
+	deploy.AssertSuccess(t)
+
+	greet := h.Call(alice, deploy.ContractID(), "greet", nil, 0, 0)
+	greet.AssertSuccess(t)
+	greet.AssertResult(t, []byte("hello"))
+	greet.AssertLogged(t, "hello")
+
+	assert.NotZero(t, greet.Gas())
+	greet.AssertGasAtMost(t, greet.Gas())
+
+	h.AssertBalance(t, alice.ID, 1000000000-deploy.Gas()-greet.Gas())
+
+	missing := h.Call(alice, deploy.ContractID(), "missing", nil, 0, 0)
+	missing.AssertFailure(t)
+	assert.Error(t, missing.InvocationErr())
+}
+
+func TestHarnessBalances(t *testing.T) {
+	code, err := ioutil.ReadFile("../testdata/transfer_back.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	h := New()
+	alice := h.NewAccount(1000000000)
+
+	deploy := h.Deploy(alice, code, nil, 0)
+	deploy.AssertSuccess(t)
+
+	contract := deploy.ContractID()
+
+	call := h.Call(alice, contract, "on_money_received", nil, 1000, 0)
+	call.AssertSuccess(t)
+
+	// The contract sends half of the PERLs it receives back to the sender.
+	h.AssertBalance(t, contract, 500)
+	h.AssertBalance(t, alice.ID, 1000000000-500-deploy.Gas()-call.Gas())
+
+	// Rejected transactions leave the ledger state untouched.
+	poor := h.NewAccount(0)
+	checksum := h.Tree().Checksum()
+
+	h.Call(poor, contract, "on_money_received", nil, 1000, 0).AssertFailure(t)
+
+	h.AssertBalance(t, poor.ID, 0)
+	assert.Equal(t, checksum, h.Tree().Checksum())
+}
+
+func TestHarnessDeterminism(t *testing.T) {
+	run := func() *Harness {
+		h := New()
+		alice := h.NewAccount(1000000000)
+
+		h.AdvanceBlocks(3)
+		h.Deploy(alice, greeter, nil, 0).AssertSuccess(t)
+
+		h.AdvanceBlock()
+		h.Call(alice, h.Deploy(alice, greeter, nil, 0).ContractID(), "greet", nil, 0, 0).AssertSuccess(t)
+
+		return h
+	}
+
+	a, b := run(), run()
+
+	assert.EqualValues(t, 4, a.Height())
+	assert.Equal(t, a.Block(), b.Block())
+	assert.Equal(t, a.Tree().Checksum(), b.Tree().Checksum())
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/perlin-network/wavelet/avl"
 	"github.com/perlin-network/wavelet/store"
 	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
 	"io"
 	"strconv"
 )
@@ -41,6 +42,15 @@ var (
 	keyBlockStoredCount     = [...]byte{0x6}
 	keyRewardWithdrawals    = [...]byte{0x7}
 	keyTransactionFinalized = [...]byte{0x8}
+	keyModuleCache          = [...]byte{0x9}
+	keyModuleCacheIndex     = [...]byte{0xA}
+	keyContractCode         = [...]byte{0xB}
+	keyAssets               = [...]byte{0xC}
+	keyHTLCs                = [...]byte{0xD}
+	keySchedules            = [...]byte{0xE}
+	keySchedulesDue         = [...]byte{0xF}
+	keyContractCodeMigrated = [...]byte{0x10}
+	keyBatchResults         = [...]byte{0x11}
 
 	// Account-local prefixes.
 	keyAccountBalance            = [...]byte{0x2}
@@ -51,6 +61,14 @@ var (
 	keyAccountContractPages      = [...]byte{0x7}
 	keyAccountContractGasBalance = [...]byte{0x8}
 	keyAccountContractGlobals    = [...]byte{0x9}
+	keyAccountContractStorage    = [...]byte{0xA}
+	keyAccountContractOwner      = [...]byte{0xB}
+	keyAccountContractCodeID     = [...]byte{0xC}
+	keyAccountMultisig           = [...]byte{0xD}
+	keyAccountSigningKey         = [...]byte{0xE}
+	keyAccountAssetBalance       = [...]byte{0xF}
+	keyAccountSchedules          = [...]byte{0x10}
+	keyAccountAllowances         = [...]byte{0x11}
 )
 
 type RewardWithdrawalRequest struct {
@@ -162,7 +180,65 @@ func WriteAccountReward(tree *avl.Tree, id AccountID, reward uint64) {
 	writeUnderAccounts(tree, id, keyAccountReward[:], buf[:])
 }
 
+// Smart contract code is stored once under the key [HEADER | 256-bit code ID], where the code ID is the BLAKE2b-256
+// hash of the code. Contracts reference the code they were spawned with by its code ID. Code is never deleted once
+// uploaded, so that new contracts may always be spawned from it by its code ID.
+func contractCodeKey(codeID ContractCodeID) []byte {
+	k := make([]byte, 0, len(keyContractCode)+len(codeID))
+	k = append(k, keyContractCode[:]...)
+	k = append(k, codeID[:]...)
+
+	return k
+}
+
+// HashContractCode returns the code ID under which code is stored.
+func HashContractCode(code []byte) ContractCodeID {
+	return blake2b.Sum256(code)
+}
+
+func ReadContractCode(tree *avl.Tree, codeID ContractCodeID) ([]byte, bool) {
+	buf, exists := tree.Lookup(contractCodeKey(codeID))
+	if !exists || len(buf) == 0 {
+		return nil, false
+	}
+
+	return buf, true
+}
+
+// WriteContractCode stores code under its code ID, which it returns. Code that has already been stored is not
+// written again.
+func WriteContractCode(tree *avl.Tree, code []byte) ContractCodeID {
+	codeID := HashContractCode(code)
+
+	k := contractCodeKey(codeID)
+	if _, exists := tree.Lookup(k); !exists {
+		tree.Insert(k, code)
+	}
+
+	return codeID
+}
+
+func ReadAccountContractCodeID(tree *avl.Tree, id TransactionID) (ContractCodeID, bool) {
+	var codeID ContractCodeID
+
+	buf, exists := readUnderAccounts(tree, id, keyAccountContractCodeID[:])
+	if !exists || len(buf) != SizeContractCodeID {
+		return codeID, false
+	}
+
+	copy(codeID[:], buf)
+
+	return codeID, true
+}
+
+// ReadAccountContractCode resolves the code of a contract through the code ID it references. Code stored under the
+// contract itself, as it was prior to code being stored by its code ID, is still read should it not have been
+// migrated through MigrateContractCode yet.
 func ReadAccountContractCode(tree *avl.Tree, id TransactionID) ([]byte, bool) {
+	if codeID, exists := ReadAccountContractCodeID(tree, id); exists {
+		return ReadContractCode(tree, codeID)
+	}
+
 	buf, exists := readUnderAccounts(tree, id, keyAccountContractCode[:])
 	if !exists || len(buf) == 0 {
 		return nil, false
@@ -171,8 +247,51 @@ func ReadAccountContractCode(tree *avl.Tree, id TransactionID) ([]byte, bool) {
 	return buf, true
 }
 
+// WriteAccountContractCode stores code under its code ID should it not already be stored, and has the contract
+// reference it.
 func WriteAccountContractCode(tree *avl.Tree, id TransactionID, code []byte) {
-	writeUnderAccounts(tree, id, keyAccountContractCode[:], code)
+	codeID := WriteContractCode(tree, code)
+
+	writeUnderAccounts(tree, id, keyAccountContractCodeID[:], codeID[:])
+	deleteUnderAccounts(tree, id, keyAccountContractCode[:])
+}
+
+// MigrateContractCode moves the code of contracts which is stored under the contracts themselves to be stored once
+// under its code ID instead, and returns the number of contracts migrated. The migration is only ever run once per
+// ledger state, as a marker recording that it was run is stored alongside the migrated code.
+func MigrateContractCode(tree *avl.Tree) int {
+	if _, migrated := tree.Lookup(keyContractCodeMigrated[:]); migrated {
+		return 0
+	}
+
+	tree.Insert(keyContractCodeMigrated[:], []byte{1})
+
+	var (
+		ids   []TransactionID
+		codes [][]byte
+	)
+
+	prefix := append(keyAccounts[:], keyAccountContractCode[:]...)
+
+	tree.IteratePrefix(prefix, func(key, value []byte) bool {
+		if len(key) != SizeTransactionID {
+			return true
+		}
+
+		var id TransactionID
+		copy(id[:], key)
+
+		ids = append(ids, id)
+		codes = append(codes, value)
+
+		return true
+	})
+
+	for i, id := range ids {
+		WriteAccountContractCode(tree, id, codes[i])
+	}
+
+	return len(ids)
 }
 
 func ReadAccountContractNumPages(tree *avl.Tree, id TransactionID) (uint64, bool) {
@@ -245,6 +364,400 @@ func WriteAccountContractGasBalance(tree *avl.Tree, id TransactionID, gasBalance
 	writeUnderAccounts(tree, id, keyAccountContractGasBalance[:], buf[:])
 }
 
+func ReadAccountContractOwner(tree *avl.Tree, id TransactionID) (AccountID, bool) {
+	var owner AccountID
+
+	buf, exists := readUnderAccounts(tree, id, keyAccountContractOwner[:])
+	if !exists || len(buf) != SizeAccountID {
+		return owner, false
+	}
+
+	copy(owner[:], buf)
+
+	return owner, true
+}
+
+func WriteAccountContractOwner(tree *avl.Tree, id TransactionID, owner AccountID) {
+	writeUnderAccounts(tree, id, keyAccountContractOwner[:], owner[:])
+}
+
+// ReadAccountMultisig reads the threshold and keys of a multisig account. It reports false should the account not
+// be a multisig account.
+func ReadAccountMultisig(tree *avl.Tree, id AccountID) (Multisig, bool) {
+	buf, exists := readUnderAccounts(tree, id, keyAccountMultisig[:])
+	if !exists {
+		return Multisig{}, false
+	}
+
+	multisig, err := ParseMultisig(buf)
+	if err != nil {
+		return Multisig{}, false
+	}
+
+	return multisig, true
+}
+
+func WriteAccountMultisig(tree *avl.Tree, id AccountID, multisig Multisig) {
+	buf, _ := multisig.Marshal()
+	writeUnderAccounts(tree, id, keyAccountMultisig[:], buf)
+}
+
+// ReadAccountSigningKey reads the key bound to an account to sign transactions on its behalf. It reports false
+// should the key of the account never have been rotated, in which case transactions are signed by the account ID.
+func ReadAccountSigningKey(tree *avl.Tree, id AccountID) (AccountID, bool) {
+	var key AccountID
+
+	buf, exists := readUnderAccounts(tree, id, keyAccountSigningKey[:])
+	if !exists || len(buf) != SizeAccountID {
+		return key, false
+	}
+
+	copy(key[:], buf)
+
+	return key, true
+}
+
+func WriteAccountSigningKey(tree *avl.Tree, id AccountID, key AccountID) {
+	writeUnderAccounts(tree, id, keyAccountSigningKey[:], key[:])
+}
+
+func ReadAsset(tree *avl.Tree, id AssetID) (AssetInfo, bool) {
+	buf, exists := tree.Lookup(append(keyAssets[:], id[:]...))
+	if !exists {
+		return AssetInfo{}, false
+	}
+
+	info, err := UnmarshalAssetInfo(bytes.NewReader(buf))
+	if err != nil {
+		return AssetInfo{}, false
+	}
+
+	return info, true
+}
+
+func WriteAsset(tree *avl.Tree, id AssetID, info AssetInfo) {
+	tree.Insert(append(keyAssets[:], id[:]...), info.Marshal())
+}
+
+func ReadHTLC(tree *avl.Tree, id TransactionID) (HTLCInfo, bool) {
+	buf, exists := tree.Lookup(append(keyHTLCs[:], id[:]...))
+	if !exists {
+		return HTLCInfo{}, false
+	}
+
+	info, err := UnmarshalHTLCInfo(bytes.NewReader(buf))
+	if err != nil {
+		return HTLCInfo{}, false
+	}
+
+	return info, true
+}
+
+func WriteHTLC(tree *avl.Tree, id TransactionID, info HTLCInfo) {
+	tree.Insert(append(keyHTLCs[:], id[:]...), info.Marshal())
+}
+
+// ReadBatchResults reads the outcomes of the entries of the best-effort batch transaction with the given ID.
+func ReadBatchResults(tree *avl.Tree, id TransactionID) ([]BatchEntryResult, bool) {
+	buf, exists := tree.Lookup(append(keyBatchResults[:], id[:]...))
+	if !exists {
+		return nil, false
+	}
+
+	results, err := UnmarshalBatchResults(bytes.NewReader(buf))
+	if err != nil {
+		return nil, false
+	}
+
+	return results, true
+}
+
+func WriteBatchResults(tree *avl.Tree, id TransactionID, results []BatchEntryResult) {
+	tree.Insert(append(keyBatchResults[:], id[:]...), MarshalBatchResults(results))
+}
+
+// Scheduled transfers are stored under the key [HEADER | 256-bit schedule ID], and are indexed both by the block
+// height at which they are next due under [HEADER | 64-bit big-endian block height | 256-bit schedule ID], and by
+// their sender under [HEADER | schedules prefix | 256-bit account ID | 256-bit schedule ID].
+func scheduleDueKey(next uint64, id TransactionID) []byte {
+	k := make([]byte, len(keySchedulesDue)+8+len(id))
+	copy(k, keySchedulesDue[:])
+
+	binary.BigEndian.PutUint64(k[len(keySchedulesDue):], next)
+	copy(k[len(keySchedulesDue)+8:], id[:])
+
+	return k
+}
+
+func accountScheduleKey(sender AccountID, id TransactionID) []byte {
+	k := make([]byte, 0, len(keyAccounts)+len(keyAccountSchedules)+len(sender)+len(id))
+	k = append(k, keyAccounts[:]...)
+	k = append(k, keyAccountSchedules[:]...)
+	k = append(k, sender[:]...)
+	k = append(k, id[:]...)
+
+	return k
+}
+
+func ReadSchedule(tree *avl.Tree, id TransactionID) (ScheduleInfo, bool) {
+	buf, exists := tree.Lookup(append(keySchedules[:], id[:]...))
+	if !exists {
+		return ScheduleInfo{}, false
+	}
+
+	info, err := UnmarshalScheduleInfo(bytes.NewReader(buf))
+	if err != nil {
+		return ScheduleInfo{}, false
+	}
+
+	return info, true
+}
+
+// WriteSchedule writes a scheduled transfer along with its indices. Should the scheduled transfer already be stored,
+// it must be deleted through DeleteSchedule first such that it is no longer indexed by the block height at which it
+// was previously due.
+func WriteSchedule(tree *avl.Tree, id TransactionID, info ScheduleInfo) {
+	tree.Insert(append(keySchedules[:], id[:]...), info.Marshal())
+	tree.Insert(scheduleDueKey(info.Next, id), []byte{})
+	tree.Insert(accountScheduleKey(info.Sender, id), []byte{})
+}
+
+func DeleteSchedule(tree *avl.Tree, id TransactionID, info ScheduleInfo) {
+	tree.Delete(append(keySchedules[:], id[:]...))
+	tree.Delete(scheduleDueKey(info.Next, id))
+	tree.Delete(accountScheduleKey(info.Sender, id))
+}
+
+// ReadDueSchedules reads the IDs of all scheduled transfers due at or before the block height, ordered by the block
+// height at which they are due and then by their ID.
+func ReadDueSchedules(tree *avl.Tree, height uint64) []TransactionID {
+	var ids []TransactionID
+
+	tree.IteratePrefix(keySchedulesDue[:], func(key, _ []byte) bool {
+		if len(key) != 8+SizeTransactionID {
+			return true
+		}
+
+		if binary.BigEndian.Uint64(key[:8]) > height {
+			return false
+		}
+
+		var id TransactionID
+		copy(id[:], key[8:])
+
+		ids = append(ids, id)
+
+		return true
+	})
+
+	return ids
+}
+
+// ReadAccountSchedules reads the IDs of all scheduled transfers registered by an account, ordered by their ID.
+func ReadAccountSchedules(tree *avl.Tree, sender AccountID) []TransactionID {
+	var ids []TransactionID
+
+	prefix := accountScheduleKey(sender, ZeroTransactionID)
+	prefix = prefix[:len(prefix)-SizeTransactionID]
+
+	tree.IteratePrefix(prefix, func(key, _ []byte) bool {
+		if len(key) != SizeTransactionID {
+			return true
+		}
+
+		var id TransactionID
+		copy(id[:], key)
+
+		ids = append(ids, id)
+
+		return true
+	})
+
+	return ids
+}
+
+// Asset balances are stored under the key [HEADER | asset balance prefix | 256-bit account ID | 256-bit asset ID],
+// such that all asset balances of an account may be iterated over.
+func accountAssetBalanceKey(id AccountID, asset AssetID) []byte {
+	k := make([]byte, 0, len(keyAccounts)+len(keyAccountAssetBalance)+len(id)+len(asset))
+	k = append(k, keyAccounts[:]...)
+	k = append(k, keyAccountAssetBalance[:]...)
+	k = append(k, id[:]...)
+	k = append(k, asset[:]...)
+
+	return k
+}
+
+func ReadAccountAssetBalance(tree *avl.Tree, id AccountID, asset AssetID) (uint64, bool) {
+	buf, exists := tree.Lookup(accountAssetBalanceKey(id, asset))
+	if !exists || len(buf) == 0 {
+		return 0, false
+	}
+
+	return binary.LittleEndian.Uint64(buf), true
+}
+
+// WriteAccountAssetBalance writes the balance of an asset held by an account. A balance of zero is deleted, such
+// that only assets an account holds are listed by ReadAccountAssetBalances.
+func WriteAccountAssetBalance(tree *avl.Tree, id AccountID, asset AssetID, balance uint64) {
+	if balance == 0 {
+		tree.Delete(accountAssetBalanceKey(id, asset))
+		return
+	}
+
+	var buf [8]byte
+
+	binary.LittleEndian.PutUint64(buf[:], balance)
+	tree.Insert(accountAssetBalanceKey(id, asset), buf[:])
+}
+
+// ReadAccountAssetBalances reads the balances of all assets held by an account, ordered by asset ID.
+func ReadAccountAssetBalances(tree *avl.Tree, id AccountID) []AssetBalance {
+	var balances []AssetBalance
+
+	prefix := accountAssetBalanceKey(id, ZeroAssetID)
+	prefix = prefix[:len(prefix)-SizeAssetID]
+
+	tree.IteratePrefix(prefix, func(key, value []byte) bool {
+		if len(key) != SizeAssetID || len(value) != 8 {
+			return true
+		}
+
+		var balance AssetBalance
+
+		copy(balance.Asset[:], key)
+		balance.Balance = binary.LittleEndian.Uint64(value)
+
+		balances = append(balances, balance)
+
+		return true
+	})
+
+	return balances
+}
+
+// Allowances are stored under the key [HEADER | allowance prefix | 256-bit owner account ID | 256-bit spender
+// account ID], such that all allowances approved by an account may be iterated over.
+func accountAllowanceKey(owner AccountID, spender AccountID) []byte {
+	k := make([]byte, 0, len(keyAccounts)+len(keyAccountAllowances)+len(owner)+len(spender))
+	k = append(k, keyAccounts[:]...)
+	k = append(k, keyAccountAllowances[:]...)
+	k = append(k, owner[:]...)
+	k = append(k, spender[:]...)
+
+	return k
+}
+
+func ReadAccountAllowance(tree *avl.Tree, owner AccountID, spender AccountID) (Allowance, bool) {
+	buf, exists := tree.Lookup(accountAllowanceKey(owner, spender))
+	if !exists {
+		return Allowance{}, false
+	}
+
+	allowance, err := UnmarshalAllowance(spender, bytes.NewReader(buf))
+	if err != nil {
+		return Allowance{}, false
+	}
+
+	return allowance, true
+}
+
+// WriteAccountAllowance writes an allowance approved by an account. An allowance of zero PERLs is deleted, such that
+// only allowances which may be spent are listed by ReadAccountAllowances.
+func WriteAccountAllowance(tree *avl.Tree, owner AccountID, allowance Allowance) {
+	if allowance.Amount == 0 {
+		tree.Delete(accountAllowanceKey(owner, allowance.Spender))
+		return
+	}
+
+	tree.Insert(accountAllowanceKey(owner, allowance.Spender), allowance.Marshal())
+}
+
+// ReadAccountAllowances reads all allowances approved by an account, ordered by spender account ID.
+func ReadAccountAllowances(tree *avl.Tree, owner AccountID) []Allowance {
+	var allowances []Allowance
+
+	prefix := accountAllowanceKey(owner, ZeroAccountID)
+	prefix = prefix[:len(prefix)-SizeAccountID]
+
+	tree.IteratePrefix(prefix, func(key, value []byte) bool {
+		if len(key) != SizeAccountID {
+			return true
+		}
+
+		var spender AccountID
+		copy(spender[:], key)
+
+		allowance, err := UnmarshalAllowance(spender, bytes.NewReader(value))
+		if err != nil {
+			return true
+		}
+
+		allowances = append(allowances, allowance)
+
+		return true
+	})
+
+	return allowances
+}
+
+// DeleteAccountContract deletes the code, memory, globals, gas balance, owner and key-value storage of a
+// smart contract. The balance, stake and reward of the smart contracts account are left untouched.
+func DeleteAccountContract(tree *avl.Tree, id TransactionID) {
+	numPages, _ := ReadAccountContractNumPages(tree, id)
+
+	for idx := uint64(0); idx < numPages; idx++ {
+		k := make([]byte, len(keyAccountContractPages)+8)
+		copy(k, keyAccountContractPages[:])
+
+		binary.LittleEndian.PutUint64(k[len(keyAccountContractPages):], idx)
+
+		deleteUnderAccounts(tree, id, k)
+	}
+
+	deleteUnderAccounts(tree, id, keyAccountContractCode[:])
+	deleteUnderAccounts(tree, id, keyAccountContractCodeID[:])
+	deleteUnderAccounts(tree, id, keyAccountContractNumPages[:])
+	deleteUnderAccounts(tree, id, keyAccountContractGlobals[:])
+	deleteUnderAccounts(tree, id, keyAccountContractGasBalance[:])
+	deleteUnderAccounts(tree, id, keyAccountContractOwner[:])
+
+	var keys [][]byte
+
+	tree.IteratePrefix(contractStorageKey(id, nil), func(key, _ []byte) bool {
+		keys = append(keys, append([]byte{}, key...))
+		return true
+	})
+
+	for _, key := range keys {
+		DeleteAccountContractStorage(tree, id, key)
+	}
+}
+
+// Contract storage entries are stored under the key [HEADER | storage prefix | 256-bit contract ID | storage key],
+// such that storage keys of variable length may never collide across contracts.
+func contractStorageKey(id TransactionID, key []byte) []byte {
+	k := make([]byte, 0, len(keyAccounts)+len(keyAccountContractStorage)+len(id)+len(key))
+	k = append(k, keyAccounts[:]...)
+	k = append(k, keyAccountContractStorage[:]...)
+	k = append(k, id[:]...)
+	k = append(k, key...)
+
+	return k
+}
+
+func ReadAccountContractStorage(tree *avl.Tree, id TransactionID, key []byte) ([]byte, bool) {
+	return tree.Lookup(contractStorageKey(id, key))
+}
+
+func WriteAccountContractStorage(tree *avl.Tree, id TransactionID, key, value []byte) {
+	tree.Insert(contractStorageKey(id, key), value)
+}
+
+func DeleteAccountContractStorage(tree *avl.Tree, id TransactionID, key []byte) {
+	tree.Delete(contractStorageKey(id, key))
+}
+
 func readUnderAccounts(tree *avl.Tree, id AccountID, key []byte) ([]byte, bool) {
 	k := make([]byte, 0, len(keyAccounts)+len(key)+len(id))
 	k = append(k, keyAccounts[:]...)
@@ -268,6 +781,15 @@ func writeUnderAccounts(tree *avl.Tree, id AccountID, key, value []byte) {
 	tree.Insert(k, value)
 }
 
+func deleteUnderAccounts(tree *avl.Tree, id AccountID, key []byte) {
+	k := make([]byte, 0, len(keyAccounts)+len(key)+len(id))
+	k = append(k, keyAccounts[:]...)
+	k = append(k, key...)
+	k = append(k, id[:]...)
+
+	tree.Delete(k)
+}
+
 func ReadAccountsLen(tree *avl.Tree) uint64 {
 	buf, exists := tree.Lookup(keyAccountsLen[:])
 	if !exists {
@@ -0,0 +1,123 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrContractGasLimitExceeded = errors.New("gas limit exceeded")
+)
+
+// ContractEngine compiles and instantiates the code of smart contracts. Engines must instantiate code under the
+// limits in package sys, and must charge gas identically to one another, as the gas consumed by a smart contract
+// is agreed upon by consensus.
+type ContractEngine interface {
+	// Version uniquely identifies the engine, and the way in which it charges gas.
+	Version() string
+
+	// Instantiate instantiates code with a gas limit of gasLimit. Imports of code are resolved through host.
+	Instantiate(code []byte, gasLimit uint64, host ContractHost) (ContractInstance, error)
+}
+
+// ContractInstance is a single instance of the code of a smart contract, along with its globals and memory.
+type ContractInstance interface {
+	// Globals returns the globals of the instance.
+	Globals() []int64
+
+	// Memory returns the linear memory of the instance.
+	Memory() []byte
+
+	// Restore replaces the globals and memory of the instance with those of state. The instance takes ownership
+	// of the globals and memory of state.
+	Restore(state VMState) error
+
+	// Clone returns a copy of the instance whose imports are resolved through host. The globals and memory of
+	// the instance are copied, while its code may be shared.
+	Clone(host ContractHost) (ContractInstance, error)
+
+	// DataSegments returns the data segments declared by the code of the instance.
+	DataSegments() ([]ContractDataSegment, error)
+
+	// Export returns the number of parameters the function exported under name takes, and whether or not it
+	// exists.
+	Export(name string) (params int, exists bool)
+
+	// SetGasLimit sets the amount of gas that may be consumed by the instance.
+	SetGasLimit(limit uint64)
+
+	// Gas returns the amount of gas consumed by the instance so far.
+	Gas() uint64
+
+	// Start calls the start function of the code of the instance, should it declare one.
+	Start() error
+
+	// Call calls the function exported under name, which must not take any parameters. Should the instance run
+	// out of gas, ErrContractGasLimitExceeded is returned.
+	Call(name string) error
+}
+
+// ContractTracingInstance is implemented by instances able to trace the invocations made into them. Instances of
+// engines that do not implement it are left untraced.
+type ContractTracingInstance interface {
+	ContractInstance
+
+	// StartTrace starts tracing an invocation of function on smart contract id.
+	StartTrace(id AccountID, function string, gasLimit uint64)
+
+	// FinishTrace stops tracing, and returns the trace of the invocation which consumed gas.
+	FinishTrace(gas uint64) *ContractInvocationTrace
+}
+
+// ContractDataSegment is a segment of data the code of a smart contract places into its memory at offset upon
+// being instantiated.
+type ContractDataSegment struct {
+	Offset int
+	Data   []byte
+}
+
+// ContractHost resolves the functions imported by the code of smart contracts.
+type ContractHost interface {
+	// ResolveHostFunc resolves the host function imported under field from module.
+	ResolveHostFunc(module, field string) ContractHostFunc
+
+	// GetCost returns the amount of gas charged for an operation named key.
+	GetCost(key string) int64
+}
+
+// ContractHostFunc is a host function callable by smart contracts. Host functions may abort the invocation that
+// called them by panicking.
+type ContractHostFunc func(call ContractHostCall) int64
+
+// ContractHostCall is the view a host function has of the instance calling it.
+type ContractHostCall interface {
+	// Params returns the parameters passed to the host function.
+	Params() []int64
+
+	// Memory returns the linear memory of the calling instance.
+	Memory() []byte
+
+	// AddGas charges gas to the calling instance without checking it against its gas limit.
+	AddGas(gas uint64)
+
+	// AddAndCheckGas charges gas to the calling instance, aborting it should it exceed its gas limit.
+	AddAndCheckGas(gas uint64)
+}
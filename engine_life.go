@@ -0,0 +1,281 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"fmt"
+
+	"github.com/perlin-network/life/compiler"
+	"github.com/perlin-network/life/exec"
+	"github.com/perlin-network/life/utils"
+	"github.com/perlin-network/wavelet/sys"
+	"github.com/pkg/errors"
+)
+
+var (
+	_ ContractEngine          = (*LifeEngine)(nil)
+	_ ContractTracingInstance = (*lifeInstance)(nil)
+	_ exec.ImportResolver     = (*lifeInstance)(nil)
+	_ compiler.GasPolicy      = (*lifeInstance)(nil)
+	_ ContractHostCall        = lifeHostCall{}
+)
+
+// LifeEngine executes smart contracts with the life WebAssembly interpreter.
+type LifeEngine struct {
+	modules *ModuleCache
+}
+
+// NewLifeEngine returns an engine that executes smart contracts with the life WebAssembly interpreter. If
+// modules is not nil, compiled code is cached in modules.
+func NewLifeEngine(modules *ModuleCache) *LifeEngine {
+	return &LifeEngine{modules: modules}
+}
+
+func (l *LifeEngine) Version() string {
+	return ContractEngineVersion
+}
+
+func (l *LifeEngine) Instantiate(code []byte, gasLimit uint64, host ContractHost) (ContractInstance, error) {
+	config := exec.VMConfig{
+		DefaultMemoryPages: sys.ContractDefaultMemoryPages,
+		MaxMemoryPages:     sys.ContractMaxMemoryPages,
+
+		DefaultTableSize: sys.ContractTableSize,
+		MaxTableSize:     sys.ContractTableSize,
+
+		MaxValueSlots:     sys.ContractMaxValueSlots,
+		MaxCallStackDepth: sys.ContractMaxCallStackDepth,
+		GasLimit:          gasLimit,
+	}
+
+	instance := &lifeInstance{host: host}
+
+	var err error
+
+	if l.modules != nil {
+		instance.vm, err = l.modules.Instantiate(code, config, instance, instance)
+	} else {
+		instance.vm, err = exec.NewVirtualMachine(code, config, instance, instance)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return instance, nil
+}
+
+// lifeInstance is an instance of a smart contract executed by the life WebAssembly interpreter.
+type lifeInstance struct {
+	vm   *exec.VirtualMachine
+	host ContractHost
+
+	tracer *invocationTracer
+}
+
+func (i *lifeInstance) Globals() []int64 {
+	return i.vm.Globals
+}
+
+func (i *lifeInstance) Memory() []byte {
+	return i.vm.Memory
+}
+
+func (i *lifeInstance) Restore(state VMState) error {
+	if len(i.vm.Globals) != len(state.Globals) {
+		return errors.New("global count mismatch")
+	}
+
+	i.vm.Globals = state.Globals
+	i.vm.Memory = state.Memory
+
+	return nil
+}
+
+func (i *lifeInstance) Clone(host ContractHost) (ContractInstance, error) {
+	cloned := &lifeInstance{host: host}
+
+	cloned.vm = &exec.VirtualMachine{
+		Config:          i.vm.Config,
+		Module:          i.vm.Module,
+		FunctionCode:    i.vm.FunctionCode,
+		FunctionImports: i.vm.FunctionImports,
+		CallStack:       make([]exec.Frame, exec.DefaultCallStackSize),
+		CurrentFrame:    -1,
+		Table:           i.vm.Table,
+		Globals:         append([]int64{}, i.vm.Globals...),
+		Memory:          append([]byte{}, i.vm.Memory...),
+		Exited:          true,
+		GasPolicy:       cloned,
+		ImportResolver:  cloned,
+	}
+
+	return cloned, nil
+}
+
+func (i *lifeInstance) DataSegments() ([]ContractDataSegment, error) {
+	if i.vm.Module.Base.Data == nil {
+		return nil, nil
+	}
+
+	segments := make([]ContractDataSegment, 0, len(i.vm.Module.Base.Data.Entries))
+
+	for _, segment := range i.vm.Module.Base.Data.Entries {
+		val, err := i.vm.Module.Base.ExecInitExpr(segment.Offset)
+		if err != nil {
+			return nil, errors.Wrap(err, "cannot evaluate offset of data segment")
+		}
+
+		offset, ok := val.(int32)
+		if !ok {
+			return nil, errors.New("data segment is out of bounds")
+		}
+
+		segments = append(segments, ContractDataSegment{Offset: int(offset), Data: segment.Data})
+	}
+
+	return segments, nil
+}
+
+func (i *lifeInstance) Export(name string) (int, bool) {
+	entry, exists := i.vm.GetFunctionExport(name)
+	if !exists {
+		return 0, false
+	}
+
+	return i.vm.FunctionCode[entry].NumParams, true
+}
+
+func (i *lifeInstance) SetGasLimit(limit uint64) {
+	i.vm.Config.GasLimit = limit
+}
+
+func (i *lifeInstance) Gas() uint64 {
+	return i.vm.Gas
+}
+
+func (i *lifeInstance) Start() error {
+	if i.vm.Module.Base.Start == nil {
+		return nil
+	}
+
+	i.vm.Ignite(int(i.vm.Module.Base.Start.Index))
+
+	return i.run()
+}
+
+func (i *lifeInstance) Call(name string) error {
+	entry, exists := i.vm.GetFunctionExport(name)
+	if !exists {
+		return errors.Wrapf(ErrContractFunctionNotFound, `fn "%s" does not exist`, name)
+	}
+
+	i.vm.Ignite(entry)
+
+	return i.run()
+}
+
+// run executes the virtual machine of the instance until it exits.
+func (i *lifeInstance) run() error {
+	vm := i.vm
+
+	if i.tracer != nil {
+		i.tracer.run(vm)
+	} else {
+		for !vm.Exited {
+			vm.Execute()
+
+			if vm.Delegate != nil {
+				vm.Delegate()
+				vm.Delegate = nil
+			}
+		}
+	}
+
+	if vm.ExitError == nil {
+		return nil
+	}
+
+	err := utils.UnifyError(vm.ExitError)
+
+	fmt.Println("error: ", err)
+	vm.PrintStackTrace()
+
+	if err.Error() == ErrContractGasLimitExceeded.Error() {
+		return ErrContractGasLimitExceeded
+	}
+
+	return err
+}
+
+func (i *lifeInstance) StartTrace(id AccountID, function string, gasLimit uint64) {
+	i.tracer = newInvocationTracer(id, function, gasLimit, i.vm.Memory)
+}
+
+func (i *lifeInstance) FinishTrace(gas uint64) *ContractInvocationTrace {
+	trace := i.tracer.finish(i.vm, gas)
+	i.tracer = nil
+
+	return trace
+}
+
+func (i *lifeInstance) ResolveFunc(module, field string) exec.FunctionImport {
+	f := i.host.ResolveHostFunc(module, field)
+
+	imp := func(vm *exec.VirtualMachine) int64 {
+		return f(lifeHostCall{vm: vm})
+	}
+
+	if i.tracer != nil {
+		return i.tracer.hostCall(field, imp)
+	}
+
+	return imp
+}
+
+func (i *lifeInstance) ResolveGlobal(module, field string) int64 {
+	panic("global variables are disallowed in smart contracts")
+}
+
+func (i *lifeInstance) GetCost(key string) int64 {
+	return i.host.GetCost(key)
+}
+
+// lifeHostCall is a call made into a host function by a smart contract executed by the life WebAssembly
+// interpreter.
+type lifeHostCall struct {
+	vm *exec.VirtualMachine
+}
+
+func (c lifeHostCall) Params() []int64 {
+	return c.vm.GetCurrentFrame().Locals
+}
+
+func (c lifeHostCall) Memory() []byte {
+	return c.vm.Memory
+}
+
+func (c lifeHostCall) AddGas(gas uint64) {
+	c.vm.Gas += gas
+}
+
+func (c lifeHostCall) AddAndCheckGas(gas uint64) {
+	c.vm.AddAndCheckGas(gas)
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// +build unit
+
+package wavelet
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/perlin-network/noise/skademlia"
+	"github.com/perlin-network/wavelet/avl"
+	"github.com/perlin-network/wavelet/store"
+	"github.com/perlin-network/wavelet/sys"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blake2b"
+)
+
+type namedContractEngine struct {
+	name   string
+	engine ContractEngine
+}
+
+// contractEngines returns every engine the conformance suite is run against. The first engine is the reference
+// engine, whose results every other engine must reproduce exactly.
+func contractEngines(t *testing.T) []namedContractEngine {
+	modules, err := NewModuleCache(store.NewInmem(), DefaultModuleCacheSize, ModuleCacheEvictLRU)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	return []namedContractEngine{
+		{name: "life", engine: NewLifeEngine(nil)},
+		{name: "life/module_cache", engine: NewLifeEngine(modules)},
+	}
+}
+
+// contractConformanceCases lists the functions of every smart contract in testdata invoked by the conformance
+// suite, in the order they are invoked.
+var contractConformanceCases = []struct {
+	path      string
+	functions []string
+}{
+	{path: "testdata/transfer_back.wasm", functions: []string{"init", "on_money_received", "on_money_received"}},
+	{path: "testdata/migrate.wasm", functions: []string{"init", "migrate"}},
+	{path: "testdata/recursive_invocation.wasm", functions: []string{"init", "bomb"}},
+	{path: "testdata/invoke.wasm", functions: []string{"init", "invoke"}},
+	{path: "testdata/dummy.wasm", functions: []string{"init", "say"}},
+	{
+		path:      "testdata/crypto.wasm",
+		functions: []string{"init", "keccak256", "verify_secp256k1", "recover_secp256k1", "verify_ed25519_batch"},
+	},
+}
+
+type contractConformanceResult struct {
+	Error            string
+	Gas              uint64
+	GasLimitExceeded bool
+	Result           []byte
+	Queue            []Transaction
+	Globals          []int64
+	Memory           [blake2b.Size256]byte
+}
+
+func executeForConformance(
+	engine ContractEngine, code []byte, function string, gasLimit uint64, vmCache *VMLRU, state *VMState,
+) (contractConformanceResult, *VMState) {
+	var id AccountID
+	id[0] = 1
+
+	block := NewBlock(1, ZeroMerkleNodeID)
+	tx := &Transaction{Sender: AccountID{2}}
+
+	executor := &ContractExecutor{Engine: engine}
+
+	newState, err := executor.Execute(
+		id, &block, tx, 100, gasLimit, function, nil, code, avl.New(store.NewInmem()), vmCache, state,
+	)
+
+	result := contractConformanceResult{
+		Gas:              executor.Gas,
+		GasLimitExceeded: executor.GasLimitExceeded,
+		Result:           executor.Error,
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	for _, queued := range executor.Queue {
+		result.Queue = append(result.Queue, *queued)
+	}
+
+	if newState != nil {
+		result.Globals = newState.Globals
+		result.Memory = blake2b.Sum256(newState.Memory)
+	}
+
+	return result, newState
+}
+
+func TestContractEngineConformance(t *testing.T) {
+	t.Parallel()
+
+	engines := contractEngines(t)
+
+	for _, test := range contractConformanceCases {
+		code, err := ioutil.ReadFile(test.path)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		var expected []contractConformanceResult
+
+		for i, engine := range engines {
+			vmCache := NewVMLRU(4)
+
+			var (
+				results []contractConformanceResult
+				state   *VMState
+			)
+
+			for _, function := range test.functions {
+				result, newState := executeForConformance(engine.engine, code, function, 1000000, vmCache, state)
+				results = append(results, result)
+
+				if newState != nil {
+					state = newState
+				}
+			}
+
+			// Running out of gas must be reported identically by every engine.
+			if limit := results[0].Gas / 2; limit > 0 {
+				result, _ := executeForConformance(engine.engine, code, test.functions[0], limit, NewVMLRU(4), nil)
+				results = append(results, result)
+
+				assert.True(t, result.GasLimitExceeded, "%s: %s", engine.name, test.path)
+				assert.Equal(t, limit, result.Gas, "%s: %s", engine.name, test.path)
+			}
+
+			if i == 0 {
+				expected = results
+				assert.Empty(t, results[0].Error, "%s: %s", engine.name, test.path)
+
+				continue
+			}
+
+			assert.Equal(t, expected, results, "%s: %s", engine.name, test.path)
+		}
+	}
+}
+
+func TestContractEngineLedgerConformance(t *testing.T) {
+	t.Parallel()
+
+	keys, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var expected []MerkleNodeID
+
+	for i, engine := range contractEngines(t) {
+		state := avl.New(store.NewInmem())
+		WriteAccountBalance(state, keys.PublicKey(), 1000000000)
+
+		block := NewBlock(0, state.Checksum())
+
+		var (
+			nonce     uint64
+			checksums []MerkleNodeID
+		)
+
+		apply := func(tag sys.Tag, payload []byte) *Transaction {
+			nonce++
+			tx := buildSignedTransaction(keys, tag, nonce, block.Index+1, payload)
+
+			ctx := NewCollapseContext(state)
+			ctx.Engine = engine.engine
+
+			assert.NoError(t, ctx.ApplyTransaction(&block, &tx), engine.name)
+			assert.NoError(t, ctx.Flush(), engine.name)
+
+			checksums = append(checksums, state.Checksum())
+
+			return &tx
+		}
+
+		for _, test := range contractConformanceCases {
+			code, err := ioutil.ReadFile(test.path)
+			if !assert.NoError(t, err) {
+				continue
+			}
+
+			payload, err := buildContractSpawnPayload(100000, 0, code).Marshal()
+			if !assert.NoError(t, err) {
+				continue
+			}
+
+			spawned := apply(sys.TagContract, payload)
+
+			for _, function := range test.functions[1:] {
+				payload, err := buildTransferWithInvocationPayload(
+					spawned.ID, 200, 100000, []byte(function), nil, 0,
+				).Marshal()
+				if !assert.NoError(t, err) {
+					continue
+				}
+
+				apply(sys.TagTransfer, payload)
+			}
+		}
+
+		if i == 0 {
+			expected = checksums
+			continue
+		}
+
+		assert.Equal(t, expected, checksums, engine.name)
+	}
+}
+
+func TestContractEngineInstance(t *testing.T) {
+	t.Parallel()
+
+	code, err := ioutil.ReadFile("testdata/transfer_back.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	for _, engine := range contractEngines(t) {
+		instance, err := engine.engine.Instantiate(code, 1000000, &ContractExecutor{})
+		if !assert.NoError(t, err, engine.name) {
+			continue
+		}
+
+		params, exists := instance.Export("_contract_on_money_received")
+		assert.True(t, exists, engine.name)
+		assert.Zero(t, params, engine.name)
+
+		_, exists = instance.Export("_contract_missing")
+		assert.False(t, exists, engine.name)
+
+		// Data segments must lie within memory.
+		segments, err := instance.DataSegments()
+		assert.NoError(t, err, engine.name)
+
+		for _, segment := range segments {
+			assert.True(t, segment.Offset+len(segment.Data) <= len(instance.Memory()), engine.name)
+		}
+
+		// Clones must not share globals nor memory with the instance they were cloned from.
+		cloned, err := instance.Clone(&ContractExecutor{})
+		if !assert.NoError(t, err, engine.name) {
+			continue
+		}
+
+		cloned.Memory()[0]++
+		assert.NotEqual(t, instance.Memory()[0], cloned.Memory()[0], engine.name)
+
+		if len(instance.Globals()) > 0 {
+			cloned.Globals()[0]++
+			assert.NotEqual(t, instance.Globals()[0], cloned.Globals()[0], engine.name)
+		}
+
+		// Restoring state requires for the number of globals to match.
+		assert.Error(t, instance.Restore(VMState{Globals: make([]int64, len(instance.Globals())+1)}), engine.name)
+
+		memory := make([]byte, len(instance.Memory()))
+		memory[0] = 42
+
+		assert.NoError(t, instance.Restore(VMState{Globals: make([]int64, len(instance.Globals())), Memory: memory}))
+		assert.EqualValues(t, 42, instance.Memory()[0], engine.name)
+
+		// Running out of gas must be reported through ErrContractGasLimitExceeded.
+		instance.SetGasLimit(1)
+		assert.Equal(t, ErrContractGasLimitExceeded, errors.Cause(instance.Call("_contract_on_money_received")))
+	}
+}
@@ -448,6 +448,22 @@ func restoreAccount(tree *avl.Tree, id AccountID, val *fastjson.Value) error {
 				err = errors.Wrapf(err, "failed to cast type for key %q", key)
 				return
 			}
+		case "signing_key":
+			var buf []byte
+
+			buf, err = v.StringBytes()
+			if err != nil {
+				err = errors.Wrapf(err, "failed to cast type for key %q", key)
+				return
+			}
+
+			var signingKey AccountID
+			if n, decodeErr := hex.Decode(signingKey[:], buf); decodeErr != nil || n != SizeAccountID {
+				err = errors.Errorf("key %q must be a hex-encoded %d-byte public key", key, SizeAccountID)
+				return
+			}
+
+			WriteAccountSigningKey(tree, id, signingKey)
 		}
 	})
 
@@ -539,6 +555,8 @@ func Dump(tree *avl.Tree, dir string, isDumpContract bool, useContractFolder boo
 
 		isContract bool
 		gasBalance *uint64
+
+		signingKey *AccountID
 	}
 
 	var (
@@ -556,7 +574,9 @@ func Dump(tree *avl.Tree, dir string, isDumpContract bool, useContractFolder boo
 		if prefix != keyAccountBalance &&
 			prefix != keyAccountStake &&
 			prefix != keyAccountReward &&
-			prefix != keyAccountContractCode {
+			prefix != keyAccountContractCode &&
+			prefix != keyAccountContractCodeID &&
+			prefix != keyAccountSigningKey {
 			return true
 		}
 
@@ -593,6 +613,10 @@ func Dump(tree *avl.Tree, dir string, isDumpContract bool, useContractFolder boo
 			acc.gasBalance = &gasBalance
 		}
 
+		if signingKey, exist := ReadAccountSigningKey(tree, id); exist {
+			acc.signingKey = &signingKey
+		}
+
 		var folder = dir
 
 		if useContractFolder {
@@ -645,6 +669,10 @@ func Dump(tree *avl.Tree, dir string, isDumpContract bool, useContractFolder boo
 			o.Set("reward", arena.NewNumberString(strconv.FormatUint(*v.reward, 10)))
 		}
 
+		if v.signingKey != nil {
+			o.Set("signing_key", arena.NewString(hex.EncodeToString(v.signingKey[:])))
+		}
+
 		data = o.MarshalTo(data)
 		filename := fmt.Sprintf("%x.json", id)
 
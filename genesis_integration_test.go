@@ -233,10 +233,12 @@ func compareTree(t *testing.T, expected *avl.Tree, actual *avl.Tree, checkContra
 
 		cond1 = accountPrefix == keyAccountBalance ||
 			accountPrefix == keyAccountStake ||
-			accountPrefix == keyAccountReward
+			accountPrefix == keyAccountReward ||
+			accountPrefix == keyAccountSigningKey
 
 		if checkContract {
 			cond2 = accountPrefix == keyAccountContractCode ||
+				accountPrefix == keyAccountContractCodeID ||
 				accountPrefix == keyAccountContractNumPages ||
 				accountPrefix == keyAccountContractPages ||
 				accountPrefix == keyAccountContractGasBalance ||
@@ -12,9 +12,11 @@ require (
 	github.com/buaazp/fasthttprouter v0.1.1
 	github.com/chzyer/logex v1.1.10 // indirect
 	github.com/chzyer/test v0.0.0-20180213035817-a1ea475d72b1 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v2 v2.0.0
 	github.com/dgraph-io/badger/v2 v2.0.0
 	github.com/djherbis/buffer v1.1.0
 	github.com/fasthttp/websocket v1.4.0
+	github.com/go-interpreter/wagon v0.0.0
 	github.com/gogo/protobuf v1.3.0
 	github.com/golang/protobuf v1.3.2
 	github.com/golang/snappy v0.0.1
@@ -0,0 +1,125 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Statuses of a hash time-locked transfer.
+const (
+	HTLCLocked byte = iota
+	HTLCClaimed
+	HTLCRefunded
+)
+
+// HTLCInfo is a hash time-locked transfer, whose ID is the ID of the transaction that locked its amount. The amount
+// is held in escrow until it is either claimed by revealing Preimage, or refunded to Sender once Expiry is reached.
+type HTLCInfo struct {
+	Sender    AccountID
+	Recipient AccountID
+	Amount    uint64
+	HashLock  [32]byte
+	Expiry    uint64
+
+	Status   byte
+	Preimage []byte
+}
+
+// Unlocks reports whether or not preimage unlocks the hash lock of the transfer.
+func (h HTLCInfo) Unlocks(preimage []byte) bool {
+	return sha256.Sum256(preimage) == h.HashLock
+}
+
+func (h HTLCInfo) Marshal() []byte {
+	buf := bytes.NewBuffer(make([]byte, 0, SizeAccountID*2+8+32+8+1+len(h.Preimage)))
+
+	buf.Write(h.Sender[:])
+	buf.Write(h.Recipient[:])
+
+	var b [8]byte
+
+	binary.LittleEndian.PutUint64(b[:], h.Amount)
+	buf.Write(b[:])
+
+	buf.Write(h.HashLock[:])
+
+	binary.LittleEndian.PutUint64(b[:], h.Expiry)
+	buf.Write(b[:])
+
+	buf.WriteByte(h.Status)
+	buf.Write(h.Preimage)
+
+	return buf.Bytes()
+}
+
+func UnmarshalHTLCInfo(r io.Reader) (HTLCInfo, error) {
+	var (
+		info HTLCInfo
+		b    [8]byte
+		err  error
+	)
+
+	if _, err = io.ReadFull(r, info.Sender[:]); err != nil {
+		return info, errors.Wrap(err, "failed to decode htlc sender")
+	}
+
+	if _, err = io.ReadFull(r, info.Recipient[:]); err != nil {
+		return info, errors.Wrap(err, "failed to decode htlc recipient")
+	}
+
+	if _, err = io.ReadFull(r, b[:]); err != nil {
+		return info, errors.Wrap(err, "failed to decode htlc amount")
+	}
+
+	info.Amount = binary.LittleEndian.Uint64(b[:])
+
+	if _, err = io.ReadFull(r, info.HashLock[:]); err != nil {
+		return info, errors.Wrap(err, "failed to decode htlc hash lock")
+	}
+
+	if _, err = io.ReadFull(r, b[:]); err != nil {
+		return info, errors.Wrap(err, "failed to decode htlc expiry")
+	}
+
+	info.Expiry = binary.LittleEndian.Uint64(b[:])
+
+	if _, err = io.ReadFull(r, b[:1]); err != nil {
+		return info, errors.Wrap(err, "failed to decode htlc status")
+	}
+
+	info.Status = b[0]
+
+	if info.Preimage, err = ioutil.ReadAll(r); err != nil {
+		return info, errors.Wrap(err, "failed to decode htlc preimage")
+	}
+
+	if len(info.Preimage) == 0 {
+		info.Preimage = nil
+	}
+
+	return info, nil
+}
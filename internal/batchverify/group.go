@@ -0,0 +1,202 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package batchverify
+
+import "github.com/perlin-network/noise/edwards25519"
+
+var (
+	// d2 is 2*d, where d = -121665/121666 is the constant of the curve.
+	d2 edwards25519.FieldElement
+
+	// negatedBase is the negation of the base point B.
+	negatedBase edwards25519.ExtendedGroupElement
+)
+
+func init() {
+	var numerator, denominator, d edwards25519.FieldElement
+
+	edwards25519.FeFromBytes(&numerator, &[32]byte{0x41, 0xdb, 0x01})   // 121665
+	edwards25519.FeFromBytes(&denominator, &[32]byte{0x42, 0xdb, 0x01}) // 121666
+
+	edwards25519.FeInvert(&denominator, &denominator)
+	edwards25519.FeMul(&d, &numerator, &denominator)
+	edwards25519.FeNeg(&d, &d)
+	edwards25519.FeAdd(&d2, &d, &d)
+
+	base := [32]byte{0x58}
+	for i := 1; i < len(base); i++ {
+		base[i] = 0x66
+	}
+
+	if !negatedBase.FromBytes(&base) {
+		panic("batchverify: failed to decode the base point")
+	}
+
+	edwards25519.FeNeg(&negatedBase.X, &negatedBase.X)
+	edwards25519.FeNeg(&negatedBase.T, &negatedBase.T)
+}
+
+// cachedGroupElement is a point (Y+X, Y-X, Z, 2dT) cached for it to be added to other points.
+type cachedGroupElement struct {
+	yPlusX, yMinusX, Z, T2d edwards25519.FieldElement
+}
+
+func toCached(r *cachedGroupElement, p *edwards25519.ExtendedGroupElement) {
+	edwards25519.FeAdd(&r.yPlusX, &p.Y, &p.X)
+	edwards25519.FeSub(&r.yMinusX, &p.Y, &p.X)
+	edwards25519.FeCopy(&r.Z, &p.Z)
+	edwards25519.FeMul(&r.T2d, &p.T, &d2)
+}
+
+// add sets r = p + q.
+func add(r *edwards25519.CompletedGroupElement, p *edwards25519.ExtendedGroupElement, q *cachedGroupElement) {
+	var t0 edwards25519.FieldElement
+
+	edwards25519.FeAdd(&r.X, &p.Y, &p.X)
+	edwards25519.FeSub(&r.Y, &p.Y, &p.X)
+	edwards25519.FeMul(&r.Z, &r.X, &q.yPlusX)
+	edwards25519.FeMul(&r.Y, &r.Y, &q.yMinusX)
+	edwards25519.FeMul(&r.T, &q.T2d, &p.T)
+	edwards25519.FeMul(&r.X, &p.Z, &q.Z)
+	edwards25519.FeAdd(&t0, &r.X, &r.X)
+	edwards25519.FeSub(&r.X, &r.Z, &r.Y)
+	edwards25519.FeAdd(&r.Y, &r.Z, &r.Y)
+	edwards25519.FeAdd(&r.Z, &t0, &r.T)
+	edwards25519.FeSub(&r.T, &t0, &r.T)
+}
+
+// sub sets r = p - q.
+func sub(r *edwards25519.CompletedGroupElement, p *edwards25519.ExtendedGroupElement, q *cachedGroupElement) {
+	var t0 edwards25519.FieldElement
+
+	edwards25519.FeAdd(&r.X, &p.Y, &p.X)
+	edwards25519.FeSub(&r.Y, &p.Y, &p.X)
+	edwards25519.FeMul(&r.Z, &r.X, &q.yMinusX)
+	edwards25519.FeMul(&r.Y, &r.Y, &q.yPlusX)
+	edwards25519.FeMul(&r.T, &q.T2d, &p.T)
+	edwards25519.FeMul(&r.X, &p.Z, &q.Z)
+	edwards25519.FeAdd(&t0, &r.X, &r.X)
+	edwards25519.FeSub(&r.X, &r.Z, &r.Y)
+	edwards25519.FeAdd(&r.Y, &r.Z, &r.Y)
+	edwards25519.FeSub(&r.Z, &t0, &r.T)
+	edwards25519.FeAdd(&r.T, &t0, &r.T)
+}
+
+// oddMultiples sets table to P, 3P, 5P, ..., 15P.
+func oddMultiples(table *[8]cachedGroupElement, p *edwards25519.ExtendedGroupElement) {
+	var (
+		t     edwards25519.CompletedGroupElement
+		u, p2 edwards25519.ExtendedGroupElement
+	)
+
+	toCached(&table[0], p)
+
+	p.Double(&t)
+	t.ToExtended(&p2)
+
+	for i := 0; i < 7; i++ {
+		add(&t, &p2, &table[i])
+		t.ToExtended(&u)
+		toCached(&table[i+1], &u)
+	}
+}
+
+// slide converts a scalar into its signed digits in a sliding window of odd digits between -15 and 15.
+func slide(r *[256]int8, a *[32]byte) {
+	for i := range r {
+		r[i] = int8(1 & (a[i>>3] >> uint(i&7)))
+	}
+
+	for i := range r {
+		if r[i] == 0 {
+			continue
+		}
+
+		for b := 1; b <= 6 && i+b < 256; b++ {
+			if r[i+b] == 0 {
+				continue
+			}
+
+			if r[i]+(r[i+b]<<uint(b)) <= 15 {
+				r[i] += r[i+b] << uint(b)
+				r[i+b] = 0
+			} else if r[i]-(r[i+b]<<uint(b)) >= -15 {
+				r[i] -= r[i+b] << uint(b)
+
+				for k := i + b; k < 256; k++ {
+					if r[k] == 0 {
+						r[k] = 1
+						break
+					}
+
+					r[k] = 0
+				}
+			} else {
+				break
+			}
+		}
+	}
+}
+
+// multiScalarMultVartime sets r to the sum of scalars[i] * points[i] in variable time, sharing the doublings of all
+// points with Straus' method.
+func multiScalarMultVartime(
+	r *edwards25519.ProjectiveGroupElement, scalars [][32]byte, points []edwards25519.ExtendedGroupElement,
+) {
+	digits := make([][256]int8, len(points))
+	tables := make([][8]cachedGroupElement, len(points))
+
+	top := -1
+
+	for i := range points {
+		slide(&digits[i], &scalars[i])
+		oddMultiples(&tables[i], &points[i])
+
+		for j := 255; j > top; j-- {
+			if digits[i][j] != 0 {
+				top = j
+				break
+			}
+		}
+	}
+
+	var (
+		t edwards25519.CompletedGroupElement
+		u edwards25519.ExtendedGroupElement
+	)
+
+	r.Zero()
+
+	for j := top; j >= 0; j-- {
+		r.Double(&t)
+
+		for i := range points {
+			if digit := digits[i][j]; digit > 0 {
+				t.ToExtended(&u)
+				add(&t, &u, &tables[i][digit/2])
+			} else if digit < 0 {
+				t.ToExtended(&u)
+				sub(&t, &u, &tables[i][(-digit)/2])
+			}
+		}
+
+		t.ToProjective(r)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// Package batchverify verifies batches of ed25519 signatures at once, which is considerably cheaper than verifying
+// each signature of a batch one by one.
+//
+// A batch of n signatures (R_i, s_i) of messages M_i by public keys A_i is verified by checking that
+//
+//	sum(z_i * R_i) + sum(z_i * h_i * A_i) - sum(z_i * s_i) * B = 0
+//
+// for 128-bit random coefficients z_i, where h_i = SHA-512(R_i || A_i || M_i) and B is the base point. All points
+// share the doublings of a single multi-scalar multiplication, instead of each signature requiring its own
+// double-scalar multiplication.
+//
+// Just as with edwards25519.Verify, the equation is checked without multiplying by the cofactor. A batch carrying a
+// signature that is only invalid by a small-order component, which may only be made by the holder of the private key
+// the signature is made with, is nonetheless only rejected by the equation with a probability of at least 1/2. The
+// batch is otherwise rejected with overwhelming probability should any of its signatures be invalid.
+package batchverify
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+
+	"github.com/perlin-network/noise/edwards25519"
+)
+
+// Entry is a signature of a message by a public key.
+type Entry struct {
+	PublicKey edwards25519.PublicKey
+	Message   []byte
+	Signature edwards25519.Signature
+}
+
+// Verify verifies the signatures of a batch of entries, and returns whether each signature is valid. The batch is
+// first verified at once, and its signatures are only verified one by one should the batch not be valid.
+func Verify(entries []Entry) []bool {
+	valid := make([]bool, len(entries))
+
+	if len(entries) > 1 && VerifyBatch(entries) {
+		for i := range valid {
+			valid[i] = true
+		}
+
+		return valid
+	}
+
+	for i := range entries {
+		valid[i] = edwards25519.Verify(entries[i].PublicKey, entries[i].Message, entries[i].Signature)
+	}
+
+	return valid
+}
+
+// VerifyBatch reports whether the signatures of all entries are valid. It does not report which signatures are not.
+func VerifyBatch(entries []Entry) bool {
+	if len(entries) == 0 {
+		return true
+	}
+
+	coefficients := make([]byte, 16*len(entries))
+	if _, err := rand.Read(coefficients); err != nil {
+		return false
+	}
+
+	// The points of the equation are R_0, A_0, R_1, A_1, ..., R_n-1, A_n-1 and -B.
+	points := make([]edwards25519.ExtendedGroupElement, 2*len(entries)+1)
+	scalars := make([][32]byte, len(points))
+
+	var (
+		zero   [32]byte
+		sum    [32]byte
+		digest [sha512.Size]byte
+	)
+
+	for i := range entries {
+		entry := &entries[i]
+
+		// Just as with edwards25519.Verify, s must be at most 253 bits.
+		if entry.Signature[edwards25519.SizeSignature-1]&224 != 0 {
+			return false
+		}
+
+		var r, s, h [32]byte
+
+		copy(r[:], entry.Signature[:32])
+		copy(s[:], entry.Signature[32:])
+
+		// R must be canonically encoded, as edwards25519.Verify compares the encoding of the point it derives for
+		// R against the one in the signature.
+		if !points[2*i].FromBytes(&r) {
+			return false
+		}
+
+		var encoded [32]byte
+		if points[2*i].ToBytes(&encoded); encoded != r {
+			return false
+		}
+
+		publicKey := [32]byte(entry.PublicKey)
+		if !points[2*i+1].FromBytes(&publicKey) {
+			return false
+		}
+
+		hash := sha512.New()
+		_, _ = hash.Write(r[:])
+		_, _ = hash.Write(publicKey[:])
+		_, _ = hash.Write(entry.Message)
+		_ = hash.Sum(digest[:0])
+
+		edwards25519.ScReduce(&h, &digest)
+
+		z := &scalars[2*i]
+		copy(z[:16], coefficients[16*i:16*(i+1)])
+
+		edwards25519.ScMulAdd(&scalars[2*i+1], z, &h, &zero)
+		edwards25519.ScMulAdd(&sum, z, &s, &sum)
+	}
+
+	points[len(points)-1] = negatedBase
+	scalars[len(scalars)-1] = sum
+
+	var result edwards25519.ProjectiveGroupElement
+
+	multiScalarMultVartime(&result, scalars, points)
+
+	return isIdentity(&result)
+}
+
+// isIdentity reports whether p is the identity point (0, 1).
+func isIdentity(p *edwards25519.ProjectiveGroupElement) bool {
+	var t edwards25519.FieldElement
+
+	edwards25519.FeSub(&t, &p.Y, &p.Z)
+
+	return edwards25519.FeIsNonZero(&p.X) == 0 && edwards25519.FeIsNonZero(&t) == 0
+}
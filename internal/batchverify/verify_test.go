@@ -0,0 +1,116 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// +build unit
+
+package batchverify
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/perlin-network/noise/edwards25519"
+	"github.com/stretchr/testify/assert"
+)
+
+func generateEntries(t testing.TB, n int) []Entry {
+	entries := make([]Entry, n)
+
+	for i := range entries {
+		publicKey, privateKey, err := edwards25519.GenerateKey(rand.Reader)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		message := make([]byte, 1+i)
+		if _, err := rand.Read(message); !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		entries[i] = Entry{
+			PublicKey: publicKey,
+			Message:   message,
+			Signature: edwards25519.Sign(privateKey, message),
+		}
+	}
+
+	return entries
+}
+
+func TestVerifyBatch(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 16, 64} {
+		entries := generateEntries(t, n)
+
+		assert.True(t, VerifyBatch(entries), "batch of %d signatures", n)
+		assert.Equal(t, n, count(Verify(entries)))
+
+		// Tamper with the message, R, s and public key of a signature.
+		for _, tamper := range []func(entry *Entry){
+			func(entry *Entry) { entry.Message = append(entry.Message, 0x00) },
+			func(entry *Entry) { entry.Signature[0] ^= 0x01 },
+			func(entry *Entry) { entry.Signature[32] ^= 0x01 },
+			func(entry *Entry) { entry.PublicKey[0] ^= 0x01 },
+		} {
+			tampered := append([]Entry(nil), entries...)
+			tamper(&tampered[n-1])
+
+			assert.False(t, VerifyBatch(tampered), "batch of %d signatures", n)
+
+			valid := Verify(tampered)
+			assert.Equal(t, n-1, count(valid))
+			assert.False(t, valid[n-1])
+		}
+	}
+
+	assert.True(t, VerifyBatch(nil))
+}
+
+func TestVerifyBatchEncodings(t *testing.T) {
+	entries := generateEntries(t, 4)
+
+	// Signatures with s exceeding 253 bits are rejected.
+	tampered := append([]Entry(nil), entries...)
+	tampered[1].Signature[63] |= 0x20
+
+	assert.False(t, VerifyBatch(tampered))
+	assert.Equal(t, []bool{true, false, true, true}, Verify(tampered))
+
+	// Signatures with a non-canonically encoded R are rejected. The identity point (0, 1) is encoded
+	// non-canonically by setting the sign bit of its x-coordinate, which is zero.
+	identity := [32]byte{0x01}
+	identity[31] |= 0x80
+
+	tampered = append([]Entry(nil), entries...)
+	copy(tampered[2].Signature[:32], identity[:])
+
+	assert.False(t, VerifyBatch(tampered))
+	assert.Equal(t, []bool{true, true, false, true}, Verify(tampered))
+}
+
+func count(valid []bool) int {
+	n := 0
+
+	for _, v := range valid {
+		if v {
+			n++
+		}
+	}
+
+	return n
+}
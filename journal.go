@@ -0,0 +1,358 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+// journal records how to undo each change made to a CollapseContext while at least one checkpoint is open, such
+// that all changes made since a checkpoint may be reverted. No changes are recorded while no checkpoint is open.
+type journal struct {
+	undo []func()
+	open int
+}
+
+// Checkpoint opens a checkpoint which all changes made to the context since may be reverted to through
+// RevertToCheckpoint. Checkpoints may be nested, and must be closed through either RevertToCheckpoint or
+// DiscardCheckpoint in the reverse order in which they were opened.
+func (c *CollapseContext) Checkpoint() int {
+	c.journal.open++
+	return len(c.journal.undo)
+}
+
+// RevertToCheckpoint undoes all changes made to the context since the checkpoint was opened, and closes it.
+func (c *CollapseContext) RevertToCheckpoint(checkpoint int) {
+	for i := len(c.journal.undo) - 1; i >= checkpoint; i-- {
+		c.journal.undo[i]()
+		c.journal.undo[i] = nil
+	}
+
+	c.journal.undo = c.journal.undo[:checkpoint]
+	c.closeCheckpoint()
+}
+
+// DiscardCheckpoint closes the checkpoint, keeping all changes made since it was opened. The changes may still be
+// reverted through any checkpoint opened before it.
+func (c *CollapseContext) DiscardCheckpoint(checkpoint int) {
+	c.closeCheckpoint()
+}
+
+func (c *CollapseContext) closeCheckpoint() {
+	if c.journal.open == 0 {
+		panic("BUG: closed a checkpoint that was never opened")
+	}
+
+	c.journal.open--
+
+	if c.journal.open == 0 {
+		for i := range c.journal.undo {
+			c.journal.undo[i] = nil
+		}
+
+		c.journal.undo = c.journal.undo[:0]
+	}
+}
+
+func (c *CollapseContext) journaling() bool {
+	return c.journal.open > 0
+}
+
+func (c *CollapseContext) record(undo func()) {
+	c.journal.undo = append(c.journal.undo, undo)
+}
+
+func (c *CollapseContext) journalAccountsLen() {
+	if !c.journaling() {
+		return
+	}
+
+	prev := c.accountLen
+	c.record(func() { c.accountLen = prev })
+}
+
+// journalNewAccount records an account being added to the ordered list of accounts written within the context.
+func (c *CollapseContext) journalNewAccount(id AccountID) {
+	if !c.journaling() {
+		return
+	}
+
+	c.record(func() {
+		delete(c.accounts, id)
+		c.accountIDs = c.accountIDs[:len(c.accountIDs)-1]
+	})
+}
+
+func (c *CollapseContext) journalUint64(m map[AccountID]uint64, id AccountID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := m[id]
+	c.record(func() {
+		if existed {
+			m[id] = prev
+		} else {
+			delete(m, id)
+		}
+	})
+}
+
+func (c *CollapseContext) journalID(m map[AccountID]AccountID, id AccountID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := m[id]
+	c.record(func() {
+		if existed {
+			m[id] = prev
+		} else {
+			delete(m, id)
+		}
+	})
+}
+
+func (c *CollapseContext) journalMultisig(id AccountID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := c.multisigs[id]
+	c.record(func() {
+		if existed {
+			c.multisigs[id] = prev
+		} else {
+			delete(c.multisigs, id)
+		}
+	})
+}
+
+// journalContractCode records the code of a contract being changed, along with code being uploaded under a new code
+// ID. As the VM of a contract cached in the context may have been instantiated from its changed code, the cached VM
+// is invalidated upon reverting.
+func (c *CollapseContext) journalContractCode(id TransactionID, codeID ContractCodeID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := c.contracts[id]
+	_, uploaded := c.contractCode[codeID]
+
+	c.record(func() {
+		if existed {
+			c.contracts[id] = prev
+		} else {
+			delete(c.contracts, id)
+		}
+
+		if !uploaded {
+			delete(c.contractCode, codeID)
+			c.contractCodeIDs = c.contractCodeIDs[:len(c.contractCodeIDs)-1]
+		}
+
+		c.VMCache.Remove(id)
+	})
+}
+
+func (c *CollapseContext) journalContractState(id AccountID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := c.contractVMs[id]
+	c.record(func() {
+		if existed {
+			c.contractVMs[id] = prev
+		} else {
+			delete(c.contractVMs, id)
+		}
+	})
+}
+
+func (c *CollapseContext) journalContractStorage(id TransactionID, key string) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := c.contractStorage[id][key]
+	c.record(func() {
+		if existed {
+			c.contractStorage[id][key] = prev
+			return
+		}
+
+		delete(c.contractStorage[id], key)
+		c.contractStorageKeys[id] = c.contractStorageKeys[id][:len(c.contractStorageKeys[id])-1]
+	})
+}
+
+// journalDestroyedContract records a contract being destroyed, along with all of its pending changes being
+// discarded.
+func (c *CollapseContext) journalDestroyedContract(id TransactionID) {
+	if !c.journaling() {
+		return
+	}
+
+	_, destroyed := c.destroyedContracts[id]
+	code, hasCode := c.contracts[id]
+	gasBalance, hasGasBalance := c.contractGasBalances[id]
+	vm, hasVM := c.contractVMs[id]
+	owner, hasOwner := c.contractOwners[id]
+	storage, hasStorage := c.contractStorage[id]
+	storageKeys, hasStorageKeys := c.contractStorageKeys[id]
+
+	c.record(func() {
+		if !destroyed {
+			delete(c.destroyedContracts, id)
+		}
+
+		if hasCode {
+			c.contracts[id] = code
+		}
+
+		if hasGasBalance {
+			c.contractGasBalances[id] = gasBalance
+		}
+
+		if hasVM {
+			c.contractVMs[id] = vm
+		}
+
+		if hasOwner {
+			c.contractOwners[id] = owner
+		}
+
+		if hasStorage {
+			c.contractStorage[id] = storage
+		}
+
+		if hasStorageKeys {
+			c.contractStorageKeys[id] = storageKeys
+		}
+	})
+}
+
+func (c *CollapseContext) journalAsset(id AssetID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := c.assets[id]
+	c.record(func() {
+		if existed {
+			c.assets[id] = prev
+			return
+		}
+
+		delete(c.assets, id)
+		c.assetIDs = c.assetIDs[:len(c.assetIDs)-1]
+	})
+}
+
+func (c *CollapseContext) journalAssetBalance(id AccountID, asset AssetID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := c.assetBalances[id][asset]
+	c.record(func() {
+		if existed {
+			c.assetBalances[id][asset] = prev
+			return
+		}
+
+		delete(c.assetBalances[id], asset)
+		c.assetBalanceIDs[id] = c.assetBalanceIDs[id][:len(c.assetBalanceIDs[id])-1]
+	})
+}
+
+func (c *CollapseContext) journalAllowance(owner AccountID, spender AccountID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := c.allowances[owner][spender]
+	c.record(func() {
+		if existed {
+			c.allowances[owner][spender] = prev
+			return
+		}
+
+		delete(c.allowances[owner], spender)
+		c.allowanceIDs[owner] = c.allowanceIDs[owner][:len(c.allowanceIDs[owner])-1]
+	})
+}
+
+func (c *CollapseContext) journalHTLC(id TransactionID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := c.htlcs[id]
+	c.record(func() {
+		if existed {
+			c.htlcs[id] = prev
+			return
+		}
+
+		delete(c.htlcs, id)
+		c.htlcIDs = c.htlcIDs[:len(c.htlcIDs)-1]
+	})
+}
+
+func (c *CollapseContext) journalBatchResults(id TransactionID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := c.batchResults[id]
+	c.record(func() {
+		if existed {
+			c.batchResults[id] = prev
+			return
+		}
+
+		delete(c.batchResults, id)
+		c.batchResultIDs = c.batchResultIDs[:len(c.batchResultIDs)-1]
+	})
+}
+
+func (c *CollapseContext) journalSchedule(id TransactionID) {
+	if !c.journaling() {
+		return
+	}
+
+	prev, existed := c.schedules[id]
+	c.record(func() {
+		if existed {
+			c.schedules[id] = prev
+			return
+		}
+
+		delete(c.schedules, id)
+		c.scheduleIDs = c.scheduleIDs[:len(c.scheduleIDs)-1]
+	})
+}
+
+func (c *CollapseContext) journalRewardWithdrawalRequests() {
+	if !c.journaling() {
+		return
+	}
+
+	prev := c.rewardWithdrawalRequests
+	c.record(func() { c.rewardWithdrawalRequests = prev })
+}
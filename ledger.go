@@ -23,6 +23,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"runtime"
 	"sync"
 	"time"
 
@@ -40,6 +41,7 @@ import (
 	"github.com/perlin-network/wavelet/store"
 	"github.com/perlin-network/wavelet/sys"
 	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/peer"
 )
@@ -79,15 +81,33 @@ type Ledger struct {
 	queryPeerBlockCache  *PeerBlockLRU
 	queryBlockValidCache map[BlockID]struct{}
 
+	// collapseCache holds the results of collapsing block proposals, such that they need not be collapsed again
+	// once they are finalized.
+	collapseCache *CollapseResultsLRU
+
 	queryWorkerPool *worker.Pool
 
+	// signatureWorkerPool verifies the signatures of transactions received from peers in batches.
+	signatureWorkerPool *worker.Pool
+
+	// contractSenderLimiter limits the rate at which transactions sent by smart contract accounts are accepted
+	// from peers.
+	contractSenderLimiter *rate.Limiter
+
 	collapseResultsLogger *CollapseResultsLogger
+
+	engine ContractEngine
 }
 
 type config struct {
 	GCDisabled  bool
 	Genesis     *string
 	MaxMemoryMB uint64
+
+	ModuleCacheSize   int
+	ModuleCachePolicy ModuleCacheEvictionPolicy
+
+	ContractEngine ContractEngine
 }
 
 type Option func(cfg *config)
@@ -111,8 +131,28 @@ func WithMaxMemoryMB(n uint64) Option {
 	}
 }
 
+// WithModuleCache configures the cache of compiled smart contract code persisted by the ledger to hold at most
+// size modules, evicting modules in accordance to policy. A size of zero disables the cache.
+func WithModuleCache(size int, policy ModuleCacheEvictionPolicy) Option {
+	return func(cfg *config) {
+		cfg.ModuleCacheSize = size
+		cfg.ModuleCachePolicy = policy
+	}
+}
+
+// WithContractEngine has the ledger execute smart contracts with engine in place of a LifeEngine. The cache of
+// compiled smart contract code configured by WithModuleCache only applies to a LifeEngine.
+func WithContractEngine(engine ContractEngine) Option {
+	return func(cfg *config) {
+		cfg.ContractEngine = engine
+	}
+}
+
 func NewLedger(kv store.KV, client *skademlia.Client, opts ...Option) (*Ledger, error) {
-	var cfg config
+	cfg := config{
+		ModuleCacheSize:   DefaultModuleCacheSize,
+		ModuleCachePolicy: ModuleCacheEvictLRU,
+	}
 
 	for _, opt := range opts {
 		opt(&cfg)
@@ -147,6 +187,22 @@ func NewLedger(kv store.KV, client *skademlia.Client, opts ...Option) (*Ledger,
 		block = blocks.Latest()
 	}
 
+	engine := cfg.ContractEngine
+
+	if engine == nil {
+		var modules *ModuleCache
+
+		if cfg.ModuleCacheSize > 0 {
+			if modules, err = NewModuleCache(kv, cfg.ModuleCacheSize, cfg.ModuleCachePolicy); err != nil {
+				return nil, errors.Wrap(err, "error opening compiled module cache")
+			}
+
+			modules = modules.WithMetrics(metrics)
+		}
+
+		engine = NewLifeEngine(modules)
+	}
+
 	transactions := NewTransactions(*block)
 	transactions.BatchMarkFinalized(LoadFinalizedTransactionIDs(accounts.tree)...)
 
@@ -178,9 +234,17 @@ func NewLedger(kv store.KV, client *skademlia.Client, opts ...Option) (*Ledger,
 		queryPeerBlockCache:  NewPeerBlockLRU(16),
 		queryBlockValidCache: make(map[BlockID]struct{}),
 
+		collapseCache: NewCollapseResultsLRU(16, 4*int(conf.GetBlockTXLimit())),
+
 		queryWorkerPool: worker.NewWorkerPool(),
 
+		signatureWorkerPool: worker.NewWorkerPool(),
+
+		contractSenderLimiter: rate.NewLimiter(rate.Limit(sys.ContractSenderGossipRate), sys.ContractSenderGossipRate),
+
 		collapseResultsLogger: NewCollapseResultsLogger(),
+
+		engine: engine,
 	}
 
 	var kickstart sync.Once
@@ -206,6 +270,7 @@ func NewLedger(kv store.KV, client *skademlia.Client, opts ...Option) (*Ledger,
 
 	syncManager.OnSynced = append(syncManager.OnSynced, func(block Block) {
 		ledger.transactions.ReshufflePending(block)
+		ledger.collapseCache.Reset()
 
 		ledger.transactionFilterLock.Lock()
 		ledger.transactionFilter.Reset()
@@ -254,6 +319,7 @@ func NewLedger(kv store.KV, client *skademlia.Client, opts ...Option) (*Ledger,
 	ledger.stallDetector = stallDetector
 
 	ledger.queryWorkerPool.Start(16)
+	ledger.signatureWorkerPool.Start(runtime.NumCPU())
 
 	go ledger.syncManager.Start()
 
@@ -275,6 +341,7 @@ func (l *Ledger) Close() {
 	}
 
 	l.queryWorkerPool.Stop()
+	l.signatureWorkerPool.Stop()
 
 	l.stallDetector.Stop()
 
@@ -387,6 +454,89 @@ func (l *Ledger) Snapshot() *avl.Tree {
 	return l.accounts.Snapshot()
 }
 
+// SimulateTransaction applies a transaction against the latest state of the ledger without persisting any of the
+// changes it makes, and returns a trace of all smart contract invocations made while applying it.
+func (l *Ledger) SimulateTransaction(tx *Transaction) *ContractTrace {
+	trace := new(ContractTrace)
+
+	ctx := NewCollapseContext(l.accounts.Snapshot())
+	ctx.Engine = l.engine
+
+	if outcome := applyBlockTransaction(ctx, l.blocks.Latest(), tx, trace); outcome.err != nil {
+		trace.Error = outcome.err.Error()
+	}
+
+	return trace
+}
+
+// TraceTransaction replays a finalized transaction against the state of the block prior to the block it was
+// finalized in, and returns a trace of all smart contract invocations made while applying it. Only transactions
+// finalized in the latest block are guaranteed to be able to be replayed, as the states of older blocks are
+// eventually garbage collected.
+func (l *Ledger) TraceTransaction(id TransactionID) (*ContractTrace, error) {
+	var block *Block
+
+	for _, b := range l.blocks.Clone() {
+		for _, txID := range b.Transactions {
+			if txID == id {
+				block = b
+				break
+			}
+		}
+
+		if block != nil {
+			break
+		}
+	}
+
+	if block == nil {
+		return nil, errors.Errorf("transaction %x was not finalized in any of the blocks stored by the node", id)
+	}
+
+	parent, err := l.blocks.GetByIndex(block.Index - 1)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not find the block prior to block %d", block.Index)
+	}
+
+	snapshot, err := l.accounts.SnapshotAt(parent.Merkle)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot.SetViewID(block.Index)
+
+	// Contract code is migrated just as it was when the block was collapsed, should the block have been the first
+	// collapsed since the migration was introduced.
+	MigrateContractCode(snapshot)
+
+	txs, err := l.transactions.BatchFind(block.Transactions)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not find the transactions finalized in block %d", block.Index)
+	}
+
+	ctx := NewCollapseContext(snapshot)
+	ctx.Engine = l.engine
+
+	// Transactions finalized in the block prior to the transaction are applied first, so that the transaction
+	// is replayed against the exact same state it was originally applied against.
+	for _, tx := range txs {
+		if tx.ID != id {
+			applyBlockTransaction(ctx, parent, tx, nil)
+			continue
+		}
+
+		trace := new(ContractTrace)
+
+		if outcome := applyBlockTransaction(ctx, parent, tx, trace); outcome.err != nil {
+			trace.Error = outcome.err.Error()
+		}
+
+		return trace, nil
+	}
+
+	return nil, errors.Errorf("transaction %x was not found amongst the transactions of block %d", id, block.Index)
+}
+
 // SyncTransactions is an infinite loop which constantly sends transaction ids from its index
 // into a Cuckoo Filter to randomly sampled number of peers and adds to it's state all received
 // transactions.
@@ -502,7 +652,7 @@ func (l *Ledger) SyncTransactions() { // nolint:gocognit
 						return
 					}
 
-					transactions := make([]Transaction, 0, len(txResponse.Transactions))
+					received := make([]Transaction, 0, len(txResponse.Transactions))
 
 					for _, txBody := range txResponse.Transactions {
 						tx, err := UnmarshalTransaction(bytes.NewReader(txBody))
@@ -511,15 +661,21 @@ func (l *Ledger) SyncTransactions() { // nolint:gocognit
 							continue
 						}
 
-						if err := ValidateTransaction(snapshot, tx); err != nil && err != ErrContractAlreadyExists {
+						received = append(received, tx)
+					}
+
+					transactions := make([]Transaction, 0, len(received))
+
+					for i, err := range ValidateTransactions(snapshot, received, l.signatureWorkerPool) {
+						if err != nil && err != ErrContractAlreadyExists {
 							logger.Error().
 								Err(err).
-								Hex("tx_id", tx.ID[:]).
+								Hex("tx_id", received[i].ID[:]).
 								Msg("transaction validation error")
 							continue
 						}
 
-						transactions = append(transactions, tx)
+						transactions = append(transactions, received[i])
 					}
 
 					downloadedNum := len(transactions)
@@ -641,21 +797,26 @@ func (l *Ledger) PullMissingTransactions() {
 
 		close(responseChan)
 
-		pulledTXs := make([]Transaction, 0, len(pulled))
-		snapshot := l.Snapshot()
+		received := make([]Transaction, 0, len(pulled))
 
 		for _, tx := range pulled {
-			if err := ValidateTransaction(snapshot, tx); err != nil {
+			received = append(received, tx)
+		}
+
+		pulledTXs := make([]Transaction, 0, len(pulled))
+
+		for i, err := range ValidateTransactions(l.Snapshot(), received, l.signatureWorkerPool) {
+			if err != nil {
 				if err == ErrTxInvalidSignature {
 					logger.Error().
-						Hex("tx_id", tx.ID[:]).
+						Hex("tx_id", received[i].ID[:]).
 						Msg("bad signature")
 				}
 
 				continue
 			}
 
-			pulledTXs = append(pulledTXs, tx)
+			pulledTXs = append(pulledTXs, received[i])
 		}
 
 		l.AddTransaction(pulledTXs...)
@@ -750,6 +911,8 @@ func (l *Ledger) proposeBlock() *Block {
 
 	proposed := NewBlock(latest.Index+1, results.snapshot.Checksum(), proposing...)
 
+	l.collapseCache.Put(latest.ID, proposed.ID, results)
+
 	return &proposed
 }
 
@@ -758,14 +921,24 @@ func (l *Ledger) finalize(block Block) {
 
 	logger := log.Consensus("finalized")
 
-	results, err := l.collapseTransactions(block.Index, current, block.Transactions, true)
-	if err != nil {
-		logger := log.Node()
-		logger.Error().
-			Err(err).
-			Msg("error collapsing transactions during finalization")
+	// Reuse the results of collapsing the block should it have already been collapsed while being proposed or
+	// voted for.
+	results, cached := l.collapseCache.Load(current.ID, block.ID)
 
-		return
+	if cached {
+		l.collapseResultsLogger.Log(results)
+	} else {
+		var err error
+
+		results, err = l.collapseTransactions(block.Index, current, block.Transactions, true)
+		if err != nil {
+			logger := log.Node()
+			logger.Error().
+				Err(err).
+				Msg("error collapsing transactions during finalization")
+
+			return
+		}
 	}
 
 	if checksum := results.snapshot.Checksum(); checksum != block.Merkle {
@@ -780,13 +953,14 @@ func (l *Ledger) finalize(block Block) {
 	}
 
 	pruned := l.transactions.ReshufflePending(block)
+	l.collapseCache.Reset()
 	l.transactionFilterLock.Lock()
 	for _, id := range pruned {
 		l.transactionFilter.Delete(id)
 	}
 	l.transactionFilterLock.Unlock()
 
-	if _, err = l.blocks.Save(&block); err != nil {
+	if _, err := l.blocks.Save(&block); err != nil {
 		logger := log.Node()
 		logger.Error().
 			Err(err).
@@ -795,7 +969,7 @@ func (l *Ledger) finalize(block Block) {
 		return
 	}
 
-	if err = l.accounts.Commit(results.snapshot); err != nil {
+	if err := l.accounts.Commit(results.snapshot); err != nil {
 		logger := log.Node()
 		logger.Error().
 			Err(err).
@@ -821,6 +995,7 @@ func (l *Ledger) finalize(block Block) {
 		Int("num_applied_tx", results.appliedCount).
 		Int("num_rejected_tx", results.rejectedCount).
 		Int("num_pruned_tx", len(pruned)).
+		Bool("cached_collapse", cached).
 		Uint64("old_block_height", current.Index).
 		Uint64("new_block_height", block.Index).
 		Hex("old_block_id", current.ID[:]).
@@ -980,7 +1155,7 @@ func (l *Ledger) collapseTransactions(
 		return nil, errors.Wrap(err, "could not find transactions to collapse in node")
 	}
 
-	results, err := collapseTransactions(height, transactions, current, l.accounts)
+	results, err := collapseTransactions(height, transactions, current, l.accounts, l.engine)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to collapse transactions")
 	}
@@ -1130,5 +1305,6 @@ ValidateVotes:
 		}
 
 		l.queryBlockValidCache[vote.block.ID] = struct{}{}
+		l.collapseCache.Put(current.ID, vote.block.ID, results)
 	}
 }
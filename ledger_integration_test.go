@@ -154,6 +154,55 @@ func TestLedger_CallContract(t *testing.T) {
 	assert.NoError(t, waitFor(func() bool { return alice.Balance() > 700000 }))
 }
 
+func TestLedger_TraceTransaction(t *testing.T) {
+	testnet, err := NewTestNetwork()
+	FailTest(t, err)
+
+	defer testnet.Cleanup()
+
+	alice, err := testnet.AddNode()
+	FailTest(t, err)
+
+	_, err = testnet.AddNode() // bob
+	FailTest(t, err)
+
+	FailTest(t, testnet.WaitUntilSync())
+
+	_, err = testnet.Faucet().Pay(alice, 1000000)
+	FailTest(t, err)
+
+	FailTest(t, alice.WaitUntilBalance(1000000))
+
+	contract, err := alice.SpawnContract(
+		"testdata/transfer_back.wasm", 10000, nil,
+	)
+	FailTest(t, err)
+
+	FailTest(t, alice.WaitUntilBlock(2))
+
+	tx, err := alice.CallContract(
+		contract.ID, 500000, 100000, "on_money_received", contract.ID[:],
+	)
+	FailTest(t, err)
+
+	// The transaction may only be traced once it has been finalized.
+	var trace *ContractTrace
+
+	FailTest(t, waitFor(func() bool {
+		trace, err = alice.Ledger().TraceTransaction(tx.ID)
+		return err == nil
+	}))
+
+	assert.Empty(t, trace.Error)
+
+	if assert.Len(t, trace.Invocations, 1) {
+		assert.Equal(t, contract.ID, trace.Invocations[0].ContractID)
+		assert.Equal(t, "on_money_received", trace.Invocations[0].Function)
+		assert.Empty(t, trace.Invocations[0].Error)
+		assert.NotEmpty(t, trace.Invocations[0].Events)
+	}
+}
+
 func TestLedger_DepositGas(t *testing.T) {
 	testnet, err := NewTestNetwork()
 	FailTest(t, err)
@@ -64,3 +64,78 @@ func TestLedger_FilterInvalidVotes(t *testing.T) {
 
 	assert.Nil(t, votes[len(votes)-1].(*finalizationVote).block)
 }
+
+func TestLedger_FinalizeCollapseCache(t *testing.T) {
+	testnet, err := NewTestNetwork()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer testnet.Cleanup()
+
+	// The faucet has no peers, and hence never starts performing consensus on its own.
+	faucet := testnet.Faucet()
+	ledger := faucet.Ledger()
+
+	recipient := AccountID{1}
+
+	propose := func(amount uint64) *Block {
+		payload, err := Transfer{Recipient: recipient, Amount: amount}.Marshal()
+		if !assert.NoError(t, err) {
+			return nil
+		}
+
+		ledger.AddTransaction(faucet.newSignedTransaction(sys.TagTransfer, payload))
+
+		proposed := ledger.proposeBlock()
+		if !assert.NotNil(t, proposed) {
+			return nil
+		}
+
+		ledger.finalizer.Prefer(&finalizationVote{voter: faucet.Client().ID(), block: proposed})
+
+		return ledger.finalizer.Preferred().Value().(*Block)
+	}
+
+	// Case 1 - Finalizing a block collapsed while being proposed commits the state it was collapsed into, without
+	// collapsing the block again.
+	block := propose(1)
+	if block == nil {
+		return
+	}
+
+	results, cached := ledger.collapseCache.Load(ledger.blocks.Latest().ID, block.ID)
+	if !assert.True(t, cached) {
+		return
+	}
+
+	ledger.finalize(*block)
+
+	assert.Equal(t, block.Index, ledger.blocks.Latest().Index)
+	assert.Equal(t, block.Merkle, ledger.accounts.tree.Checksum())
+	assert.True(t, results.snapshot == ledger.accounts.tree)
+	assert.EqualValues(t, 1, faucet.BalanceWithPublicKey(recipient))
+
+	// Case 2 - Finalizing a block whose collapse is no longer cached collapses it again.
+	block = propose(2)
+	if block == nil {
+		return
+	}
+
+	results, cached = ledger.collapseCache.Load(ledger.blocks.Latest().ID, block.ID)
+	if !assert.True(t, cached) {
+		return
+	}
+
+	ledger.collapseCache.Reset()
+
+	_, cached = ledger.collapseCache.Load(ledger.blocks.Latest().ID, block.ID)
+	assert.False(t, cached)
+
+	ledger.finalize(*block)
+
+	assert.Equal(t, block.Index, ledger.blocks.Latest().Index)
+	assert.Equal(t, block.Merkle, ledger.accounts.tree.Checksum())
+	assert.False(t, results.snapshot == ledger.accounts.tree)
+	assert.EqualValues(t, 3, faucet.BalanceWithPublicKey(recipient))
+}
@@ -73,7 +73,7 @@ func TestCollapseResultsLogger(t *testing.T) {
 
 	block := NewBlock(0, MerkleNodeID{})
 
-	results, err := collapseTransactions(block.Index, txs, &block, accounts)
+	results, err := collapseTransactions(block.Index, txs, &block, accounts, nil)
 	if !assert.NoError(t, err) {
 		return
 	}
@@ -0,0 +1,126 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"container/list"
+	"sync"
+)
+
+// CollapseResultsLRU caches the results of collapsing the transactions of proposed blocks, keyed by the ID of the
+// block they were proposed on top of and the ID of the proposed block. The cache holds at most size results, and at
+// most maxTransactions transactions collapsed across all of its results.
+type CollapseResultsLRU struct {
+	sync.Mutex
+
+	size            int
+	maxTransactions int
+	transactions    int
+
+	elements map[collapseResultsKey]*list.Element
+	access   *list.List
+}
+
+type collapseResultsKey struct {
+	parent BlockID
+	block  BlockID
+}
+
+type objectInfoCollapseResults struct {
+	key collapseResultsKey
+	obj *collapseResults
+}
+
+func NewCollapseResultsLRU(size int, maxTransactions int) *CollapseResultsLRU {
+	return &CollapseResultsLRU{
+		size:            size,
+		maxTransactions: maxTransactions,
+		elements:        make(map[collapseResultsKey]*list.Element, size),
+		access:          list.New(),
+	}
+}
+
+func (l *CollapseResultsLRU) Load(parent BlockID, block BlockID) (*collapseResults, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	elem, ok := l.elements[collapseResultsKey{parent: parent, block: block}]
+	if !ok {
+		return nil, false
+	}
+
+	l.access.MoveToFront(elem)
+
+	return elem.Value.(*objectInfoCollapseResults).obj, ok
+}
+
+// Put caches results under the IDs of a parent block and a block proposed on top of it. Results collapsing more
+// transactions than the cache may hold at once are not cached.
+func (l *CollapseResultsLRU) Put(parent BlockID, block BlockID, val *collapseResults) {
+	l.Lock()
+	defer l.Unlock()
+
+	weight := collapseResultsWeight(val)
+	if weight > l.maxTransactions {
+		return
+	}
+
+	key := collapseResultsKey{parent: parent, block: block}
+
+	if elem, ok := l.elements[key]; ok {
+		info := elem.Value.(*objectInfoCollapseResults)
+
+		l.transactions += weight - collapseResultsWeight(info.obj)
+		info.obj = val
+
+		l.access.MoveToFront(elem)
+	} else {
+		l.elements[key] = l.access.PushFront(&objectInfoCollapseResults{
+			key: key,
+			obj: val,
+		})
+
+		l.transactions += weight
+	}
+
+	for len(l.elements) > l.size || l.transactions > l.maxTransactions {
+		back := l.access.Back()
+		info := back.Value.(*objectInfoCollapseResults)
+		delete(l.elements, info.key)
+		l.access.Remove(back)
+
+		l.transactions -= collapseResultsWeight(info.obj)
+	}
+}
+
+// Reset removes all cached results.
+func (l *CollapseResultsLRU) Reset() {
+	l.Lock()
+	defer l.Unlock()
+
+	l.elements = make(map[collapseResultsKey]*list.Element, l.size)
+	l.access.Init()
+	l.transactions = 0
+}
+
+// collapseResultsWeight approximates the memory held by collapse results by the number of transactions collapsed.
+func collapseResultsWeight(results *collapseResults) int {
+	return len(results.applied) + len(results.rejected)
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// +build unit
+
+package wavelet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollapseResultsLRU(t *testing.T) {
+	results := func(n int) *collapseResults {
+		return &collapseResults{applied: make([]*Transaction, n)}
+	}
+
+	lru := NewCollapseResultsLRU(2, 10)
+
+	parent := BlockID{0x01}
+
+	lru.Put(parent, BlockID{0x01}, results(3))
+	lru.Put(parent, BlockID{0x02}, results(3))
+
+	_, ok := lru.Load(parent, BlockID{0x01})
+	assert.True(t, ok)
+
+	// Results are keyed by both their parent and their block.
+	_, ok = lru.Load(BlockID{0x02}, BlockID{0x01})
+	assert.False(t, ok)
+
+	// The least recently used results are evicted once the cache is full.
+	lru.Put(parent, BlockID{0x03}, results(3))
+
+	_, ok = lru.Load(parent, BlockID{0x02})
+	assert.False(t, ok)
+
+	_, ok = lru.Load(parent, BlockID{0x01})
+	assert.True(t, ok)
+
+	// Results are evicted until the cache holds no more than its limit of transactions.
+	lru.Put(parent, BlockID{0x04}, results(8))
+
+	_, ok = lru.Load(parent, BlockID{0x03})
+	assert.False(t, ok)
+
+	_, ok = lru.Load(parent, BlockID{0x01})
+	assert.False(t, ok)
+
+	_, ok = lru.Load(parent, BlockID{0x04})
+	assert.True(t, ok)
+
+	// Results collapsing more transactions than the cache may hold are not cached.
+	lru.Put(parent, BlockID{0x05}, results(11))
+
+	_, ok = lru.Load(parent, BlockID{0x05})
+	assert.False(t, ok)
+
+	_, ok = lru.Load(parent, BlockID{0x04})
+	assert.True(t, ok)
+
+	lru.Reset()
+
+	_, ok = lru.Load(parent, BlockID{0x04})
+	assert.False(t, ok)
+	assert.Zero(t, lru.transactions)
+}
@@ -22,8 +22,6 @@ package wavelet
 import (
 	"container/list"
 	"sync"
-
-	"github.com/perlin-network/life/exec"
 )
 
 type VMLRU struct {
@@ -33,11 +31,16 @@ type VMLRU struct {
 
 	elements map[[32]byte]*list.Element
 	access   *list.List
+
+	// code, if set, caches VMs by the ID of the code they were instantiated from in addition to the VMs cached by
+	// the ID of their smart contracts. As a freshly instantiated VM only depends on its code, code may be shared by
+	// the caches of contexts applying transactions in parallel.
+	code *VMLRU
 }
 
 type objectInfoVM struct {
 	key [32]byte
-	obj *exec.VirtualMachine
+	obj ContractInstance
 }
 
 func NewVMLRU(size int) *VMLRU {
@@ -48,7 +51,7 @@ func NewVMLRU(size int) *VMLRU {
 	}
 }
 
-func (l *VMLRU) Load(key [32]byte) (*exec.VirtualMachine, bool) {
+func (l *VMLRU) Load(key [32]byte) (ContractInstance, bool) {
 	l.Lock()
 	defer l.Unlock()
 
@@ -62,7 +65,7 @@ func (l *VMLRU) Load(key [32]byte) (*exec.VirtualMachine, bool) {
 	return elem.Value.(*objectInfoVM).obj, ok
 }
 
-func (l *VMLRU) LoadOrPut(key [32]byte, val *exec.VirtualMachine) (*exec.VirtualMachine, bool) {
+func (l *VMLRU) LoadOrPut(key [32]byte, val ContractInstance) (ContractInstance, bool) {
 	l.Lock()
 	defer l.Unlock()
 
@@ -87,7 +90,7 @@ func (l *VMLRU) LoadOrPut(key [32]byte, val *exec.VirtualMachine) (*exec.Virtual
 	return val, ok
 }
 
-func (l *VMLRU) Put(key [32]byte, val *exec.VirtualMachine) {
+func (l *VMLRU) Put(key [32]byte, val ContractInstance) {
 	l.Lock()
 	defer l.Unlock()
 
@@ -40,6 +40,9 @@ type Metrics struct {
 	finalizedBlocks metrics.Meter
 
 	queryLatency metrics.Timer
+
+	moduleCacheHits   metrics.Meter
+	moduleCacheMisses metrics.Meter
 }
 
 func NewMetrics(ctx context.Context) *Metrics {
@@ -56,6 +59,9 @@ func NewMetrics(ctx context.Context) *Metrics {
 
 	queryLatency := metrics.NewRegisteredTimer("query.latency", registry)
 
+	moduleCacheHits := metrics.NewRegisteredMeter("contract.module_cache.hits", registry)
+	moduleCacheMisses := metrics.NewRegisteredMeter("contract.module_cache.misses", registry)
+
 	go func() {
 		logger := log.Metrics()
 
@@ -77,6 +83,8 @@ func NewMetrics(ctx context.Context) *Metrics {
 					Int64("query.latency.max.ms", queryLatency.Max()/(1.0e+7)).
 					Int64("query.latency.min.ms", queryLatency.Min()/(1.0e+7)).
 					Float64("query.latency.mean.ms", queryLatency.Mean()/(1.0e+7)).
+					Int64("contract.module_cache.hits", moduleCacheHits.Count()).
+					Int64("contract.module_cache.misses", moduleCacheMisses.Count()).
 					Msg("Updated metrics.")
 			case <-ctx.Done():
 				return
@@ -97,6 +105,9 @@ func NewMetrics(ctx context.Context) *Metrics {
 		finalizedBlocks: finalizedBlocks,
 
 		queryLatency: queryLatency,
+
+		moduleCacheHits:   moduleCacheHits,
+		moduleCacheMisses: moduleCacheMisses,
 	}
 }
 
@@ -111,4 +122,7 @@ func (m *Metrics) Stop() {
 	m.finalizedBlocks.Stop()
 
 	m.queryLatency.Stop()
+
+	m.moduleCacheHits.Stop()
+	m.moduleCacheMisses.Stop()
 }
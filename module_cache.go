@@ -0,0 +1,567 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/go-interpreter/wagon/wasm"
+	"github.com/go-interpreter/wagon/wasm/leb128"
+	ops "github.com/go-interpreter/wagon/wasm/operators"
+	"github.com/perlin-network/life/compiler"
+	"github.com/perlin-network/life/exec"
+	"github.com/perlin-network/wavelet/store"
+	"github.com/pkg/errors"
+	"github.com/rcrowley/go-metrics"
+	"golang.org/x/crypto/blake2b"
+)
+
+// ContractEngineVersion identifies the engine smart contracts are compiled and executed with. It must be changed
+// whenever the engine, or the gas policy smart contracts are compiled under, changes so that modules compiled by
+// an older engine are no longer loaded from a ModuleCache.
+const ContractEngineVersion = "life-3091ed0c1be8/gas-v1"
+
+// DefaultModuleCacheSize is the default maximum number of compiled modules persisted by a ModuleCache.
+const DefaultModuleCacheSize = 256
+
+// ModuleCacheEvictionPolicy decides which compiled module is evicted once a ModuleCache is full.
+type ModuleCacheEvictionPolicy uint8
+
+const (
+	// ModuleCacheEvictLRU evicts the least recently used compiled module.
+	ModuleCacheEvictLRU ModuleCacheEvictionPolicy = iota
+	// ModuleCacheEvictLFU evicts the least frequently used compiled module.
+	ModuleCacheEvictLFU
+)
+
+func (p ModuleCacheEvictionPolicy) String() string {
+	switch p {
+	case ModuleCacheEvictLRU:
+		return "lru"
+	case ModuleCacheEvictLFU:
+		return "lfu"
+	}
+
+	return fmt.Sprintf("ModuleCacheEvictionPolicy(%d)", p)
+}
+
+// ParseModuleCacheEvictionPolicy parses the name of an eviction policy, which is either "lru" or "lfu".
+func ParseModuleCacheEvictionPolicy(name string) (ModuleCacheEvictionPolicy, error) {
+	switch name {
+	case "lru":
+		return ModuleCacheEvictLRU, nil
+	case "lfu":
+		return ModuleCacheEvictLFU, nil
+	}
+
+	return 0, errors.Errorf("unknown module cache eviction policy %q, must be either lru or lfu", name)
+}
+
+type moduleCacheEntry struct {
+	lastUsed uint64
+	uses     uint64
+}
+
+// ModuleCache persists smart contract code compiled for the interpreter, keyed by the hash of the code and
+// ContractEngineVersion, such that smart contracts need not be recompiled every time they are instantiated.
+//
+// The set of modules persisted along with how recently and frequently each was used is kept in memory, and is
+// written to disk whenever a module is added to the cache.
+type ModuleCache struct {
+	sync.Mutex
+
+	kv     store.KV
+	size   int
+	policy ModuleCacheEvictionPolicy
+
+	entries map[[blake2b.Size256]byte]*moduleCacheEntry
+	clock   uint64
+
+	hits   metrics.Meter
+	misses metrics.Meter
+}
+
+// NewModuleCache opens a cache of compiled modules persisted in kv, holding at most size modules.
+func NewModuleCache(kv store.KV, size int, policy ModuleCacheEvictionPolicy) (*ModuleCache, error) {
+	c := &ModuleCache{
+		kv:      kv,
+		size:    size,
+		policy:  policy,
+		entries: make(map[[blake2b.Size256]byte]*moduleCacheEntry),
+		hits:    metrics.NilMeter{},
+		misses:  metrics.NilMeter{},
+	}
+
+	buf, err := kv.Get(keyModuleCacheIndex[:])
+	if err != nil || len(buf) == 0 {
+		return c, nil
+	}
+
+	if err := c.unmarshalIndex(buf); err != nil {
+		return nil, errors.Wrap(err, "module cache: index is malformed")
+	}
+
+	// The size of the cache may have been reduced since the index was last written.
+	if len(c.entries) > c.size {
+		c.evict()
+
+		if err := kv.Put(keyModuleCacheIndex[:], c.marshalIndex()); err != nil {
+			return nil, errors.Wrap(err, "module cache: failed to persist index")
+		}
+	}
+
+	return c, nil
+}
+
+// WithMetrics has the cache report its hits and misses into m.
+func (c *ModuleCache) WithMetrics(m *Metrics) *ModuleCache {
+	c.hits = m.moduleCacheHits
+	c.misses = m.moduleCacheMisses
+
+	return c
+}
+
+// Len returns the number of compiled modules persisted by the cache.
+func (c *ModuleCache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+
+	return len(c.entries)
+}
+
+// Instantiate instantiates a virtual machine for code, loading the code compiled for the interpreter from the
+// cache should it exist. Otherwise, code is compiled and the result is persisted into the cache.
+func (c *ModuleCache) Instantiate(
+	code []byte, config exec.VMConfig, importResolver exec.ImportResolver, gasPolicy compiler.GasPolicy,
+) (*exec.VirtualMachine, error) {
+	key := moduleCacheKey(code)
+
+	if compiled, ok := c.load(key); ok {
+		c.hits.Mark(1)
+
+		m, err := compiler.LoadModule(code)
+		if err != nil {
+			return nil, err
+		}
+
+		m.DisableFloatingPoint = config.DisableFloatingPoint
+
+		return instantiateModule(m, compiled, config, importResolver, gasPolicy)
+	}
+
+	c.misses.Mark(1)
+
+	vm, err := exec.NewVirtualMachine(code, config, importResolver, gasPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	c.store(key, vm.FunctionCode)
+
+	return vm, nil
+}
+
+func (c *ModuleCache) load(key [blake2b.Size256]byte) ([]compiler.InterpreterCode, bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	entry, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+
+	buf, err := c.kv.Get(append(keyModuleCache[:], key[:]...))
+	if err != nil {
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	compiled, err := unmarshalInterpreterCode(buf)
+	if err != nil {
+		delete(c.entries, key)
+		_ = c.kv.Delete(append(keyModuleCache[:], key[:]...))
+
+		return nil, false
+	}
+
+	c.clock++
+
+	entry.lastUsed = c.clock
+	entry.uses++
+
+	return compiled, true
+}
+
+func (c *ModuleCache) store(key [blake2b.Size256]byte, compiled []compiler.InterpreterCode) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.kv.Put(append(keyModuleCache[:], key[:]...), marshalInterpreterCode(compiled)); err != nil {
+		return
+	}
+
+	c.clock++
+	c.entries[key] = &moduleCacheEntry{lastUsed: c.clock, uses: 1}
+
+	c.evict()
+
+	_ = c.kv.Put(keyModuleCacheIndex[:], c.marshalIndex())
+}
+
+// evict removes modules from the cache in accordance to its eviction policy until it holds no more modules
+// than its size permits.
+func (c *ModuleCache) evict() {
+	for len(c.entries) > 0 && len(c.entries) > c.size {
+		var (
+			victim [blake2b.Size256]byte
+			oldest *moduleCacheEntry
+		)
+
+		for key, entry := range c.entries {
+			if oldest == nil || c.less(entry, oldest) {
+				victim, oldest = key, entry
+			}
+		}
+
+		delete(c.entries, victim)
+		_ = c.kv.Delete(append(keyModuleCache[:], victim[:]...))
+	}
+}
+
+// less returns true if a is to be evicted before b.
+func (c *ModuleCache) less(a, b *moduleCacheEntry) bool {
+	if c.policy == ModuleCacheEvictLFU && a.uses != b.uses {
+		return a.uses < b.uses
+	}
+
+	return a.lastUsed < b.lastUsed
+}
+
+func (c *ModuleCache) marshalIndex() []byte {
+	buf := make([]byte, 8, 8+len(c.entries)*(blake2b.Size256+16))
+	binary.LittleEndian.PutUint64(buf[:8], c.clock)
+
+	var scratch [8]byte
+
+	for key, entry := range c.entries {
+		buf = append(buf, key[:]...)
+
+		binary.LittleEndian.PutUint64(scratch[:], entry.lastUsed)
+		buf = append(buf, scratch[:]...)
+
+		binary.LittleEndian.PutUint64(scratch[:], entry.uses)
+		buf = append(buf, scratch[:]...)
+	}
+
+	return buf
+}
+
+func (c *ModuleCache) unmarshalIndex(buf []byte) error {
+	if len(buf) < 8 || (len(buf)-8)%(blake2b.Size256+16) != 0 {
+		return io.ErrUnexpectedEOF
+	}
+
+	c.clock = binary.LittleEndian.Uint64(buf[:8])
+
+	for buf = buf[8:]; len(buf) > 0; buf = buf[blake2b.Size256+16:] {
+		var key [blake2b.Size256]byte
+		copy(key[:], buf[:blake2b.Size256])
+
+		c.entries[key] = &moduleCacheEntry{
+			lastUsed: binary.LittleEndian.Uint64(buf[blake2b.Size256 : blake2b.Size256+8]),
+			uses:     binary.LittleEndian.Uint64(buf[blake2b.Size256+8 : blake2b.Size256+16]),
+		}
+	}
+
+	return nil
+}
+
+func moduleCacheKey(code []byte) [blake2b.Size256]byte {
+	return blake2b.Sum256(append([]byte(ContractEngineVersion), code...))
+}
+
+func marshalInterpreterCode(compiled []compiler.InterpreterCode) []byte {
+	var (
+		w       bytes.Buffer
+		scratch [4]byte
+	)
+
+	binary.LittleEndian.PutUint32(scratch[:], uint32(len(compiled)))
+	w.Write(scratch[:])
+
+	for _, code := range compiled {
+		for _, n := range []int{code.NumRegs, code.NumParams, code.NumLocals, code.NumReturns, len(code.Bytes)} {
+			binary.LittleEndian.PutUint32(scratch[:], uint32(n))
+			w.Write(scratch[:])
+		}
+
+		w.Write(code.Bytes)
+	}
+
+	return w.Bytes()
+}
+
+func unmarshalInterpreterCode(buf []byte) ([]compiler.InterpreterCode, error) {
+	r := bytes.NewReader(buf)
+
+	var scratch [4]byte
+
+	readUint32 := func() (int, error) {
+		if _, err := io.ReadFull(r, scratch[:]); err != nil {
+			return 0, err
+		}
+
+		return int(binary.LittleEndian.Uint32(scratch[:])), nil
+	}
+
+	count, err := readUint32()
+	if err != nil {
+		return nil, err
+	}
+
+	if count > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	compiled := make([]compiler.InterpreterCode, count)
+
+	for i := range compiled {
+		var fields [5]int
+
+		for j := range fields {
+			if fields[j], err = readUint32(); err != nil {
+				return nil, err
+			}
+		}
+
+		if fields[4] > r.Len() {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		compiled[i] = compiler.InterpreterCode{
+			NumRegs:    fields[0],
+			NumParams:  fields[1],
+			NumLocals:  fields[2],
+			NumReturns: fields[3],
+			Bytes:      make([]byte, fields[4]),
+		}
+
+		if _, err := io.ReadFull(r, compiled[i].Bytes); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.Len() != 0 {
+		return nil, errors.New("unexpected trailing bytes")
+	}
+
+	return compiled, nil
+}
+
+// instantiateModule instantiates a virtual machine for a module whose code has already been compiled for the
+// interpreter, in exactly the same manner as exec.NewVirtualMachine does. The exec package offers no way to
+// instantiate a virtual machine from compiled code, so any change to exec.NewVirtualMachine brought by upgrading
+// the interpreter must be mirrored here. TestModuleCacheInstantiate checks that both instantiate the same globals,
+// memory and table.
+func instantiateModule(
+	m *compiler.Module, compiled []compiler.InterpreterCode, config exec.VMConfig,
+	importResolver exec.ImportResolver, gasPolicy compiler.GasPolicy,
+) (_ *exec.VirtualMachine, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("%v", r)
+		}
+	}()
+
+	var (
+		table       []uint32
+		globals     []int64
+		funcImports []exec.FunctionImportInfo
+	)
+
+	if m.Base.Import != nil && importResolver != nil {
+		for _, imp := range m.Base.Import.Entries {
+			switch imp.Type.Kind() {
+			case wasm.ExternalFunction:
+				funcImports = append(funcImports, exec.FunctionImportInfo{
+					ModuleName: imp.ModuleName,
+					FieldName:  imp.FieldName,
+				})
+			case wasm.ExternalGlobal:
+				globals = append(globals, importResolver.ResolveGlobal(imp.ModuleName, imp.FieldName))
+			case wasm.ExternalMemory:
+				if m.Base.Memory != nil && len(m.Base.Memory.Entries) > 0 {
+					panic("cannot import another memory while we already have one")
+				}
+
+				m.Base.Memory = &wasm.SectionMemories{
+					Entries: []wasm.Memory{{Limits: wasm.ResizableLimits{Initial: uint32(config.DefaultMemoryPages)}}},
+				}
+			case wasm.ExternalTable:
+				if m.Base.Table != nil && len(m.Base.Table.Entries) > 0 {
+					panic("cannot import another table while we already have one")
+				}
+
+				m.Base.Table = &wasm.SectionTables{
+					Entries: []wasm.Table{{Limits: wasm.ResizableLimits{Initial: uint32(config.DefaultTableSize)}}},
+				}
+			default:
+				panic(fmt.Errorf("import kind not supported: %d", imp.Type.Kind()))
+			}
+		}
+	}
+
+	for _, entry := range m.Base.GlobalIndexSpace {
+		globals = append(globals, execInitExpr(entry.Init, globals))
+	}
+
+	if m.Base.Table != nil && len(m.Base.Table.Entries) > 0 {
+		t := &m.Base.Table.Entries[0]
+
+		if config.MaxTableSize != 0 && int(t.Limits.Initial) > config.MaxTableSize {
+			panic("max table size exceeded")
+		}
+
+		table = make([]uint32, int(t.Limits.Initial))
+		for i := range table {
+			table[i] = math.MaxUint32
+		}
+
+		if m.Base.Elements != nil {
+			for _, e := range m.Base.Elements.Entries {
+				copy(table[int(execInitExpr(e.Offset, globals)):], e.Elems)
+			}
+		}
+	}
+
+	memory := make([]byte, 0)
+
+	if m.Base.Memory != nil && len(m.Base.Memory.Entries) > 0 {
+		initialLimit := int(m.Base.Memory.Entries[0].Limits.Initial)
+		if config.MaxMemoryPages != 0 && initialLimit > config.MaxMemoryPages {
+			panic("max memory exceeded")
+		}
+
+		memory = make([]byte, initialLimit*exec.DefaultPageSize)
+
+		if m.Base.Data != nil {
+			for _, e := range m.Base.Data.Entries {
+				copy(memory[int(execInitExpr(e.Offset, globals)):], e.Data)
+			}
+		}
+	}
+
+	if table == nil {
+		table = make([]uint32, 0)
+	}
+
+	if globals == nil {
+		globals = make([]int64, 0)
+	}
+
+	if funcImports == nil {
+		funcImports = make([]exec.FunctionImportInfo, 0)
+	}
+
+	return &exec.VirtualMachine{
+		Module:          m,
+		Config:          config,
+		FunctionCode:    compiled,
+		FunctionImports: funcImports,
+		CallStack:       make([]exec.Frame, exec.DefaultCallStackSize),
+		CurrentFrame:    -1,
+		Table:           table,
+		Globals:         globals,
+		Memory:          memory,
+		Exited:          true,
+		GasPolicy:       gasPolicy,
+		ImportResolver:  importResolver,
+	}, nil
+}
+
+// execInitExpr evaluates a constant initializer expression into the raw representation of its value held by the
+// virtual machine, exactly as the unexported evaluator exec.NewVirtualMachine uses does. Unlike the evaluator of the
+// wasm package, get_global resolves to the value of a global from globals, being the globals instantiated so far.
+func execInitExpr(expr []byte, globals []int64) int64 {
+	var stack []int64
+
+	r := bytes.NewReader(expr)
+
+	for {
+		b, err := r.ReadByte()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			panic(err)
+		}
+
+		switch b {
+		case ops.I32Const:
+			i, err := leb128.ReadVarint32(r)
+			if err != nil {
+				panic(err)
+			}
+
+			stack = append(stack, int64(i))
+		case ops.I64Const:
+			i, err := leb128.ReadVarint64(r)
+			if err != nil {
+				panic(err)
+			}
+
+			stack = append(stack, i)
+		case ops.F32Const:
+			var buf [4]byte
+			if _, err := io.ReadFull(r, buf[:]); err != nil {
+				panic(err)
+			}
+
+			stack = append(stack, int64(binary.LittleEndian.Uint32(buf[:])))
+		case ops.F64Const:
+			var buf [8]byte
+			if _, err := io.ReadFull(r, buf[:]); err != nil {
+				panic(err)
+			}
+
+			stack = append(stack, int64(binary.LittleEndian.Uint64(buf[:])))
+		case ops.GetGlobal:
+			index, err := leb128.ReadVarUint32(r)
+			if err != nil {
+				panic(err)
+			}
+
+			stack = append(stack, globals[int(index)])
+		case ops.End:
+		default:
+			panic("invalid opcode in init expr")
+		}
+	}
+
+	return stack[len(stack)-1]
+}
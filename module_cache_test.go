@@ -0,0 +1,211 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// +build unit
+
+package wavelet
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+
+	"github.com/perlin-network/life/exec"
+	"github.com/perlin-network/wavelet/store"
+	"github.com/stretchr/testify/assert"
+)
+
+var moduleCacheTestdata = []string{
+	"testdata/transfer_back.wasm",
+	"testdata/migrate.wasm",
+	"testdata/recursive_invocation.wasm",
+	"testdata/invoke.wasm",
+	"testdata/dummy.wasm",
+	"testdata/globals.wasm", // Places its data and element segments at offsets read from globals.
+}
+
+func loadModuleCacheTestdata(t *testing.T) [][]byte {
+	codes := make([][]byte, 0, len(moduleCacheTestdata))
+
+	for _, path := range moduleCacheTestdata {
+		code, err := ioutil.ReadFile(path)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes
+}
+
+func instantiateLifeVM(t *testing.T, modules *ModuleCache, code []byte) *exec.VirtualMachine {
+	instance, err := NewLifeEngine(modules).Instantiate(code, 1000000, &ContractExecutor{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	return instance.(*lifeInstance).vm
+}
+
+func TestModuleCacheInstantiate(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	metrics := NewMetrics(ctx)
+	defer metrics.Stop()
+
+	cache, err := NewModuleCache(store.NewInmem(), DefaultModuleCacheSize, ModuleCacheEvictLRU)
+	assert.NoError(t, err)
+
+	cache.WithMetrics(metrics)
+
+	codes := loadModuleCacheTestdata(t)
+
+	for i, code := range codes {
+		expected := instantiateLifeVM(t, nil, code)
+
+		// The first instantiation compiles the module, and the second loads it from the cache. The compiler does
+		// not emit byte-for-byte identical code across compilations, so the code loaded from the cache is compared
+		// against the code that was compiled and persisted.
+		compiled := instantiateLifeVM(t, cache, code)
+		loaded := instantiateLifeVM(t, cache, code)
+
+		assert.Equal(t, compiled.FunctionCode, loaded.FunctionCode, moduleCacheTestdata[i])
+
+		for _, vm := range []*exec.VirtualMachine{compiled, loaded} {
+			assert.Equal(t, expected.Globals, vm.Globals, moduleCacheTestdata[i])
+			assert.Equal(t, expected.Memory, vm.Memory, moduleCacheTestdata[i])
+			assert.Equal(t, expected.Table, vm.Table, moduleCacheTestdata[i])
+			assert.Equal(t, expected.Config, vm.Config, moduleCacheTestdata[i])
+			assert.Len(t, vm.FunctionImports, len(expected.FunctionImports), moduleCacheTestdata[i])
+		}
+	}
+
+	assert.Equal(t, len(codes), cache.Len())
+	assert.EqualValues(t, len(codes), metrics.moduleCacheMisses.Count())
+	assert.EqualValues(t, len(codes), metrics.moduleCacheHits.Count())
+}
+
+func TestModuleCachePersistence(t *testing.T) {
+	t.Parallel()
+
+	kv := store.NewInmem()
+	codes := loadModuleCacheTestdata(t)
+
+	cache, err := NewModuleCache(kv, DefaultModuleCacheSize, ModuleCacheEvictLRU)
+	assert.NoError(t, err)
+
+	for _, code := range codes {
+		instantiateLifeVM(t, cache, code)
+	}
+
+	// Reopening the cache over the same store should not require any module to be compiled again.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	metrics := NewMetrics(ctx)
+	defer metrics.Stop()
+
+	cache, err = NewModuleCache(kv, DefaultModuleCacheSize, ModuleCacheEvictLRU)
+	assert.NoError(t, err)
+
+	cache.WithMetrics(metrics)
+	assert.Equal(t, len(codes), cache.Len())
+
+	for _, code := range codes {
+		instantiateLifeVM(t, cache, code)
+	}
+
+	assert.EqualValues(t, 0, metrics.moduleCacheMisses.Count())
+	assert.EqualValues(t, len(codes), metrics.moduleCacheHits.Count())
+
+	// Reopening the cache with a smaller size evicts modules until it fits.
+	cache, err = NewModuleCache(kv, 2, ModuleCacheEvictLRU)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cache.Len())
+
+	cache, err = NewModuleCache(kv, DefaultModuleCacheSize, ModuleCacheEvictLRU)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestModuleCacheEviction(t *testing.T) {
+	t.Parallel()
+
+	codes := loadModuleCacheTestdata(t)
+
+	cached := func(cache *ModuleCache, code []byte) bool {
+		cache.Lock()
+		defer cache.Unlock()
+
+		_, exists := cache.entries[moduleCacheKey(code)]
+		return exists
+	}
+
+	// Under LRU, the module used least recently is evicted.
+	lru, err := NewModuleCache(store.NewInmem(), 2, ModuleCacheEvictLRU)
+	assert.NoError(t, err)
+
+	instantiateLifeVM(t, lru, codes[0])
+	instantiateLifeVM(t, lru, codes[0])
+	instantiateLifeVM(t, lru, codes[1])
+	instantiateLifeVM(t, lru, codes[2])
+
+	assert.Equal(t, 2, lru.Len())
+	assert.False(t, cached(lru, codes[0]))
+	assert.True(t, cached(lru, codes[1]))
+	assert.True(t, cached(lru, codes[2]))
+
+	// Under LFU, the module used least frequently is evicted.
+	lfu, err := NewModuleCache(store.NewInmem(), 2, ModuleCacheEvictLFU)
+	assert.NoError(t, err)
+
+	instantiateLifeVM(t, lfu, codes[0])
+	instantiateLifeVM(t, lfu, codes[0])
+	instantiateLifeVM(t, lfu, codes[1])
+	instantiateLifeVM(t, lfu, codes[2])
+
+	assert.Equal(t, 2, lfu.Len())
+	assert.True(t, cached(lfu, codes[0]))
+	assert.False(t, cached(lfu, codes[1]))
+	assert.True(t, cached(lfu, codes[2]))
+
+	// A cache with no capacity never persists anything.
+	disabled, err := NewModuleCache(store.NewInmem(), 0, ModuleCacheEvictLRU)
+	assert.NoError(t, err)
+
+	instantiateLifeVM(t, disabled, codes[0])
+	assert.Equal(t, 0, disabled.Len())
+}
+
+func TestParseModuleCacheEvictionPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []ModuleCacheEvictionPolicy{ModuleCacheEvictLRU, ModuleCacheEvictLFU} {
+		parsed, err := ParseModuleCacheEvictionPolicy(policy.String())
+		assert.NoError(t, err)
+		assert.Equal(t, policy, parsed)
+	}
+
+	_, err := ParseModuleCacheEvictionPolicy("fifo")
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+
+	"github.com/perlin-network/noise/edwards25519"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/blake2b"
+)
+
+// SizeMultisigSignature is the size of an encoded multisig signature.
+const SizeMultisigSignature = 1 + SizeSignature
+
+// MultisigSignature is a signature of a transaction sent from a multisig account, made by the key found at index
+// Key amongst the keys of the account.
+type MultisigSignature struct {
+	Key       uint8
+	Signature Signature
+}
+
+func (s MultisigSignature) Marshal() []byte {
+	return append([]byte{s.Key}, s.Signature[:]...)
+}
+
+func UnmarshalMultisigSignature(buf []byte) (MultisigSignature, error) {
+	var signature MultisigSignature
+
+	if len(buf) != SizeMultisigSignature {
+		return signature, errors.Errorf("multisig signature must be exactly %d bytes", SizeMultisigSignature)
+	}
+
+	signature.Key = buf[0]
+	copy(signature.Signature[:], buf[1:])
+
+	return signature, nil
+}
+
+func unmarshalMultisigSignatures(r io.Reader) ([]MultisigSignature, error) {
+	var buf [SizeMultisigSignature]byte
+
+	if _, err := io.ReadFull(r, buf[:1]); err != nil {
+		return nil, errors.Wrap(err, "failed to read number of multisig signatures")
+	}
+
+	if buf[0] == 0 {
+		return nil, errors.New("transaction must carry at least one multisig signature")
+	}
+
+	signatures := make([]MultisigSignature, buf[0])
+
+	for i := range signatures {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return nil, errors.Wrap(err, "failed to decode multisig signature")
+		}
+
+		signatures[i], _ = UnmarshalMultisigSignature(buf[:])
+	}
+
+	return signatures, nil
+}
+
+// multisigMessage returns the message signed by the keys of a multisig account to authorize a transaction. Unlike
+// the message signed to authorize any other transaction, it covers the sender of the transaction, so that
+// signatures authorizing a transaction of one multisig account may not be reused for another account registered
+// with the same keys. Should the transaction be sponsored, the keys sign the message signed by its fee payer.
+func (tx Transaction) multisigMessage() []byte {
+	if tx.IsSponsored() {
+		return tx.sponsoredMessage()
+	}
+
+	message := make([]byte, 0, SizeAccountID+8+8+1+len(tx.Payload))
+	message = append(message, tx.Sender[:]...)
+
+	var buf [8]byte
+
+	binary.BigEndian.PutUint64(buf[:], tx.Nonce)
+	message = append(message, buf[:]...)
+
+	binary.BigEndian.PutUint64(buf[:], tx.Block)
+	message = append(message, buf[:]...)
+
+	message = append(message, byte(tx.Tag))
+	message = append(message, tx.Payload...)
+
+	return message
+}
+
+// SignMultisig signs a transaction sent from a multisig account with the private key of the key found at index key
+// amongst the keys of the account. The signature may be made offline, and combined with the signatures of other
+// keys of the account using CombineMultisigSignatures.
+func (tx Transaction) SignMultisig(key uint8, privateKey edwards25519.PrivateKey) MultisigSignature {
+	return MultisigSignature{Key: key, Signature: edwards25519.Sign(privateKey, tx.multisigMessage())}
+}
+
+// CombineMultisigSignatures returns a copy of a transaction sent from a multisig account, carrying the signatures of
+// the transaction it already carries alongside the given signatures ordered by the indices of their keys. Should
+// more than one signature be given for a key, the last signature given is kept. The transaction is only authorized
+// should it carry exactly as many signatures as the threshold of the account.
+func CombineMultisigSignatures(tx Transaction, signatures ...MultisigSignature) Transaction {
+	byKey := make(map[uint8]MultisigSignature, len(tx.Signatures)+len(signatures))
+
+	for _, signature := range tx.Signatures {
+		byKey[signature.Key] = signature
+	}
+
+	for _, signature := range signatures {
+		byKey[signature.Key] = signature
+	}
+
+	tx.Signature = ZeroSignature
+	tx.Signatures = make([]MultisigSignature, 0, len(byKey))
+
+	for _, signature := range byKey {
+		tx.Signatures = append(tx.Signatures, signature)
+	}
+
+	sort.Slice(tx.Signatures, func(i, j int) bool {
+		return tx.Signatures[i].Key < tx.Signatures[j].Key
+	})
+
+	tx.ID = blake2b.Sum256(tx.Marshal())
+
+	return tx
+}
+
+// VerifyMultisig checks that a transaction carries valid signatures from exactly as many distinct keys of a
+// multisig account as the threshold of the account, ordered by the indices of their keys. As the signatures are
+// covered by the ID of the transaction, any other number or order of signatures is rejected, such that a third party
+// may not derive a transaction with a different ID from a transaction that was already authorized.
+func (tx Transaction) VerifyMultisig(multisig Multisig) bool {
+	if len(tx.Signatures) != int(multisig.Threshold) {
+		return false
+	}
+
+	message := tx.multisigMessage()
+
+	for i, signature := range tx.Signatures {
+		if int(signature.Key) >= len(multisig.Keys) {
+			return false
+		}
+
+		if i > 0 && signature.Key <= tx.Signatures[i-1].Key {
+			return false
+		}
+
+		if !edwards25519.Verify(multisig.Keys[signature.Key], message, signature.Signature) {
+			return false
+		}
+	}
+
+	return true
+}
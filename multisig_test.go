@@ -0,0 +1,198 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// +build unit
+
+package wavelet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/perlin-network/noise/skademlia"
+	"github.com/perlin-network/wavelet/avl"
+	"github.com/perlin-network/wavelet/store"
+	"github.com/perlin-network/wavelet/sys"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blake2b"
+)
+
+func TestMultisigTransaction(t *testing.T) {
+	state := avl.New(store.NewInmem())
+	block := NewBlock(1, state.Checksum())
+
+	funder, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	WriteAccountBalance(state, funder.PublicKey(), 1000000)
+
+	var (
+		keys     []*skademlia.Keypair
+		multisig Multisig
+	)
+
+	for i := 0; i < 3; i++ {
+		key, err := skademlia.NewKeys(1, 1)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		keys = append(keys, key)
+		multisig.Keys = append(multisig.Keys, key.PublicKey())
+	}
+
+	multisig.Threshold = 2
+
+	register := func(nonce uint64) AccountID {
+		payload, err := multisig.Marshal()
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		tx := NewTransaction(funder, nonce, block.Index, sys.TagMultisig, payload)
+
+		if !assert.NoError(t, ValidateTransaction(state, tx)) {
+			t.FailNow()
+		}
+
+		if !assert.NoError(t, ApplyTransaction(state, &block, &tx)) {
+			t.FailNow()
+		}
+
+		registered, exists := ReadAccountMultisig(state, tx.ID)
+		if !assert.True(t, exists) || !assert.Equal(t, multisig, registered) {
+			t.FailNow()
+		}
+
+		WriteAccountBalance(state, tx.ID, 1000000)
+
+		return tx.ID
+	}
+
+	accountID := register(1)
+
+	payload, err := buildTransferPayload(AccountID{0xBB}, 100).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := Transaction{Sender: accountID, Nonce: 1, Block: block.Index, Tag: sys.TagTransfer, Payload: payload}
+
+	signatures := []MultisigSignature{
+		tx.SignMultisig(0, keys[0].PrivateKey()),
+		tx.SignMultisig(1, keys[1].PrivateKey()),
+		tx.SignMultisig(2, keys[2].PrivateKey()),
+	}
+
+	t.Run("below threshold", func(t *testing.T) {
+		assert.Equal(t, ErrTxInvalidSignature, ValidateTransaction(state, CombineMultisigSignatures(tx, signatures[2])))
+	})
+
+	t.Run("threshold met", func(t *testing.T) {
+		// Partial signatures may be combined in any order, and across several steps.
+		signed := CombineMultisigSignatures(tx, signatures[2])
+		signed = CombineMultisigSignatures(signed, signatures[0])
+
+		assert.Equal(t, []MultisigSignature{signatures[0], signatures[2]}, signed.Signatures)
+		assert.NoError(t, ValidateTransaction(state, signed))
+
+		decoded, err := UnmarshalTransaction(bytes.NewReader(signed.Marshal()))
+		if assert.NoError(t, err) {
+			assert.Equal(t, signed, decoded)
+		}
+
+	})
+
+	t.Run("signatures beyond threshold", func(t *testing.T) {
+		err := ValidateTransaction(state, CombineMultisigSignatures(tx, signatures...))
+		assert.Equal(t, ErrTxInvalidSignature, err)
+	})
+
+	t.Run("signatures reordered", func(t *testing.T) {
+		signed := CombineMultisigSignatures(tx, signatures[0], signatures[2])
+
+		reordered := signed
+		reordered.Signatures = []MultisigSignature{signatures[2], signatures[0]}
+		reordered.ID = blake2b.Sum256(reordered.Marshal())
+
+		assert.NotEqual(t, signed.ID, reordered.ID)
+		assert.Equal(t, ErrTxInvalidSignature, ValidateTransaction(state, reordered))
+	})
+
+	t.Run("signatures extended", func(t *testing.T) {
+		signed := CombineMultisigSignatures(tx, signatures[0], signatures[2])
+		extended := CombineMultisigSignatures(signed, signatures[1])
+
+		assert.NotEqual(t, signed.ID, extended.ID)
+		assert.Equal(t, ErrTxInvalidSignature, ValidateTransaction(state, extended))
+	})
+
+	t.Run("invalid signature", func(t *testing.T) {
+		forged := signatures[1]
+		forged.Key = 2
+
+		err := ValidateTransaction(state, CombineMultisigSignatures(tx, signatures[0], forged))
+		assert.Equal(t, ErrTxInvalidSignature, err)
+	})
+
+	t.Run("duplicate signatures", func(t *testing.T) {
+		duplicated := tx
+		duplicated.Signatures = []MultisigSignature{signatures[0], signatures[0]}
+
+		assert.Equal(t, ErrTxInvalidSignature, ValidateTransaction(state, duplicated))
+	})
+
+	t.Run("signed by a single key", func(t *testing.T) {
+		single := NewTransaction(keys[0], 1, block.Index, sys.TagTransfer, payload)
+		single.Sender = accountID
+
+		assert.Equal(t, ErrTxInvalidSignature, ValidateTransaction(state, single))
+	})
+
+	t.Run("signatures reused for another account", func(t *testing.T) {
+		other := tx
+		other.Sender = register(2)
+
+		err := ValidateTransaction(state, CombineMultisigSignatures(other, signatures...))
+		assert.Equal(t, ErrTxInvalidSignature, err)
+	})
+
+	t.Run("not a multisig account", func(t *testing.T) {
+		other := tx
+		other.Sender = funder.PublicKey()
+
+		err := ValidateTransaction(state, CombineMultisigSignatures(other, signatures...))
+		assert.Equal(t, ErrTxInvalidSignature, err)
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		signed := CombineMultisigSignatures(tx, signatures[0], signatures[1])
+		if !assert.NoError(t, ApplyTransaction(state, &block, &signed)) {
+			return
+		}
+
+		balance, _ := ReadAccountBalance(state, AccountID{0xBB})
+		assert.EqualValues(t, 100, balance)
+
+		balance, _ = ReadAccountBalance(state, accountID)
+		assert.EqualValues(t, 1000000-100, balance)
+	})
+}
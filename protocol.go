@@ -40,6 +40,8 @@ type Protocol struct {
 func (p *Protocol) Gossip(ctx context.Context, req *GossipRequest) (*empty.Empty, error) {
 	txs := make([]Transaction, 0, len(req.Transactions))
 
+	snapshot := p.ledger.Snapshot()
+
 	for _, buf := range req.Transactions {
 		tx, err := UnmarshalTransaction(bytes.NewReader(buf))
 		if err != nil {
@@ -53,10 +55,32 @@ func (p *Protocol) Gossip(ctx context.Context, req *GossipRequest) (*empty.Empty
 			continue
 		}
 
+		// Transactions sent by smart contract accounts are authorized by invoking the validate function of the
+		// smart contract, and are hence rate-limited.
+		if isContractSender(snapshot, tx.Sender) && !p.ledger.contractSenderLimiter.Allow() {
+			logger := log.TX("gossip")
+			logger.Warn().Hex("tx_id", tx.ID[:]).Msg("Dropped transaction sent by a smart contract due to rate limiting")
+
+			continue
+		}
+
 		txs = append(txs, tx)
 	}
 
-	p.ledger.AddTransaction(txs...)
+	authorized := txs[:0]
+
+	for i, err := range authorizeTransactions(snapshot, txs, p.ledger.signatureWorkerPool) {
+		if err != nil {
+			logger := log.TX("gossip")
+			logger.Err(err).Hex("tx_id", txs[i].ID[:]).Msg("Failed to authorize transaction")
+
+			continue
+		}
+
+		authorized = append(authorized, txs[i])
+	}
+
+	p.ledger.AddTransaction(authorized...)
 
 	return new(empty.Empty), nil
 }
@@ -0,0 +1,83 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// ScheduleInfo is a scheduled transfer, whose ID is the ID of the transaction that registered it. Amount PERLs are
+// paid to Recipient at the block height Next, and every Interval blocks thereafter, until Remaining transfers have
+// been paid. The PERLs of the transfers remaining are held in escrow.
+type ScheduleInfo struct {
+	Sender    AccountID
+	Recipient AccountID
+	Amount    uint64
+	Next      uint64
+	Interval  uint64
+	Remaining uint64
+}
+
+// Escrowed returns the amount of PERLs held in escrow for the transfers remaining.
+func (s ScheduleInfo) Escrowed() uint64 {
+	return s.Amount * s.Remaining
+}
+
+func (s ScheduleInfo) Marshal() []byte {
+	buf := make([]byte, SizeAccountID*2+8*4)
+
+	copy(buf[0:], s.Sender[:])
+	copy(buf[SizeAccountID:], s.Recipient[:])
+
+	fields := buf[SizeAccountID*2:]
+
+	binary.LittleEndian.PutUint64(fields[0:8], s.Amount)
+	binary.LittleEndian.PutUint64(fields[8:16], s.Next)
+	binary.LittleEndian.PutUint64(fields[16:24], s.Interval)
+	binary.LittleEndian.PutUint64(fields[24:32], s.Remaining)
+
+	return buf
+}
+
+func UnmarshalScheduleInfo(r io.Reader) (ScheduleInfo, error) {
+	var (
+		info ScheduleInfo
+		buf  [SizeAccountID*2 + 8*4]byte
+	)
+
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return info, errors.Wrap(err, "failed to decode schedule")
+	}
+
+	copy(info.Sender[:], buf[0:])
+	copy(info.Recipient[:], buf[SizeAccountID:])
+
+	fields := buf[SizeAccountID*2:]
+
+	info.Amount = binary.LittleEndian.Uint64(fields[0:8])
+	info.Next = binary.LittleEndian.Uint64(fields[8:16])
+	info.Interval = binary.LittleEndian.Uint64(fields[16:24])
+	info.Remaining = binary.LittleEndian.Uint64(fields[24:32])
+
+	return info, nil
+}
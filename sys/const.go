@@ -29,6 +29,18 @@ const (
 	TagContract
 	TagStake
 	TagBatch
+	TagContractLifecycle
+	TagMultisig
+	TagKeyRotation
+	TagAsset
+	TagHTLC
+	TagSchedule
+)
+
+const (
+	TransferValue byte = iota
+	ApproveAllowance
+	TransferFromAllowance
 )
 
 const (
@@ -37,6 +49,36 @@ const (
 	WithdrawReward
 )
 
+// Sources of the code a smart contract is spawned from.
+const (
+	ContractCodeUploaded byte = iota
+	ContractCodeReferenced
+)
+
+const (
+	UpgradeContract byte = iota
+	DestroyContract
+	WithdrawContractGas
+)
+
+const (
+	CreateAsset byte = iota
+	TransferAsset
+	MintAsset
+	BurnAsset
+)
+
+const (
+	LockHTLC byte = iota
+	ClaimHTLC
+	RefundHTLC
+)
+
+const (
+	CreateSchedule byte = iota
+	CancelSchedule
+)
+
 const (
 	// Size of individual chunks sent for a syncing peer.
 	SyncChunkSize = 16 * 1024 // 64KB
@@ -65,191 +107,208 @@ var (
 
 	FaucetAddress = "0f569c84d434fb0ca682c733176f7c0c2d853fce04d95ae131d2f9b4124d93d8"
 
-	GasTable = map[string]uint64{ // nolint:unused
-		"nop":                     1,
-		"unreachable":             1,
-		"select":                  12,
-		"i32.const":               1,
-		"i64.const":               1,
-		"f32.const":               1,
-		"f64.const":               1,
-		"i32.add":                 5,
-		"i32.sub":                 5,
-		"i32.mul":                 5,
-		"i32.div_s":               7,
-		"i32.div_u":               7,
-		"i32.rem_s":               7,
-		"i32.rem_u":               7,
-		"i32.and":                 5,
-		"i32.or":                  5,
-		"i32.xor":                 5,
-		"i32.shl":                 7,
-		"i32.shr_s":               7,
-		"i32.shr_u":               7,
-		"i32.rotl":                9,
-		"i32.rotr":                9,
-		"i32.eq":                  5,
-		"i32.ne":                  5,
-		"i32.lt_s":                5,
-		"i32.lt_u":                5,
-		"i32.le_s":                5,
-		"i32.le_u":                5,
-		"i32.gt_s":                5,
-		"i32.gt_u":                5,
-		"i32.ge_u":                5,
-		"i64.add":                 5,
-		"i64.sub":                 5,
-		"i64.mul":                 5,
-		"i64.div_s":               5,
-		"i64.div_u":               5,
-		"i64.rem_s":               5,
-		"i64.rem_u":               5,
-		"i64.and":                 5,
-		"i64.or":                  5,
-		"i64.xor":                 5,
-		"i64.shl":                 7,
-		"i64.shr_s":               7,
-		"i64.shr_u":               7,
-		"i64.rotl":                9,
-		"i64.rotr":                9,
-		"i64.eq":                  5,
-		"i64.ne":                  5,
-		"i64.lt_s":                5,
-		"i64.lt_u":                5,
-		"i64.le_s":                5,
-		"i64.le_u":                5,
-		"i64.gt_s":                5,
-		"i64.gt_u":                5,
-		"i64.ge_s":                5,
-		"i64.ge_u":                5,
-		"f32.add":                 5,
-		"f32.sub":                 5,
-		"f32.mul":                 5,
-		"f32.div":                 5,
-		"f32.min":                 5,
-		"f32.max":                 5,
-		"f32.copysign":            5,
-		"f32.eq":                  5,
-		"f32.ne":                  5,
-		"f32.lt":                  5,
-		"f32.le":                  5,
-		"f32.gt":                  5,
-		"f32.ge":                  5,
-		"f64.add":                 5,
-		"f64.sub":                 5,
-		"f64.mul":                 5,
-		"f64.div":                 5,
-		"f64.min":                 5,
-		"f64.max":                 5,
-		"f64.copysign":            5,
-		"f64.eq":                  5,
-		"f64.ne":                  5,
-		"f64.lt":                  5,
-		"f64.le":                  5,
-		"f64.gt":                  5,
-		"f64.ge":                  5,
-		"i32.ge_s":                5,
-		"i32.clz":                 5,
-		"i32.ctz":                 5,
-		"i32.popcnt":              5,
-		"i32.eqz":                 5,
-		"i64.clz":                 5,
-		"i64.ctz":                 5,
-		"i64.popcnt":              5,
-		"i64.eqz":                 5,
-		"f32.sqrt":                9,
-		"f32.ceil":                9,
-		"f32.floor":               9,
-		"f32.trunc":               9,
-		"f32.nearest":             9,
-		"f32.abs":                 9,
-		"f32.neg":                 9,
-		"f64.sqrt":                9,
-		"f64.ceil":                9,
-		"f64.floor":               9,
-		"f64.trunc":               9,
-		"f64.nearest":             9,
-		"f64.abs":                 9,
-		"f64.neg":                 9,
-		"i32.wrap/i64":            5,
-		"i64.extend_u/i32":        7,
-		"i64.extend_s/i32":        7,
-		"i32.trunc_u/f32":         7,
-		"i32.trunc_u/f64":         7,
-		"i64.trunc_u/f32":         7,
-		"i64.trunc_u/f64":         7,
-		"i32.trunc_s/f32":         7,
-		"i32.trunc_s/f64":         7,
-		"i64.trunc_s/f32":         7,
-		"i64.trunc_s/f64":         7,
-		"f32.demote/f64":          7,
-		"f64.promote/f32":         7,
-		"f32.convert_u/i32":       7,
-		"f32.convert_u/i64":       7,
-		"f64.convert_u/i32":       7,
-		"f64.convert_u/i64":       7,
-		"f32.convert_s/i32":       7,
-		"f32.convert_s/i64":       7,
-		"f64.convert_s/i32":       7,
-		"f64.convert_s/i64":       7,
-		"i32.reinterpret/f32":     5,
-		"i64.reinterpret/f64":     5,
-		"f32.reinterpret/i32":     5,
-		"f64.reinterpret/i64":     5,
-		"drop":                    12,
-		"i32.load":                12,
-		"i64.load":                12,
-		"i32.load8_s":             12,
-		"i32.load16_s":            12,
-		"i64.load8_s":             12,
-		"i64.load16_s":            12,
-		"i64.load32_s":            12,
-		"i32.load8_u":             12,
-		"i32.load16_u":            12,
-		"i64.load8_u":             12,
-		"i64.load16_u":            12,
-		"i64.load32_u":            12,
-		"f32.load":                12,
-		"f64.load":                12,
-		"i32.store":               12,
-		"i32.store8":              12,
-		"i32.store16":             12,
-		"i64.store":               12,
-		"i64.store8":              12,
-		"i64.store16":             12,
-		"i64.store32":             12,
-		"f32.store":               12,
-		"f64.store":               12,
-		"get_local":               12,
-		"get_global":              12,
-		"set_local":               12,
-		"set_global":              12,
-		"tee_local":               12,
-		"block":                   1,
-		"loop":                    1,
-		"if":                      1,
-		"else":                    1,
-		"end":                     1,
-		"br":                      1,
-		"br_if":                   1,
-		"br_table":                1,
-		"return":                  1,
-		"call":                    9,
-		"call_indirect":           100,
-		"current_memory":          10,
-		"grow_memory":             1000,
-		"wavelet.hash.blake2b256": 1500, // TODO: Review
-		"wavelet.hash.blake2b512": 2000, // TODO: Review
-		"wavelet.hash.sha256":     2500, // TODO: Review
-		"wavelet.hash.sha512":     3000, // TODO: Review
-		"wavelet.verify.ed25519":  5000, // TODO: Review
+	GasTable = map[string]uint64{
+		"nop":                          1,
+		"unreachable":                  1,
+		"select":                       12,
+		"i32.const":                    1,
+		"i64.const":                    1,
+		"f32.const":                    1,
+		"f64.const":                    1,
+		"i32.add":                      5,
+		"i32.sub":                      5,
+		"i32.mul":                      5,
+		"i32.div_s":                    7,
+		"i32.div_u":                    7,
+		"i32.rem_s":                    7,
+		"i32.rem_u":                    7,
+		"i32.and":                      5,
+		"i32.or":                       5,
+		"i32.xor":                      5,
+		"i32.shl":                      7,
+		"i32.shr_s":                    7,
+		"i32.shr_u":                    7,
+		"i32.rotl":                     9,
+		"i32.rotr":                     9,
+		"i32.eq":                       5,
+		"i32.ne":                       5,
+		"i32.lt_s":                     5,
+		"i32.lt_u":                     5,
+		"i32.le_s":                     5,
+		"i32.le_u":                     5,
+		"i32.gt_s":                     5,
+		"i32.gt_u":                     5,
+		"i32.ge_u":                     5,
+		"i64.add":                      5,
+		"i64.sub":                      5,
+		"i64.mul":                      5,
+		"i64.div_s":                    5,
+		"i64.div_u":                    5,
+		"i64.rem_s":                    5,
+		"i64.rem_u":                    5,
+		"i64.and":                      5,
+		"i64.or":                       5,
+		"i64.xor":                      5,
+		"i64.shl":                      7,
+		"i64.shr_s":                    7,
+		"i64.shr_u":                    7,
+		"i64.rotl":                     9,
+		"i64.rotr":                     9,
+		"i64.eq":                       5,
+		"i64.ne":                       5,
+		"i64.lt_s":                     5,
+		"i64.lt_u":                     5,
+		"i64.le_s":                     5,
+		"i64.le_u":                     5,
+		"i64.gt_s":                     5,
+		"i64.gt_u":                     5,
+		"i64.ge_s":                     5,
+		"i64.ge_u":                     5,
+		"f32.add":                      5,
+		"f32.sub":                      5,
+		"f32.mul":                      5,
+		"f32.div":                      5,
+		"f32.min":                      5,
+		"f32.max":                      5,
+		"f32.copysign":                 5,
+		"f32.eq":                       5,
+		"f32.ne":                       5,
+		"f32.lt":                       5,
+		"f32.le":                       5,
+		"f32.gt":                       5,
+		"f32.ge":                       5,
+		"f64.add":                      5,
+		"f64.sub":                      5,
+		"f64.mul":                      5,
+		"f64.div":                      5,
+		"f64.min":                      5,
+		"f64.max":                      5,
+		"f64.copysign":                 5,
+		"f64.eq":                       5,
+		"f64.ne":                       5,
+		"f64.lt":                       5,
+		"f64.le":                       5,
+		"f64.gt":                       5,
+		"f64.ge":                       5,
+		"i32.ge_s":                     5,
+		"i32.clz":                      5,
+		"i32.ctz":                      5,
+		"i32.popcnt":                   5,
+		"i32.eqz":                      5,
+		"i64.clz":                      5,
+		"i64.ctz":                      5,
+		"i64.popcnt":                   5,
+		"i64.eqz":                      5,
+		"f32.sqrt":                     9,
+		"f32.ceil":                     9,
+		"f32.floor":                    9,
+		"f32.trunc":                    9,
+		"f32.nearest":                  9,
+		"f32.abs":                      9,
+		"f32.neg":                      9,
+		"f64.sqrt":                     9,
+		"f64.ceil":                     9,
+		"f64.floor":                    9,
+		"f64.trunc":                    9,
+		"f64.nearest":                  9,
+		"f64.abs":                      9,
+		"f64.neg":                      9,
+		"i32.wrap/i64":                 5,
+		"i64.extend_u/i32":             7,
+		"i64.extend_s/i32":             7,
+		"i32.trunc_u/f32":              7,
+		"i32.trunc_u/f64":              7,
+		"i64.trunc_u/f32":              7,
+		"i64.trunc_u/f64":              7,
+		"i32.trunc_s/f32":              7,
+		"i32.trunc_s/f64":              7,
+		"i64.trunc_s/f32":              7,
+		"i64.trunc_s/f64":              7,
+		"f32.demote/f64":               7,
+		"f64.promote/f32":              7,
+		"f32.convert_u/i32":            7,
+		"f32.convert_u/i64":            7,
+		"f64.convert_u/i32":            7,
+		"f64.convert_u/i64":            7,
+		"f32.convert_s/i32":            7,
+		"f32.convert_s/i64":            7,
+		"f64.convert_s/i32":            7,
+		"f64.convert_s/i64":            7,
+		"i32.reinterpret/f32":          5,
+		"i64.reinterpret/f64":          5,
+		"f32.reinterpret/i32":          5,
+		"f64.reinterpret/i64":          5,
+		"drop":                         12,
+		"i32.load":                     12,
+		"i64.load":                     12,
+		"i32.load8_s":                  12,
+		"i32.load16_s":                 12,
+		"i64.load8_s":                  12,
+		"i64.load16_s":                 12,
+		"i64.load32_s":                 12,
+		"i32.load8_u":                  12,
+		"i32.load16_u":                 12,
+		"i64.load8_u":                  12,
+		"i64.load16_u":                 12,
+		"i64.load32_u":                 12,
+		"f32.load":                     12,
+		"f64.load":                     12,
+		"i32.store":                    12,
+		"i32.store8":                   12,
+		"i32.store16":                  12,
+		"i64.store":                    12,
+		"i64.store8":                   12,
+		"i64.store16":                  12,
+		"i64.store32":                  12,
+		"f32.store":                    12,
+		"f64.store":                    12,
+		"get_local":                    12,
+		"get_global":                   12,
+		"set_local":                    12,
+		"set_global":                   12,
+		"tee_local":                    12,
+		"block":                        1,
+		"loop":                         1,
+		"if":                           1,
+		"else":                         1,
+		"end":                          1,
+		"br":                           1,
+		"br_if":                        1,
+		"br_table":                     1,
+		"return":                       1,
+		"call":                         9,
+		"call_indirect":                100,
+		"current_memory":               10,
+		"grow_memory":                  1000,
+		"wavelet.hash.blake2b256":      1500, // TODO: Review
+		"wavelet.hash.blake2b512":      2000, // TODO: Review
+		"wavelet.hash.sha256":          2500, // TODO: Review
+		"wavelet.hash.sha512":          3000, // TODO: Review
+		"wavelet.hash.keccak256":       2500, // TODO: Review
+		"wavelet.verify.ed25519":       5000, // TODO: Review
+		"wavelet.verify.ed25519.batch": 4000, // TODO: Review
+		"wavelet.verify.secp256k1":     8000, // TODO: Review
+		"wavelet.recover.secp256k1":    9000, // TODO: Review
+		"wavelet.storage.get":          200,  // TODO: Review
+		"wavelet.storage.set":          5000, // TODO: Review
+		"wavelet.storage.delete":       5000, // TODO: Review
+		"wavelet.storage.byte":         10,   // TODO: Review
+		"wavelet.chain.block":          10,   // TODO: Review
+		"wavelet.chain.account":        200,  // TODO: Review
+		"wavelet.chain.seed":           1500, // TODO: Review
 	}
 
 	TagLabels = map[string]Tag{
-		`transfer`: TagTransfer,
-		`contract`: TagContract,
-		`batch`:    TagBatch,
-		`stake`:    TagStake,
+		`transfer`:           TagTransfer,
+		`contract`:           TagContract,
+		`batch`:              TagBatch,
+		`stake`:              TagStake,
+		`contract_lifecycle`: TagContractLifecycle,
+		`multisig`:           TagMultisig,
+		`key_rotation`:       TagKeyRotation,
+		`asset`:              TagAsset,
+		`htlc`:               TagHTLC,
+		`schedule`:           TagSchedule,
 	}
 
 	ContractDefaultMemoryPages = 4
@@ -258,6 +317,27 @@ var (
 	ContractMaxValueSlots      = 8192
 	ContractMaxCallStackDepth  = 256
 	ContractMaxGlobals         = 64
+
+	// Limits on the size of keys and values placed in a contracts key-value storage.
+	ContractMaxStorageKeySize   = 256
+	ContractMaxStorageValueSize = 64 * 1024
+
+	// Maximum number of keys a multisig account may be registered with.
+	MultisigMaxKeys = 16
+
+	// Limits on the symbol and number of decimals of a native asset.
+	AssetMaxSymbolSize = 16
+	AssetMaxDecimals   = 18
+
+	// Maximum size of the preimage revealed to claim a hash time-locked transfer.
+	HTLCMaxPreimageSize = 256
+
+	// Maximum amount of gas a smart contract account may spend validating a transaction it sends.
+	ContractValidateGasLimit uint64 = 100000
+
+	// Maximum number of transactions sent by smart contract accounts a node accepts from its peers per second, as
+	// authorizing each of them requires invoking the validate function of the smart contract that sent it.
+	ContractSenderGossipRate = 64
 )
 
 func init() { // nolint:gochecknoinits
@@ -268,9 +348,11 @@ func init() { // nolint:gochecknoinits
 
 // String converts a given tag to a string.
 func (tag Tag) String() string {
-	if tag < 0 || tag > 3 { // nolint:staticcheck
+	if tag < TagTransfer || tag > TagSchedule {
 		return "" // Return invalid tag
 	}
 
-	return []string{"transfer", "contract", "stake", "batch"}[tag] // Return tag
+	return []string{
+		"transfer", "contract", "stake", "batch", "contract_lifecycle", "multisig", "key_rotation", "asset", "htlc", "schedule",
+	}[tag-TagTransfer] // Return tag
 }
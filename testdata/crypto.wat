@@ -0,0 +1,51 @@
+;; Source of crypto.wasm, a smart contract exercising the cryptographic host functions.
+;;
+;; Every function copies its payload to the start of memory, and passes the 32-bit little-endian integers found at
+;; the start of its parameters (which follow the 112-byte payload header) as arguments to the host function it
+;; wraps. The 32-bit integer the host function returns is stored at the start of memory, and the region of memory
+;; denoted by the next two integers of its parameters is returned as its result.
+
+(module
+  (import "env" "_payload" (func $payload (param i32)))
+  (import "env" "_result" (func $result (param i32 i32)))
+  (import "env" "_hash_keccak256" (func $hash_keccak256 (param i32 i32 i32 i32) (result i32)))
+  (import "env" "_verify_secp256k1" (func $verify_secp256k1 (param i32 i32 i32 i32 i32 i32) (result i32)))
+  (import "env" "_recover_secp256k1" (func $recover_secp256k1 (param i32 i32 i32 i32 i32 i32) (result i32)))
+  (import "env" "_verify_ed25519_batch" (func $verify_ed25519_batch (param i32 i32) (result i32)))
+
+  (memory 2)
+
+  (func (export "_contract_init"))
+
+  (func (export "_contract_keccak256")
+    (call $payload (i32.const 0))
+    (i32.store (i32.const 0)
+      (call $hash_keccak256
+        (i32.load offset=112 (i32.const 0)) (i32.load offset=116 (i32.const 0))
+        (i32.load offset=120 (i32.const 0)) (i32.load offset=124 (i32.const 0))))
+    (call $result (i32.load offset=128 (i32.const 0)) (i32.load offset=132 (i32.const 0))))
+
+  (func (export "_contract_verify_secp256k1")
+    (call $payload (i32.const 0))
+    (i32.store (i32.const 0)
+      (call $verify_secp256k1
+        (i32.load offset=112 (i32.const 0)) (i32.load offset=116 (i32.const 0))
+        (i32.load offset=120 (i32.const 0)) (i32.load offset=124 (i32.const 0))
+        (i32.load offset=128 (i32.const 0)) (i32.load offset=132 (i32.const 0))))
+    (call $result (i32.load offset=136 (i32.const 0)) (i32.load offset=140 (i32.const 0))))
+
+  (func (export "_contract_recover_secp256k1")
+    (call $payload (i32.const 0))
+    (i32.store (i32.const 0)
+      (call $recover_secp256k1
+        (i32.load offset=112 (i32.const 0)) (i32.load offset=116 (i32.const 0))
+        (i32.load offset=120 (i32.const 0)) (i32.load offset=124 (i32.const 0))
+        (i32.load offset=128 (i32.const 0)) (i32.load offset=132 (i32.const 0))))
+    (call $result (i32.load offset=136 (i32.const 0)) (i32.load offset=140 (i32.const 0))))
+
+  (func (export "_contract_verify_ed25519_batch")
+    (call $payload (i32.const 0))
+    (i32.store (i32.const 0)
+      (call $verify_ed25519_batch
+        (i32.load offset=112 (i32.const 0)) (i32.load offset=116 (i32.const 0))))
+    (call $result (i32.load offset=120 (i32.const 0)) (i32.load offset=124 (i32.const 0)))))
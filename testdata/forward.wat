@@ -0,0 +1,20 @@
+;; Source of forward.wasm, a smart contract that sends the transaction it is invoked with as a sub-transaction.
+;;
+;; The forward function is invoked with the tag of the transaction to send as its first parameter, followed by the
+;; payload of the transaction. Parameters follow the 112-byte payload header.
+
+(module
+  (import "env" "_payload_len" (func $payload_len (result i32)))
+  (import "env" "_payload" (func $payload (param i32)))
+  (import "env" "_send_transaction" (func $send_transaction (param i32 i32 i32)))
+
+  (memory 1)
+
+  (func (export "_contract_init"))
+
+  (func (export "_contract_forward")
+    (call $payload (i32.const 0))
+    (call $send_transaction
+      (i32.load8_u offset=112 (i32.const 0))
+      (i32.const 113)
+      (i32.sub (call $payload_len) (i32.const 113)))))
@@ -0,0 +1,18 @@
+;; Source of globals.wasm, a smart contract whose data and element segments are placed at offsets read from globals.
+;;
+;; Offsets are read from globals defined by the module rather than imported, which the WebAssembly specification
+;; does not permit but the interpreter accepts. Assemble with `wat2wasm --no-check globals.wat`.
+
+(module
+  (global $data_offset i32 (i32.const 1024))
+  (global $elem_offset i32 (i32.const 2))
+
+  (table 4 anyfunc)
+  (memory 1)
+
+  (elem (get_global $elem_offset) $nop)
+  (data (get_global $data_offset) "globals")
+
+  (func $nop)
+
+  (func (export "_contract_init")))
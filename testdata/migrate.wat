@@ -0,0 +1,14 @@
+;; Source of migrate.wasm, a smart contract that migrates the memory of the contract it upgrades.
+;;
+;; The migrate function stores the 32-bit integer 42 at the start of memory, while the data segment of the contract
+;; places "hi" at offset 16.
+
+(module
+  (memory 1)
+
+  (data (i32.const 16) "hi")
+
+  (func (export "_contract_init"))
+
+  (func (export "_contract_migrate")
+    (i32.store (i32.const 0) (i32.const 42))))
@@ -0,0 +1,24 @@
+;; Source of validator.wasm, a smart contract account that authorizes the transactions it sends.
+;;
+;; The validate function is invoked with the transaction being authorized as its parameters, which follow the
+;; 112-byte payload header. The tag of the transaction is found 48 bytes into it, after its sender, nonce and block.
+;; Only transfers are authorized, and no more than two of them, counted at address 8192 of memory.
+
+(module
+  (import "env" "_payload" (func $payload (param i32)))
+  (import "env" "_result" (func $result (param i32 i32)))
+
+  (memory 1)
+  (data (i32.const 4096) "tag not allowed")
+
+  (func (export "_contract_init"))
+
+  (func (export "_contract_validate")
+    (call $payload (i32.const 0))
+    (if (i32.ne (i32.load8_u offset=160 (i32.const 0)) (i32.const 1))
+      (then
+        (call $result (i32.const 4096) (i32.const 15))
+        (return)))
+    (if (i32.ge_u (i32.load (i32.const 8192)) (i32.const 2))
+      (then (unreachable)))
+    (i32.store (i32.const 8192) (i32.add (i32.load (i32.const 8192)) (i32.const 1)))))
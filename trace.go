@@ -0,0 +1,574 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+package wavelet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/go-interpreter/wagon/wasm"
+	"github.com/perlin-network/life/exec"
+	"github.com/perlin-network/life/utils"
+	"github.com/perlin-network/wavelet/sys"
+	"github.com/pkg/errors"
+	"github.com/valyala/fastjson"
+)
+
+// ContractTraceEventKind denotes what happened throughout a smart contract invocation to produce a trace event.
+type ContractTraceEventKind string
+
+// Kinds of events recorded while tracing a smart contract invocation.
+const (
+	ContractTraceEnter    ContractTraceEventKind = "enter"
+	ContractTraceExit     ContractTraceEventKind = "exit"
+	ContractTraceHostCall ContractTraceEventKind = "host_call"
+)
+
+// ContractTrace is a trace of all smart contract functions invoked throughout the application of a transaction.
+type ContractTrace struct {
+	Invocations []*ContractInvocationTrace
+
+	// Batch holds the outcome of each entry of a best-effort batch transaction, in the order in which its entries
+	// were applied.
+	Batch []BatchEntryResult
+
+	// Error is the error the transaction was rejected with, if it was rejected.
+	Error string
+}
+
+// BatchEntryResult is the outcome of applying a single entry of a best-effort batch transaction. Error is set should
+// the entry have failed, in which case all changes it made were reverted.
+type BatchEntryResult struct {
+	Tag   sys.Tag
+	Error string
+}
+
+// MarshalBatchResults encodes the outcomes of the entries of a best-effort batch transaction, as they are stored in
+// the ledger state. Each entry is encoded as its tag, followed by its error prefixed by its length.
+func MarshalBatchResults(results []BatchEntryResult) []byte {
+	size := 1
+
+	for _, result := range results {
+		size += 1 + 4 + len(result.Error)
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, size))
+	buf.WriteByte(uint8(len(results)))
+
+	var b [4]byte
+
+	for _, result := range results {
+		buf.WriteByte(byte(result.Tag))
+
+		binary.BigEndian.PutUint32(b[:], uint32(len(result.Error)))
+		buf.Write(b[:])
+		buf.WriteString(result.Error)
+	}
+
+	return buf.Bytes()
+}
+
+// UnmarshalBatchResults decodes the outcomes of the entries of a best-effort batch transaction encoded by
+// MarshalBatchResults.
+func UnmarshalBatchResults(r io.Reader) ([]BatchEntryResult, error) {
+	var b [4]byte
+
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return nil, errors.Wrap(err, "failed to decode number of batch results")
+	}
+
+	results := make([]BatchEntryResult, b[0])
+
+	for i := range results {
+		if _, err := io.ReadFull(r, b[:1]); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode tag of batch result %d", i)
+		}
+
+		results[i].Tag = sys.Tag(b[0])
+
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode error length of batch result %d", i)
+		}
+
+		msg := make([]byte, binary.BigEndian.Uint32(b[:]))
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode error of batch result %d", i)
+		}
+
+		results[i].Error = string(msg)
+	}
+
+	return results, nil
+}
+
+// ContractInvocationTrace is a trace of a single invocation of an exported smart contract function.
+type ContractInvocationTrace struct {
+	ContractID AccountID
+	Function   string
+
+	GasLimit uint64
+	Gas      uint64
+
+	Events []ContractTraceEvent
+
+	// PagesWritten are the indices of all pages of memory whose contents were changed by the invocation.
+	PagesWritten []uint64
+
+	// Result is the result the invocation returned through `_result`, and Logs are the messages it logged
+	// through `_log`.
+	Result []byte
+	Logs   [][]byte
+
+	// Error and Trap are set should the invocation have failed.
+	Error string
+	Trap  *ContractTrap
+}
+
+// ContractTraceEvent is either a call into a function of a smart contract, a return out of one, or a call into a
+// host function. Depth is the depth of the call stack the function was called at, starting from zero.
+//
+// Params holds the arguments a function was called with. Result holds the value returned by a host function.
+// Gas is the gas consumed by a function and all functions it called once it returns, or the gas consumed by a
+// host function.
+type ContractTraceEvent struct {
+	Kind     ContractTraceEventKind
+	Depth    int
+	Function string
+
+	Params []int64
+	Result int64
+	Gas    uint64
+}
+
+// ContractTrap is the location at which a smart contract invocation failed. Stack lists the functions on the call
+// stack at the time, starting from the function that failed.
+type ContractTrap struct {
+	Function string
+	IP       int
+	Stack    []string
+}
+
+func (t *ContractTrace) add(invocation *ContractInvocationTrace) {
+	t.Invocations = append(t.Invocations, invocation)
+}
+
+type tracedFrame struct {
+	slots      *int64
+	functionID int
+	gas        uint64
+}
+
+// invocationTracer traces a single smart contract invocation by stepping through its execution one basic block
+// at a time. Each basic block is prefixed with an instruction charging gas for it, which the virtual machine may
+// be made to stop at by setting its gas limit to the gas consumed so far.
+type invocationTracer struct {
+	trace *ContractInvocationTrace
+
+	memory []byte
+	frames []tracedFrame
+}
+
+func newInvocationTracer(id AccountID, function string, gasLimit uint64, memory []byte) *invocationTracer {
+	return &invocationTracer{
+		trace:  &ContractInvocationTrace{ContractID: id, Function: function, GasLimit: gasLimit},
+		memory: append([]byte(nil), memory...),
+	}
+}
+
+// run executes vm until it exits, recording all calls into and returns out of the functions of a smart contract.
+func (t *invocationTracer) run(vm *exec.VirtualMachine) {
+	limit := vm.Config.GasLimit
+
+	// A gas limit of zero denotes that there is no gas limit. Gas is therefore offset by one while stepping
+	// through vm so that the limit it is stepped with is never zero.
+	offsetLimit := limit
+	if limit != 0 {
+		offsetLimit++
+	}
+
+	vm.Gas++
+
+	defer func() {
+		vm.Gas--
+		vm.Config.GasLimit = limit
+		vm.Config.ReturnOnGasLimitExceeded = false
+	}()
+
+	vm.Config.ReturnOnGasLimitExceeded = true
+
+	for !vm.Exited {
+		vm.Config.GasLimit = vm.Gas
+		vm.Execute()
+		vm.Config.GasLimit = offsetLimit
+
+		if vm.GasLimitExceeded {
+			t.sync(vm, vm.CurrentFrame+1)
+
+			frame := vm.GetCurrentFrame()
+
+			if !vm.AddAndCheckGas(binary.LittleEndian.Uint64(frame.Code[frame.IP-8 : frame.IP])) {
+				vm.Exited = true
+				vm.ExitError = "gas limit exceeded"
+			}
+
+			continue
+		}
+
+		if vm.Delegate != nil {
+			vm.Config.ReturnOnGasLimitExceeded = false
+			vm.Delegate()
+			vm.Delegate = nil
+			vm.Config.ReturnOnGasLimitExceeded = true
+		}
+	}
+
+	t.sync(vm, 0)
+}
+
+// sync records a return out of every traced frame no longer on the call stack of vm, and a call into every frame
+// amongst the first depth frames on the call stack of vm that have yet to be traced.
+func (t *invocationTracer) sync(vm *exec.VirtualMachine, depth int) {
+	n := 0
+
+	for n < len(t.frames) && n < depth {
+		frame := &vm.CallStack[n]
+
+		if t.frames[n].slots != frameSlots(frame) || t.frames[n].functionID != frame.FunctionID {
+			break
+		}
+
+		n++
+	}
+
+	for i := len(t.frames) - 1; i >= n; i-- {
+		t.trace.Events = append(t.trace.Events, ContractTraceEvent{
+			Kind:     ContractTraceExit,
+			Depth:    i,
+			Function: functionName(vm, t.frames[i].functionID),
+			Gas:      vm.Gas - t.frames[i].gas,
+		})
+	}
+
+	t.frames = t.frames[:n]
+
+	for i := n; i < depth; i++ {
+		frame := &vm.CallStack[i]
+
+		t.frames = append(t.frames, tracedFrame{slots: frameSlots(frame), functionID: frame.FunctionID, gas: vm.Gas})
+
+		t.trace.Events = append(t.trace.Events, ContractTraceEvent{
+			Kind:     ContractTraceEnter,
+			Depth:    i,
+			Function: functionName(vm, frame.FunctionID),
+			Params:   append([]int64{}, frame.Locals[:vm.FunctionCode[frame.FunctionID].NumParams]...),
+		})
+	}
+}
+
+// hostCall wraps the host function f such that calls into it are recorded.
+func (t *invocationTracer) hostCall(name string, f exec.FunctionImport) exec.FunctionImport {
+	return func(vm *exec.VirtualMachine) int64 {
+		t.sync(vm, vm.CurrentFrame)
+
+		frame := vm.GetCurrentFrame()
+
+		event := ContractTraceEvent{
+			Kind:     ContractTraceHostCall,
+			Depth:    vm.CurrentFrame,
+			Function: name,
+			Params:   append([]int64{}, frame.Locals...),
+		}
+
+		gas := vm.Gas
+
+		defer func() {
+			event.Gas = vm.Gas - gas
+			t.trace.Events = append(t.trace.Events, event)
+		}()
+
+		event.Result = f(vm)
+
+		return event.Result
+	}
+}
+
+// finish completes the trace once vm has exited.
+func (t *invocationTracer) finish(vm *exec.VirtualMachine, gas uint64) *ContractInvocationTrace {
+	if vm.ExitError != nil {
+		t.trace.Error = utils.UnifyError(vm.ExitError).Error()
+
+		if vm.CurrentFrame >= 0 && vm.CurrentFrame < len(vm.CallStack) {
+			frame := &vm.CallStack[vm.CurrentFrame]
+
+			trap := &ContractTrap{Function: functionName(vm, frame.FunctionID), IP: frame.IP}
+
+			for i := vm.CurrentFrame; i >= 0; i-- {
+				trap.Stack = append(trap.Stack, functionName(vm, vm.CallStack[i].FunctionID))
+			}
+
+			t.trace.Trap = trap
+		}
+	}
+
+	for i := 0; i < len(vm.Memory)/PageSize; i++ {
+		page := vm.Memory[i*PageSize : (i+1)*PageSize]
+
+		if (i+1)*PageSize <= len(t.memory) && bytes.Equal(page, t.memory[i*PageSize:(i+1)*PageSize]) {
+			continue
+		}
+
+		if (i+1)*PageSize > len(t.memory) && bytes.Equal(page, ZeroPage) {
+			continue
+		}
+
+		t.trace.PagesWritten = append(t.trace.PagesWritten, uint64(i))
+	}
+
+	t.trace.Gas = gas
+
+	return t.trace
+}
+
+// frameSlots identifies a single instance of a call frame by the value slots allocated to it.
+func frameSlots(frame *exec.Frame) *int64 {
+	if cap(frame.Regs) == 0 {
+		return nil
+	}
+
+	return &frame.Regs[:cap(frame.Regs)][0]
+}
+
+func functionName(vm *exec.VirtualMachine, id int) string {
+	if name, exists := vm.Module.FunctionNames[id]; exists {
+		return name
+	}
+
+	if id < len(vm.FunctionImports) {
+		return vm.FunctionImports[id].FieldName
+	}
+
+	if vm.Module.Base.Export != nil {
+		for name, entry := range vm.Module.Base.Export.Entries {
+			if entry.Kind == wasm.ExternalFunction && int(entry.Index) == id {
+				return name
+			}
+		}
+	}
+
+	return fmt.Sprintf("func[%d]", id)
+}
+
+// ParseContractTrace parses a contract trace encoded into JSON by MarshalJSON.
+func ParseContractTrace(raw []byte) (*ContractTrace, error) {
+	var p fastjson.Parser
+
+	v, err := p.ParseBytes(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "contract trace is malformed")
+	}
+
+	trace := &ContractTrace{Error: string(v.GetStringBytes("error"))}
+
+	for _, i := range v.GetArray("invocations") {
+		invocation := &ContractInvocationTrace{
+			Function: string(i.GetStringBytes("function")),
+			GasLimit: i.GetUint64("gas_limit"),
+			Gas:      i.GetUint64("gas"),
+			Error:    string(i.GetStringBytes("error")),
+		}
+
+		if n, err := hex.Decode(invocation.ContractID[:], i.GetStringBytes("contract_id")); n != SizeAccountID || err != nil {
+			return nil, errors.New("contract trace has an invalid contract id")
+		}
+
+		for _, e := range i.GetArray("events") {
+			event := ContractTraceEvent{
+				Kind:     ContractTraceEventKind(e.GetStringBytes("kind")),
+				Depth:    e.GetInt("depth"),
+				Function: string(e.GetStringBytes("function")),
+				Result:   e.GetInt64("result"),
+				Gas:      e.GetUint64("gas"),
+			}
+
+			for _, param := range e.GetArray("params") {
+				event.Params = append(event.Params, param.GetInt64())
+			}
+
+			invocation.Events = append(invocation.Events, event)
+		}
+
+		for _, page := range i.GetArray("pages_written") {
+			invocation.PagesWritten = append(invocation.PagesWritten, page.GetUint64())
+		}
+
+		if result := i.GetStringBytes("result"); result != nil {
+			if invocation.Result, err = hex.DecodeString(string(result)); err != nil {
+				return nil, errors.Wrap(err, "contract trace has an invalid result")
+			}
+		}
+
+		for _, msg := range i.GetArray("logs") {
+			invocation.Logs = append(invocation.Logs, append([]byte{}, msg.GetStringBytes()...))
+		}
+
+		if trap := i.Get("trap"); trap != nil && trap.Type() == fastjson.TypeObject {
+			invocation.Trap = &ContractTrap{
+				Function: string(trap.GetStringBytes("function")),
+				IP:       trap.GetInt("ip"),
+			}
+
+			for _, fn := range trap.GetArray("stack") {
+				invocation.Trap.Stack = append(invocation.Trap.Stack, string(fn.GetStringBytes()))
+			}
+		}
+
+		trace.Invocations = append(trace.Invocations, invocation)
+	}
+
+	for _, entry := range v.GetArray("batch") {
+		trace.Batch = append(trace.Batch, BatchEntryResult{
+			Tag:   sys.Tag(entry.GetUint("tag")),
+			Error: string(entry.GetStringBytes("error")),
+		})
+	}
+
+	return trace, nil
+}
+
+// MarshalJSON encodes the trace into JSON, in the same format expected by ParseContractTrace.
+func (t *ContractTrace) MarshalJSON() ([]byte, error) {
+	var arena fastjson.Arena
+
+	return t.getObject(&arena).MarshalTo(nil), nil
+}
+
+func (t *ContractTrace) getObject(arena *fastjson.Arena) *fastjson.Value {
+	invocations := arena.NewArray()
+
+	for i, invocation := range t.Invocations {
+		events := arena.NewArray()
+
+		for j, event := range invocation.Events {
+			o := arena.NewObject()
+			o.Set("kind", arena.NewString(string(event.Kind)))
+			o.Set("depth", arena.NewNumberInt(event.Depth))
+			o.Set("function", arena.NewString(event.Function))
+
+			if event.Kind != ContractTraceExit {
+				params := arena.NewArray()
+
+				for k, param := range event.Params {
+					params.SetArrayItem(k, arena.NewNumberString(strconv.FormatInt(param, 10)))
+				}
+
+				o.Set("params", params)
+			}
+
+			if event.Kind == ContractTraceHostCall {
+				o.Set("result", arena.NewNumberString(strconv.FormatInt(event.Result, 10)))
+			}
+
+			if event.Kind != ContractTraceEnter {
+				o.Set("gas", arena.NewNumberString(strconv.FormatUint(event.Gas, 10)))
+			}
+
+			events.SetArrayItem(j, o)
+		}
+
+		pages := arena.NewArray()
+
+		for j, page := range invocation.PagesWritten {
+			pages.SetArrayItem(j, arena.NewNumberString(strconv.FormatUint(page, 10)))
+		}
+
+		o := arena.NewObject()
+		o.Set("contract_id", arena.NewString(hex.EncodeToString(invocation.ContractID[:])))
+		o.Set("function", arena.NewString(invocation.Function))
+		o.Set("gas_limit", arena.NewNumberString(strconv.FormatUint(invocation.GasLimit, 10)))
+		o.Set("gas", arena.NewNumberString(strconv.FormatUint(invocation.Gas, 10)))
+		o.Set("events", events)
+		o.Set("pages_written", pages)
+
+		if invocation.Result != nil {
+			o.Set("result", arena.NewString(hex.EncodeToString(invocation.Result)))
+		}
+
+		if len(invocation.Logs) > 0 {
+			logs := arena.NewArray()
+
+			for j, msg := range invocation.Logs {
+				logs.SetArrayItem(j, arena.NewStringBytes(msg))
+			}
+
+			o.Set("logs", logs)
+		}
+
+		if invocation.Error != "" {
+			o.Set("error", arena.NewString(invocation.Error))
+		}
+
+		if invocation.Trap != nil {
+			stack := arena.NewArray()
+
+			for j, fn := range invocation.Trap.Stack {
+				stack.SetArrayItem(j, arena.NewString(fn))
+			}
+
+			trap := arena.NewObject()
+			trap.Set("function", arena.NewString(invocation.Trap.Function))
+			trap.Set("ip", arena.NewNumberInt(invocation.Trap.IP))
+			trap.Set("stack", stack)
+
+			o.Set("trap", trap)
+		}
+
+		invocations.SetArrayItem(i, o)
+	}
+
+	o := arena.NewObject()
+	o.Set("invocations", invocations)
+
+	if len(t.Batch) > 0 {
+		batch := arena.NewArray()
+
+		for i, entry := range t.Batch {
+			e := arena.NewObject()
+			e.Set("tag", arena.NewNumberInt(int(entry.Tag)))
+
+			if entry.Error != "" {
+				e.Set("error", arena.NewString(entry.Error))
+			}
+
+			batch.SetArrayItem(i, e)
+		}
+
+		o.Set("batch", batch)
+	}
+
+	if t.Error != "" {
+		o.Set("error", arena.NewString(t.Error))
+	}
+
+	return o
+}
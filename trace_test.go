@@ -0,0 +1,204 @@
+// Copyright (c) 2019 Perlin
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy of
+// this software and associated documentation files (the "Software"), to deal in
+// the Software without restriction, including without limitation the rights to
+// use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+// the Software, and to permit persons to whom the Software is furnished to do so,
+// subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+// FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+// COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+// IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+// CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.
+
+// +build unit
+
+package wavelet
+
+import (
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+
+	"github.com/perlin-network/noise/skademlia"
+	"github.com/perlin-network/wavelet/avl"
+	"github.com/perlin-network/wavelet/store"
+	"github.com/perlin-network/wavelet/sys"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceTransaction(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	account, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	WriteAccountBalance(state, account.PublicKey(), 1000000000)
+
+	code, err := ioutil.ReadFile("testdata/transfer_back.wasm")
+	assert.NoError(t, err)
+
+	var nonce uint64
+
+	payload, err := buildContractSpawnPayload(100000, 0, code).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := buildSignedTransaction(account, sys.TagContract, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+	assert.NoError(t, ApplyTransaction(state, &block, &tx))
+
+	contractID := tx.ID
+
+	invoke := func(gasLimit uint64) (*ContractInvocationTrace, error) {
+		payload, err := buildTransferWithInvocationPayload(contractID, 200, gasLimit, []byte("on_money_received"), nil, 0).Marshal()
+		if err != nil {
+			return nil, err
+		}
+
+		tx := buildSignedTransaction(account, sys.TagTransfer, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+
+		// Tracing a transaction must not affect the state it yields.
+		applied, traced := NewCollapseContext(state.Snapshot()), NewCollapseContext(state.Snapshot())
+
+		trace := new(ContractTrace)
+
+		if err := applied.ApplyTransaction(&block, &tx); err != nil {
+			return nil, err
+		}
+
+		if err := traced.TraceTransaction(&block, &tx, trace); err != nil {
+			return nil, err
+		}
+
+		appliedBalance, _ := applied.ReadAccountBalance(account.PublicKey())
+		tracedBalance, _ := traced.ReadAccountBalance(account.PublicKey())
+		assert.Equal(t, appliedBalance, tracedBalance)
+
+		if !assert.Len(t, trace.Invocations, 1) {
+			return nil, nil
+		}
+
+		invocation := trace.Invocations[0]
+
+		assert.Equal(t, contractID, invocation.ContractID)
+		assert.Equal(t, "on_money_received", invocation.Function)
+		assert.Equal(t, gasLimit, invocation.GasLimit)
+
+		// Calls into and returns out of functions must be balanced, with the entry function entered first
+		// and returned out of last.
+		depth := 0
+
+		for _, event := range invocation.Events {
+			switch event.Kind {
+			case ContractTraceEnter:
+				assert.Equal(t, depth, event.Depth)
+				depth++
+			case ContractTraceExit:
+				depth--
+				assert.Equal(t, depth, event.Depth)
+			case ContractTraceHostCall:
+				assert.Equal(t, depth, event.Depth)
+			}
+		}
+
+		assert.Zero(t, depth)
+
+		first, last := invocation.Events[0], invocation.Events[len(invocation.Events)-1]
+
+		assert.Equal(t, ContractTraceEnter, first.Kind)
+		assert.Equal(t, "_contract_on_money_received", first.Function)
+		assert.Equal(t, ContractTraceExit, last.Kind)
+		assert.Equal(t, "_contract_on_money_received", last.Function)
+		assert.True(t, last.Gas <= invocation.Gas)
+
+		return invocation, nil
+	}
+
+	// Case 1 - Function calls, host calls, and pages of memory written to are traced.
+	invocation, err := invoke(500000)
+	if !assert.NoError(t, err) || !assert.NotNil(t, invocation) {
+		return
+	}
+
+	assert.Empty(t, invocation.Error)
+	assert.Nil(t, invocation.Trap)
+	assert.Equal(t, invocation.Gas, invocation.Events[len(invocation.Events)-1].Gas)
+	assert.NotEmpty(t, invocation.PagesWritten)
+
+	var sent bool
+
+	for _, event := range invocation.Events {
+		if event.Kind == ContractTraceHostCall && event.Function == "_send_transaction" {
+			sent = true
+			assert.Len(t, event.Params, 3)
+		}
+	}
+
+	assert.True(t, sent)
+
+	// Case 2 - The location at which an invocation runs out of gas is traced.
+	invocation, err = invoke(1000)
+	if !assert.NoError(t, err) || !assert.NotNil(t, invocation) {
+		return
+	}
+
+	assert.Equal(t, "gas limit exceeded", invocation.Error)
+	assert.EqualValues(t, 1000, invocation.Gas)
+
+	if assert.NotNil(t, invocation.Trap) {
+		assert.Equal(t, invocation.Trap.Function, invocation.Trap.Stack[0])
+		assert.Equal(t, "_contract_on_money_received", invocation.Trap.Stack[len(invocation.Trap.Stack)-1])
+	}
+}
+
+func TestContractTraceJSON(t *testing.T) {
+	t.Parallel()
+
+	trace := &ContractTrace{
+		Invocations: []*ContractInvocationTrace{
+			{
+				ContractID: AccountID{1, 2, 3},
+				Function:   "on_money_received",
+				GasLimit:   1000,
+				Gas:        1000,
+				Events: []ContractTraceEvent{
+					{Kind: ContractTraceEnter, Function: "_contract_on_money_received"},
+					{Kind: ContractTraceHostCall, Depth: 1, Function: "_payload", Params: []int64{-1}, Result: 7, Gas: 3},
+					{Kind: ContractTraceExit, Function: "_contract_on_money_received", Gas: 1000},
+				},
+				PagesWritten: []uint64{0, 16},
+				Result:       []byte{0xde, 0xad},
+				Logs:         [][]byte{[]byte("hello"), []byte("world")},
+				Error:        "gas limit exceeded",
+				Trap:         &ContractTrap{Function: "func[3]", IP: 42, Stack: []string{"func[3]", "_contract_on_money_received"}},
+			},
+		},
+		Batch: []BatchEntryResult{
+			{Tag: sys.TagTransfer},
+			{Tag: sys.TagStake, Error: "could not apply stake transaction"},
+		},
+		Error: "could not apply transfer transaction",
+	}
+
+	raw, err := trace.MarshalJSON()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	parsed, err := ParseContractTrace(raw)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, trace, parsed)
+}
@@ -42,9 +42,27 @@ type Transaction struct {
 
 	Signature Signature
 
+	// Signatures, should the sender be a multisig account, authorize the transaction in place of Signature.
+	Signatures []MultisigSignature
+
+	// FeePayer, if set, is the account that pays the fee of the transaction, and the gas of any smart contract
+	// function it invokes, in place of the sender. The fee payer co-signs the transaction with FeePayerSignature.
+	FeePayer          AccountID
+	FeePayerSignature Signature
+
 	ID TransactionID // BLAKE2b(*).
 }
 
+const (
+	// multisigFlag is set on the tag of an encoded transaction to denote that the transaction carries the signatures
+	// of the keys of a multisig account, which follow its payload in place of a single signature.
+	multisigFlag = 0x80
+
+	// feePayerFlag is set on the tag of an encoded transaction to denote that the transaction is sponsored by a fee
+	// payer, whose account ID and signature follow the signature(s) of the sender.
+	feePayerFlag = 0x40
+)
+
 func NewTransaction(sender *skademlia.Keypair, nonce, block uint64, tag sys.Tag, payload []byte) Transaction {
 	var nonceBuf [8]byte
 
@@ -61,6 +79,25 @@ func NewTransaction(sender *skademlia.Keypair, nonce, block uint64, tag sys.Tag,
 	return NewSignedTransaction(sender.PublicKey(), nonce, block, tag, payload, signature)
 }
 
+// NewSponsoredTransaction creates a transaction sent by sender whose fee, and the gas of any smart contract function
+// it invokes, is paid by feePayer. The transaction is signed by both the sender and the fee payer.
+func NewSponsoredTransaction(
+	sender, feePayer *skademlia.Keypair, nonce, block uint64, tag sys.Tag, payload []byte,
+) Transaction {
+	tx := Transaction{
+		Sender:   sender.PublicKey(),
+		Nonce:    nonce,
+		Block:    block,
+		Tag:      tag,
+		Payload:  payload,
+		FeePayer: feePayer.PublicKey(),
+	}
+
+	tx.Signature = tx.SignSponsored(sender.PrivateKey())
+
+	return SponsorTransaction(tx, tx.SignSponsored(feePayer.PrivateKey()))
+}
+
 func NewSignedTransaction(
 	sender edwards25519.PublicKey, nonce, block uint64, tag sys.Tag, payload []byte, signature edwards25519.Signature,
 ) Transaction {
@@ -83,14 +120,38 @@ func (tx Transaction) Marshal() []byte {
 	binary.BigEndian.PutUint64(buf[:8], tx.Block)
 	w.Write(buf[:8])
 
-	w.WriteByte(byte(tx.Tag))
+	tag := byte(tx.Tag)
+
+	if len(tx.Signatures) > 0 {
+		tag |= multisigFlag
+	}
+
+	if tx.IsSponsored() {
+		tag |= feePayerFlag
+	}
+
+	w.WriteByte(tag)
 
 	binary.BigEndian.PutUint32(buf[:4], uint32(len(tx.Payload)))
 	w.Write(buf[:4])
 
 	w.Write(tx.Payload)
 
-	w.Write(tx.Signature[:])
+	if len(tx.Signatures) > 0 {
+		w.WriteByte(uint8(len(tx.Signatures)))
+
+		for _, signature := range tx.Signatures {
+			w.WriteByte(signature.Key)
+			w.Write(signature.Signature[:])
+		}
+	} else {
+		w.Write(tx.Signature[:])
+	}
+
+	if tx.IsSponsored() {
+		w.Write(tx.FeePayer[:])
+		w.Write(tx.FeePayerSignature[:])
+	}
 
 	return w.Bytes()
 }
@@ -122,9 +183,11 @@ func UnmarshalTransaction(r io.Reader) (t Transaction, err error) {
 		return
 	}
 
-	t.Tag = sys.Tag(buf[0])
+	multisig := buf[0]&multisigFlag != 0
+	sponsored := buf[0]&feePayerFlag != 0
+	t.Tag = sys.Tag(buf[0] &^ (multisigFlag | feePayerFlag))
 
-	if t.Tag < sys.TagTransfer || t.Tag > sys.TagBatch {
+	if t.Tag < sys.TagTransfer || t.Tag > sys.TagSchedule {
 		err = errors.Wrapf(err, "got an unknown tag %d", t.Tag)
 		return
 	}
@@ -141,11 +204,32 @@ func UnmarshalTransaction(r io.Reader) (t Transaction, err error) {
 		return
 	}
 
-	if _, err = io.ReadFull(r, t.Signature[:]); err != nil {
+	if multisig {
+		if t.Signatures, err = unmarshalMultisigSignatures(r); err != nil {
+			return
+		}
+	} else if _, err = io.ReadFull(r, t.Signature[:]); err != nil {
 		err = errors.Wrap(err, "failed to decode signature")
 		return
 	}
 
+	if sponsored {
+		if _, err = io.ReadFull(r, t.FeePayer[:]); err != nil {
+			err = errors.Wrap(err, "failed to decode fee payer")
+			return
+		}
+
+		if t.FeePayer == ZeroAccountID {
+			err = errors.New("fee payer of a sponsored transaction must be set")
+			return
+		}
+
+		if _, err = io.ReadFull(r, t.FeePayerSignature[:]); err != nil {
+			err = errors.Wrap(err, "failed to decode fee payer signature")
+			return
+		}
+	}
+
 	t.ID = blake2b.Sum256(t.Marshal())
 
 	return t, nil
@@ -185,7 +269,83 @@ func (tx Transaction) String() string {
 	return fmt.Sprintf("Transaction{ID: %x}", tx.ID)
 }
 
+// IsSponsored returns whether or not the fee of the transaction is paid by a fee payer in place of its sender.
+func (tx Transaction) IsSponsored() bool {
+	return tx.FeePayer != ZeroAccountID
+}
+
+// Payer returns the account that pays the fee of the transaction, and the gas of any smart contract function it
+// invokes.
+func (tx Transaction) Payer() AccountID {
+	if tx.IsSponsored() {
+		return tx.FeePayer
+	}
+
+	return tx.Sender
+}
+
+// sponsoredMessage returns the message signed by both the sender and the fee payer of a sponsored transaction. It
+// covers both the sender and the fee payer, so that neither signature may be reused for a transaction of another
+// sender or fee payer, and carries feePayerFlag on its tag, so that it is never mistaken for the message of a
+// transaction that is not sponsored.
+func (tx Transaction) sponsoredMessage() []byte {
+	message := make([]byte, 0, SizeAccountID+8+8+1+len(tx.Payload)+SizeAccountID)
+	message = append(message, tx.Sender[:]...)
+
+	var buf [8]byte
+
+	binary.BigEndian.PutUint64(buf[:], tx.Nonce)
+	message = append(message, buf[:]...)
+
+	binary.BigEndian.PutUint64(buf[:], tx.Block)
+	message = append(message, buf[:]...)
+
+	message = append(message, byte(tx.Tag)|feePayerFlag)
+	message = append(message, tx.Payload...)
+	message = append(message, tx.FeePayer[:]...)
+
+	return message
+}
+
+// SignSponsored signs a transaction sponsored by the fee payer set on it with the private key of either its sender
+// or its fee payer. Both signatures may be made offline. The signature of the fee payer is attached to the
+// transaction with SponsorTransaction.
+func (tx Transaction) SignSponsored(privateKey edwards25519.PrivateKey) Signature {
+	return edwards25519.Sign(privateKey, tx.sponsoredMessage())
+}
+
+// SponsorTransaction returns a copy of a transaction sponsored by the fee payer set on it, carrying the signature
+// of the fee payer.
+func SponsorTransaction(tx Transaction, feePayerSignature Signature) Transaction {
+	tx.FeePayerSignature = feePayerSignature
+	tx.ID = blake2b.Sum256(tx.Marshal())
+
+	return tx
+}
+
+// VerifyFeePayerSignature verifies the signature of the fee payer of a sponsored transaction against the given key,
+// which is the key bound to the account of the fee payer should the key of the account have been rotated.
+func (tx Transaction) VerifyFeePayerSignature(key edwards25519.PublicKey) bool {
+	return tx.IsSponsored() && edwards25519.Verify(key, tx.sponsoredMessage(), tx.FeePayerSignature)
+}
+
+// VerifySignature verifies the signature of a transaction against the ID of its sender.
 func (tx Transaction) VerifySignature() bool {
+	return tx.VerifySignatureWithKey(tx.Sender)
+}
+
+// VerifySignatureWithKey verifies the signature of a transaction against the given key, which is the key bound to
+// the account of its sender should the key of the account have been rotated.
+func (tx Transaction) VerifySignatureWithKey(key edwards25519.PublicKey) bool {
+	return edwards25519.Verify(key, tx.signedMessage(), tx.Signature)
+}
+
+// signedMessage returns the message signed by the sender of a transaction.
+func (tx Transaction) signedMessage() []byte {
+	if tx.IsSponsored() {
+		return tx.sponsoredMessage()
+	}
+
 	var (
 		nonceBuf [8]byte
 		blockBuf [8]byte
@@ -200,5 +360,5 @@ func (tx Transaction) VerifySignature() bool {
 	message = append(message, byte(tx.Tag))
 	message = append(message, tx.Payload...)
 
-	return edwards25519.Verify(tx.Sender, message, tx.Signature)
+	return message
 }
@@ -35,6 +35,24 @@ type contractExecutorState struct {
 	GasLimit      uint64
 	GasLimitIsSet bool
 	Context       *CollapseContext
+
+	// GasUsed is the total gas consumed by all smart contract invocations made while applying a transaction.
+	GasUsed uint64
+
+	// GasCharges records how the gas consumed by each smart contract invocation made while applying a transaction was
+	// paid for, in the order in which the invocations were made.
+	GasCharges []gasCharge
+
+	// Trace, if set, records a trace of every smart contract invocation made while applying a transaction.
+	Trace *ContractTrace
+}
+
+// gasCharge is the gas consumed by a single smart contract invocation, split between the part paid for out of the gas
+// balance of the smart contract and the part paid for out of the balance of the gas payer.
+type gasCharge struct {
+	ContractID   AccountID
+	FromContract uint64
+	FromGasPayer uint64
 }
 
 // Apply the transaction and immediately write the states into the tree.
@@ -48,7 +66,29 @@ func ApplyTransaction(tree *avl.Tree, block *Block, tx *Transaction) error {
 	return ctx.Flush()
 }
 
+// applyTransaction applies a transaction into the context. Should the transaction fail, all changes it made are
+// reverted, such that a failed transaction, be it a batch entry or a sub-transaction sent by a smart contract, leaves
+// no trace in the context. Gas consumed by smart contract invocations made while applying a failed transaction is
+// still paid for, out of the same gas balances and balances it was originally paid for out of.
 func applyTransaction(block *Block, ctx *CollapseContext, tx *Transaction, executorState *contractExecutorState) error {
+	checkpoint := ctx.Checkpoint()
+	numGasCharges := len(executorState.GasCharges)
+
+	if err := applyTransactionByTag(block, ctx, tx, executorState); err != nil {
+		ctx.RevertToCheckpoint(checkpoint)
+		chargeRevertedGas(ctx, executorState, executorState.GasCharges[numGasCharges:])
+
+		return err
+	}
+
+	ctx.DiscardCheckpoint(checkpoint)
+
+	return nil
+}
+
+func applyTransactionByTag(
+	block *Block, ctx *CollapseContext, tx *Transaction, executorState *contractExecutorState,
+) error {
 	switch tx.Tag {
 	case sys.TagTransfer:
 		if err := applyTransferTransaction(ctx, block, tx, executorState); err != nil {
@@ -66,6 +106,30 @@ func applyTransaction(block *Block, ctx *CollapseContext, tx *Transaction, execu
 		if err := applyBatchTransaction(ctx, block, tx, executorState); err != nil {
 			return errors.Wrap(err, "could not apply batch transaction")
 		}
+	case sys.TagContractLifecycle:
+		if err := applyContractLifecycleTransaction(ctx, block, tx, executorState); err != nil {
+			return errors.Wrap(err, "could not apply contract lifecycle transaction")
+		}
+	case sys.TagMultisig:
+		if err := applyMultisigTransaction(ctx, tx); err != nil {
+			return errors.Wrap(err, "could not apply multisig transaction")
+		}
+	case sys.TagKeyRotation:
+		if err := applyKeyRotationTransaction(ctx, tx); err != nil {
+			return errors.Wrap(err, "could not apply key rotation transaction")
+		}
+	case sys.TagAsset:
+		if err := applyAssetTransaction(ctx, tx); err != nil {
+			return errors.Wrap(err, "could not apply asset transaction")
+		}
+	case sys.TagHTLC:
+		if err := applyHTLCTransaction(ctx, block, tx); err != nil {
+			return errors.Wrap(err, "could not apply htlc transaction")
+		}
+	case sys.TagSchedule:
+		if err := applyScheduleTransaction(ctx, block, tx); err != nil {
+			return errors.Wrap(err, "could not apply schedule transaction")
+		}
 	}
 
 	return nil
@@ -85,6 +149,13 @@ func applyTransferTransaction(ctx *CollapseContext, block *Block, tx *Transactio
 		)
 	}
 
+	switch payload.Opcode {
+	case sys.ApproveAllowance:
+		return applyApproveAllowance(ctx, block, tx, payload)
+	case sys.TransferFromAllowance:
+		return applyTransferFromAllowance(ctx, block, tx, payload)
+	}
+
 	// FIXME(kenta): FOR TESTNET ONLY. FAUCET DOES NOT GET ANY PERLs DEDUCTED.
 	if hex.EncodeToString(tx.Sender[:]) == sys.FaucetAddress {
 		recipientBalance, _ := ctx.ReadAccountBalance(payload.Recipient)
@@ -93,6 +164,36 @@ func applyTransferTransaction(ctx *CollapseContext, block *Block, tx *Transactio
 		return nil
 	}
 
+	// Should the smart contract function invoked by the transfer fail, the transfer is reverted alongside the
+	// invocation, while gas consumed by the invocation is still charged.
+	checkpoint := ctx.Checkpoint()
+	numGasCharges := len(state.GasCharges)
+
+	invocationErr, err := transferAndInvokeContract(ctx, block, tx, payload, code, codeAvailable, state)
+	if err != nil {
+		ctx.DiscardCheckpoint(checkpoint)
+		return err
+	}
+
+	if invocationErr != nil {
+		ctx.RevertToCheckpoint(checkpoint)
+		chargeRevertedGas(ctx, state, state.GasCharges[numGasCharges:])
+
+		return nil
+	}
+
+	ctx.DiscardCheckpoint(checkpoint)
+
+	return nil
+}
+
+// transferAndInvokeContract transfers PERLs and gas deposits to the recipient of a transfer, and then invokes the
+// smart contract function named by the transfer should there be one. Should the invocation itself fail, the failure
+// is returned as invocationErr.
+func transferAndInvokeContract(
+	ctx *CollapseContext, block *Block, tx *Transaction, payload Transfer, code []byte, codeAvailable bool,
+	state *contractExecutorState,
+) (invocationErr error, err error) {
 	err = transferValue(
 		"PERL",
 		tx.Sender, payload.Recipient,
@@ -101,11 +202,11 @@ func applyTransferTransaction(ctx *CollapseContext, block *Block, tx *Transactio
 		ctx.ReadAccountBalance, ctx.WriteAccountBalance,
 	)
 	if err != nil {
-		return errors.Wrap(err, "failed to execute transferValue on balance")
+		return nil, errors.Wrap(err, "failed to execute transferValue on balance")
 	}
 
 	if !codeAvailable {
-		return nil
+		return nil, nil
 	}
 
 	if payload.GasDeposit != 0 {
@@ -117,18 +218,86 @@ func applyTransferTransaction(ctx *CollapseContext, block *Block, tx *Transactio
 			ctx.ReadAccountContractGasBalance, ctx.WriteAccountContractGasBalance,
 		)
 		if err != nil {
-			return errors.Wrap(err, "failed to execute transferValue on gas deposit")
+			return nil, errors.Wrap(err, "failed to execute transferValue on gas deposit")
 		}
 	}
 
 	if len(payload.FuncName) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	return executeContractInTransactionContext(
+	contractState, _ := ctx.GetContractState(payload.Recipient)
+
+	return invokeContractInTransactionContext(
 		tx, payload.Recipient, code, ctx, block, payload.Amount, payload.GasLimit, payload.FuncName, payload.FuncParams,
-		state,
+		contractState, state,
+	)
+}
+
+// applyApproveAllowance approves the recipient of a transfer to spend up to the amount of the transfer out of the
+// balance of the sender, replacing any allowance previously approved to the recipient. Approving an allowance of zero
+// PERLs revokes it.
+func applyApproveAllowance(ctx *CollapseContext, block *Block, tx *Transaction, payload Transfer) error {
+	if payload.Recipient == tx.Sender {
+		return errors.New("transfer: accounts may not approve allowances to themselves")
+	}
+
+	if block != nil && payload.Expiry != 0 && payload.Expiry <= block.Index {
+		return errors.Errorf(
+			"transfer: allowance expiry %d has already passed at block height %d", payload.Expiry, block.Index,
+		)
+	}
+
+	ctx.WriteAccountAllowance(tx.Sender, Allowance{
+		Spender: payload.Recipient,
+		Amount:  payload.Amount,
+		Expiry:  payload.Expiry,
+	})
+
+	return nil
+}
+
+// applyTransferFromAllowance transfers the amount of a transfer out of the balance of the account which approved the
+// sender an allowance into the balance of the recipient, deducting the amount from the allowance.
+func applyTransferFromAllowance(ctx *CollapseContext, block *Block, tx *Transaction, payload Transfer) error {
+	allowance, exists := ctx.ReadAccountAllowance(payload.Owner, tx.Sender)
+	if !exists {
+		return errors.Errorf("transfer: %x has not approved an allowance to %x", payload.Owner, tx.Sender)
+	}
+
+	if allowance.Expiry != 0 {
+		if block == nil {
+			return errors.New("transfer: allowances which expire may only be spent within a block")
+		}
+
+		if allowance.Expired(block.Index) {
+			return errors.Errorf(
+				"transfer: allowance of %x to %x expired at block height %d", payload.Owner, tx.Sender, allowance.Expiry,
+			)
+		}
+	}
+
+	if allowance.Amount < payload.Amount {
+		return errors.Errorf(
+			"transfer: %x is only allowed to spend %d PERLs of %x", tx.Sender, allowance.Amount, payload.Owner,
+		)
+	}
+
+	err := transferValue(
+		"PERL",
+		payload.Owner, payload.Recipient,
+		payload.Amount,
+		ctx.ReadAccountBalance, ctx.WriteAccountBalance,
+		ctx.ReadAccountBalance, ctx.WriteAccountBalance,
 	)
+	if err != nil {
+		return errors.Wrap(err, "failed to execute transferValue on balance")
+	}
+
+	allowance.Amount -= payload.Amount
+	ctx.WriteAccountAllowance(payload.Owner, allowance)
+
+	return nil
 }
 
 func applyStakeTransaction(ctx *CollapseContext, block *Block, tx *Transaction) error {
@@ -200,12 +369,29 @@ func applyContractTransaction(ctx *CollapseContext, block *Block, tx *Transactio
 		return errors.New("contract: already exists")
 	}
 
-	// Record the code of the smart contract into the ledgers state.
-	if err := wasm.GetValidator().ValidateWasm(payload.Code); err != nil {
-		return errors.Wrap(err, "invalid wasm")
+	code := payload.Code
+
+	if len(code) == 0 {
+		// Smart contracts may be spawned from code previously uploaded, which has already been validated.
+		var exists bool
+
+		if code, exists = ctx.ReadContractCode(payload.CodeID); !exists {
+			return errors.Errorf("contract: code %x does not exist", payload.CodeID)
+		}
+	} else {
+		if err := wasm.GetValidator().ValidateWasm(code); err != nil {
+			return errors.Wrap(err, "invalid wasm")
+		}
+
+		// Smart contracts may optionally ship an ABI, which must be valid should it be provided.
+		if _, err := ReadContractABI(code); err != nil && errors.Cause(err) != ErrNoABI {
+			return errors.Wrap(err, "invalid abi")
+		}
 	}
 
-	ctx.WriteAccountContractCode(tx.ID, payload.Code)
+	// Record the code of the smart contract into the ledgers state.
+	ctx.WriteAccountContractCode(tx.ID, code)
+	ctx.WriteAccountContractOwner(tx.ID, tx.Sender)
 
 	if payload.GasDeposit != 0 {
 		err = transferValue(
@@ -221,25 +407,168 @@ func applyContractTransaction(ctx *CollapseContext, block *Block, tx *Transactio
 	}
 
 	return executeContractInTransactionContext(
-		tx, tx.ID, payload.Code, ctx, block, 0, payload.GasLimit, []byte("init"), payload.Params, state,
+		tx, tx.ID, code, ctx, block, 0, payload.GasLimit, []byte("init"), payload.Params, state,
+	)
+}
+
+func applyContractLifecycleTransaction(
+	ctx *CollapseContext, block *Block, tx *Transaction, state *contractExecutorState,
+) error {
+	payload, err := ParseContractLifecycle(tx.Payload)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := ctx.ReadAccountContractCode(payload.ContractID); !exists {
+		return errors.Errorf("contract_lifecycle: smart contract %x does not exist", payload.ContractID)
+	}
+
+	// Only the owner of a smart contract, or the smart contract itself, may upgrade, destroy, or withdraw gas from it.
+	owner, hasOwner := ctx.ReadAccountContractOwner(payload.ContractID)
+	if (!hasOwner || tx.Sender != owner) && tx.Sender != payload.ContractID {
+		return errors.Errorf(
+			"contract_lifecycle: %x is neither the owner of smart contract %x nor the smart contract itself",
+			tx.Sender, payload.ContractID,
+		)
+	}
+
+	switch payload.Opcode {
+	case sys.UpgradeContract:
+		if err := wasm.GetValidator().ValidateWasm(payload.Code); err != nil {
+			return errors.Wrap(err, "invalid wasm")
+		}
+
+		if _, err := ReadContractABI(payload.Code); err != nil && errors.Cause(err) != ErrNoABI {
+			return errors.Wrap(err, "invalid abi")
+		}
+
+		return upgradeContract(ctx, block, tx, payload, state)
+	case sys.DestroyContract:
+		// Funds held by the smart contract are refunded to its owner. Smart contracts spawned before owners were
+		// recorded have their gas balance refunded into their own balance instead.
+		recipient := payload.ContractID
+		if hasOwner {
+			recipient = owner
+		}
+
+		if gasBalance, _ := ctx.ReadAccountContractGasBalance(payload.ContractID); gasBalance > 0 {
+			err = transferValue(
+				"PERL (Gas Balance)",
+				payload.ContractID, recipient,
+				gasBalance,
+				ctx.ReadAccountContractGasBalance, ctx.WriteAccountContractGasBalance,
+				ctx.ReadAccountBalance, ctx.WriteAccountBalance,
+			)
+			if err != nil {
+				return errors.Wrap(err, "failed to execute transferValue on gas balance")
+			}
+		}
+
+		if balance, _ := ctx.ReadAccountBalance(payload.ContractID); balance > 0 && recipient != payload.ContractID {
+			err = transferValue(
+				"PERL",
+				payload.ContractID, recipient,
+				balance,
+				ctx.ReadAccountBalance, ctx.WriteAccountBalance,
+				ctx.ReadAccountBalance, ctx.WriteAccountBalance,
+			)
+			if err != nil {
+				return errors.Wrap(err, "failed to execute transferValue on balance")
+			}
+		}
+
+		ctx.DestroyAccountContract(payload.ContractID)
+	case sys.WithdrawContractGas:
+		err = transferValue(
+			"PERL (Gas Balance)",
+			payload.ContractID, payload.Recipient,
+			payload.Amount,
+			ctx.ReadAccountContractGasBalance, ctx.WriteAccountContractGasBalance,
+			ctx.ReadAccountBalance, ctx.WriteAccountBalance,
+		)
+		if err != nil {
+			return errors.Wrap(err, "failed to execute transferValue on gas balance")
+		}
+	}
+
+	return nil
+}
+
+// upgradeContract swaps the code of a smart contract while carrying over its memory and globals. Should the new
+// code export a `migrate` function, it is invoked under the upgraded state, and the upgrade is rejected should
+// the migration fail.
+func upgradeContract(
+	ctx *CollapseContext, block *Block, tx *Transaction, payload ContractLifecycle,
+	state *contractExecutorState,
+) error {
+	instance, err := ctx.engine().Instantiate(payload.Code, payload.GasLimit, &ContractExecutor{})
+	if err != nil {
+		return errors.Wrap(err, "contract_lifecycle: cannot initialize vm")
+	}
+
+	if len(instance.Globals()) > sys.ContractMaxGlobals {
+		return errors.New("contract_lifecycle: too many globals")
+	}
+
+	oldState, exists := ctx.GetContractState(payload.ContractID)
+	if !exists {
+		if mem := LoadContractMemorySnapshot(ctx.tree, payload.ContractID); mem != nil {
+			globals, _ := LoadContractGlobals(ctx.tree, payload.ContractID)
+			oldState = &VMState{Globals: globals, Memory: mem}
+		}
+	}
+
+	upgradedState, err := UpgradeContractState(instance, oldState)
+	if err != nil {
+		return errors.Wrap(err, "contract_lifecycle: cannot carry over state")
+	}
+
+	cloned, err := instance.Clone(nil)
+	if err != nil {
+		return errors.Wrap(err, "contract_lifecycle: cannot clone vm")
+	}
+
+	// Invalidate the cached VM of the old code.
+	ctx.VMCache.Put(payload.ContractID, cloned)
+	ctx.WriteAccountContractCode(payload.ContractID, payload.Code)
+
+	if _, exists := instance.Export("_contract_migrate"); !exists {
+		ctx.SetContractState(payload.ContractID, upgradedState)
+		return nil
+	}
+
+	invocationErr, err := invokeContractInTransactionContext(
+		tx, payload.ContractID, payload.Code, ctx, block, 0, payload.GasLimit, []byte("migrate"), payload.Params,
+		upgradedState, state,
 	)
+	if err != nil {
+		return err
+	}
+
+	// The upgrade is reverted along with the rest of the transaction should the migration fail.
+	if invocationErr != nil {
+		return errors.Wrap(invocationErr, "contract_lifecycle: failed to migrate smart contract")
+	}
+
+	return nil
 }
 
+// applyBatchTransaction applies all entries of a batch in order. Should any entry fail, the batch fails, such that all
+// changes made by its entries are reverted. Entries of a best-effort batch are instead applied on their own, with
+// entries that fail being reverted and skipped.
 func applyBatchTransaction(ctx *CollapseContext, block *Block, tx *Transaction, state *contractExecutorState) error {
 	payload, err := ParseBatch(tx.Payload)
 	if err != nil {
 		return err
 	}
 
+	if payload.BestEffort {
+		applyBestEffortBatch(ctx, block, tx, payload, state)
+		return nil
+	}
+
 	for i := uint8(0); i < payload.Size; i++ {
-		entry := &Transaction{
-			ID:      tx.ID,
-			Sender:  tx.Sender,
-			Nonce:   tx.Nonce,
-			Tag:     sys.Tag(payload.Tags[i]),
-			Payload: payload.Payloads[i],
-		}
-		if err := applyTransaction(block, ctx, entry, state); err != nil {
+		if err := applyTransaction(block, ctx, batchEntry(tx, payload, i), state); err != nil {
 			return errors.Wrapf(err, "Error while processing %d/%d transaction in a batch.", i+1, payload.Size)
 		}
 	}
@@ -247,6 +576,387 @@ func applyBatchTransaction(ctx *CollapseContext, block *Block, tx *Transaction,
 	return nil
 }
 
+// applyBestEffortBatch applies each entry of a best-effort batch on its own, skipping those that fail. The outcome of
+// each entry is stored into the ledger state under the ID of the batch, and recorded into the trace of the
+// transaction, if it is being traced.
+func applyBestEffortBatch(
+	ctx *CollapseContext, block *Block, tx *Transaction, payload Batch, state *contractExecutorState,
+) {
+	logger := log.Node()
+
+	results := make([]BatchEntryResult, 0, payload.Size)
+
+	for i := uint8(0); i < payload.Size; i++ {
+		result := BatchEntryResult{Tag: sys.Tag(payload.Tags[i])}
+
+		if err := applyTransaction(block, ctx, batchEntry(tx, payload, i), state); err != nil {
+			result.Error = err.Error()
+
+			logger.Info().
+				Err(err).
+				Hex("tx_id", tx.ID[:]).
+				Uint8("entry", i).
+				Msg("Reverted a failed entry of a best-effort batch.")
+		}
+
+		results = append(results, result)
+
+		if state.Trace != nil {
+			state.Trace.Batch = append(state.Trace.Batch, result)
+		}
+	}
+
+	ctx.WriteBatchResults(tx.ID, results)
+}
+
+// batchEntry returns the i-th entry of a batch as a transaction sent by the sender of the batch.
+func batchEntry(tx *Transaction, payload Batch, i uint8) *Transaction {
+	return &Transaction{
+		ID:      tx.ID,
+		Sender:  tx.Sender,
+		Nonce:   tx.Nonce,
+		Tag:     sys.Tag(payload.Tags[i]),
+		Payload: payload.Payloads[i],
+	}
+}
+
+// applyMultisigTransaction registers a multisig account, whose ID is the ID of the transaction registering it.
+func applyMultisigTransaction(ctx *CollapseContext, tx *Transaction) error {
+	payload, err := ParseMultisig(tx.Payload)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := ctx.ReadAccountMultisig(tx.ID); exists {
+		return errors.Errorf("multisig: account %x already exists", tx.ID)
+	}
+
+	ctx.WriteAccountMultisig(tx.ID, payload)
+
+	return nil
+}
+
+// applyKeyRotationTransaction binds a new key to the account of the sender, which from then on signs transactions on
+// behalf of the account in place of the key it was previously bound to.
+func applyKeyRotationTransaction(ctx *CollapseContext, tx *Transaction) error {
+	payload, err := ParseKeyRotation(tx.Payload)
+	if err != nil {
+		return err
+	}
+
+	if _, isContract := ctx.ReadAccountContractCode(tx.Sender); isContract {
+		return errors.New("key_rotation: smart contract accounts have no key to rotate")
+	}
+
+	if _, isMultisig := ctx.ReadAccountMultisig(tx.Sender); isMultisig {
+		return errors.New("key_rotation: multisig accounts have no key to rotate")
+	}
+
+	ctx.WriteAccountSigningKey(tx.Sender, payload.Key)
+
+	return nil
+}
+
+// applyAssetTransaction creates a native asset, whose ID is the ID of the transaction creating it and whose issuer is
+// the sender, or transfers, mints or burns some amount of an existing native asset. Only the issuer of an asset may
+// mint or burn it.
+func applyAssetTransaction(ctx *CollapseContext, tx *Transaction) error {
+	payload, err := ParseAsset(tx.Payload)
+	if err != nil {
+		return err
+	}
+
+	if payload.Opcode == sys.CreateAsset {
+		if _, exists := ctx.ReadAsset(tx.ID); exists {
+			return errors.Errorf("asset: asset %x already exists", tx.ID)
+		}
+
+		ctx.WriteAsset(tx.ID, AssetInfo{
+			Issuer:   tx.Sender,
+			Symbol:   payload.Symbol,
+			Decimals: payload.Decimals,
+			Supply:   payload.Supply,
+		})
+
+		if payload.Supply > 0 {
+			ctx.WriteAccountAssetBalance(tx.Sender, tx.ID, payload.Supply)
+		}
+
+		return nil
+	}
+
+	info, exists := ctx.ReadAsset(payload.AssetID)
+	if !exists {
+		return errors.Errorf("asset: asset %x does not exist", payload.AssetID)
+	}
+
+	read := func(id AccountID) (uint64, bool) {
+		return ctx.ReadAccountAssetBalance(id, payload.AssetID)
+	}
+
+	write := func(id AccountID, balance uint64) {
+		ctx.WriteAccountAssetBalance(id, payload.AssetID, balance)
+	}
+
+	if payload.Opcode == sys.TransferAsset {
+		return transferValue(info.Symbol, tx.Sender, payload.Recipient, payload.Amount, read, write, read, write)
+	}
+
+	if tx.Sender != info.Issuer {
+		return errors.Errorf("asset: %x is not the issuer of asset %x", tx.Sender, payload.AssetID)
+	}
+
+	if payload.Opcode == sys.MintAsset {
+		if info.Supply+payload.Amount < info.Supply {
+			return errors.Errorf("asset: minting %d %s overflows the supply of the asset", payload.Amount, info.Symbol)
+		}
+
+		info.Supply += payload.Amount
+		ctx.WriteAsset(payload.AssetID, info)
+
+		balance, _ := read(payload.Recipient)
+		write(payload.Recipient, balance+payload.Amount)
+
+		return nil
+	}
+
+	balance, _ := read(tx.Sender)
+	if balance < payload.Amount {
+		return errors.Errorf(
+			"asset: attempt to burn %d %s, but issuer only has %d %s", payload.Amount, info.Symbol, balance, info.Symbol,
+		)
+	}
+
+	info.Supply -= payload.Amount
+	ctx.WriteAsset(payload.AssetID, info)
+
+	write(tx.Sender, balance-payload.Amount)
+
+	return nil
+}
+
+// applyHTLCTransaction locks an amount of the balance of the sender in escrow under a hash time-locked transfer, whose
+// ID is the ID of the transaction locking it, or claims or refunds the escrowed amount of a transfer. A transfer is
+// claimed into the balance of its recipient by revealing a preimage of its hash lock before the block it is applied in
+// reaches its expiry, and is refunded to its sender from then on. Claims and refunds may be sent by any account. All
+// checks are made before any state is written, such that a transaction that fails leaves no changes behind.
+func applyHTLCTransaction(ctx *CollapseContext, block *Block, tx *Transaction) error {
+	payload, err := ParseHTLC(tx.Payload)
+	if err != nil {
+		return err
+	}
+
+	if block == nil {
+		return errors.New("htlc: hash time-locked transfers may only be applied within a block")
+	}
+
+	if payload.Opcode == sys.LockHTLC {
+		if _, exists := ctx.ReadHTLC(tx.ID); exists {
+			return errors.Errorf("htlc: %x already exists", tx.ID)
+		}
+
+		if payload.Expiry <= block.Index {
+			return errors.Errorf(
+				"htlc: expiry at block height %d has already been reached at block height %d", payload.Expiry, block.Index,
+			)
+		}
+
+		balance, _ := ctx.ReadAccountBalance(tx.Sender)
+		if balance < payload.Amount {
+			return errors.Errorf(
+				"htlc: %x tried to lock %d PERLs, but only has %d PERLs", tx.Sender, payload.Amount, balance,
+			)
+		}
+
+		ctx.WriteAccountBalance(tx.Sender, balance-payload.Amount)
+		ctx.WriteHTLC(tx.ID, HTLCInfo{
+			Sender:    tx.Sender,
+			Recipient: payload.Recipient,
+			Amount:    payload.Amount,
+			HashLock:  payload.HashLock,
+			Expiry:    payload.Expiry,
+			Status:    HTLCLocked,
+		})
+
+		return nil
+	}
+
+	info, exists := ctx.ReadHTLC(payload.HTLCID)
+	if !exists {
+		return errors.Errorf("htlc: %x does not exist", payload.HTLCID)
+	}
+
+	if info.Status != HTLCLocked {
+		return errors.Errorf("htlc: %x has already been claimed or refunded", payload.HTLCID)
+	}
+
+	payee := info.Sender
+
+	if payload.Opcode == sys.ClaimHTLC {
+		if block.Index >= info.Expiry {
+			return errors.Errorf("htlc: %x expired at block height %d", payload.HTLCID, info.Expiry)
+		}
+
+		if !info.Unlocks(payload.Preimage) {
+			return errors.Errorf("htlc: preimage does not unlock %x", payload.HTLCID)
+		}
+
+		payee = info.Recipient
+
+		info.Status = HTLCClaimed
+		info.Preimage = payload.Preimage
+	} else {
+		if block.Index < info.Expiry {
+			return errors.Errorf("htlc: %x may not be refunded before block height %d", payload.HTLCID, info.Expiry)
+		}
+
+		info.Status = HTLCRefunded
+	}
+
+	balance, _ := ctx.ReadAccountBalance(payee)
+
+	ctx.WriteAccountBalance(payee, balance+info.Amount)
+	ctx.WriteHTLC(payload.HTLCID, info)
+
+	return nil
+}
+
+// applyScheduleTransaction registers a scheduled transfer, whose ID is the ID of the transaction registering it, by
+// escrowing the PERLs of all of its transfers from the balance of the sender. Scheduled transfers are paid as blocks
+// are collapsed. Cancelling a scheduled transfer refunds the PERLs escrowed for the transfers not yet paid.
+func applyScheduleTransaction(ctx *CollapseContext, block *Block, tx *Transaction) error {
+	payload, err := ParseSchedule(tx.Payload)
+	if err != nil {
+		return err
+	}
+
+	if payload.Opcode == sys.CancelSchedule {
+		info, exists := ctx.ReadSchedule(payload.ScheduleID)
+		if !exists {
+			return errors.Errorf("schedule: %x does not exist", payload.ScheduleID)
+		}
+
+		if info.Sender != tx.Sender {
+			return errors.Errorf("schedule: %x did not register %x", tx.Sender, payload.ScheduleID)
+		}
+
+		balance, _ := ctx.ReadAccountBalance(tx.Sender)
+
+		ctx.WriteAccountBalance(tx.Sender, balance+info.Escrowed())
+		ctx.DeleteSchedule(payload.ScheduleID)
+
+		return nil
+	}
+
+	if block == nil {
+		return errors.New("schedule: scheduled transfers may only be registered within a block")
+	}
+
+	if _, exists := ctx.ReadSchedule(tx.ID); exists {
+		return errors.Errorf("schedule: %x already exists", tx.ID)
+	}
+
+	if payload.Start < block.Index {
+		return errors.Errorf(
+			"schedule: start at block height %d has already passed at block height %d", payload.Start, block.Index,
+		)
+	}
+
+	total := payload.Amount * payload.Count
+
+	balance, _ := ctx.ReadAccountBalance(tx.Sender)
+	if balance < total {
+		return errors.Errorf("schedule: %x tried to escrow %d PERLs, but only has %d PERLs", tx.Sender, total, balance)
+	}
+
+	ctx.WriteAccountBalance(tx.Sender, balance-total)
+	ctx.WriteSchedule(tx.ID, ScheduleInfo{
+		Sender:    tx.Sender,
+		Recipient: payload.Recipient,
+		Amount:    payload.Amount,
+		Next:      payload.Start,
+		Interval:  payload.Interval,
+		Remaining: payload.Count,
+	})
+
+	return nil
+}
+
+// chargeRevertedGas charges once more for gas consumed by smart contract invocations whose changes were reverted,
+// such that the work done by reverted invocations is not free of charge. Each invocation is paid for out of the gas
+// balance of its smart contract and the balance of the gas payer in the same shares it was originally paid for.
+//
+// Should the gas balance of a smart contract no longer cover its share, as gas was deposited into it by the reverted
+// changes, the remainder is paid for by the gas payer. Should the balance of the gas payer no longer cover its share
+// either, as PERLs were transferred into it by the reverted changes, it pays for as much of the gas as its balance
+// covers.
+func chargeRevertedGas(ctx *CollapseContext, state *contractExecutorState, charges []gasCharge) {
+	logger := log.Contracts("execute")
+
+	for _, charge := range charges {
+		fromGasPayer := charge.FromGasPayer
+
+		if charge.FromContract > 0 {
+			gasBalance, _ := ctx.ReadAccountContractGasBalance(charge.ContractID)
+
+			fromContract := charge.FromContract
+			if fromContract > gasBalance {
+				fromGasPayer += fromContract - gasBalance
+				fromContract = gasBalance
+			}
+
+			if fromContract > 0 {
+				ctx.WriteAccountContractGasBalance(charge.ContractID, gasBalance-fromContract)
+			}
+		}
+
+		if fromGasPayer == 0 {
+			continue
+		}
+
+		balance, _ := ctx.ReadAccountBalance(state.GasPayer)
+		if fromGasPayer > balance {
+			logger.Warn().
+				Hex("gas_payer", state.GasPayer[:]).
+				Hex("contract_id", charge.ContractID[:]).
+				Uint64("gas", fromGasPayer).
+				Uint64("balance", balance).
+				Msg("Gas payer is unable to pay for gas consumed by reverted smart contract invocation.")
+
+			fromGasPayer = balance
+		}
+
+		ctx.WriteAccountBalance(state.GasPayer, balance-fromGasPayer)
+	}
+}
+
+// chargeGas has an invocation of a smart contract pay for gas, first out of the gas balance of the smart contract, and
+// then out of the balance of the gas payer. The balances read prior to the invocation are passed in.
+func chargeGas(
+	ctx *CollapseContext, state *contractExecutorState, contractID AccountID, contractGasBalance, gasPayerBalance,
+	gas uint64,
+) {
+	charge := gasCharge{ContractID: contractID, FromContract: gas}
+
+	if gas > contractGasBalance {
+		charge.FromContract = contractGasBalance
+		charge.FromGasPayer = gas - contractGasBalance
+
+		if gasPayerBalance < charge.FromGasPayer {
+			logger := log.Contracts("execute")
+			logger.Fatal().Msg("BUG: gasPayerBalance < (executor.Gas - contractGasBalance)")
+		}
+
+		ctx.WriteAccountBalance(state.GasPayer, gasPayerBalance-charge.FromGasPayer)
+	}
+
+	ctx.WriteAccountContractGasBalance(contractID, contractGasBalance-charge.FromContract)
+
+	state.GasLimit -= gas
+	state.GasUsed += gas
+	state.GasCharges = append(state.GasCharges, charge)
+}
+
 // Transfers value of any form (balance, gasDeposit/gasBalance).
 func transferValue(
 	unitName string,
@@ -284,6 +994,31 @@ func executeContractInTransactionContext(
 	funcParams []byte,
 	state *contractExecutorState,
 ) error {
+	contractState, _ := ctx.GetContractState(contractID)
+
+	_, err := invokeContractInTransactionContext(
+		tx, contractID, code, ctx, block, amount, requestedGasLimit, funcName, funcParams, contractState, state,
+	)
+
+	return err
+}
+
+// invokeContractInTransactionContext invokes a smart contract function against the given VM state, and charges
+// gas for the invocation. Should the invocation itself fail, gas is still charged, the VM state and key-value
+// storage of the smart contract are left untouched, and the failure is returned as invocationErr.
+func invokeContractInTransactionContext(
+	tx *Transaction,
+	contractID AccountID,
+	code []byte,
+	ctx *CollapseContext,
+	block *Block,
+	amount uint64,
+	requestedGasLimit uint64,
+	funcName []byte,
+	funcParams []byte,
+	contractState *VMState,
+	state *contractExecutorState,
+) (invocationErr error, err error) {
 	logger := log.Contracts("execute")
 
 	gasPayerBalance, _ := ctx.ReadAccountBalance(state.GasPayer)
@@ -302,22 +1037,19 @@ func executeContractInTransactionContext(
 	}
 
 	if realGasLimit == 0 {
-		return errors.New(
+		return nil, errors.New(
 			"execute_contract: gas limit for invoking smart contract function must be greater than zero",
 		)
 	}
 
 	if availableBalance < realGasLimit {
-		return errors.Errorf(
+		return nil, errors.Errorf(
 			"execute_contract: attempted to deduct gas fee from %x of %d PERLs, but only has %d PERLs",
 			state.GasPayer, realGasLimit, availableBalance,
 		)
 	}
 
-	executor := &ContractExecutor{}
-
-	var contractState *VMState
-	contractState, _ = ctx.GetContractState(contractID)
+	executor := &ContractExecutor{Context: ctx, Trace: state.Trace, Engine: ctx.engine()}
 
 	newContractState, invocationErr := executor.Execute(
 		contractID, block, tx, amount, realGasLimit, string(funcName), funcParams, code, ctx.tree, ctx.VMCache,
@@ -334,19 +1066,7 @@ func executeContractInTransactionContext(
 	}
 
 	if invocationErr != nil { // Revert changes and have the gas payer pay gas fees.
-		if executor.Gas > contractGasBalance {
-			ctx.WriteAccountContractGasBalance(contractID, 0)
-
-			if gasPayerBalance < (executor.Gas - contractGasBalance) {
-				logger.Fatal().Msg("BUG: gasPayerBalance < (executor.Gas - contractGasBalance)")
-			}
-
-			ctx.WriteAccountBalance(state.GasPayer, gasPayerBalance-(executor.Gas-contractGasBalance))
-		} else {
-			ctx.WriteAccountContractGasBalance(contractID, contractGasBalance-executor.Gas)
-		}
-
-		state.GasLimit -= executor.Gas
+		chargeGas(ctx, state, contractID, contractGasBalance, gasPayerBalance, executor.Gas)
 
 		if executor.GasLimitExceeded {
 			logger.Info().
@@ -362,22 +1082,32 @@ func executeContractInTransactionContext(
 		// Contract invocation succeeded. VM state can be safely saved now.
 		ctx.SetContractState(contractID, newContractState)
 
-		if executor.Gas > contractGasBalance {
-			ctx.WriteAccountContractGasBalance(contractID, 0)
-			if gasPayerBalance < (executor.Gas - contractGasBalance) {
-				logger.Fatal().Msg("BUG: gasPayerBalance < (executor.Gas - contractGasBalance)")
+		for _, write := range executor.StorageWrites {
+			if write.Deleted {
+				ctx.DeleteAccountContractStorage(contractID, write.Key)
+			} else {
+				ctx.WriteAccountContractStorage(contractID, write.Key, write.Value)
 			}
-			ctx.WriteAccountBalance(state.GasPayer, gasPayerBalance-(executor.Gas-contractGasBalance))
-		} else {
-			ctx.WriteAccountContractGasBalance(contractID, contractGasBalance-executor.Gas)
 		}
-		state.GasLimit -= executor.Gas
+
+		chargeGas(ctx, state, contractID, contractGasBalance, gasPayerBalance, executor.Gas)
+
+		// Results are logged at debug level as they are logged for every invocation applied while collapsing.
+		if len(executor.Error) > 0 {
+			resultLogger := log.Contracts("result")
+			resultLogger.Debug().
+				Hex("contract_id", contractID[:]).
+				Hex("tx_id", tx.ID[:]).
+				Hex("result", executor.Error).
+				Msg("Smart contract function returned a result.")
+		}
 
 		//logger.Info().
 		//	Uint64("gas", executor.Gas).
 		//	Uint64("gas_limit", realGasLimit).
 		//	Msg("Deducted PERLs for invoking smart contract function.")
 
+		// Sub-transactions that fail are reverted without failing the invocation that sent them.
 		for _, entry := range executor.Queue {
 			err := applyTransaction(block, ctx, entry, state)
 			if err != nil {
@@ -386,5 +1116,5 @@ func executeContractInTransactionContext(
 		}
 	}
 
-	return nil
+	return invocationErr, nil
 }
@@ -22,18 +22,23 @@
 package wavelet
 
 import (
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"io/ioutil"
 	"math/rand"
 	"sync/atomic"
 	"testing"
 
+	"github.com/decred/dcrd/dcrec/secp256k1/v2"
+	"github.com/perlin-network/life/exec"
 	"github.com/perlin-network/noise/edwards25519"
 	"github.com/perlin-network/noise/skademlia"
 	"github.com/perlin-network/wavelet/avl"
 	"github.com/perlin-network/wavelet/store"
 	"github.com/perlin-network/wavelet/sys"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/blake2b"
 )
 
 type testAccount struct {
@@ -308,6 +313,340 @@ func TestApplyBatchTransaction(t *testing.T) {
 	assert.Equal(t, finalBobBalance, uint64(100))
 }
 
+func TestApplyBatchTransactionRevert(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	alice, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	aliceID := alice.PublicKey()
+	bobID := AccountID{0xBB}
+	carolID := AccountID{0xCC}
+
+	WriteAccountBalance(state, aliceID, 100000100)
+
+	code, err := ioutil.ReadFile("testdata/dummy.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The last transfer of the batch fails, as alice is unable to afford it.
+	var batch Batch
+	assert.NoError(t, batch.AddContract(Contract{GasLimit: 100000000, Code: code}))
+	assert.NoError(t, batch.AddTransfer(buildTransferPayload(bobID, 60)))
+	assert.NoError(t, batch.AddTransfer(buildTransferPayload(carolID, 100000000)))
+
+	// Case 1 - All changes made by the entries of a failed batch are reverted, though gas is still paid for.
+	payload, err := batch.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := buildSignedTransaction(alice, sys.TagBatch, 1, block.Index+1, payload)
+
+	ctx := NewCollapseContext(state)
+	assert.Error(t, ctx.ApplyTransaction(&block, &tx))
+
+	_, exists := ctx.ReadAccountContractCode(tx.ID)
+	assert.False(t, exists)
+
+	balance, _ := ctx.ReadAccountBalance(bobID)
+	assert.EqualValues(t, 0, balance)
+
+	balance, _ = ctx.ReadAccountBalance(aliceID)
+	assert.True(t, balance < 100000100)
+	assert.True(t, balance > 100)
+
+	assert.Equal(t, []AccountID{aliceID}, ctx.accountIDs)
+
+	// Case 2 - Only the changes made by failed entries of a best-effort batch are reverted.
+	batch.BestEffort = true
+
+	payload, err = batch.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx = buildSignedTransaction(alice, sys.TagBatch, 2, block.Index+1, payload)
+
+	ctx = NewCollapseContext(state)
+	trace := new(ContractTrace)
+
+	if !assert.NoError(t, ctx.TraceTransaction(&block, &tx, trace)) {
+		return
+	}
+
+	_, exists = ctx.ReadAccountContractCode(tx.ID)
+	assert.True(t, exists)
+
+	balance, _ = ctx.ReadAccountBalance(bobID)
+	assert.EqualValues(t, 60, balance)
+
+	balance, _ = ctx.ReadAccountBalance(carolID)
+	assert.EqualValues(t, 0, balance)
+
+	if assert.Len(t, trace.Batch, 3) {
+		assert.Equal(t, sys.TagContract, trace.Batch[0].Tag)
+		assert.Empty(t, trace.Batch[0].Error)
+		assert.Empty(t, trace.Batch[1].Error)
+		assert.Equal(t, sys.TagTransfer, trace.Batch[2].Tag)
+		assert.NotEmpty(t, trace.Batch[2].Error)
+	}
+
+	assert.NoError(t, ctx.Flush())
+
+	// Case 3 - The outcome of each entry of a best-effort batch is stored, whether or not the batch is traced.
+	WriteAccountBalance(state, aliceID, 100000100)
+
+	tx = buildSignedTransaction(alice, sys.TagBatch, 3, block.Index+1, payload)
+	if !assert.NoError(t, ApplyTransaction(state, &block, &tx)) {
+		return
+	}
+
+	results, exists := ReadBatchResults(state, tx.ID)
+	if assert.True(t, exists) && assert.Len(t, results, 3) {
+		assert.Equal(t, sys.TagContract, results[0].Tag)
+		assert.Empty(t, results[0].Error)
+		assert.Equal(t, sys.TagTransfer, results[1].Tag)
+		assert.Empty(t, results[1].Error)
+		assert.Equal(t, sys.TagTransfer, results[2].Tag)
+		assert.NotEmpty(t, results[2].Error)
+	}
+}
+
+func TestApplyFailedTransactionRevert(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	alice, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	WriteAccountBalance(state, alice.PublicKey(), 100)
+
+	code, err := ioutil.ReadFile("testdata/dummy.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// The code and owner of the smart contract are written before its gas deposit fails to be paid.
+	payload, err := Contract{GasLimit: 1, GasDeposit: 1000, Code: code}.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := buildSignedTransaction(alice, sys.TagContract, 1, block.Index+1, payload)
+
+	ctx := NewCollapseContext(state)
+	assert.Error(t, ctx.ApplyTransaction(&block, &tx))
+
+	_, exists := ctx.ReadAccountContractCode(tx.ID)
+	assert.False(t, exists)
+
+	_, exists = ctx.ReadAccountContractOwner(tx.ID)
+	assert.False(t, exists)
+
+	assert.Empty(t, ctx.accountIDs)
+	assert.Empty(t, ctx.contractCodeIDs)
+}
+
+func TestApplySubTransactionRevert(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	alice, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	WriteAccountBalance(state, alice.PublicKey(), 1000000000)
+
+	code, err := ioutil.ReadFile("testdata/forward.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	payload, err := Contract{GasLimit: 100000, Code: code}.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	spawn := buildSignedTransaction(alice, sys.TagContract, 1, block.Index+1, payload)
+	if !assert.NoError(t, ApplyTransaction(state, &block, &spawn)) {
+		return
+	}
+
+	contractID := spawn.ID
+	WriteAccountBalance(state, contractID, 100)
+
+	var nonce uint64 = 1
+
+	// forward has the smart contract send a batch as a sub-transaction.
+	forward := func(batch Batch) error {
+		marshaled, err := batch.Marshal()
+		if err != nil {
+			return err
+		}
+
+		transfer := buildTransferPayload(contractID, 1)
+		transfer.GasLimit = 100000
+		transfer.FuncName = []byte("forward")
+		transfer.FuncParams = append([]byte{byte(sys.TagBatch)}, marshaled...)
+
+		payload, err := transfer.Marshal()
+		if err != nil {
+			return err
+		}
+
+		nonce++
+		tx := buildSignedTransaction(alice, sys.TagTransfer, nonce, block.Index+1, payload)
+
+		return ApplyTransaction(state, &block, &tx)
+	}
+
+	// Case 1 - A sub-transaction that succeeds is applied.
+	var batch Batch
+	assert.NoError(t, batch.AddTransfer(buildTransferPayload(AccountID{0xBB}, 10)))
+	assert.NoError(t, forward(batch))
+
+	balance, _ := ReadAccountBalance(state, AccountID{0xBB})
+	assert.EqualValues(t, 10, balance)
+
+	balance, _ = ReadAccountBalance(state, contractID)
+	assert.EqualValues(t, 91, balance)
+
+	// Case 2 - A sub-transaction that fails is reverted without failing the invocation that sent it.
+	assert.NoError(t, batch.AddTransfer(buildTransferPayload(AccountID{0xCC}, 1000)))
+	assert.NoError(t, forward(batch))
+
+	balance, _ = ReadAccountBalance(state, AccountID{0xBB})
+	assert.EqualValues(t, 10, balance)
+
+	balance, _ = ReadAccountBalance(state, contractID)
+	assert.EqualValues(t, 92, balance)
+}
+
+func TestApplyRevertedGasFromContractGasBalance(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	alice, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	aliceID := alice.PublicKey()
+	WriteAccountBalance(state, aliceID, 1000)
+
+	code, err := ioutil.ReadFile("testdata/forward.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	contractID := AccountID{0xAA}
+	WriteAccountContractCode(state, contractID, code)
+	WriteAccountContractGasBalance(state, contractID, 1000000)
+
+	// The smart contract forwards an empty batch, after which the batch fails as alice is unable to afford its last
+	// transfer.
+	var forwarded Batch
+
+	marshaled, err := forwarded.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	invoke := buildTransferPayload(contractID, 1)
+	invoke.GasLimit = 100000
+	invoke.FuncName = []byte("forward")
+	invoke.FuncParams = append([]byte{byte(sys.TagBatch)}, marshaled...)
+
+	var batch Batch
+	assert.NoError(t, batch.AddTransfer(invoke))
+	assert.NoError(t, batch.AddTransfer(buildTransferPayload(AccountID{0xBB}, 1000)))
+
+	payload, err := batch.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := buildSignedTransaction(alice, sys.TagBatch, 1, block.Index+1, payload)
+
+	ctx := NewCollapseContext(state)
+	assert.Error(t, ctx.ApplyTransaction(&block, &tx))
+
+	// The gas consumed by the reverted invocation is paid for out of the gas balance of the smart contract alone.
+	balance, _ := ctx.ReadAccountBalance(aliceID)
+	assert.EqualValues(t, 1000, balance)
+
+	gasBalance, _ := ctx.ReadAccountContractGasBalance(contractID)
+	assert.True(t, gasBalance < 1000000)
+
+	balance, _ = ctx.ReadAccountBalance(contractID)
+	assert.EqualValues(t, 0, balance)
+}
+
+func TestApplyTransferToFailedInvocationRevert(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	alice, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	aliceID := alice.PublicKey()
+	WriteAccountBalance(state, aliceID, 1000)
+
+	code, err := ioutil.ReadFile("testdata/forward.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	contractID := AccountID{0xAA}
+	WriteAccountContractCode(state, contractID, code)
+
+	// The invocation fails as it exceeds its gas limit.
+	transfer := buildTransferPayload(contractID, 100)
+	transfer.GasLimit = 1
+	transfer.GasDeposit = 10
+	transfer.FuncName = []byte("forward")
+
+	payload, err := transfer.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := buildSignedTransaction(alice, sys.TagTransfer, 1, block.Index+1, payload)
+	if !assert.NoError(t, ApplyTransaction(state, &block, &tx)) {
+		return
+	}
+
+	// The PERLs and gas deposit transferred are returned to alice, who still pays for the gas consumed.
+	balance, _ := ReadAccountBalance(state, aliceID)
+	assert.EqualValues(t, 999, balance)
+
+	balance, _ = ReadAccountBalance(state, contractID)
+	assert.EqualValues(t, 0, balance)
+
+	gasBalance, _ := ReadAccountContractGasBalance(state, contractID)
+	assert.EqualValues(t, 0, gasBalance)
+}
+
 func TestApplyContractTransaction(t *testing.T) {
 	t.Parallel()
 
@@ -549,3 +888,1043 @@ func buildSignedTransaction(keys *skademlia.Keypair, tag sys.Tag, nonce uint64,
 		tag, payload, signature,
 	)
 }
+
+func TestContractExecutorStorage(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+
+	var id AccountID
+	id[0] = 1
+
+	WriteAccountContractStorage(state, id, []byte("key"), []byte("old"))
+
+	ctx := NewCollapseContext(state)
+	executor := &ContractExecutor{ID: id, Context: ctx}
+
+	vm := &exec.VirtualMachine{
+		Config:    exec.VMConfig{GasLimit: 1000000},
+		Memory:    make([]byte, PageSize),
+		CallStack: make([]exec.Frame, 1),
+	}
+
+	call := func(field string, locals ...int64) int64 {
+		vm.CallStack[0].Locals = locals
+		return executor.ResolveHostFunc("env", field)(lifeHostCall{vm: vm})
+	}
+
+	copy(vm.Memory[0:], "key")
+	copy(vm.Memory[16:], "new")
+
+	// Reads fall through to the collapse context.
+	assert.EqualValues(t, 3, call("_storage_get", 0, 3, 32, 8))
+	assert.Equal(t, []byte("old"), vm.Memory[32:35])
+
+	// Writes are only visible to the executor until they are applied.
+	assert.EqualValues(t, 0, call("_storage_set", 0, 3, 16, 3))
+	assert.EqualValues(t, 3, call("_storage_get", 0, 3, 32, 8))
+	assert.Equal(t, []byte("new"), vm.Memory[32:35])
+
+	value, _ := ctx.ReadAccountContractStorage(id, []byte("key"))
+	assert.Equal(t, []byte("old"), value)
+
+	assert.EqualValues(t, 0, call("_storage_delete", 0, 3))
+	assert.EqualValues(t, 1, call("_storage_delete", 0, 3))
+	assert.EqualValues(t, -1, call("_storage_get", 0, 3, 32, 8))
+
+	if assert.Len(t, executor.StorageWrites, 1) {
+		assert.True(t, executor.StorageWrites[0].Deleted)
+	}
+
+	assert.True(t, vm.Gas > 0)
+
+	// Keys exceeding the maximum size abort execution.
+	assert.Panics(t, func() { call("_storage_get", 0, int64(sys.ContractMaxStorageKeySize+1), 32, 8) })
+}
+
+func TestContractExecutorStorageGas(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+
+	var id AccountID
+	id[0] = 1
+
+	executor := &ContractExecutor{ID: id, Context: NewCollapseContext(state)}
+
+	vm := &exec.VirtualMachine{
+		Config:    exec.VMConfig{GasLimit: 1000000},
+		Memory:    make([]byte, PageSize),
+		CallStack: make([]exec.Frame, 1),
+	}
+
+	gasUsed := func(field string, locals ...int64) uint64 {
+		vm.Gas = 0
+		vm.CallStack[0].Locals = locals
+		executor.ResolveHostFunc("env", field)(lifeHostCall{vm: vm})
+
+		return vm.Gas
+	}
+
+	copy(vm.Memory[0:], "key")
+
+	// Storing a value costs gas for the write, and for every byte of its key and value.
+	small := gasUsed("_storage_set", 0, 3, 16, 1)
+	large := gasUsed("_storage_set", 0, 3, 16, 100)
+
+	assert.Equal(t, sys.GasTable["wavelet.storage.set"]+sys.GasTable["wavelet.storage.byte"]*4, small)
+	assert.Equal(t, small+sys.GasTable["wavelet.storage.byte"]*99, large)
+
+	// Reading a value back costs gas for every byte read.
+	assert.Equal(t, sys.GasTable["wavelet.storage.get"]+sys.GasTable["wavelet.storage.byte"]*103,
+		gasUsed("_storage_get", 0, 3, 128, 100),
+	)
+
+	assert.Equal(t, sys.GasTable["wavelet.storage.delete"]+sys.GasTable["wavelet.storage.byte"]*3,
+		gasUsed("_storage_delete", 0, 3),
+	)
+}
+
+func TestContractExecutorChainContext(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+
+	var contractID, callerID, otherID AccountID
+	contractID[0], callerID[0], otherID[0] = 1, 2, 3
+
+	WriteAccountBalance(state, callerID, 100)
+	WriteAccountStake(state, callerID, 200)
+	WriteAccountBalance(state, otherID, 300)
+	WriteAccountStake(state, otherID, 400)
+	WriteAccountBalance(state, contractID, 500)
+	WriteAccountContractGasBalance(state, contractID, 600)
+
+	ctx := NewCollapseContext(state)
+
+	// Writes made earlier within the same block must be visible to contracts.
+	ctx.WriteAccountBalance(otherID, 301)
+
+	block := NewBlock(42, state.Checksum())
+
+	executor := &ContractExecutor{ID: contractID, Context: ctx}
+	executor.block = &block
+	executor.tx = &Transaction{Sender: callerID}
+
+	vm := &exec.VirtualMachine{
+		Config:    exec.VMConfig{GasLimit: 1000000},
+		Memory:    make([]byte, PageSize),
+		CallStack: make([]exec.Frame, 1),
+	}
+
+	call := func(field string, locals ...int64) int64 {
+		vm.CallStack[0].Locals = locals
+		return executor.ResolveHostFunc("env", field)(lifeHostCall{vm: vm})
+	}
+
+	assert.EqualValues(t, 42, call("_block_height"))
+
+	assert.EqualValues(t, 0, call("_block_id", 0, SizeBlockID))
+	assert.Equal(t, block.ID[:], vm.Memory[:SizeBlockID])
+	assert.EqualValues(t, 1, call("_block_id", 0, SizeBlockID-1))
+
+	assert.EqualValues(t, 100, call("_caller_balance"))
+	assert.EqualValues(t, 200, call("_caller_stake"))
+
+	copy(vm.Memory[64:], otherID[:])
+	assert.EqualValues(t, 301, call("_account_balance", 64, SizeAccountID))
+	assert.EqualValues(t, 400, call("_account_stake", 64, SizeAccountID))
+	assert.EqualValues(t, -1, call("_account_balance", 64, 4))
+
+	assert.EqualValues(t, 500, call("_self_balance"))
+	assert.EqualValues(t, 600, call("_self_gas_balance"))
+
+	// The seed must be deterministic for a given block and contract.
+	assert.EqualValues(t, 0, call("_block_seed", 128, 32))
+	seed := append([]byte{}, vm.Memory[128:160]...)
+
+	assert.EqualValues(t, 0, call("_block_seed", 128, 32))
+	assert.Equal(t, seed, vm.Memory[128:160])
+	assert.NotEqual(t, make([]byte, 32), seed)
+
+	other := NewBlock(43, state.Checksum())
+	executor.block = &other
+
+	assert.EqualValues(t, 0, call("_block_seed", 128, 32))
+	assert.NotEqual(t, seed, vm.Memory[128:160])
+}
+
+// callCryptoContract invokes fn of testdata/crypto.wasm, which passes each input to the host function fn wraps as a
+// pointer and length pair, followed by a pointer and length pair to an output buffer of outLen bytes should outLen
+// not be zero. It returns the value returned by the host function along with the contents of the output buffer.
+func callCryptoContract(t *testing.T, fn string, outLen int, inputs ...[]byte) (uint32, []byte) {
+	t.Helper()
+
+	code, err := ioutil.ReadFile("testdata/crypto.wasm")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// The payload of the invocation is laid out at the start of memory, with its parameters following its 112-byte
+	// header. The host function returns into the first 4 bytes of memory, followed by the output buffer.
+	const headerSize = 8 + 32 + 32 + 32 + 8
+
+	numArgs := 2 * len(inputs)
+	if outLen > 0 {
+		numArgs += 2
+	}
+
+	args := make([]byte, 4*(numArgs+2))
+	offset := headerSize + len(args)
+
+	var data []byte
+
+	for i, input := range inputs {
+		binary.LittleEndian.PutUint32(args[8*i:], uint32(offset+len(data)))
+		binary.LittleEndian.PutUint32(args[8*i+4:], uint32(len(input)))
+
+		data = append(data, input...)
+	}
+
+	if outLen > 0 {
+		binary.LittleEndian.PutUint32(args[8*len(inputs):], 4)
+		binary.LittleEndian.PutUint32(args[8*len(inputs)+4:], uint32(outLen))
+	}
+
+	binary.LittleEndian.PutUint32(args[4*numArgs+4:], uint32(4+outLen))
+
+	block := NewBlock(1, ZeroMerkleNodeID)
+	executor := &ContractExecutor{}
+
+	_, err = executor.Execute(
+		AccountID{1}, &block, &Transaction{}, 0, 1000000, fn, append(args, data...), code,
+		avl.New(store.NewInmem()), NewVMLRU(1), nil,
+	)
+	if !assert.NoError(t, err) || !assert.Len(t, executor.Error, 4+outLen) {
+		t.FailNow()
+	}
+
+	return binary.LittleEndian.Uint32(executor.Error), executor.Error[4:]
+}
+
+func TestContractCryptoHostFunctions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("keccak256", func(t *testing.T) {
+		ret, out := callCryptoContract(t, "keccak256", 32, []byte("abc"))
+		assert.EqualValues(t, 0, ret)
+		assert.Equal(t, "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45", hex.EncodeToString(out))
+
+		ret, out = callCryptoContract(t, "keccak256", 32, nil)
+		assert.EqualValues(t, 0, ret)
+		assert.Equal(t, "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470", hex.EncodeToString(out))
+
+		ret, _ = callCryptoContract(t, "keccak256", 31, []byte("abc"))
+		assert.EqualValues(t, 1, ret)
+	})
+
+	seed := blake2b.Sum256([]byte("secp256k1"))
+	priv, pub := secp256k1.PrivKeyFromBytes(seed[:])
+	hash := blake2b.Sum256([]byte("hello"))
+
+	compact, err := secp256k1.SignCompact(priv, hash[:], false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Compact signatures are laid out as [27 + recovery ID | R | S], whereas the host expects [R | S | recovery ID].
+	sig := compact[1:]
+	recoverable := append(append([]byte{}, sig...), compact[0]-27)
+
+	t.Run("verify_secp256k1", func(t *testing.T) {
+		for _, key := range [][]byte{pub.SerializeCompressed(), pub.SerializeUncompressed()} {
+			ret, _ := callCryptoContract(t, "verify_secp256k1", 0, key, hash[:], sig)
+			assert.EqualValues(t, 0, ret)
+		}
+
+		other := blake2b.Sum256([]byte("world"))
+
+		ret, _ := callCryptoContract(t, "verify_secp256k1", 0, pub.SerializeCompressed(), other[:], sig)
+		assert.EqualValues(t, 1, ret)
+
+		ret, _ = callCryptoContract(t, "verify_secp256k1", 0, pub.SerializeCompressed(), hash[:], recoverable)
+		assert.EqualValues(t, 1, ret)
+
+		ret, _ = callCryptoContract(t, "verify_secp256k1", 0, []byte{0x02}, hash[:], sig)
+		assert.EqualValues(t, 1, ret)
+	})
+
+	t.Run("recover_secp256k1", func(t *testing.T) {
+		ret, out := callCryptoContract(t, "recover_secp256k1", 65, hash[:], recoverable)
+		assert.EqualValues(t, 0, ret)
+		assert.Equal(t, pub.SerializeUncompressed(), out)
+
+		// Recovery IDs may also be offset by 27.
+		recoverable := append(append([]byte{}, sig...), compact[0])
+
+		ret, out = callCryptoContract(t, "recover_secp256k1", 33, hash[:], recoverable)
+		assert.EqualValues(t, 0, ret)
+		assert.Equal(t, pub.SerializeCompressed(), out)
+
+		recoverable[64] = 2
+
+		ret, _ = callCryptoContract(t, "recover_secp256k1", 33, hash[:], recoverable)
+		assert.EqualValues(t, 1, ret)
+
+		ret, _ = callCryptoContract(t, "recover_secp256k1", 32, hash[:], recoverable)
+		assert.EqualValues(t, 1, ret)
+	})
+
+	t.Run("verify_ed25519_batch", func(t *testing.T) {
+		var batch []byte
+
+		for i := 0; i < 3; i++ {
+			publicKey, privateKey, err := edwards25519.GenerateKey(nil)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			msg := make([]byte, i*10)
+			_, _ = rand.Read(msg)
+
+			signature := edwards25519.Sign(privateKey, msg)
+
+			var size [4]byte
+			binary.LittleEndian.PutUint32(size[:], uint32(len(msg)))
+
+			batch = append(batch, publicKey[:]...)
+			batch = append(batch, signature[:]...)
+			batch = append(batch, size[:]...)
+			batch = append(batch, msg...)
+		}
+
+		ret, _ := callCryptoContract(t, "verify_ed25519_batch", 0, batch)
+		assert.EqualValues(t, 0, ret)
+
+		// A batch is only valid should all of its signatures be valid.
+		tampered := append([]byte{}, batch...)
+		tampered[len(tampered)-1]++
+
+		ret, _ = callCryptoContract(t, "verify_ed25519_batch", 0, tampered)
+		assert.EqualValues(t, 1, ret)
+
+		// Malformed batches are invalid.
+		ret, _ = callCryptoContract(t, "verify_ed25519_batch", 0, batch[:len(batch)-1])
+		assert.EqualValues(t, 1, ret)
+	})
+}
+
+func TestApplyContractTransactionWithABI(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	account, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	WriteAccountBalance(state, account.PublicKey(), 1000000)
+
+	code, err := ioutil.ReadFile("testdata/transfer_back.wasm")
+	assert.NoError(t, err)
+
+	var nonce uint64
+
+	// Case 1 - Invalid ABI
+	payload, err := buildContractSpawnPayload(100000, 0, appendWasmCustomSection(code, ContractABISection, []byte("{"))).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := buildSignedTransaction(account, sys.TagContract, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+	assert.Error(t, ApplyTransaction(state, &block, &tx))
+
+	// Case 2 - Valid ABI
+	payload, err = buildContractSpawnPayload(100000, 0, appendWasmCustomSection(code, ContractABISection, []byte(testABI))).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx = buildSignedTransaction(account, sys.TagContract, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+	assert.NoError(t, ApplyTransaction(state, &block, &tx))
+
+	stored, exists := ReadAccountContractCode(state, tx.ID)
+	if assert.True(t, exists) {
+		abi, err := ReadContractABI(stored)
+		assert.NoError(t, err)
+		assert.Len(t, abi.Functions, 2)
+	}
+}
+
+func TestApplyContractTransactionFromCodeID(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	account, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	WriteAccountBalance(state, account.PublicKey(), 1000000000)
+
+	code, err := ioutil.ReadFile("testdata/transfer_back.wasm")
+	assert.NoError(t, err)
+
+	codeID := HashContractCode(code)
+
+	var nonce uint64
+
+	spawn := func(ctx *CollapseContext, codeID ContractCodeID) (TransactionID, error) {
+		payload, err := Contract{GasLimit: 100000, CodeID: codeID}.Marshal()
+		if err != nil {
+			return ZeroTransactionID, err
+		}
+
+		tx := buildSignedTransaction(account, sys.TagContract, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+
+		return tx.ID, ctx.ApplyTransaction(&block, &tx)
+	}
+
+	// Case 1 - Code that has not been uploaded may not be spawned from.
+	_, err = spawn(NewCollapseContext(state), codeID)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "does not exist")
+	}
+
+	// Case 2 - Code uploaded may be spawned from within the same context, and is stored only once.
+	ctx := NewCollapseContext(state)
+
+	payload, err := buildContractSpawnPayload(100000, 0, code).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := buildSignedTransaction(account, sys.TagContract, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+	assert.NoError(t, ctx.ApplyTransaction(&block, &tx))
+
+	spawned, err := spawn(ctx, codeID)
+	assert.NoError(t, err)
+	assert.NoError(t, ctx.Flush())
+
+	for _, id := range []TransactionID{tx.ID, spawned} {
+		stored, exists := ReadAccountContractCode(state, id)
+		assert.True(t, exists)
+		assert.Equal(t, code, stored)
+
+		numPages, _ := ReadAccountContractNumPages(state, id)
+		assert.NotZero(t, numPages)
+	}
+
+	assert.Equal(t, 1, countContractCode(state))
+
+	// Case 3 - Code outlives the contracts spawned from it.
+	payload, err = ContractLifecycle{Opcode: sys.DestroyContract, ContractID: tx.ID}.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	destroy := buildSignedTransaction(account, sys.TagContractLifecycle, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+	assert.NoError(t, ApplyTransaction(state, &block, &destroy))
+
+	ctx = NewCollapseContext(state)
+
+	spawned, err = spawn(ctx, codeID)
+	assert.NoError(t, err)
+	assert.NoError(t, ctx.Flush())
+
+	stored, exists := ReadAccountContractCode(state, spawned)
+	assert.True(t, exists)
+	assert.Equal(t, code, stored)
+}
+
+func TestApplyContractLifecycleTransaction(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	owner, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	other, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	WriteAccountBalance(state, owner.PublicKey(), 1000000000)
+	WriteAccountBalance(state, other.PublicKey(), 1000000000)
+
+	code, err := ioutil.ReadFile("testdata/transfer_back.wasm")
+	assert.NoError(t, err)
+
+	migrateCode, err := ioutil.ReadFile("testdata/migrate.wasm")
+	assert.NoError(t, err)
+
+	var nonce uint64
+
+	payload, err := buildContractSpawnPayload(100000, 0, code).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := buildSignedTransaction(owner, sys.TagContract, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+	assert.NoError(t, ApplyTransaction(state, &block, &tx))
+
+	contractID := tx.ID
+
+	recorded, exists := ReadAccountContractOwner(state, contractID)
+	assert.True(t, exists)
+	assert.Equal(t, AccountID(owner.PublicKey()), recorded)
+
+	numPages, _ := ReadAccountContractNumPages(state, contractID)
+
+	upgrade := func(keys *skademlia.Keypair, gasLimit uint64, code []byte) error {
+		payload, err := ContractLifecycle{
+			Opcode:     sys.UpgradeContract,
+			ContractID: contractID,
+			GasLimit:   gasLimit,
+			Code:       code,
+		}.Marshal()
+		if err != nil {
+			return err
+		}
+
+		tx := buildSignedTransaction(keys, sys.TagContractLifecycle, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+
+		return ApplyTransaction(state, &block, &tx)
+	}
+
+	// Case 1 - Only the owner may upgrade the contract.
+	assert.Error(t, upgrade(other, 100000, migrateCode))
+
+	// Case 2 - Failing to migrate rejects the upgrade.
+	assert.Error(t, upgrade(owner, 0, migrateCode))
+
+	stored, _ := ReadAccountContractCode(state, contractID)
+	assert.Equal(t, code, stored)
+
+	// Case 3 - Upgrading keeps memory, lays the new data segments over it, and runs the migration.
+	assert.NoError(t, upgrade(owner, 100000, migrateCode))
+
+	stored, _ = ReadAccountContractCode(state, contractID)
+	assert.Equal(t, migrateCode, stored)
+
+	mem := LoadContractMemorySnapshot(state, contractID)
+	assert.Len(t, mem, int(numPages)*PageSize)
+	assert.Equal(t, []byte{42, 0, 0, 0}, mem[0:4])
+	assert.Equal(t, []byte("hi"), mem[16:18])
+
+	// Case 4 - Only the owner may destroy the contract, upon which its gas balance is refunded.
+	destroy := func(keys *skademlia.Keypair) error {
+		payload, err := ContractLifecycle{Opcode: sys.DestroyContract, ContractID: contractID}.Marshal()
+		if err != nil {
+			return err
+		}
+
+		tx := buildSignedTransaction(keys, sys.TagContractLifecycle, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+
+		return ApplyTransaction(state, &block, &tx)
+	}
+
+	assert.Error(t, destroy(other))
+
+	WriteAccountContractGasBalance(state, contractID, 1337)
+	balance, _ := ReadAccountBalance(state, owner.PublicKey())
+
+	assert.NoError(t, destroy(owner))
+
+	finalBalance, _ := ReadAccountBalance(state, owner.PublicKey())
+	assert.Equal(t, balance+1337, finalBalance)
+
+	_, exists = ReadAccountContractCode(state, contractID)
+	assert.False(t, exists)
+	_, exists = ReadAccountContractGasBalance(state, contractID)
+	assert.False(t, exists)
+	_, exists = ReadAccountContractNumPages(state, contractID)
+	assert.False(t, exists)
+	_, exists = ReadAccountContractOwner(state, contractID)
+	assert.False(t, exists)
+
+	// Case 5 - Destroyed contracts may no longer be upgraded nor destroyed.
+	assert.Error(t, upgrade(owner, 100000, migrateCode))
+	assert.Error(t, destroy(owner))
+}
+
+func TestApplyWithdrawContractGas(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	owner, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	other, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	WriteAccountBalance(state, owner.PublicKey(), 1000000000)
+
+	code, err := ioutil.ReadFile("testdata/transfer_back.wasm")
+	assert.NoError(t, err)
+
+	var nonce uint64
+
+	payload, err := buildContractSpawnPayload(100000, 0, code).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := buildSignedTransaction(owner, sys.TagContract, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+	assert.NoError(t, ApplyTransaction(state, &block, &tx))
+
+	contractID := tx.ID
+
+	WriteAccountContractGasBalance(state, contractID, 1000)
+
+	withdraw := func(keys *skademlia.Keypair, amount uint64, recipient AccountID) error {
+		payload, err := ContractLifecycle{
+			Opcode:     sys.WithdrawContractGas,
+			ContractID: contractID,
+			Amount:     amount,
+			Recipient:  recipient,
+		}.Marshal()
+		if err != nil {
+			return err
+		}
+
+		tx := buildSignedTransaction(keys, sys.TagContractLifecycle, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+
+		return ApplyTransaction(state, &block, &tx)
+	}
+
+	// Case 1 - Only the owner may withdraw gas.
+	assert.Error(t, withdraw(other, 100, other.PublicKey()))
+
+	// Case 2 - Withdrawing more than the gas balance fails.
+	assert.Error(t, withdraw(owner, 1001, owner.PublicKey()))
+
+	// Case 3 - The owner may withdraw gas into any account.
+	assert.NoError(t, withdraw(owner, 400, other.PublicKey()))
+
+	gasBalance, _ := ReadAccountContractGasBalance(state, contractID)
+	assert.EqualValues(t, 600, gasBalance)
+
+	balance, _ := ReadAccountBalance(state, other.PublicKey())
+	assert.EqualValues(t, 400, balance)
+
+	// Case 4 - The contract itself may withdraw gas through a host call, which queues a withdrawal.
+	executor := &ContractExecutor{ID: contractID}
+
+	vm := &exec.VirtualMachine{
+		Memory:    make([]byte, PageSize),
+		CallStack: make([]exec.Frame, 1),
+	}
+
+	recipient := other.PublicKey()
+	copy(vm.Memory, recipient[:])
+
+	vm.CallStack[0].Locals = []int64{600, 0, SizeAccountID}
+	assert.EqualValues(t, 0, executor.ResolveHostFunc("env", "_withdraw_gas")(lifeHostCall{vm: vm}))
+
+	vm.CallStack[0].Locals = []int64{600, 0, SizeAccountID - 1}
+	assert.EqualValues(t, 1, executor.ResolveHostFunc("env", "_withdraw_gas")(lifeHostCall{vm: vm}))
+
+	if assert.Len(t, executor.Queue, 1) {
+		assert.NoError(t, ApplyTransaction(state, &block, executor.Queue[0]))
+	}
+
+	gasBalance, _ = ReadAccountContractGasBalance(state, contractID)
+	assert.EqualValues(t, 0, gasBalance)
+
+	balance, _ = ReadAccountBalance(state, other.PublicKey())
+	assert.EqualValues(t, 1000, balance)
+}
+
+func TestApplyAssetTransaction(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+	block := NewBlock(0, state.Checksum())
+
+	issuer, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	other, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	WriteAccountBalance(state, issuer.PublicKey(), 1000000)
+	WriteAccountBalance(state, other.PublicKey(), 1000000)
+
+	var nonce uint64
+
+	apply := func(keys *skademlia.Keypair, tag sys.Tag, m interface{ Marshal() ([]byte, error) }) (TransactionID, error) {
+		payload, err := m.Marshal()
+		if err != nil {
+			return ZeroTransactionID, err
+		}
+
+		tx := buildSignedTransaction(keys, tag, atomic.AddUint64(&nonce, 1), block.Index+1, payload)
+
+		return tx.ID, ApplyTransaction(state, &block, &tx)
+	}
+
+	assetID, err := apply(issuer, sys.TagAsset, Asset{Opcode: sys.CreateAsset, Symbol: "GOLD", Decimals: 2, Supply: 1000})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	info, exists := ReadAsset(state, assetID)
+	assert.True(t, exists)
+	assert.Equal(t, AssetInfo{Issuer: issuer.PublicKey(), Symbol: "GOLD", Decimals: 2, Supply: 1000}, info)
+
+	balance, _ := ReadAccountAssetBalance(state, issuer.PublicKey(), assetID)
+	assert.EqualValues(t, 1000, balance)
+
+	// Case 1 - Any account may transfer an asset it holds.
+	_, err = apply(issuer, sys.TagAsset, Asset{
+		Opcode: sys.TransferAsset, AssetID: assetID, Recipient: other.PublicKey(), Amount: 300,
+	})
+	assert.NoError(t, err)
+
+	_, err = apply(other, sys.TagAsset, Asset{
+		Opcode: sys.TransferAsset, AssetID: assetID, Recipient: issuer.PublicKey(), Amount: 301,
+	})
+	assert.Error(t, err)
+
+	// Case 2 - Only the issuer may mint or burn an asset.
+	_, err = apply(other, sys.TagAsset, Asset{
+		Opcode: sys.MintAsset, AssetID: assetID, Recipient: other.PublicKey(), Amount: 100,
+	})
+	assert.Error(t, err)
+
+	_, err = apply(other, sys.TagAsset, Asset{Opcode: sys.BurnAsset, AssetID: assetID, Amount: 100})
+	assert.Error(t, err)
+
+	_, err = apply(issuer, sys.TagAsset, Asset{
+		Opcode: sys.MintAsset, AssetID: assetID, Recipient: other.PublicKey(), Amount: 100,
+	})
+	assert.NoError(t, err)
+
+	_, err = apply(issuer, sys.TagAsset, Asset{Opcode: sys.BurnAsset, AssetID: assetID, Amount: 701})
+	assert.Error(t, err)
+
+	_, err = apply(issuer, sys.TagAsset, Asset{Opcode: sys.BurnAsset, AssetID: assetID, Amount: 700})
+	assert.NoError(t, err)
+
+	info, _ = ReadAsset(state, assetID)
+	assert.EqualValues(t, 400, info.Supply)
+
+	// Case 3 - Assets may be transferred within a batch.
+	var batch Batch
+	assert.NoError(t, batch.AddAsset(Asset{
+		Opcode: sys.TransferAsset, AssetID: assetID, Recipient: issuer.PublicKey(), Amount: 150,
+	}))
+	assert.NoError(t, batch.AddAsset(Asset{
+		Opcode: sys.TransferAsset, AssetID: assetID, Recipient: AccountID{0xCC}, Amount: 50,
+	}))
+
+	_, err = apply(other, sys.TagBatch, batch)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []AssetBalance{{Asset: assetID, Balance: 150}}, ReadAccountAssetBalances(state, issuer.PublicKey()))
+	assert.Equal(t, []AssetBalance{{Asset: assetID, Balance: 200}}, ReadAccountAssetBalances(state, other.PublicKey()))
+	assert.Equal(t, []AssetBalance{{Asset: assetID, Balance: 50}}, ReadAccountAssetBalances(state, AccountID{0xCC}))
+}
+
+func TestApplyHTLCTransaction(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+
+	sender, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	recipient, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	WriteAccountBalance(state, sender.PublicKey(), 1000)
+
+	preimage := []byte("secret")
+	hashLock := sha256.Sum256(preimage)
+
+	var nonce uint64
+
+	apply := func(keys *skademlia.Keypair, height uint64, htlc HTLC) (TransactionID, error) {
+		payload, err := htlc.Marshal()
+		if err != nil {
+			return ZeroTransactionID, err
+		}
+
+		block := NewBlock(height, state.Checksum())
+		tx := buildSignedTransaction(keys, sys.TagHTLC, atomic.AddUint64(&nonce, 1), height, payload)
+
+		return tx.ID, ApplyTransaction(state, &block, &tx)
+	}
+
+	lock := func(height, amount, expiry uint64) (TransactionID, error) {
+		return apply(sender, height, HTLC{
+			Opcode: sys.LockHTLC, Recipient: recipient.PublicKey(), Amount: amount, HashLock: hashLock, Expiry: expiry,
+		})
+	}
+
+	// Case 1 - Locking more than the balance of the sender, or with an expiry already reached, fails.
+	_, err = lock(1, 1001, 10)
+	assert.Error(t, err)
+
+	_, err = lock(10, 100, 10)
+	assert.Error(t, err)
+
+	// Case 2 - A locked amount is held in escrow, and may be claimed by revealing the preimage before its expiry.
+	claimed, err := lock(1, 100, 10)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	balance, _ := ReadAccountBalance(state, sender.PublicKey())
+	assert.EqualValues(t, 900, balance)
+
+	_, err = apply(recipient, 2, HTLC{Opcode: sys.ClaimHTLC, HTLCID: claimed, Preimage: []byte("wrong")})
+	assert.Error(t, err)
+
+	_, err = apply(sender, 2, HTLC{Opcode: sys.RefundHTLC, HTLCID: claimed})
+	assert.Error(t, err)
+
+	_, err = apply(recipient, 9, HTLC{Opcode: sys.ClaimHTLC, HTLCID: claimed, Preimage: preimage})
+	assert.NoError(t, err)
+
+	balance, _ = ReadAccountBalance(state, recipient.PublicKey())
+	assert.EqualValues(t, 100, balance)
+
+	info, _ := ReadHTLC(state, claimed)
+	assert.Equal(t, HTLCClaimed, info.Status)
+	assert.Equal(t, preimage, info.Preimage)
+
+	// Case 3 - A transfer may only be claimed or refunded once.
+	_, err = apply(recipient, 9, HTLC{Opcode: sys.ClaimHTLC, HTLCID: claimed, Preimage: preimage})
+	assert.Error(t, err)
+
+	_, err = apply(sender, 10, HTLC{Opcode: sys.RefundHTLC, HTLCID: claimed})
+	assert.Error(t, err)
+
+	// Case 4 - An expired transfer may no longer be claimed, and is refunded to its sender.
+	refunded, err := lock(10, 200, 20)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = apply(recipient, 20, HTLC{Opcode: sys.ClaimHTLC, HTLCID: refunded, Preimage: preimage})
+	assert.Error(t, err)
+
+	_, err = apply(recipient, 20, HTLC{Opcode: sys.RefundHTLC, HTLCID: refunded})
+	assert.NoError(t, err)
+
+	balance, _ = ReadAccountBalance(state, sender.PublicKey())
+	assert.EqualValues(t, 900, balance)
+
+	info, _ = ReadHTLC(state, refunded)
+	assert.Equal(t, HTLCRefunded, info.Status)
+	assert.Nil(t, info.Preimage)
+}
+
+func TestApplyScheduleTransaction(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+
+	sender, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	recipient, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	WriteAccountBalance(state, sender.PublicKey(), 1000)
+
+	var nonce uint64
+
+	apply := func(keys *skademlia.Keypair, height uint64, schedule Schedule) (TransactionID, error) {
+		payload, err := schedule.Marshal()
+		if err != nil {
+			return ZeroTransactionID, err
+		}
+
+		block := NewBlock(height, state.Checksum())
+		tx := buildSignedTransaction(keys, sys.TagSchedule, atomic.AddUint64(&nonce, 1), height, payload)
+
+		return tx.ID, ApplyTransaction(state, &block, &tx)
+	}
+
+	create := func(height, amount, start, interval, count uint64) (TransactionID, error) {
+		return apply(sender, height, Schedule{
+			Opcode:    sys.CreateSchedule,
+			Recipient: recipient.PublicKey(),
+			Amount:    amount,
+			Start:     start,
+			Interval:  interval,
+			Count:     count,
+		})
+	}
+
+	process := func(height uint64) {
+		ctx := NewCollapseContext(state)
+		ctx.processSchedules(height)
+		assert.NoError(t, ctx.Flush())
+	}
+
+	balanceOf := func(keys *skademlia.Keypair) uint64 {
+		balance, _ := ReadAccountBalance(state, keys.PublicKey())
+		return balance
+	}
+
+	// Case 1 - Scheduling more than the balance of the sender, or starting at a block height already passed, fails.
+	_, err = create(1, 400, 5, 2, 3)
+	assert.Error(t, err)
+
+	_, err = create(10, 100, 5, 2, 3)
+	assert.Error(t, err)
+
+	// Case 2 - The PERLs of all transfers are escrowed upfront, and paid out as their block heights are reached.
+	paid, err := create(1, 100, 5, 2, 3)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.EqualValues(t, 700, balanceOf(sender))
+
+	process(4)
+	assert.EqualValues(t, 0, balanceOf(recipient))
+
+	process(5)
+	assert.EqualValues(t, 100, balanceOf(recipient))
+
+	process(8)
+	assert.EqualValues(t, 200, balanceOf(recipient))
+
+	info, exists := ReadSchedule(state, paid)
+	assert.True(t, exists)
+	assert.EqualValues(t, 9, info.Next)
+	assert.EqualValues(t, 1, info.Remaining)
+
+	// Case 3 - Only the sender may cancel a scheduled transfer, which refunds the transfers not yet paid.
+	cancelled, err := create(8, 50, 20, 10, 2)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.EqualValues(t, 600, balanceOf(sender))
+	assert.ElementsMatch(t, []TransactionID{paid, cancelled}, ReadAccountSchedules(state, sender.PublicKey()))
+
+	_, err = apply(recipient, 8, Schedule{Opcode: sys.CancelSchedule, ScheduleID: cancelled})
+	assert.Error(t, err)
+
+	_, err = apply(sender, 8, Schedule{Opcode: sys.CancelSchedule, ScheduleID: cancelled})
+	assert.NoError(t, err)
+
+	assert.EqualValues(t, 700, balanceOf(sender))
+
+	_, exists = ReadSchedule(state, cancelled)
+	assert.False(t, exists)
+
+	_, err = apply(sender, 8, Schedule{Opcode: sys.CancelSchedule, ScheduleID: cancelled})
+	assert.Error(t, err)
+
+	// Case 4 - A scheduled transfer is removed once its last transfer is paid.
+	process(9)
+	assert.EqualValues(t, 300, balanceOf(recipient))
+	assert.EqualValues(t, 700, balanceOf(sender))
+
+	_, exists = ReadSchedule(state, paid)
+	assert.False(t, exists)
+	assert.Empty(t, ReadAccountSchedules(state, sender.PublicKey()))
+	assert.Empty(t, ReadDueSchedules(state, 100))
+}
+
+func TestApplyTransferAllowance(t *testing.T) {
+	t.Parallel()
+
+	state := avl.New(store.NewInmem())
+
+	owner, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	spender, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	recipient, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	WriteAccountBalance(state, owner.PublicKey(), 1000)
+
+	var nonce uint64
+
+	apply := func(keys *skademlia.Keypair, height uint64, transfer Transfer) error {
+		payload, err := transfer.Marshal()
+		if err != nil {
+			return err
+		}
+
+		block := NewBlock(height, state.Checksum())
+		tx := buildSignedTransaction(keys, sys.TagTransfer, atomic.AddUint64(&nonce, 1), height, payload)
+
+		return ApplyTransaction(state, &block, &tx)
+	}
+
+	approve := func(height, amount, expiry uint64) error {
+		return apply(owner, height, Transfer{
+			Opcode: sys.ApproveAllowance, Recipient: spender.PublicKey(), Amount: amount, Expiry: expiry,
+		})
+	}
+
+	transferFrom := func(height, amount uint64) error {
+		return apply(spender, height, Transfer{
+			Opcode: sys.TransferFromAllowance, Owner: owner.PublicKey(), Recipient: recipient.PublicKey(), Amount: amount,
+		})
+	}
+
+	balanceOf := func(keys *skademlia.Keypair) uint64 {
+		balance, _ := ReadAccountBalance(state, keys.PublicKey())
+		return balance
+	}
+
+	// Case 1 - Nothing may be spent without an allowance, and allowances may not be approved to oneself or with an
+	// expiry already passed.
+	assert.Error(t, transferFrom(1, 1))
+
+	assert.Error(t, apply(owner, 1, Transfer{Opcode: sys.ApproveAllowance, Recipient: owner.PublicKey(), Amount: 1}))
+	assert.Error(t, approve(10, 100, 10))
+
+	// Case 2 - An allowance may be spent up to its amount, without debiting the spender.
+	assert.NoError(t, approve(1, 300, 0))
+	assert.Equal(t,
+		[]Allowance{{Spender: spender.PublicKey(), Amount: 300}}, ReadAccountAllowances(state, owner.PublicKey()),
+	)
+	assert.EqualValues(t, 1000, balanceOf(owner))
+
+	assert.NoError(t, transferFrom(2, 200))
+	assert.Error(t, transferFrom(2, 101))
+
+	assert.EqualValues(t, 800, balanceOf(owner))
+	assert.EqualValues(t, 200, balanceOf(recipient))
+	assert.EqualValues(t, 0, balanceOf(spender))
+
+	allowance, exists := ReadAccountAllowance(state, owner.PublicKey(), spender.PublicKey())
+	assert.True(t, exists)
+	assert.EqualValues(t, 100, allowance.Amount)
+
+	// Case 3 - Spending an allowance in full removes it.
+	assert.NoError(t, transferFrom(2, 100))
+	assert.Empty(t, ReadAccountAllowances(state, owner.PublicKey()))
+	assert.Error(t, transferFrom(2, 1))
+
+	// Case 4 - An allowance may not be spent beyond the balance of its owner, nor once it has expired.
+	assert.NoError(t, approve(3, 1000, 10))
+	assert.Error(t, transferFrom(3, 701))
+	assert.NoError(t, transferFrom(9, 100))
+	assert.Error(t, transferFrom(10, 100))
+
+	// Case 5 - Approving an allowance of zero revokes it.
+	assert.NoError(t, approve(10, 500, 0))
+	assert.NoError(t, approve(10, 0, 0))
+	assert.Empty(t, ReadAccountAllowances(state, owner.PublicKey()))
+	assert.Error(t, transferFrom(10, 1))
+
+	assert.EqualValues(t, 600, balanceOf(owner))
+	assert.EqualValues(t, 400, balanceOf(recipient))
+}
@@ -427,6 +427,8 @@ func parseContract(data []byte) ([]byte, error) {
 		return nil, err // Return found error
 	}
 
+	payload.WriteByte(sys.ContractCodeUploaded) // Write contract code source
+
 	_, err = payload.Write(code) // Write contract code to buffer
 	if err != nil {              // Check for errors
 		return nil, err // Return found error
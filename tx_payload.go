@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math"
 
 	"github.com/perlin-network/wavelet/sys"
 	"github.com/pkg/errors"
@@ -25,6 +26,14 @@ type (
 
 		FuncName   []byte
 		FuncParams []byte
+
+		// Opcode, should it not be TransferValue, either approves Recipient to spend up to Amount PERLs of the
+		// sender until the block height Expiry, or spends Amount PERLs out of the allowance Owner approved to the
+		// sender into the balance of Recipient. Allowance operations carry no function name nor params.
+
+		Opcode byte
+		Owner  AccountID
+		Expiry uint64
 	}
 
 	Stake struct {
@@ -38,12 +47,106 @@ type (
 
 		Params []byte
 		Code   []byte
+
+		// CodeID, should Code be empty, references code previously uploaded by the spawning of some other smart
+		// contract to spawn the smart contract from instead.
+		CodeID ContractCodeID
+	}
+
+	ContractLifecycle struct {
+		Opcode     byte
+		ContractID TransactionID
+
+		// The fields below are only used to upgrade a smart contract. Should the new code of the
+		// smart contract export a `migrate` function, it is invoked with Params under GasLimit.
+
+		GasLimit uint64
+		Params   []byte
+		Code     []byte
+
+		// The fields below are only used to withdraw some amount of gas balance from a smart contract into the
+		// balance of Recipient.
+
+		Amount    uint64
+		Recipient AccountID
+	}
+
+	// Multisig registers a multisig account, from which transactions must be signed by at least Threshold of Keys.
+	Multisig struct {
+		Threshold uint8
+		Keys      []AccountID
+	}
+
+	// KeyRotation binds Key as the key that signs transactions on behalf of the account of the sender.
+	KeyRotation struct {
+		Key AccountID
+	}
+
+	// Asset creates a native asset issued by the sender, or transfers, mints or burns some amount of an existing
+	// native asset.
+	Asset struct {
+		Opcode byte
+
+		// The fields below are only used to create an asset, whose initial Supply is credited to its issuer.
+
+		Symbol   string
+		Decimals uint8
+		Supply   uint64
+
+		// The fields below are used to transfer, mint or burn Amount of the asset AssetID. Minted and transferred
+		// amounts are credited to Recipient, while burnt amounts are debited from the issuer of the asset.
+
+		AssetID   AssetID
+		Recipient AccountID
+		Amount    uint64
+	}
+
+	// HTLC locks an amount of PERLs of the sender under a hash lock and an expiry block height, or claims or refunds
+	// the amount locked by the hash time-locked transfer HTLCID.
+	HTLC struct {
+		Opcode byte
+
+		// The fields below are only used to lock Amount, which is paid to Recipient should a preimage whose SHA-256
+		// hash is HashLock be revealed before the block height Expiry, and refunded to the sender otherwise.
+
+		Recipient AccountID
+		Amount    uint64
+		HashLock  [32]byte
+		Expiry    uint64
+
+		// The fields below are only used to claim or refund a hash time-locked transfer. Preimage is only used to
+		// claim it.
+
+		HTLCID   TransactionID
+		Preimage []byte
+	}
+
+	// Schedule registers a scheduled transfer of Amount PERLs to Recipient, paid Count times every Interval blocks
+	// starting from the block height Start, or cancels the scheduled transfer ScheduleID.
+	Schedule struct {
+		Opcode byte
+
+		// The fields below are only used to register a scheduled transfer, whose Amount times Count PERLs are
+		// escrowed from the balance of the sender upon being registered.
+
+		Recipient AccountID
+		Amount    uint64
+		Start     uint64
+		Interval  uint64
+		Count     uint64
+
+		// ScheduleID is only used to cancel a scheduled transfer.
+		ScheduleID TransactionID
 	}
 
 	Batch struct {
 		Size     uint8
 		Tags     []uint8
 		Payloads [][]byte
+
+		// BestEffort, if set, has each entry of the batch applied on its own, such that entries which fail are
+		// reverted and skipped rather than having the entire batch reverted and rejected.
+		BestEffort bool
 	}
 )
 
@@ -86,6 +189,10 @@ func ParseTransfer(payload []byte) (Transfer, error) {
 		}
 
 		size := binary.LittleEndian.Uint32(b[:4])
+		if size == transferAllowanceMarker {
+			return transfer, parseTransferAllowance(r, &transfer)
+		}
+
 		if size > 1024 {
 			return transfer, errors.New("transfer: smart contract function name exceeds 1024 characters")
 		}
@@ -131,6 +238,54 @@ func ParseTransfer(payload []byte) (Transfer, error) {
 	return transfer, nil
 }
 
+// transferAllowanceMarker takes the place of the size of the smart contract function name in the payload of a
+// transfer transaction to mark the transfer as an allowance operation, whose opcode and parameters follow in place of
+// the function name and params. As function names may not exceed 1024 characters, no payload of a transfer that
+// does not operate on an allowance is ever parsed as one.
+const transferAllowanceMarker = math.MaxUint32
+
+// parseTransferAllowance parses the allowance operation following the allowance marker in the payload of a transfer
+// transaction.
+func parseTransferAllowance(r *bytes.Reader, transfer *Transfer) error {
+	opcode, err := r.ReadByte()
+	if err != nil {
+		return errors.Wrap(err, "transfer: failed to decode opcode")
+	}
+
+	transfer.Opcode = opcode
+
+	switch transfer.Opcode {
+	case sys.ApproveAllowance:
+		var b [8]byte
+
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return errors.Wrap(err, "transfer: failed to decode allowance expiry")
+		}
+
+		transfer.Expiry = binary.LittleEndian.Uint64(b[:])
+	case sys.TransferFromAllowance:
+		if _, err := io.ReadFull(r, transfer.Owner[:]); err != nil {
+			return errors.Wrap(err, "transfer: failed to decode allowance owner")
+		}
+
+		if transfer.Amount == 0 {
+			return errors.New("transfer: amount to transfer from an allowance must be greater than zero")
+		}
+	default:
+		return errors.New("transfer: opcode must be 1 or 2")
+	}
+
+	if r.Len() > 0 {
+		return errors.New("transfer: allowance operations take no other parameters")
+	}
+
+	if transfer.GasLimit > 0 || transfer.GasDeposit > 0 {
+		return errors.New("transfer: allowance operations should not specify gas limit or gas deposit")
+	}
+
+	return nil
+}
+
 // ParseStake parses and performs sanity checks on the payload of a stake transaction.
 func ParseStake(payload []byte) (Stake, error) {
 	var stake Stake
@@ -191,6 +346,7 @@ func ParseContract(payload []byte) (Contract, error) {
 	}
 
 	size := binary.LittleEndian.Uint32(b[:4])
+
 	if size > 1024*1024 {
 		return contract, errors.New("contract: smart contract payload exceeds 1MB")
 	}
@@ -201,7 +357,26 @@ func ParseContract(payload []byte) (Contract, error) {
 		return contract, errors.Wrap(err, "contract: failed to decode smart contract init parameters")
 	}
 
-	var err error
+	source, err := r.ReadByte()
+	if err != nil {
+		return contract, errors.Wrap(err, "contract: failed to decode smart contract code source")
+	}
+
+	switch source {
+	case sys.ContractCodeUploaded:
+	case sys.ContractCodeReferenced:
+		if _, err := io.ReadFull(r, contract.CodeID[:]); err != nil {
+			return contract, errors.Wrap(err, "contract: failed to decode smart contract code ID")
+		}
+
+		if r.Len() != 0 {
+			return contract, errors.New("contract: smart contract spawned from a code ID must not have code")
+		}
+
+		return contract, nil
+	default:
+		return contract, errors.Errorf("contract: unknown smart contract code source %d", source)
+	}
 
 	if contract.Code, err = ioutil.ReadAll(r); err != nil {
 		return contract, errors.Wrap(err, "contract: failed to decode smart contract code")
@@ -214,6 +389,381 @@ func ParseContract(payload []byte) (Contract, error) {
 	return contract, nil
 }
 
+// ParseContractLifecycle parses and performs sanity checks on the payload of a contract lifecycle transaction.
+func ParseContractLifecycle(payload []byte) (ContractLifecycle, error) {
+	r := bytes.NewReader(payload)
+	b := make([]byte, 8)
+
+	var lifecycle ContractLifecycle
+
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return lifecycle, errors.Wrap(err, "contract_lifecycle: failed to decode opcode")
+	}
+
+	lifecycle.Opcode = b[0]
+
+	if lifecycle.Opcode > sys.WithdrawContractGas {
+		return lifecycle, errors.New("contract_lifecycle: opcode must be 0, 1, or 2")
+	}
+
+	if _, err := io.ReadFull(r, lifecycle.ContractID[:]); err != nil {
+		return lifecycle, errors.Wrap(err, "contract_lifecycle: failed to decode smart contract id")
+	}
+
+	if lifecycle.Opcode == sys.DestroyContract {
+		if r.Len() > 0 {
+			return lifecycle, errors.New("contract_lifecycle: destroying a smart contract takes no other parameters")
+		}
+
+		return lifecycle, nil
+	}
+
+	if lifecycle.Opcode == sys.WithdrawContractGas {
+		if _, err := io.ReadFull(r, b[:8]); err != nil {
+			return lifecycle, errors.Wrap(err, "contract_lifecycle: failed to decode amount of gas to withdraw")
+		}
+
+		lifecycle.Amount = binary.LittleEndian.Uint64(b)
+
+		if lifecycle.Amount == 0 {
+			return lifecycle, errors.New("contract_lifecycle: amount of gas to withdraw must be greater than zero")
+		}
+
+		if _, err := io.ReadFull(r, lifecycle.Recipient[:]); err != nil {
+			return lifecycle, errors.Wrap(err, "contract_lifecycle: failed to decode recipient")
+		}
+
+		if r.Len() > 0 {
+			return lifecycle, errors.New("contract_lifecycle: withdrawing gas takes no other parameters")
+		}
+
+		return lifecycle, nil
+	}
+
+	if _, err := io.ReadFull(r, b[:8]); err != nil {
+		return lifecycle, errors.Wrap(err, "contract_lifecycle: failed to decode gas limit")
+	}
+
+	lifecycle.GasLimit = binary.LittleEndian.Uint64(b)
+
+	if _, err := io.ReadFull(r, b[:4]); err != nil {
+		return lifecycle, errors.Wrap(
+			err, "contract_lifecycle: failed to decode number of smart contract migrate parameters",
+		)
+	}
+
+	size := binary.LittleEndian.Uint32(b[:4])
+	if size > 1024*1024 {
+		return lifecycle, errors.New("contract_lifecycle: smart contract payload exceeds 1MB")
+	}
+
+	lifecycle.Params = make([]byte, size)
+
+	if _, err := io.ReadFull(r, lifecycle.Params); err != nil {
+		return lifecycle, errors.Wrap(err, "contract_lifecycle: failed to decode smart contract migrate parameters")
+	}
+
+	var err error
+
+	if lifecycle.Code, err = ioutil.ReadAll(r); err != nil {
+		return lifecycle, errors.Wrap(err, "contract_lifecycle: failed to decode smart contract code")
+	}
+
+	if len(lifecycle.Code) == 0 {
+		return lifecycle, errors.New("contract_lifecycle: smart contract must have code of length greater than zero")
+	}
+
+	// The migrate function of the new code is invoked under the gas limit, which must hence be set.
+	if lifecycle.GasLimit == 0 {
+		migrates, err := readWasmFunctionExport(lifecycle.Code, "_contract_migrate")
+		if err != nil {
+			return lifecycle, errors.Wrap(err, "contract_lifecycle: failed to decode smart contract code")
+		}
+
+		if migrates {
+			return lifecycle, errors.New(
+				"contract_lifecycle: gas limit for migrating smart contract must be greater than zero",
+			)
+		}
+	}
+
+	return lifecycle, nil
+}
+
+// ParseMultisig parses and performs sanity checks on the payload of a multisig transaction.
+func ParseMultisig(payload []byte) (Multisig, error) {
+	var multisig Multisig
+
+	if len(payload) < 2 {
+		return multisig, errors.New("multisig: payload must be at least 2 bytes")
+	}
+
+	multisig.Threshold = payload[0]
+	numKeys := int(payload[1])
+
+	if numKeys == 0 || numKeys > sys.MultisigMaxKeys {
+		return multisig, errors.Errorf("multisig: number of keys must be between 1 and %d", sys.MultisigMaxKeys)
+	}
+
+	if multisig.Threshold == 0 || int(multisig.Threshold) > numKeys {
+		return multisig, errors.New("multisig: threshold must be between 1 and the number of keys")
+	}
+
+	if len(payload) != 2+numKeys*SizeAccountID {
+		return multisig, errors.Errorf("multisig: payload must be exactly %d bytes", 2+numKeys*SizeAccountID)
+	}
+
+	multisig.Keys = make([]AccountID, numKeys)
+
+	for i := range multisig.Keys {
+		copy(multisig.Keys[i][:], payload[2+i*SizeAccountID:])
+
+		for j := 0; j < i; j++ {
+			if multisig.Keys[j] == multisig.Keys[i] {
+				return multisig, errors.Errorf(
+					"multisig: keys must be unique, but %x is specified more than once", multisig.Keys[i],
+				)
+			}
+		}
+	}
+
+	return multisig, nil
+}
+
+// ParseKeyRotation parses and performs sanity checks on the payload of a key rotation transaction.
+func ParseKeyRotation(payload []byte) (KeyRotation, error) {
+	var rotation KeyRotation
+
+	if len(payload) != SizeAccountID {
+		return rotation, errors.Errorf("key_rotation: payload must be exactly %d bytes", SizeAccountID)
+	}
+
+	copy(rotation.Key[:], payload)
+
+	if rotation.Key == ZeroAccountID {
+		return rotation, errors.New("key_rotation: key must not be zero")
+	}
+
+	return rotation, nil
+}
+
+// ParseAsset parses and performs sanity checks on the payload of an asset transaction.
+func ParseAsset(payload []byte) (Asset, error) {
+	r := bytes.NewReader(payload)
+	b := make([]byte, 8)
+
+	var asset Asset
+
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return asset, errors.Wrap(err, "asset: failed to decode opcode")
+	}
+
+	asset.Opcode = b[0]
+
+	if asset.Opcode > sys.BurnAsset {
+		return asset, errors.New("asset: opcode must be 0, 1, 2, or 3")
+	}
+
+	if asset.Opcode == sys.CreateAsset {
+		if _, err := io.ReadFull(r, b[:1]); err != nil {
+			return asset, errors.Wrap(err, "asset: failed to decode decimals")
+		}
+
+		asset.Decimals = b[0]
+
+		if int(asset.Decimals) > sys.AssetMaxDecimals {
+			return asset, errors.Errorf("asset: decimals must not exceed %d", sys.AssetMaxDecimals)
+		}
+
+		if _, err := io.ReadFull(r, b[:8]); err != nil {
+			return asset, errors.Wrap(err, "asset: failed to decode supply")
+		}
+
+		asset.Supply = binary.LittleEndian.Uint64(b)
+
+		if _, err := io.ReadFull(r, b[:1]); err != nil {
+			return asset, errors.Wrap(err, "asset: failed to decode size of symbol")
+		}
+
+		size := int(b[0])
+		if size == 0 || size > sys.AssetMaxSymbolSize {
+			return asset, errors.Errorf("asset: symbol must be between 1 and %d characters", sys.AssetMaxSymbolSize)
+		}
+
+		symbol := make([]byte, size)
+
+		if _, err := io.ReadFull(r, symbol); err != nil {
+			return asset, errors.Wrap(err, "asset: failed to decode symbol")
+		}
+
+		asset.Symbol = string(symbol)
+
+		if r.Len() > 0 {
+			return asset, errors.New("asset: creating an asset takes no other parameters")
+		}
+
+		return asset, nil
+	}
+
+	if _, err := io.ReadFull(r, asset.AssetID[:]); err != nil {
+		return asset, errors.Wrap(err, "asset: failed to decode asset id")
+	}
+
+	if asset.Opcode != sys.BurnAsset {
+		if _, err := io.ReadFull(r, asset.Recipient[:]); err != nil {
+			return asset, errors.Wrap(err, "asset: failed to decode recipient")
+		}
+	}
+
+	if _, err := io.ReadFull(r, b[:8]); err != nil {
+		return asset, errors.Wrap(err, "asset: failed to decode amount")
+	}
+
+	asset.Amount = binary.LittleEndian.Uint64(b)
+
+	if asset.Amount == 0 {
+		return asset, errors.New("asset: amount must be greater than zero")
+	}
+
+	if r.Len() > 0 {
+		return asset, errors.New("asset: payload has trailing bytes")
+	}
+
+	return asset, nil
+}
+
+// ParseHTLC parses and performs sanity checks on the payload of a hash time-locked transfer transaction.
+func ParseHTLC(payload []byte) (HTLC, error) {
+	r := bytes.NewReader(payload)
+	b := make([]byte, 8)
+
+	var htlc HTLC
+
+	if _, err := io.ReadFull(r, b[:1]); err != nil {
+		return htlc, errors.Wrap(err, "htlc: failed to decode opcode")
+	}
+
+	htlc.Opcode = b[0]
+
+	if htlc.Opcode > sys.RefundHTLC {
+		return htlc, errors.New("htlc: opcode must be 0, 1, or 2")
+	}
+
+	if htlc.Opcode == sys.LockHTLC {
+		if _, err := io.ReadFull(r, htlc.Recipient[:]); err != nil {
+			return htlc, errors.Wrap(err, "htlc: failed to decode recipient")
+		}
+
+		if _, err := io.ReadFull(r, b[:8]); err != nil {
+			return htlc, errors.Wrap(err, "htlc: failed to decode amount")
+		}
+
+		htlc.Amount = binary.LittleEndian.Uint64(b)
+
+		if htlc.Amount == 0 {
+			return htlc, errors.New("htlc: amount must be greater than zero")
+		}
+
+		if _, err := io.ReadFull(r, htlc.HashLock[:]); err != nil {
+			return htlc, errors.Wrap(err, "htlc: failed to decode hash lock")
+		}
+
+		if _, err := io.ReadFull(r, b[:8]); err != nil {
+			return htlc, errors.Wrap(err, "htlc: failed to decode expiry")
+		}
+
+		htlc.Expiry = binary.LittleEndian.Uint64(b)
+
+		if r.Len() > 0 {
+			return htlc, errors.New("htlc: locking an amount takes no other parameters")
+		}
+
+		return htlc, nil
+	}
+
+	if _, err := io.ReadFull(r, htlc.HTLCID[:]); err != nil {
+		return htlc, errors.Wrap(err, "htlc: failed to decode htlc id")
+	}
+
+	if htlc.Opcode == sys.RefundHTLC {
+		if r.Len() > 0 {
+			return htlc, errors.New("htlc: refunding takes no other parameters")
+		}
+
+		return htlc, nil
+	}
+
+	if r.Len() == 0 || r.Len() > sys.HTLCMaxPreimageSize {
+		return htlc, errors.Errorf("htlc: preimage must be between 1 and %d bytes", sys.HTLCMaxPreimageSize)
+	}
+
+	htlc.Preimage = make([]byte, r.Len())
+
+	if _, err := io.ReadFull(r, htlc.Preimage); err != nil {
+		return htlc, errors.Wrap(err, "htlc: failed to decode preimage")
+	}
+
+	return htlc, nil
+}
+
+// ParseSchedule parses and performs sanity checks on the payload of a scheduled transfer transaction.
+func ParseSchedule(payload []byte) (Schedule, error) {
+	var schedule Schedule
+
+	if len(payload) == 0 {
+		return schedule, errors.New("schedule: failed to decode opcode")
+	}
+
+	schedule.Opcode = payload[0]
+
+	switch schedule.Opcode {
+	case sys.CreateSchedule:
+		if len(payload) != 1+SizeAccountID+8*4 {
+			return schedule, errors.Errorf("schedule: payload must be exactly %d bytes", 1+SizeAccountID+8*4)
+		}
+
+		copy(schedule.Recipient[:], payload[1:])
+
+		fields := payload[1+SizeAccountID:]
+
+		schedule.Amount = binary.LittleEndian.Uint64(fields[0:8])
+		schedule.Start = binary.LittleEndian.Uint64(fields[8:16])
+		schedule.Interval = binary.LittleEndian.Uint64(fields[16:24])
+		schedule.Count = binary.LittleEndian.Uint64(fields[24:32])
+
+		if schedule.Amount == 0 {
+			return schedule, errors.New("schedule: amount must be greater than zero")
+		}
+
+		if schedule.Count == 0 {
+			return schedule, errors.New("schedule: count must be greater than zero")
+		}
+
+		if schedule.Count > 1 && schedule.Interval == 0 {
+			return schedule, errors.New("schedule: interval must be greater than zero")
+		}
+
+		if schedule.Amount*schedule.Count/schedule.Count != schedule.Amount {
+			return schedule, errors.New("schedule: total amount of PERLs scheduled overflows")
+		}
+
+		span := schedule.Interval * (schedule.Count - 1)
+		if (schedule.Count > 1 && span/(schedule.Count-1) != schedule.Interval) || schedule.Start+span < schedule.Start {
+			return schedule, errors.New("schedule: last transfer is scheduled beyond the maximum block height")
+		}
+	case sys.CancelSchedule:
+		if len(payload) != 1+SizeTransactionID {
+			return schedule, errors.Errorf("schedule: payload must be exactly %d bytes", 1+SizeTransactionID)
+		}
+
+		copy(schedule.ScheduleID[:], payload[1:])
+	default:
+		return schedule, errors.New("schedule: opcode must be 0 or 1")
+	}
+
+	return schedule, nil
+}
+
 // ParseBatch parses and performs sanity checks on the payload of a batch transaction.
 func ParseBatch(payload []byte) (Batch, error) {
 	r := bytes.NewReader(payload)
@@ -261,6 +811,23 @@ func ParseBatch(payload []byte) (Batch, error) {
 		}
 	}
 
+	// The mode of the batch is optional, and trails its entries.
+	if r.Len() > 0 {
+		mode, _ := r.ReadByte()
+
+		switch mode {
+		case 0:
+		case 1:
+			batch.BestEffort = true
+		default:
+			return batch, errors.New("batch: mode must be 0 or 1")
+		}
+
+		if r.Len() > 0 {
+			return batch, errors.New("batch: unexpected bytes after the mode of the batch")
+		}
+	}
+
 	return batch, nil
 }
 
@@ -281,20 +848,41 @@ func (t Transfer) Marshal() ([]byte, error) {
 		return nil, errors.Wrap(err, "error marshaling gas deposit")
 	}
 
-	if t.FuncName != nil && len(t.FuncName) > 0 {
-		if err := binary.Write(buf, binary.LittleEndian, uint32(len(t.FuncName))); err != nil {
-			return nil, errors.Wrap(err, "error marshaling func name")
-		}
+	if t.Opcode == sys.TransferValue {
+		if len(t.FuncName) > 0 {
+			if err := binary.Write(buf, binary.LittleEndian, uint32(len(t.FuncName))); err != nil {
+				return nil, errors.Wrap(err, "error marshaling func name")
+			}
+
+			buf.Write(t.FuncName)
 
-		buf.Write(t.FuncName)
+			if len(t.FuncParams) > 0 {
+				if err := binary.Write(buf, binary.LittleEndian, uint32(len(t.FuncParams))); err != nil {
+					return nil, errors.Wrap(err, "error marshaling func params")
+				}
 
-		if t.FuncParams != nil && len(t.FuncParams) > 0 {
-			if err := binary.Write(buf, binary.LittleEndian, uint32(len(t.FuncParams))); err != nil {
-				return nil, errors.Wrap(err, "error marshaling func params")
+				buf.Write(t.FuncParams)
 			}
+		}
+
+		return buf.Bytes(), nil
+	}
 
-			buf.Write(t.FuncParams)
+	if err := binary.Write(buf, binary.LittleEndian, uint32(transferAllowanceMarker)); err != nil {
+		return nil, errors.Wrap(err, "error marshaling allowance marker")
+	}
+
+	buf.WriteByte(t.Opcode)
+
+	switch t.Opcode {
+	case sys.ApproveAllowance:
+		if err := binary.Write(buf, binary.LittleEndian, t.Expiry); err != nil {
+			return nil, errors.Wrap(err, "error marshaling allowance expiry")
 		}
+	case sys.TransferFromAllowance:
+		buf.Write(t.Owner[:])
+	default:
+		return nil, errors.New("transfer allowance opcode must be 1 or 2")
 	}
 
 	return buf.Bytes(), nil
@@ -312,8 +900,10 @@ func (s Stake) Marshal() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// Marshal marshals the contract payload. Should Code be empty, the smart contract is spawned from the code
+// referenced by CodeID.
 func (c Contract) Marshal() ([]byte, error) {
-	buf := bytes.NewBuffer(make([]byte, 0, 8+8+4+len(c.Params)+len(c.Code)))
+	buf := bytes.NewBuffer(make([]byte, 0, 8+8+4+len(c.Params)+1+len(c.Code)+SizeContractCodeID))
 
 	if err := binary.Write(buf, binary.LittleEndian, c.GasLimit); err != nil {
 		return nil, errors.Wrap(err, "error marshaling gas limit")
@@ -327,12 +917,165 @@ func (c Contract) Marshal() ([]byte, error) {
 		return nil, errors.Wrap(err, "error marshaling params")
 	}
 
+	buf.Write(c.Params)
+
+	if len(c.Code) == 0 {
+		buf.WriteByte(sys.ContractCodeReferenced)
+		buf.Write(c.CodeID[:])
+	} else {
+		buf.WriteByte(sys.ContractCodeUploaded)
+		buf.Write(c.Code)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (c ContractLifecycle) Marshal() ([]byte, error) {
+	if c.Opcode == sys.DestroyContract {
+		return append([]byte{c.Opcode}, c.ContractID[:]...), nil
+	}
+
+	if c.Opcode == sys.WithdrawContractGas {
+		buf := bytes.NewBuffer(make([]byte, 0, 1+32+8+32))
+
+		buf.WriteByte(c.Opcode)
+		buf.Write(c.ContractID[:])
+
+		if err := binary.Write(buf, binary.LittleEndian, c.Amount); err != nil {
+			return nil, errors.Wrap(err, "error marshaling amount")
+		}
+
+		buf.Write(c.Recipient[:])
+
+		return buf.Bytes(), nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 1+32+8+4+len(c.Params)+len(c.Code)))
+
+	buf.WriteByte(c.Opcode)
+	buf.Write(c.ContractID[:])
+
+	if err := binary.Write(buf, binary.LittleEndian, c.GasLimit); err != nil {
+		return nil, errors.Wrap(err, "error marshaling gas limit")
+	}
+
+	if err := binary.Write(buf, binary.LittleEndian, uint32(len(c.Params))); err != nil {
+		return nil, errors.Wrap(err, "error marshaling params")
+	}
+
 	buf.Write(c.Params)
 	buf.Write(c.Code)
 
 	return buf.Bytes(), nil
 }
 
+func (m Multisig) Marshal() ([]byte, error) {
+	if len(m.Keys) > sys.MultisigMaxKeys {
+		return nil, errors.Errorf("multisig cannot have more than %d keys", sys.MultisigMaxKeys)
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 1+1+len(m.Keys)*SizeAccountID))
+
+	buf.WriteByte(m.Threshold)
+	buf.WriteByte(uint8(len(m.Keys)))
+
+	for _, key := range m.Keys {
+		buf.Write(key[:])
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (k KeyRotation) Marshal() ([]byte, error) {
+	return append([]byte{}, k.Key[:]...), nil
+}
+
+func (a Asset) Marshal() ([]byte, error) {
+	if a.Opcode == sys.CreateAsset {
+		if len(a.Symbol) == 0 || len(a.Symbol) > sys.AssetMaxSymbolSize {
+			return nil, errors.Errorf("asset symbol must be between 1 and %d characters", sys.AssetMaxSymbolSize)
+		}
+
+		buf := bytes.NewBuffer(make([]byte, 0, 1+1+8+1+len(a.Symbol)))
+
+		buf.WriteByte(a.Opcode)
+		buf.WriteByte(a.Decimals)
+
+		if err := binary.Write(buf, binary.LittleEndian, a.Supply); err != nil {
+			return nil, errors.Wrap(err, "error marshaling supply")
+		}
+
+		buf.WriteByte(uint8(len(a.Symbol)))
+		buf.WriteString(a.Symbol)
+
+		return buf.Bytes(), nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 1+32+32+8))
+
+	buf.WriteByte(a.Opcode)
+	buf.Write(a.AssetID[:])
+
+	if a.Opcode != sys.BurnAsset {
+		buf.Write(a.Recipient[:])
+	}
+
+	if err := binary.Write(buf, binary.LittleEndian, a.Amount); err != nil {
+		return nil, errors.Wrap(err, "error marshaling amount")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (h HTLC) Marshal() ([]byte, error) {
+	switch h.Opcode {
+	case sys.LockHTLC:
+		buf := bytes.NewBuffer(make([]byte, 0, 1+32+8+32+8))
+
+		buf.WriteByte(h.Opcode)
+		buf.Write(h.Recipient[:])
+
+		if err := binary.Write(buf, binary.LittleEndian, h.Amount); err != nil {
+			return nil, errors.Wrap(err, "error marshaling amount")
+		}
+
+		buf.Write(h.HashLock[:])
+
+		if err := binary.Write(buf, binary.LittleEndian, h.Expiry); err != nil {
+			return nil, errors.Wrap(err, "error marshaling expiry")
+		}
+
+		return buf.Bytes(), nil
+	case sys.ClaimHTLC:
+		if len(h.Preimage) == 0 || len(h.Preimage) > sys.HTLCMaxPreimageSize {
+			return nil, errors.Errorf("htlc preimage must be between 1 and %d bytes", sys.HTLCMaxPreimageSize)
+		}
+
+		return append(append([]byte{h.Opcode}, h.HTLCID[:]...), h.Preimage...), nil
+	default:
+		return append([]byte{h.Opcode}, h.HTLCID[:]...), nil
+	}
+}
+
+func (s Schedule) Marshal() ([]byte, error) {
+	if s.Opcode == sys.CancelSchedule {
+		return append([]byte{s.Opcode}, s.ScheduleID[:]...), nil
+	}
+
+	buf := bytes.NewBuffer(make([]byte, 0, 1+SizeAccountID+8*4))
+
+	buf.WriteByte(s.Opcode)
+	buf.Write(s.Recipient[:])
+
+	for _, field := range []uint64{s.Amount, s.Start, s.Interval, s.Count} {
+		if err := binary.Write(buf, binary.LittleEndian, field); err != nil {
+			return nil, errors.Wrap(err, "error marshaling schedule")
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
 // AddTransfer adds a Transfer payload into a batch.
 func (b *Batch) AddTransfer(t Transfer) error {
 	if b.Size == 255 {
@@ -390,6 +1133,120 @@ func (b *Batch) AddContract(c Contract) error {
 	return nil
 }
 
+// AddContractLifecycle adds a ContractLifecycle payload into a batch.
+func (b *Batch) AddContractLifecycle(c ContractLifecycle) error {
+	if b.Size == 255 {
+		return fmt.Errorf("batch cannot have more than 255 transactions")
+	}
+
+	b.Size++
+	b.Tags = append(b.Tags, uint8(sys.TagContractLifecycle))
+
+	payload, err := c.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "error marshaling contract lifecycle")
+	}
+
+	b.Payloads = append(b.Payloads, payload)
+
+	return nil
+}
+
+// AddMultisig adds a Multisig payload into a batch.
+func (b *Batch) AddMultisig(m Multisig) error {
+	if b.Size == 255 {
+		return fmt.Errorf("batch cannot have more than 255 transactions")
+	}
+
+	b.Size++
+	b.Tags = append(b.Tags, uint8(sys.TagMultisig))
+
+	payload, err := m.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "error marshaling multisig")
+	}
+
+	b.Payloads = append(b.Payloads, payload)
+
+	return nil
+}
+
+// AddKeyRotation adds a KeyRotation payload into a batch.
+func (b *Batch) AddKeyRotation(k KeyRotation) error {
+	if b.Size == 255 {
+		return fmt.Errorf("batch cannot have more than 255 transactions")
+	}
+
+	b.Size++
+	b.Tags = append(b.Tags, uint8(sys.TagKeyRotation))
+
+	payload, err := k.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "error marshaling key rotation")
+	}
+
+	b.Payloads = append(b.Payloads, payload)
+
+	return nil
+}
+
+// AddAsset adds an Asset payload into a batch.
+func (b *Batch) AddAsset(a Asset) error {
+	if b.Size == 255 {
+		return fmt.Errorf("batch cannot have more than 255 transactions")
+	}
+
+	b.Size++
+	b.Tags = append(b.Tags, uint8(sys.TagAsset))
+
+	payload, err := a.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "error marshaling asset")
+	}
+
+	b.Payloads = append(b.Payloads, payload)
+
+	return nil
+}
+
+// AddHTLC adds an HTLC payload into a batch.
+func (b *Batch) AddHTLC(h HTLC) error {
+	if b.Size == 255 {
+		return fmt.Errorf("batch cannot have more than 255 transactions")
+	}
+
+	b.Size++
+	b.Tags = append(b.Tags, uint8(sys.TagHTLC))
+
+	payload, err := h.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "error marshaling htlc")
+	}
+
+	b.Payloads = append(b.Payloads, payload)
+
+	return nil
+}
+
+// AddSchedule adds a Schedule payload into a batch.
+func (b *Batch) AddSchedule(s Schedule) error {
+	if b.Size == 255 {
+		return fmt.Errorf("batch cannot have more than 255 transactions")
+	}
+
+	b.Size++
+	b.Tags = append(b.Tags, uint8(sys.TagSchedule))
+
+	payload, err := s.Marshal()
+	if err != nil {
+		return errors.Wrap(err, "error marshaling schedule")
+	}
+
+	b.Payloads = append(b.Payloads, payload)
+
+	return nil
+}
+
 func (b Batch) Marshal() ([]byte, error) {
 	buf := bytes.NewBuffer(make([]byte, 0, 1+(b.Size*(1+4))))
 
@@ -405,5 +1262,9 @@ func (b Batch) Marshal() ([]byte, error) {
 		buf.Write(b.Payloads[i])
 	}
 
+	if b.BestEffort {
+		buf.WriteByte(1)
+	}
+
 	return buf.Bytes(), nil
 }
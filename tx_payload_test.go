@@ -3,7 +3,10 @@
 package wavelet
 
 import (
+	"encoding/binary"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"testing"
 
 	"github.com/perlin-network/noise/skademlia"
@@ -47,6 +50,38 @@ func TestParseTransfer(t *testing.T) {
 	assert.Equal(t, tf, tfNoGasLimit)
 }
 
+func TestParseTransfer_Allowance(t *testing.T) {
+	transfers := []Transfer{
+		{Opcode: sys.ApproveAllowance, Recipient: AccountID{1}, Amount: 100, Expiry: 10},
+		{Opcode: sys.ApproveAllowance, Recipient: AccountID{1}},
+		{Opcode: sys.TransferFromAllowance, Recipient: AccountID{1}, Amount: 100, Owner: AccountID{2}},
+	}
+
+	for _, transfer := range transfers {
+		payload, err := transfer.Marshal()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		transfer2, err := ParseTransfer(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, transfer, transfer2)
+	}
+}
+
+func TestParseTransfer_TrailingBytes(t *testing.T) {
+	tf := validTransfer(t)
+	payload, err := tf.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Bytes trailing the function parameters are ignored, and are never parsed as an allowance operation.
+	tf2, err := ParseTransfer(append(payload, sys.ApproveAllowance, 1, 2, 3, 4, 5, 6, 7, 8))
+	assert.NoError(t, err)
+	assert.Equal(t, tf, tf2)
+}
+
 func TestParseTransfer_Errors(t *testing.T) {
 	tests := []struct {
 		Err     string
@@ -140,6 +175,50 @@ func TestParseTransfer_Errors(t *testing.T) {
 				return payload[:SizeAccountID+8+8+8+4+len(tf.FuncName)+4+len(tf.FuncParams)-1]
 			},
 		},
+		{
+			"opcode must be 1 or 2",
+			func(tf Transfer) []byte {
+				payload, _ := Transfer{Opcode: sys.ApproveAllowance, Amount: 1}.Marshal()
+				payload[SizeAccountID+8+8+8+4] = sys.TransferFromAllowance + 1
+				return payload
+			},
+		},
+		{
+			"failed to decode allowance expiry",
+			func(tf Transfer) []byte {
+				payload, _ := Transfer{Opcode: sys.ApproveAllowance, Amount: 1}.Marshal()
+				return payload[:len(payload)-1]
+			},
+		},
+		{
+			"failed to decode allowance owner",
+			func(tf Transfer) []byte {
+				payload, _ := Transfer{Opcode: sys.TransferFromAllowance, Amount: 1}.Marshal()
+				return payload[:len(payload)-1]
+			},
+		},
+		{
+			"amount to transfer from an allowance must be greater than zero",
+			func(tf Transfer) []byte {
+				payload, _ := Transfer{Opcode: sys.TransferFromAllowance}.Marshal()
+				return payload
+			},
+		},
+		{
+			"allowance operations take no other parameters",
+			func(tf Transfer) []byte {
+				payload, _ := Transfer{Opcode: sys.ApproveAllowance, Amount: 1}.Marshal()
+				return append(payload, 0)
+			},
+		},
+		{
+			"allowance operations should not specify gas limit or gas deposit",
+			func(tf Transfer) []byte {
+				tf.Opcode = sys.ApproveAllowance
+				payload, _ := tf.Marshal()
+				return payload
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -252,6 +331,23 @@ func TestParseContract(t *testing.T) {
 	assert.Equal(t, contract, contract2)
 }
 
+func TestParseContractFromCodeID(t *testing.T) {
+	contract := validContract()
+	contract.Code = nil
+	contract.CodeID = HashContractCode(validContract().Code)
+
+	payload, err := contract.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Len(t, payload, 8+8+4+len(contract.Params)+1+SizeContractCodeID)
+
+	contract2, err := ParseContract(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, contract, contract2)
+}
+
 func TestParseContract_Errors(t *testing.T) {
 	tests := []struct {
 		Err     string
@@ -306,14 +402,48 @@ func TestParseContract_Errors(t *testing.T) {
 			},
 		},
 		{
-			"smart contract must have code of length greater than zero",
+			"failed to decode smart contract code source",
 			func() []byte {
 				contract := validContract()
-				contract.Code = []byte{}
 				payload, _ := contract.Marshal()
+				return payload[:8+8+4+len(contract.Params)]
+			},
+		},
+		{
+			"unknown smart contract code source",
+			func() []byte {
+				contract := validContract()
+				payload, _ := contract.Marshal()
+				payload[8+8+4+len(contract.Params)] = 0xFF
 				return payload
 			},
 		},
+		{
+			"smart contract must have code of length greater than zero",
+			func() []byte {
+				contract := validContract()
+				payload, _ := contract.Marshal()
+				return payload[:8+8+4+len(contract.Params)+1]
+			},
+		},
+		{
+			"failed to decode smart contract code ID",
+			func() []byte {
+				contract := validContract()
+				contract.Code = nil
+				payload, _ := contract.Marshal()
+				return payload[:len(payload)-1]
+			},
+		},
+		{
+			"smart contract spawned from a code ID must not have code",
+			func() []byte {
+				contract := validContract()
+				contract.Code = nil
+				payload, _ := contract.Marshal()
+				return append(payload, 0x01)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -328,6 +458,462 @@ func TestParseContract_Errors(t *testing.T) {
 	}
 }
 
+func TestParseContractLifecycle(t *testing.T) {
+	for _, lifecycle := range []ContractLifecycle{
+		validContractLifecycle(sys.UpgradeContract),
+		validContractLifecycle(sys.DestroyContract),
+		validContractLifecycle(sys.WithdrawContractGas),
+	} {
+		payload, err := lifecycle.Marshal()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		lifecycle2, err := ParseContractLifecycle(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, lifecycle, lifecycle2)
+	}
+}
+
+func TestParseContractLifecycleMigrateGasLimit(t *testing.T) {
+	migrate, err := ioutil.ReadFile("testdata/migrate.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	dummy, err := ioutil.ReadFile("testdata/dummy.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	exported, err := readWasmFunctionExport(migrate, "_contract_migrate")
+	assert.NoError(t, err)
+	assert.True(t, exported)
+
+	exported, err = readWasmFunctionExport(dummy, "_contract_migrate")
+	assert.NoError(t, err)
+	assert.False(t, exported)
+
+	lifecycle := validContractLifecycle(sys.UpgradeContract)
+	lifecycle.GasLimit = 0
+
+	// Code that exports a migrate function may not be upgraded to without a gas limit to migrate under.
+	lifecycle.Code = migrate
+
+	payload, err := lifecycle.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = ParseContractLifecycle(payload)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "gas limit for migrating smart contract must be greater than zero")
+	}
+
+	// Code that does not may be.
+	lifecycle.Code = dummy
+
+	payload, err = lifecycle.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	lifecycle2, err := ParseContractLifecycle(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, lifecycle, lifecycle2)
+}
+
+func TestParseContractLifecycle_Errors(t *testing.T) {
+	tests := []struct {
+		Err     string
+		Payload func() []byte
+	}{
+		{
+			"failed to decode opcode",
+			func() []byte {
+				return []byte{}
+			},
+		},
+		{
+			"opcode must be 0, 1, or 2",
+			func() []byte {
+				payload, _ := validContractLifecycle(sys.DestroyContract).Marshal()
+				payload[0] = 3
+				return payload
+			},
+		},
+		{
+			"failed to decode smart contract id",
+			func() []byte {
+				payload, _ := validContractLifecycle(sys.DestroyContract).Marshal()
+				return payload[:1+31]
+			},
+		},
+		{
+			"destroying a smart contract takes no other parameters",
+			func() []byte {
+				payload, _ := validContractLifecycle(sys.DestroyContract).Marshal()
+				return append(payload, 0)
+			},
+		},
+		{
+			"failed to decode amount of gas to withdraw",
+			func() []byte {
+				payload, _ := validContractLifecycle(sys.WithdrawContractGas).Marshal()
+				return payload[:1+32+7]
+			},
+		},
+		{
+			"amount of gas to withdraw must be greater than zero",
+			func() []byte {
+				lifecycle := validContractLifecycle(sys.WithdrawContractGas)
+				lifecycle.Amount = 0
+				payload, _ := lifecycle.Marshal()
+				return payload
+			},
+		},
+		{
+			"failed to decode recipient",
+			func() []byte {
+				payload, _ := validContractLifecycle(sys.WithdrawContractGas).Marshal()
+				return payload[:1+32+8+31]
+			},
+		},
+		{
+			"withdrawing gas takes no other parameters",
+			func() []byte {
+				payload, _ := validContractLifecycle(sys.WithdrawContractGas).Marshal()
+				return append(payload, 0)
+			},
+		},
+		{
+			"failed to decode gas limit",
+			func() []byte {
+				payload, _ := validContractLifecycle(sys.UpgradeContract).Marshal()
+				return payload[:1+32+7]
+			},
+		},
+		{
+			"failed to decode number of smart contract migrate parameters",
+			func() []byte {
+				payload, _ := validContractLifecycle(sys.UpgradeContract).Marshal()
+				return payload[:1+32+8+3]
+			},
+		},
+		{
+			"smart contract payload exceeds 1MB",
+			func() []byte {
+				lifecycle := validContractLifecycle(sys.UpgradeContract)
+				lifecycle.Params = make([]byte, (1024*1024)+1)
+				payload, _ := lifecycle.Marshal()
+				return payload
+			},
+		},
+		{
+			"failed to decode smart contract migrate parameters",
+			func() []byte {
+				lifecycle := validContractLifecycle(sys.UpgradeContract)
+				payload, _ := lifecycle.Marshal()
+				return payload[:1+32+8+4+len(lifecycle.Params)-1]
+			},
+		},
+		{
+			"smart contract must have code of length greater than zero",
+			func() []byte {
+				lifecycle := validContractLifecycle(sys.UpgradeContract)
+				lifecycle.Code = []byte{}
+				payload, _ := lifecycle.Marshal()
+				return payload
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.Err, func(t *testing.T) {
+			_, err := ParseContractLifecycle(tt.Payload())
+			if err == nil {
+				t.Fatal("expecting an error, got nil instead")
+			}
+			assert.Contains(t, err.Error(), fmt.Sprintf("contract_lifecycle: %s", tt.Err))
+		})
+	}
+}
+
+func TestParseMultisig(t *testing.T) {
+	multisig := validMultisig()
+	payload, err := multisig.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	multisig2, err := ParseMultisig(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, multisig, multisig2)
+}
+
+func TestParseMultisig_Errors(t *testing.T) {
+	tests := []struct {
+		Err     string
+		Payload func() []byte
+	}{
+		{
+			"payload must be at least 2 bytes",
+			func() []byte {
+				return []byte{1}
+			},
+		},
+		{
+			"number of keys must be between 1 and 16",
+			func() []byte {
+				return []byte{1, 0}
+			},
+		},
+		{
+			"number of keys must be between 1 and 16",
+			func() []byte {
+				return []byte{1, byte(sys.MultisigMaxKeys + 1)}
+			},
+		},
+		{
+			"threshold must be between 1 and the number of keys",
+			func() []byte {
+				multisig := validMultisig()
+				multisig.Threshold = 0
+				payload, _ := multisig.Marshal()
+				return payload
+			},
+		},
+		{
+			"threshold must be between 1 and the number of keys",
+			func() []byte {
+				multisig := validMultisig()
+				multisig.Threshold = uint8(len(multisig.Keys) + 1)
+				payload, _ := multisig.Marshal()
+				return payload
+			},
+		},
+		{
+			"payload must be exactly 98 bytes",
+			func() []byte {
+				payload, _ := validMultisig().Marshal()
+				return payload[:len(payload)-1]
+			},
+		},
+		{
+			"keys must be unique",
+			func() []byte {
+				multisig := validMultisig()
+				multisig.Keys[2] = multisig.Keys[0]
+				payload, _ := multisig.Marshal()
+				return payload
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.Err, func(t *testing.T) {
+			_, err := ParseMultisig(tt.Payload())
+			if err == nil {
+				t.Fatal("expecting an error, got nil instead")
+			}
+			assert.Contains(t, err.Error(), fmt.Sprintf("multisig: %s", tt.Err))
+		})
+	}
+}
+
+func TestParseKeyRotation(t *testing.T) {
+	rotation := KeyRotation{Key: AccountID{1, 2, 3}}
+	payload, err := rotation.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	rotation2, err := ParseKeyRotation(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, rotation, rotation2)
+
+	_, err = ParseKeyRotation(payload[:SizeAccountID-1])
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "key_rotation: payload must be exactly 32 bytes")
+	}
+
+	_, err = ParseKeyRotation(ZeroAccountID[:])
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "key_rotation: key must not be zero")
+	}
+}
+
+func TestParseAsset(t *testing.T) {
+	assets := []Asset{
+		{Opcode: sys.CreateAsset, Symbol: "GOLD", Decimals: 2, Supply: 1000},
+		{Opcode: sys.TransferAsset, AssetID: AssetID{1}, Recipient: AccountID{2}, Amount: 10},
+		{Opcode: sys.MintAsset, AssetID: AssetID{1}, Recipient: AccountID{2}, Amount: 10},
+		{Opcode: sys.BurnAsset, AssetID: AssetID{1}, Amount: 10},
+	}
+
+	for _, asset := range assets {
+		payload, err := asset.Marshal()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		asset2, err := ParseAsset(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, asset, asset2)
+	}
+}
+
+func TestParseAsset_Errors(t *testing.T) {
+	tests := []struct {
+		Err     string
+		Payload []byte
+	}{
+		{"failed to decode opcode", nil},
+		{"opcode must be 0, 1, 2, or 3", []byte{sys.BurnAsset + 1}},
+		{"decimals must not exceed", []byte{sys.CreateAsset, byte(sys.AssetMaxDecimals + 1)}},
+		{"failed to decode supply", []byte{sys.CreateAsset, 2, 0, 0}},
+		{"symbol must be between 1 and", []byte{sys.CreateAsset, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"failed to decode symbol", []byte{sys.CreateAsset, 2, 0, 0, 0, 0, 0, 0, 0, 0, 4, 'G'}},
+		{"creating an asset takes no other parameters", []byte{sys.CreateAsset, 2, 0, 0, 0, 0, 0, 0, 0, 0, 1, 'G', 0}},
+		{"failed to decode asset id", []byte{sys.TransferAsset, 1}},
+		{"failed to decode recipient", append([]byte{sys.MintAsset}, make([]byte, SizeAssetID)...)},
+		{"amount must be greater than zero", append([]byte{sys.BurnAsset}, make([]byte, SizeAssetID+8)...)},
+		{"payload has trailing bytes", append(append([]byte{sys.BurnAsset}, make([]byte, SizeAssetID)...),
+			1, 0, 0, 0, 0, 0, 0, 0, 0)},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.Err, func(t *testing.T) {
+			_, err := ParseAsset(tt.Payload)
+			if err == nil {
+				t.Fatal("expecting an error, got nil instead")
+			}
+			assert.Contains(t, err.Error(), fmt.Sprintf("asset: %s", tt.Err))
+		})
+	}
+}
+
+func TestParseHTLC(t *testing.T) {
+	htlcs := []HTLC{
+		{Opcode: sys.LockHTLC, Recipient: AccountID{1}, Amount: 100, HashLock: [32]byte{2}, Expiry: 10},
+		{Opcode: sys.ClaimHTLC, HTLCID: TransactionID{3}, Preimage: []byte("secret")},
+		{Opcode: sys.RefundHTLC, HTLCID: TransactionID{3}},
+	}
+
+	for _, htlc := range htlcs {
+		payload, err := htlc.Marshal()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		htlc2, err := ParseHTLC(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, htlc, htlc2)
+	}
+}
+
+func TestParseHTLC_Errors(t *testing.T) {
+	lock := func(fields ...[]byte) []byte {
+		payload := append([]byte{sys.LockHTLC}, make([]byte, SizeAccountID)...)
+		for _, field := range fields {
+			payload = append(payload, field...)
+		}
+		return payload
+	}
+
+	amount := []byte{1, 0, 0, 0, 0, 0, 0, 0}
+
+	tests := []struct {
+		Err     string
+		Payload []byte
+	}{
+		{"failed to decode opcode", nil},
+		{"opcode must be 0, 1, or 2", []byte{sys.RefundHTLC + 1}},
+		{"failed to decode recipient", []byte{sys.LockHTLC, 1}},
+		{"amount must be greater than zero", lock(make([]byte, 8))},
+		{"failed to decode hash lock", lock(amount)},
+		{"failed to decode expiry", lock(amount, make([]byte, 32))},
+		{"locking an amount takes no other parameters", lock(amount, make([]byte, 32+8+1))},
+		{"failed to decode htlc id", []byte{sys.ClaimHTLC, 1}},
+		{"preimage must be between 1 and", append([]byte{sys.ClaimHTLC}, make([]byte, SizeTransactionID)...)},
+		{"preimage must be between 1 and", append([]byte{sys.ClaimHTLC}, make([]byte, SizeTransactionID+257)...)},
+		{"refunding takes no other parameters", append([]byte{sys.RefundHTLC}, make([]byte, SizeTransactionID+1)...)},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.Err, func(t *testing.T) {
+			_, err := ParseHTLC(tt.Payload)
+			if err == nil {
+				t.Fatal("expecting an error, got nil instead")
+			}
+			assert.Contains(t, err.Error(), fmt.Sprintf("htlc: %s", tt.Err))
+		})
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	schedules := []Schedule{
+		{Opcode: sys.CreateSchedule, Recipient: AccountID{1}, Amount: 100, Start: 10, Interval: 5, Count: 3},
+		{Opcode: sys.CreateSchedule, Recipient: AccountID{1}, Amount: 100, Start: 10, Count: 1},
+		{Opcode: sys.CancelSchedule, ScheduleID: TransactionID{2}},
+	}
+
+	for _, schedule := range schedules {
+		payload, err := schedule.Marshal()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		schedule2, err := ParseSchedule(payload)
+		assert.NoError(t, err)
+		assert.Equal(t, schedule, schedule2)
+	}
+}
+
+func TestParseSchedule_Errors(t *testing.T) {
+	create := func(amount, start, interval, count uint64) []byte {
+		payload := append([]byte{sys.CreateSchedule}, make([]byte, SizeAccountID)...)
+		for _, field := range []uint64{amount, start, interval, count} {
+			var buf [8]byte
+			binary.LittleEndian.PutUint64(buf[:], field)
+			payload = append(payload, buf[:]...)
+		}
+		return payload
+	}
+
+	tests := []struct {
+		Err     string
+		Payload []byte
+	}{
+		{"failed to decode opcode", nil},
+		{"opcode must be 0 or 1", []byte{sys.CancelSchedule + 1}},
+		{"payload must be exactly 65 bytes", []byte{sys.CreateSchedule, 1}},
+		{"payload must be exactly 65 bytes", append(create(1, 0, 1, 1), 0)},
+		{"amount must be greater than zero", create(0, 0, 1, 1)},
+		{"count must be greater than zero", create(1, 0, 1, 0)},
+		{"interval must be greater than zero", create(1, 0, 0, 2)},
+		{"total amount of PERLs scheduled overflows", create(math.MaxUint64, 0, 1, 2)},
+		{"last transfer is scheduled beyond the maximum block height", create(1, 0, math.MaxUint64, 3)},
+		{"last transfer is scheduled beyond the maximum block height", create(1, math.MaxUint64, 1, 2)},
+		{"payload must be exactly 33 bytes", []byte{sys.CancelSchedule, 1}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.Err, func(t *testing.T) {
+			_, err := ParseSchedule(tt.Payload)
+			if err == nil {
+				t.Fatal("expecting an error, got nil instead")
+			}
+			assert.Contains(t, err.Error(), fmt.Sprintf("schedule: %s", tt.Err))
+		})
+	}
+}
+
 func TestParseBatch(t *testing.T) {
 	batch := validBatch(t)
 	payload, err := batch.Marshal()
@@ -338,6 +924,17 @@ func TestParseBatch(t *testing.T) {
 	batch2, err := ParseBatch(payload)
 	assert.NoError(t, err)
 	assert.Equal(t, batch, batch2)
+
+	batch.BestEffort = true
+
+	payload, err = batch.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	batch2, err = ParseBatch(payload)
+	assert.NoError(t, err)
+	assert.Equal(t, batch, batch2)
 }
 
 func TestParseBatch_Errors(t *testing.T) {
@@ -402,6 +999,20 @@ func TestParseBatch_Errors(t *testing.T) {
 				return payload[:1+1+4+1]
 			},
 		},
+		{
+			"mode must be 0 or 1",
+			func() []byte {
+				payload, _ := validBatch(t).Marshal()
+				return append(payload, 2)
+			},
+		},
+		{
+			"unexpected bytes after the mode of the batch",
+			func() []byte {
+				payload, _ := validBatch(t).Marshal()
+				return append(payload, 1, 0)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -448,6 +1059,32 @@ func validContract() Contract {
 	}
 }
 
+func validContractLifecycle(opcode byte) ContractLifecycle {
+	lifecycle := ContractLifecycle{
+		Opcode:     opcode,
+		ContractID: TransactionID{1, 2, 3},
+	}
+
+	switch opcode {
+	case sys.UpgradeContract:
+		lifecycle.GasLimit = 42
+		lifecycle.Params = []byte("foobar")
+		lifecycle.Code = []byte("loremipsumdolorsitamet")
+	case sys.WithdrawContractGas:
+		lifecycle.Amount = 42
+		lifecycle.Recipient = AccountID{4, 5, 6}
+	}
+
+	return lifecycle
+}
+
+func validMultisig() Multisig {
+	return Multisig{
+		Threshold: 2,
+		Keys:      []AccountID{{1}, {2}, {3}},
+	}
+}
+
 func validBatch(t *testing.T) Batch {
 	var batch Batch
 	assert.NoError(t, batch.AddTransfer(validTransfer(t)))
@@ -77,3 +77,37 @@ func BenchmarkMarshalUnmarshalTX(b *testing.B) {
 //
 //	fmt.Println(len(buf), len(b), unsafe.Sizeof(tx))
 //}
+
+func TestSponsoredTransaction(t *testing.T) {
+	sender, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	feePayer, err := skademlia.NewKeys(1, 1)
+	assert.NoError(t, err)
+
+	tx := NewSponsoredTransaction(sender, feePayer, 1, 1, sys.TagTransfer, []byte("payload"))
+
+	assert.True(t, tx.IsSponsored())
+	assert.Equal(t, AccountID(feePayer.PublicKey()), tx.Payer())
+	assert.True(t, tx.VerifySignature())
+	assert.True(t, tx.VerifyFeePayerSignature(feePayer.PublicKey()))
+
+	decoded, err := UnmarshalTransaction(bytes.NewReader(tx.Marshal()))
+	if assert.NoError(t, err) {
+		assert.Equal(t, tx, decoded)
+	}
+
+	// Neither signature may be reused for a transaction sponsored by another fee payer, or that is not sponsored.
+	other := tx
+	other.FeePayer = sender.PublicKey()
+
+	assert.False(t, other.VerifySignature())
+	assert.False(t, other.VerifyFeePayerSignature(feePayer.PublicKey()))
+
+	unsponsored := NewSignedTransaction(tx.Sender, tx.Nonce, tx.Block, tx.Tag, tx.Payload, tx.Signature)
+
+	assert.False(t, unsponsored.IsSponsored())
+	assert.Equal(t, AccountID(sender.PublicKey()), unsponsored.Payer())
+	assert.False(t, unsponsored.VerifySignature())
+	assert.NotEqual(t, tx.ID, unsponsored.ID)
+}
@@ -20,21 +20,178 @@
 package wavelet
 
 import (
+	"sync"
+
+	"github.com/perlin-network/noise/edwards25519"
 	"github.com/perlin-network/wavelet/avl"
+	"github.com/perlin-network/wavelet/internal/batchverify"
+	"github.com/perlin-network/wavelet/internal/worker"
 	"github.com/perlin-network/wavelet/sys"
 	"github.com/pkg/errors"
 )
 
 var ErrContractAlreadyExists = errors.New("contract: already exists")
 
+// signatureBatchSize is the number of transactions whose signatures are verified together in a single batch.
+const signatureBatchSize = 64
+
 // ValidateTransaction validates signature, and state to make sure that the transaction is acceptable.
 func ValidateTransaction(snapshot *avl.Tree, tx Transaction) error {
 	return validateTransaction(snapshot, tx, true)
 }
 
+// ValidateTransactions validates transactions just like ValidateTransaction, with their signatures verified in
+// batches spread across a pool of workers. It returns the error each transaction failed to be validated with, if any.
+func ValidateTransactions(snapshot *avl.Tree, txs []Transaction, pool *worker.Pool) []error {
+	return validateTransactions(snapshot, txs, pool, true)
+}
+
+// authorizeTransactions authorizes transactions just like authorizeTransaction, with their signatures verified in
+// batches spread across a pool of workers. It returns the error each transaction failed to be authorized with, if any.
+func authorizeTransactions(snapshot *avl.Tree, txs []Transaction, pool *worker.Pool) []error {
+	return validateTransactions(snapshot, txs, pool, false)
+}
+
+func validateTransactions(snapshot *avl.Tree, txs []Transaction, pool *worker.Pool, validateState bool) []error {
+	errs := make([]error, len(txs))
+
+	var wg sync.WaitGroup
+
+	for start := 0; start < len(txs); start += signatureBatchSize {
+		end := start + signatureBatchSize
+		if end > len(txs) {
+			end = len(txs)
+		}
+
+		batch, batchErrs := txs[start:end], errs[start:end]
+
+		wg.Add(1)
+
+		pool.Queue(func() {
+			defer wg.Done()
+
+			authorizeBatch(snapshot, batch, batchErrs)
+
+			if !validateState {
+				return
+			}
+
+			for i := range batch {
+				if batchErrs[i] == nil {
+					batchErrs[i] = validateTransaction(snapshot, batch[i], false)
+				}
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// authorizeBatch authorizes a batch of transactions, verifying the signatures made by the keys of their senders and
+// fee payers all at once. Should the batch carry an invalid signature, each transaction is instead authorized on its
+// own by authorizeTransaction, just as transactions are when submitted through the API. The error each transaction
+// failed to be authorized with is stored in errs.
+//
+// A signature only invalid by a small-order component may pass the batch equation, but is rejected by
+// authorizeSender once the transaction is applied, just as it is when verified on its own.
+func authorizeBatch(snapshot *avl.Tree, txs []Transaction, errs []error) {
+	var entries []batchverify.Entry
+
+	for i := range txs {
+		signatures, err := transactionSignatures(snapshot, txs[i])
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+
+		entries = append(entries, signatures...)
+	}
+
+	if len(entries) > 1 && batchverify.VerifyBatch(entries) {
+		return
+	}
+
+	for i := range txs {
+		if errs[i] == nil {
+			errs[i] = authorizeTransaction(snapshot, txs[i])
+		}
+	}
+}
+
+// transactionSignatures authorizes a transaction just like authorizeTransaction, save for verifying the signatures
+// made by the keys of its sender and fee payer, which are instead returned to be verified in a batch. Transactions
+// sent by smart contract accounts are authorized by authorizeTransaction in full, as they are not signed by a key.
+func transactionSignatures(snapshot *avl.Tree, tx Transaction) ([]batchverify.Entry, error) {
+	if isContractSender(snapshot, tx.Sender) {
+		return nil, authorizeTransaction(snapshot, tx)
+	}
+
+	multisig, isMultisig := ReadAccountMultisig(snapshot, tx.Sender)
+
+	var signatures []batchverify.Entry
+
+	if tx.IsSponsored() {
+		key, err := feePayerKey(NewCollapseContext(snapshot), &tx)
+		if err != nil {
+			return nil, err
+		}
+
+		signatures = append(signatures, batchverify.Entry{
+			PublicKey: key,
+			Message:   tx.sponsoredMessage(),
+			Signature: tx.FeePayerSignature,
+		})
+	}
+
+	if isMultisig {
+		if !tx.VerifyMultisig(multisig) {
+			return nil, ErrTxInvalidSignature
+		}
+
+		return signatures, nil
+	}
+
+	if len(tx.Signatures) > 0 {
+		return nil, ErrTxInvalidSignature
+	}
+
+	key := tx.Sender
+	if bound, rotated := ReadAccountSigningKey(snapshot, tx.Sender); rotated {
+		key = bound
+	}
+
+	return append(signatures, batchverify.Entry{
+		PublicKey: key,
+		Message:   tx.signedMessage(),
+		Signature: tx.Signature,
+	}), nil
+}
+
+// isContractSender returns true if transactions sent by an account are authorized by the validate function of a
+// smart contract, rather than by signatures.
+func isContractSender(snapshot *avl.Tree, id AccountID) bool {
+	if _, isMultisig := ReadAccountMultisig(snapshot, id); isMultisig {
+		return false
+	}
+
+	_, isContract := ReadAccountContractCode(snapshot, id)
+
+	return isContract
+}
+
 func validateTransaction(snapshot *avl.Tree, tx Transaction, verifySignature bool) error {
-	if verifySignature && !tx.VerifySignature() {
-		return ErrTxInvalidSignature
+	if verifySignature {
+		if err := authorizeTransaction(snapshot, tx); err != nil {
+			return err
+		}
+	}
+
+	if tx.IsSponsored() {
+		if bal, _ := ReadAccountBalance(snapshot, tx.FeePayer); bal < tx.Fee() {
+			return errors.Errorf("fee payer current balance %d is not enough", bal)
+		}
 	}
 
 	switch tx.Tag {
@@ -46,6 +203,202 @@ func validateTransaction(snapshot *avl.Tree, tx Transaction, verifySignature boo
 		return validateContractTransaction(snapshot, tx)
 	case sys.TagBatch:
 		return validateBatchTransaction(snapshot, tx)
+	case sys.TagContractLifecycle:
+		return validateContractLifecycleTransaction(snapshot, tx)
+	case sys.TagMultisig:
+		return validateMultisigTransaction(snapshot, tx)
+	case sys.TagKeyRotation:
+		return validateKeyRotationTransaction(snapshot, tx)
+	case sys.TagAsset:
+		return validateAssetTransaction(snapshot, tx)
+	case sys.TagHTLC:
+		return validateHTLCTransaction(snapshot, tx)
+	case sys.TagSchedule:
+		return validateScheduleTransaction(snapshot, tx)
+	}
+
+	return nil
+}
+
+// authorizeTransaction checks that a transaction is authorized by its sender. Transactions sent by multisig
+// accounts are authorized by the signatures of the keys of the account, and transactions sent by smart contract
+// accounts by the validate function of the contract, which is invoked against a context that is discarded
+// afterwards. All other transactions are authorized by their signature, made by the key bound to the account of
+// their sender should the key of the account have been rotated.
+func authorizeTransaction(snapshot *avl.Tree, tx Transaction) error {
+	if tx.IsSponsored() {
+		if err := authorizeFeePayer(NewCollapseContext(snapshot), &tx); err != nil {
+			return err
+		}
+	}
+
+	if multisig, isMultisig := ReadAccountMultisig(snapshot, tx.Sender); isMultisig {
+		if !tx.VerifyMultisig(multisig) {
+			return ErrTxInvalidSignature
+		}
+
+		return nil
+	}
+
+	if len(tx.Signatures) > 0 {
+		return ErrTxInvalidSignature
+	}
+
+	code, isContract := ReadAccountContractCode(snapshot, tx.Sender)
+	if !isContract {
+		key := tx.Sender
+		if bound, rotated := ReadAccountSigningKey(snapshot, tx.Sender); rotated {
+			key = bound
+		}
+
+		if !tx.VerifySignatureWithKey(key) {
+			return ErrTxInvalidSignature
+		}
+
+		return nil
+	}
+
+	return validateContractSender(NewCollapseContext(snapshot), nil, &tx, code)
+}
+
+// authorizeSender re-authorizes a transaction as it is applied, against the state it is applied against. Should
+// the transaction be sent by a smart contract account, the validate function of the contract authorizes it.
+// Should the key of the account of its sender have been rotated, its signature is verified against the key bound
+// to the account, so that transactions signed by keys that have since been rotated out are rejected. All other
+// transactions are left to have had their signature verified before being accepted into the graph.
+func authorizeSender(ctx *CollapseContext, block *Block, tx *Transaction) error {
+	if tx.IsSponsored() {
+		if err := authorizeFeePayer(ctx, tx); err != nil {
+			return err
+		}
+	}
+
+	if key, rotated := ctx.ReadAccountSigningKey(tx.Sender); rotated {
+		if len(tx.Signatures) > 0 || !tx.VerifySignatureWithKey(key) {
+			return ErrTxInvalidSignature
+		}
+
+		return nil
+	}
+
+	code, isContract := ctx.ReadAccountContractCode(tx.Sender)
+	if !isContract {
+		return nil
+	}
+
+	return validateContractSender(ctx, block, tx, code)
+}
+
+// authorizeFeePayer checks that a sponsored transaction is co-signed by its fee payer, with the key bound to the
+// account of the fee payer should the key of the account have been rotated. Multisig and smart contract accounts may
+// not sponsor transactions, as they have no key to co-sign them with.
+func authorizeFeePayer(ctx *CollapseContext, tx *Transaction) error {
+	key, err := feePayerKey(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if !tx.VerifyFeePayerSignature(key) {
+		return ErrTxInvalidSignature
+	}
+
+	return nil
+}
+
+// feePayerKey checks that the fee payer of a sponsored transaction may sponsor it, and returns the key the fee payer
+// co-signs it with.
+func feePayerKey(ctx *CollapseContext, tx *Transaction) (edwards25519.PublicKey, error) {
+	if tx.FeePayer == tx.Sender {
+		return edwards25519.PublicKey{}, errors.New("fee payer: transactions may not be sponsored by their sender")
+	}
+
+	if _, isMultisig := ctx.ReadAccountMultisig(tx.FeePayer); isMultisig {
+		return edwards25519.PublicKey{}, errors.New("fee payer: multisig accounts may not sponsor transactions")
+	}
+
+	if _, isContract := ctx.ReadAccountContractCode(tx.FeePayer); isContract {
+		return edwards25519.PublicKey{}, errors.New("fee payer: smart contract accounts may not sponsor transactions")
+	}
+
+	key := tx.FeePayer
+	if bound, rotated := ctx.ReadAccountSigningKey(tx.FeePayer); rotated {
+		key = bound
+	}
+
+	return key, nil
+}
+
+// readSenderBalance reads the balance of the sender of a transaction. The account of the sender must exist, unless
+// the transaction is sponsored by a fee payer.
+func readSenderBalance(snapshot *avl.Tree, tx Transaction) (uint64, error) {
+	bal, exist := ReadAccountBalance(snapshot, tx.Sender)
+	if !exist && !tx.IsSponsored() {
+		return 0, errors.New("sender does not exist")
+	}
+
+	return bal, nil
+}
+
+// senderFee returns the fee and the gas limit of a transaction its sender must be able to pay for, both of which are
+// instead paid for by the fee payer should the transaction be sponsored.
+func senderFee(tx Transaction, gasLimit uint64) uint64 {
+	if tx.IsSponsored() {
+		return 0
+	}
+
+	return tx.Fee() + gasLimit
+}
+
+// validateContractSender invokes the validate function of the smart contract that sent a transaction, with the
+// transaction as its parameters. The contract rejects the transaction by either trapping, or by returning a
+// result describing why it was rejected. Gas spent on validating the transaction is paid for out of the gas
+// balance of the contract whether or not it accepts the transaction, and may not exceed
+// sys.ContractValidateGasLimit. Changes the validate function makes to the state of the contract are only kept
+// should it accept the transaction.
+func validateContractSender(ctx *CollapseContext, block *Block, tx *Transaction, code []byte) error {
+	gasBalance, _ := ctx.ReadAccountContractGasBalance(tx.Sender)
+
+	gasLimit := sys.ContractValidateGasLimit
+	if gasBalance < gasLimit {
+		gasLimit = gasBalance
+	}
+
+	if gasLimit == 0 {
+		return errors.Errorf("validate: contract %x has no gas to validate transactions with", tx.Sender)
+	}
+
+	contractState, _ := ctx.GetContractState(tx.Sender)
+
+	executor := &ContractExecutor{Context: ctx, Engine: ctx.engine()}
+
+	newContractState, err := executor.Execute(
+		tx.Sender, block, tx, 0, gasLimit, "validate", tx.Marshal(), code, ctx.tree, ctx.VMCache, contractState,
+	)
+
+	// Gas is paid for even should the transaction be rejected, as transactions sent by a smart contract carry no
+	// signature and may be sent by anyone to have the smart contract validate them.
+	ctx.WriteAccountContractGasBalance(tx.Sender, gasBalance-executor.Gas)
+
+	if err != nil {
+		return errors.Wrapf(err, "validate: contract %x rejected transaction", tx.Sender)
+	}
+
+	if len(executor.Error) > 0 {
+		return errors.Errorf("validate: contract %x rejected transaction: %s", tx.Sender, executor.Error)
+	}
+
+	if len(executor.Queue) > 0 {
+		return errors.Errorf("validate: contract %x must not send transactions while validating", tx.Sender)
+	}
+
+	ctx.SetContractState(tx.Sender, newContractState)
+
+	for _, write := range executor.StorageWrites {
+		if write.Deleted {
+			ctx.DeleteAccountContractStorage(tx.Sender, write.Key)
+		} else {
+			ctx.WriteAccountContractStorage(tx.Sender, write.Key, write.Value)
+		}
 	}
 
 	return nil
@@ -64,9 +417,42 @@ func validateTransferTransaction(snapshot *avl.Tree, tx Transaction) error {
 		)
 	}
 
-	if bal, exist := ReadAccountBalance(snapshot, tx.Sender); !exist {
-		return errors.New("sender does not exist")
-	} else if bal < tx.Fee()+payload.Amount+payload.GasLimit+payload.GasDeposit {
+	bal, err := readSenderBalance(snapshot, tx)
+	if err != nil {
+		return err
+	}
+
+	switch payload.Opcode {
+	case sys.ApproveAllowance:
+		if payload.Recipient == tx.Sender {
+			return errors.New("transfer: accounts may not approve allowances to themselves")
+		}
+
+		if bal < senderFee(tx, 0) {
+			return errors.Errorf("sender current balance %d is not enough", bal)
+		}
+
+		return nil
+	case sys.TransferFromAllowance:
+		if bal < senderFee(tx, 0) {
+			return errors.Errorf("sender current balance %d is not enough", bal)
+		}
+
+		allowance, _ := ReadAccountAllowance(snapshot, payload.Owner, tx.Sender)
+		if allowance.Amount < payload.Amount {
+			return errors.Errorf(
+				"transfer: %x is only allowed to spend %d PERLs of %x", tx.Sender, allowance.Amount, payload.Owner,
+			)
+		}
+
+		if ownerBal, _ := ReadAccountBalance(snapshot, payload.Owner); ownerBal < payload.Amount {
+			return errors.Errorf("owner current balance %d is not enough", ownerBal)
+		}
+
+		return nil
+	}
+
+	if bal < senderFee(tx, payload.GasLimit)+payload.Amount+payload.GasDeposit {
 		return errors.Errorf("sender current balance %d is not enough", bal)
 	}
 
@@ -129,13 +515,48 @@ func validateContractTransaction(snapshot *avl.Tree, tx Transaction) error {
 		return ErrContractAlreadyExists
 	}
 
-	if bal, _ := ReadAccountBalance(snapshot, tx.Sender); bal < tx.Fee()+payload.GasDeposit+payload.GasLimit {
+	if bal, _ := ReadAccountBalance(snapshot, tx.Sender); bal < senderFee(tx, payload.GasLimit)+payload.GasDeposit {
 		return errors.Errorf("sender current balance %d is not enough", bal)
 	}
 
 	return nil
 }
 
+func validateContractLifecycleTransaction(snapshot *avl.Tree, tx Transaction) error {
+	payload, err := ParseContractLifecycle(tx.Payload)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := ReadAccountContractCode(snapshot, payload.ContractID); !exists {
+		return errors.Errorf("contract_lifecycle: smart contract %x does not exist", payload.ContractID)
+	}
+
+	owner, hasOwner := ReadAccountContractOwner(snapshot, payload.ContractID)
+	if (!hasOwner || tx.Sender != owner) && tx.Sender != payload.ContractID {
+		return errors.Errorf(
+			"contract_lifecycle: %x is neither the owner of smart contract %x nor the smart contract itself",
+			tx.Sender, payload.ContractID,
+		)
+	}
+
+	if bal, _ := ReadAccountBalance(snapshot, tx.Sender); bal < senderFee(tx, payload.GasLimit) {
+		return errors.Errorf("sender current balance %d is not enough", bal)
+	}
+
+	if payload.Opcode == sys.WithdrawContractGas {
+		if gasBal, _ := ReadAccountContractGasBalance(snapshot, payload.ContractID); gasBal < payload.Amount {
+			return errors.Errorf(
+				"contract_lifecycle: attempt to withdraw %d PERLs of gas from smart contract %x, but it only has "+
+					"a gas balance of %d PERLs",
+				payload.Amount, payload.ContractID, gasBal,
+			)
+		}
+	}
+
+	return nil
+}
+
 func validateBatchTransaction(snapshot *avl.Tree, tx Transaction) error {
 	payload, err := ParseBatch(tx.Payload)
 	if err != nil {
@@ -144,11 +565,12 @@ func validateBatchTransaction(snapshot *avl.Tree, tx Transaction) error {
 
 	for i := uint8(0); i < payload.Size; i++ {
 		entry := Transaction{
-			ID:      tx.ID,
-			Sender:  tx.Sender,
-			Nonce:   tx.Nonce,
-			Tag:     sys.Tag(payload.Tags[i]),
-			Payload: payload.Payloads[i],
+			ID:       tx.ID,
+			Sender:   tx.Sender,
+			Nonce:    tx.Nonce,
+			Tag:      sys.Tag(payload.Tags[i]),
+			Payload:  payload.Payloads[i],
+			FeePayer: tx.FeePayer,
 		}
 		if err := validateTransaction(snapshot, entry, false); err != nil {
 			return errors.Wrapf(err, "Error while processing %d/%d transaction in a batch.", i+1, payload.Size)
@@ -157,3 +579,176 @@ func validateBatchTransaction(snapshot *avl.Tree, tx Transaction) error {
 
 	return nil
 }
+
+func validateMultisigTransaction(snapshot *avl.Tree, tx Transaction) error {
+	if _, err := ParseMultisig(tx.Payload); err != nil {
+		return errors.Wrap(err, "could not parse multisig payload")
+	}
+
+	if bal, err := readSenderBalance(snapshot, tx); err != nil {
+		return err
+	} else if bal < senderFee(tx, 0) {
+		return errors.Errorf("sender current balance %d is not enough", bal)
+	}
+
+	return nil
+}
+
+func validateKeyRotationTransaction(snapshot *avl.Tree, tx Transaction) error {
+	if _, err := ParseKeyRotation(tx.Payload); err != nil {
+		return errors.Wrap(err, "could not parse key rotation payload")
+	}
+
+	if _, isContract := ReadAccountContractCode(snapshot, tx.Sender); isContract {
+		return errors.New("key_rotation: smart contract accounts have no key to rotate")
+	}
+
+	if _, isMultisig := ReadAccountMultisig(snapshot, tx.Sender); isMultisig {
+		return errors.New("key_rotation: multisig accounts have no key to rotate")
+	}
+
+	if bal, err := readSenderBalance(snapshot, tx); err != nil {
+		return err
+	} else if bal < senderFee(tx, 0) {
+		return errors.Errorf("sender current balance %d is not enough", bal)
+	}
+
+	return nil
+}
+
+func validateAssetTransaction(snapshot *avl.Tree, tx Transaction) error {
+	payload, err := ParseAsset(tx.Payload)
+	if err != nil {
+		return errors.Wrap(err, "could not parse asset payload")
+	}
+
+	if bal, err := readSenderBalance(snapshot, tx); err != nil {
+		return err
+	} else if bal < senderFee(tx, 0) {
+		return errors.Errorf("sender current balance %d is not enough", bal)
+	}
+
+	if payload.Opcode == sys.CreateAsset {
+		if _, exists := ReadAsset(snapshot, tx.ID); exists {
+			return errors.Errorf("asset: asset %x already exists", tx.ID)
+		}
+
+		return nil
+	}
+
+	info, exists := ReadAsset(snapshot, payload.AssetID)
+	if !exists {
+		return errors.Errorf("asset: asset %x does not exist", payload.AssetID)
+	}
+
+	if payload.Opcode != sys.TransferAsset && tx.Sender != info.Issuer {
+		return errors.Errorf("asset: %x is not the issuer of asset %x", tx.Sender, payload.AssetID)
+	}
+
+	switch payload.Opcode {
+	case sys.TransferAsset:
+		if bal, _ := ReadAccountAssetBalance(snapshot, tx.Sender, payload.AssetID); bal < payload.Amount {
+			return errors.Errorf(
+				"asset: attempt to transfer %d %s, but sender only has %d %s",
+				payload.Amount, info.Symbol, bal, info.Symbol,
+			)
+		}
+	case sys.MintAsset:
+		if info.Supply+payload.Amount < info.Supply {
+			return errors.Errorf("asset: minting %d %s overflows the supply of the asset", payload.Amount, info.Symbol)
+		}
+	case sys.BurnAsset:
+		if bal, _ := ReadAccountAssetBalance(snapshot, tx.Sender, payload.AssetID); bal < payload.Amount {
+			return errors.Errorf(
+				"asset: attempt to burn %d %s, but issuer only has %d %s",
+				payload.Amount, info.Symbol, bal, info.Symbol,
+			)
+		}
+	}
+
+	return nil
+}
+
+// validateHTLCTransaction validates a hash time-locked transfer against the state it is received against. Whether or
+// not the expiry of a transfer has been reached is only checked once it is applied, against the block it is applied in.
+func validateHTLCTransaction(snapshot *avl.Tree, tx Transaction) error {
+	payload, err := ParseHTLC(tx.Payload)
+	if err != nil {
+		return errors.Wrap(err, "could not parse htlc payload")
+	}
+
+	bal, err := readSenderBalance(snapshot, tx)
+	if err != nil {
+		return err
+	}
+
+	if payload.Opcode == sys.LockHTLC {
+		if _, exists := ReadHTLC(snapshot, tx.ID); exists {
+			return errors.Errorf("htlc: %x already exists", tx.ID)
+		}
+
+		if bal < senderFee(tx, 0)+payload.Amount {
+			return errors.Errorf("sender current balance %d is not enough", bal)
+		}
+
+		return nil
+	}
+
+	if bal < senderFee(tx, 0) {
+		return errors.Errorf("sender current balance %d is not enough", bal)
+	}
+
+	info, exists := ReadHTLC(snapshot, payload.HTLCID)
+	if !exists {
+		return errors.Errorf("htlc: %x does not exist", payload.HTLCID)
+	}
+
+	if info.Status != HTLCLocked {
+		return errors.Errorf("htlc: %x has already been claimed or refunded", payload.HTLCID)
+	}
+
+	if payload.Opcode == sys.ClaimHTLC && !info.Unlocks(payload.Preimage) {
+		return errors.Errorf("htlc: preimage does not unlock %x", payload.HTLCID)
+	}
+
+	return nil
+}
+
+func validateScheduleTransaction(snapshot *avl.Tree, tx Transaction) error {
+	payload, err := ParseSchedule(tx.Payload)
+	if err != nil {
+		return errors.Wrap(err, "could not parse schedule payload")
+	}
+
+	bal, err := readSenderBalance(snapshot, tx)
+	if err != nil {
+		return err
+	}
+
+	if payload.Opcode == sys.CreateSchedule {
+		if _, exists := ReadSchedule(snapshot, tx.ID); exists {
+			return errors.Errorf("schedule: %x already exists", tx.ID)
+		}
+
+		if bal < senderFee(tx, 0)+payload.Amount*payload.Count {
+			return errors.Errorf("sender current balance %d is not enough", bal)
+		}
+
+		return nil
+	}
+
+	if bal < senderFee(tx, 0) {
+		return errors.Errorf("sender current balance %d is not enough", bal)
+	}
+
+	info, exists := ReadSchedule(snapshot, payload.ScheduleID)
+	if !exists {
+		return errors.Errorf("schedule: %x does not exist", payload.ScheduleID)
+	}
+
+	if info.Sender != tx.Sender {
+		return errors.Errorf("schedule: %x did not register %x", tx.Sender, payload.ScheduleID)
+	}
+
+	return nil
+}
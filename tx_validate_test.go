@@ -24,11 +24,13 @@ package wavelet
 import (
 	"crypto/rand"
 	"encoding/binary"
+	"io/ioutil"
 	"testing"
 
 	"github.com/perlin-network/noise/edwards25519"
 	"github.com/perlin-network/noise/skademlia"
 	"github.com/perlin-network/wavelet/avl"
+	"github.com/perlin-network/wavelet/internal/worker"
 	"github.com/perlin-network/wavelet/store"
 	"github.com/perlin-network/wavelet/sys"
 	"github.com/stretchr/testify/assert"
@@ -210,6 +212,97 @@ func TestValidateTransferTransaction(t *testing.T) {
 		tx := buildSignedTransaction(keys, sys.TagTransfer, 1, 1, payload)
 		assert.NoError(t, ValidateTransaction(state, tx))
 	})
+
+	t.Run("approve allowance to self", func(t *testing.T) {
+		payload, err := Transfer{Opcode: sys.ApproveAllowance, Recipient: keys.PublicKey(), Amount: 1}.Marshal()
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		tx := buildSignedTransaction(keys, sys.TagTransfer, 1, 1, payload)
+		assert.Error(t, ValidateTransaction(state, tx))
+	})
+
+	t.Run("transfer from allowance", func(t *testing.T) {
+		spender, err := skademlia.NewKeys(1, 1)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		WriteAccountBalance(state, spender.PublicKey(), 10)
+		WriteAccountAllowance(state, keys.PublicKey(), Allowance{Spender: spender.PublicKey(), Amount: 50})
+
+		build := func(amount uint64) Transaction {
+			payload, err := Transfer{
+				Opcode: sys.TransferFromAllowance, Owner: keys.PublicKey(), Recipient: spender.PublicKey(), Amount: amount,
+			}.Marshal()
+			assert.NoError(t, err)
+
+			return buildSignedTransaction(spender, sys.TagTransfer, 1, 1, payload)
+		}
+
+		// The owner only has a balance of 42 PERLs, despite approving an allowance of 50 PERLs.
+		assert.NoError(t, ValidateTransaction(state, build(42)))
+		assert.Error(t, ValidateTransaction(state, build(43)))
+
+		WriteAccountAllowance(state, keys.PublicKey(), Allowance{Spender: spender.PublicKey(), Amount: 10})
+		assert.NoError(t, ValidateTransaction(state, build(10)))
+		assert.Error(t, ValidateTransaction(state, build(11)))
+	})
+}
+
+func TestValidateSponsoredTransaction(t *testing.T) {
+	state := avl.New(store.NewInmem())
+
+	sender, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	feePayer, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	payload, err := Multisig{Threshold: 1, Keys: []AccountID{sender.PublicKey()}}.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	tx := NewSponsoredTransaction(sender, feePayer, 1, 1, sys.TagMultisig, payload)
+
+	// The sender has no balance, and may only send the transaction once the fee payer is able to pay for its fee.
+	assert.Error(t, ValidateTransaction(state, tx))
+
+	WriteAccountBalance(state, feePayer.PublicKey(), tx.Fee())
+	assert.NoError(t, ValidateTransaction(state, tx))
+
+	t.Run("forged fee payer signature", func(t *testing.T) {
+		forged := SponsorTransaction(tx, tx.Signature)
+		assert.Equal(t, ErrTxInvalidSignature, ValidateTransaction(state, forged))
+	})
+
+	t.Run("sponsored by the sender", func(t *testing.T) {
+		WriteAccountBalance(state, sender.PublicKey(), tx.Fee())
+		defer WriteAccountBalance(state, sender.PublicKey(), 0)
+
+		self := NewSponsoredTransaction(sender, sender, 1, 1, sys.TagMultisig, payload)
+		assert.Error(t, ValidateTransaction(state, self))
+	})
+
+	t.Run("rotated fee payer key", func(t *testing.T) {
+		rotated, err := skademlia.NewKeys(1, 1)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		WriteAccountSigningKey(state, feePayer.PublicKey(), rotated.PublicKey())
+
+		assert.Equal(t, ErrTxInvalidSignature, ValidateTransaction(state, tx))
+
+		resigned := SponsorTransaction(tx, tx.SignSponsored(rotated.PrivateKey()))
+		assert.NoError(t, ValidateTransaction(state, resigned))
+	})
 }
 
 func TestValidateContractTransaction(t *testing.T) {
@@ -346,3 +439,228 @@ func TestValidateTransaction_InvalidSignature(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, ErrTxInvalidSignature, err)
 }
+
+func TestValidateTransactions(t *testing.T) {
+	state := avl.New(store.NewInmem())
+
+	keys := make([]*skademlia.Keypair, 4)
+
+	for i := range keys {
+		var err error
+
+		if keys[i], err = skademlia.NewKeys(1, 1); !assert.NoError(t, err) {
+			return
+		}
+
+		WriteAccountBalance(state, keys[i].PublicKey(), 1000000)
+	}
+
+	rotated, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	WriteAccountSigningKey(state, keys[3].PublicKey(), rotated.PublicKey())
+
+	multisigID := AccountID{0xDD}
+	WriteAccountMultisig(state, multisigID, Multisig{Threshold: 1, Keys: []AccountID{keys[0].PublicKey()}})
+	WriteAccountBalance(state, multisigID, 1000000)
+
+	contractID := AccountID{0xCC}
+	WriteAccountContractCode(state, contractID, []byte("code"))
+	WriteAccountBalance(state, contractID, 1000000)
+
+	transfer, err := buildTransferPayload(AccountID{0xBB}, 1).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	overdraw, err := buildTransferPayload(AccountID{0xBB}, 2000000).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var txs []Transaction
+
+	// Spread enough transactions across several batches, a few of which carry invalid signatures.
+	for i := 0; i < 3*signatureBatchSize; i++ {
+		nonce := uint64(i + 1)
+		sender := keys[i%3]
+
+		switch i % 10 {
+		case 0: // Signed by the wrong key.
+			tx := NewTransaction(keys[(i+1)%3], nonce, 1, sys.TagTransfer, transfer)
+			txs = append(txs, NewSignedTransaction(sender.PublicKey(), nonce, 1, sys.TagTransfer, transfer, tx.Signature))
+		case 1: // Signed by the rotated out key of the sender.
+			txs = append(txs, NewTransaction(keys[3], nonce, 1, sys.TagTransfer, transfer))
+		case 2: // Signed by the rotated key of the sender.
+			tx := NewTransaction(rotated, nonce, 1, sys.TagTransfer, transfer)
+			txs = append(txs, NewSignedTransaction(keys[3].PublicKey(), nonce, 1, sys.TagTransfer, transfer, tx.Signature))
+		case 3: // Sponsored by a fee payer.
+			txs = append(txs, NewSponsoredTransaction(sender, keys[(i+1)%3], nonce, 1, sys.TagTransfer, transfer))
+		case 4: // Sponsored by a fee payer whose signature is forged.
+			tx := NewSponsoredTransaction(sender, keys[(i+1)%3], nonce, 1, sys.TagTransfer, transfer)
+			txs = append(txs, SponsorTransaction(tx, tx.Signature))
+		case 5: // Sent by a multisig account without signatures.
+			txs = append(txs, NewSignedTransaction(multisigID, nonce, 1, sys.TagTransfer, transfer, ZeroSignature))
+		case 6: // Sent by a smart contract account.
+			txs = append(txs, NewSignedTransaction(contractID, nonce, 1, sys.TagTransfer, transfer, ZeroSignature))
+		case 7: // Signed, but not valid against the state.
+			txs = append(txs, NewTransaction(sender, nonce, 1, sys.TagTransfer, overdraw))
+		default:
+			txs = append(txs, NewTransaction(sender, nonce, 1, sys.TagTransfer, transfer))
+		}
+	}
+
+	pool := worker.NewWorkerPool()
+	pool.Start(4)
+
+	defer pool.Stop()
+
+	errs := ValidateTransactions(state, txs, pool)
+
+	if !assert.Len(t, errs, len(txs)) {
+		return
+	}
+
+	valid := 0
+
+	for i := range txs {
+		expected := ValidateTransaction(state, txs[i])
+
+		if expected == nil {
+			assert.NoError(t, errs[i], "transaction %d", i)
+			valid++
+		} else {
+			assert.EqualError(t, errs[i], expected.Error(), "transaction %d", i)
+		}
+	}
+
+	assert.NotZero(t, valid)
+	assert.NotEqual(t, len(txs), valid)
+
+	// Authorization alone does not validate transactions against the state.
+	for i, err := range authorizeTransactions(state, txs, pool) {
+		if i%10 == 7 {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestValidateContractSender(t *testing.T) {
+	state := avl.New(store.NewInmem())
+
+	code, err := ioutil.ReadFile("testdata/validator.wasm")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	contractID := AccountID{0xAA}
+
+	WriteAccountContractCode(state, contractID, code)
+	WriteAccountBalance(state, contractID, 1000000)
+
+	transfer, err := buildTransferPayload(AccountID{0xBB}, 1).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	stake, err := buildPlaceStakePayload(1).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Transactions sent by smart contracts are not signed.
+	tx := NewSignedTransaction(contractID, 1, 1, sys.TagTransfer, transfer, edwards25519.Signature{})
+
+	err = ValidateTransaction(state, tx)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "no gas")
+	}
+
+	WriteAccountContractGasBalance(state, contractID, 1000000)
+
+	// Validating transactions does not persist any changes the validate function makes, and thus the validate
+	// function is able to accept more transactions than it would have were they applied.
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, ValidateTransaction(state, tx))
+	}
+
+	gasBalance, _ := ReadAccountContractGasBalance(state, contractID)
+	assert.EqualValues(t, 1000000, gasBalance)
+
+	tx = NewSignedTransaction(contractID, 2, 1, sys.TagStake, stake, edwards25519.Signature{})
+
+	err = ValidateTransaction(state, tx)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "tag not allowed")
+	}
+}
+
+func TestValidateKeyRotation(t *testing.T) {
+	state := avl.New(store.NewInmem())
+	block := NewBlock(1, state.Checksum())
+
+	oldKeys, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	newKeys, err := skademlia.NewKeys(1, 1)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	accountID := oldKeys.PublicKey()
+	WriteAccountBalance(state, accountID, 1000000)
+
+	// signedBy signs a transaction sent from the account with the given keys.
+	signedBy := func(keys *skademlia.Keypair, nonce uint64, tag sys.Tag, payload []byte) Transaction {
+		tx := NewTransaction(keys, nonce, block.Index, tag, payload)
+		return NewSignedTransaction(accountID, tx.Nonce, tx.Block, tx.Tag, tx.Payload, tx.Signature)
+	}
+
+	rotation, err := KeyRotation{Key: newKeys.PublicKey()}.Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	transfer, err := buildTransferPayload(AccountID{0xBB}, 1).Marshal()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Until rotated, the account is not authorized by the new key.
+	err = ValidateTransaction(state, signedBy(newKeys, 1, sys.TagKeyRotation, rotation))
+	assert.Equal(t, ErrTxInvalidSignature, err)
+
+	tx := signedBy(oldKeys, 1, sys.TagKeyRotation, rotation)
+	if !assert.NoError(t, ValidateTransaction(state, tx)) || !assert.NoError(t, ApplyTransaction(state, &block, &tx)) {
+		return
+	}
+
+	key, rotated := ReadAccountSigningKey(state, accountID)
+	assert.True(t, rotated)
+	assert.EqualValues(t, newKeys.PublicKey(), key)
+
+	// Once rotated, the account is no longer authorized by its old key.
+	assert.Equal(t, ErrTxInvalidSignature, ValidateTransaction(state, signedBy(oldKeys, 2, sys.TagTransfer, transfer)))
+	assert.NoError(t, ValidateTransaction(state, signedBy(newKeys, 2, sys.TagTransfer, transfer)))
+
+	// Smart contract and multisig accounts have no key to rotate.
+	contractID := AccountID{0xCC}
+	WriteAccountContractCode(state, contractID, []byte("code"))
+	WriteAccountBalance(state, contractID, 1000000)
+
+	tx = NewSignedTransaction(contractID, 3, block.Index, sys.TagKeyRotation, rotation, ZeroSignature)
+	assert.Error(t, validateKeyRotationTransaction(state, tx))
+	assert.Error(t, ApplyTransaction(state, &block, &tx))
+
+	multisigID := AccountID{0xDD}
+	WriteAccountMultisig(state, multisigID, Multisig{Threshold: 1, Keys: []AccountID{newKeys.PublicKey()}})
+	WriteAccountBalance(state, multisigID, 1000000)
+
+	tx = NewSignedTransaction(multisigID, 4, block.Index, sys.TagKeyRotation, rotation, ZeroSignature)
+	assert.Error(t, validateKeyRotationTransaction(state, tx))
+	assert.Error(t, ApplyTransaction(state, &block, &tx))
+}
@@ -0,0 +1,242 @@
+package wctl
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/perlin-network/wavelet"
+)
+
+// CallTyped calls a smart contract function, encoding args in accordance to the types of the functions
+// parameters described by the contract's ABI. Integers may be given as Go integers or decimal strings; bytes and
+// addresses may be given as byte slices or hex strings.
+func (c *Client) CallTyped(recipient [32]byte, fn FunctionCall, args ...interface{}) (*TxResponse, error) {
+	abi, err := c.GetContractABI(hex.EncodeToString(recipient[:]))
+	if err != nil {
+		return nil, err
+	}
+
+	desc, exists := abi.Function(fn.Name)
+	if !exists {
+		return nil, fmt.Errorf("function %q is not described by the contract's abi", fn.Name)
+	}
+
+	params, err := EncodeABIValues(desc.Params, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	fn.AddParams(params...)
+
+	return c.Call(recipient, fn)
+}
+
+// EncodeABIValues encodes args in accordance to the types described by values.
+func EncodeABIValues(values []wavelet.ABIValue, args ...interface{}) ([][]byte, error) {
+	if len(args) != len(values) {
+		return nil, fmt.Errorf("expected %d arguments, but got %d", len(values), len(args))
+	}
+
+	encoded := make([][]byte, 0, len(args))
+
+	for i, value := range values {
+		buf, err := EncodeABIValue(value.Type, args[i])
+		if err != nil {
+			return nil, fmt.Errorf("could not encode argument %q: %v", value.Name, err)
+		}
+
+		encoded = append(encoded, buf)
+	}
+
+	return encoded, nil
+}
+
+// EncodeABIValue encodes arg into the type t.
+func EncodeABIValue(t wavelet.ABIType, arg interface{}) ([]byte, error) {
+	switch t {
+	case wavelet.ABITypeU8, wavelet.ABITypeU16, wavelet.ABITypeU32, wavelet.ABITypeU64:
+		val, err := abiUint(t, arg)
+		if err != nil {
+			return nil, err
+		}
+
+		switch t {
+		case wavelet.ABITypeU8:
+			return EncodeByte(byte(val)), nil
+		case wavelet.ABITypeU16:
+			return EncodeUint16(uint16(val)), nil
+		case wavelet.ABITypeU32:
+			return EncodeUint32(uint32(val)), nil
+		default:
+			return EncodeUint64(val), nil
+		}
+	case wavelet.ABITypeString:
+		switch v := arg.(type) {
+		case string:
+			return EncodeString(v), nil
+		case []byte:
+			return EncodeString(string(v)), nil
+		}
+	case wavelet.ABITypeBytes:
+		buf, err := abiBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return EncodeBytes(buf), nil
+	case wavelet.ABITypeAddress:
+		if v, ok := arg.([32]byte); ok {
+			return v[:], nil
+		}
+
+		buf, err := abiBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(buf) != 32 {
+			return nil, fmt.Errorf("address must be 32 bytes long, but got %d bytes", len(buf))
+		}
+
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", t)
+	}
+
+	return nil, fmt.Errorf("cannot encode %T into %s", arg, t)
+}
+
+func abiUint(t wavelet.ABIType, arg interface{}) (uint64, error) {
+	bits := map[wavelet.ABIType]int{
+		wavelet.ABITypeU8:  8,
+		wavelet.ABITypeU16: 16,
+		wavelet.ABITypeU32: 32,
+		wavelet.ABITypeU64: 64,
+	}[t]
+
+	var val uint64
+
+	switch v := arg.(type) {
+	case uint8:
+		val = uint64(v)
+	case uint16:
+		val = uint64(v)
+	case uint32:
+		val = uint64(v)
+	case uint64:
+		val = v
+	case uint:
+		val = uint64(v)
+	case int:
+		if v < 0 {
+			return 0, fmt.Errorf("%d is negative", v)
+		}
+
+		val = uint64(v)
+	case string:
+		return strconv.ParseUint(v, 10, bits)
+	default:
+		return 0, fmt.Errorf("cannot encode %T into %s", arg, t)
+	}
+
+	if bits < 64 && val >= 1<<uint(bits) {
+		return 0, fmt.Errorf("%d overflows %s", val, t)
+	}
+
+	return val, nil
+}
+
+func abiBytes(arg interface{}) ([]byte, error) {
+	switch v := arg.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return hex.DecodeString(v)
+	}
+
+	return nil, fmt.Errorf("cannot encode %T into bytes", arg)
+}
+
+// DecodeABIValues decodes raw, which is comprised of values encoded one after another in accordance to the types
+// described by values. Integers are decoded into uint8, uint16, uint32 or uint64, strings into string, bytes into
+// []byte, and addresses into [32]byte.
+func DecodeABIValues(values []wavelet.ABIValue, raw []byte) ([]interface{}, error) {
+	r := bytes.NewReader(raw)
+	decoded := make([]interface{}, 0, len(values))
+
+	for _, value := range values {
+		v, err := decodeABIValue(value.Type, r)
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %q: %v", value.Name, err)
+		}
+
+		decoded = append(decoded, v)
+	}
+
+	return decoded, nil
+}
+
+func decodeABIValue(t wavelet.ABIType, r *bytes.Reader) (interface{}, error) {
+	switch t {
+	case wavelet.ABITypeU8:
+		var v uint8
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+	case wavelet.ABITypeU16:
+		var v uint16
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+	case wavelet.ABITypeU32:
+		var v uint32
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+	case wavelet.ABITypeU64:
+		var v uint64
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+	case wavelet.ABITypeString:
+		var buf []byte
+
+		for {
+			b, err := r.ReadByte()
+			if err != nil {
+				return nil, errors.New("string is not null-terminated")
+			}
+
+			if b == 0 {
+				return string(buf), nil
+			}
+
+			buf = append(buf, b)
+		}
+	case wavelet.ABITypeBytes:
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, err
+		}
+
+		if int(size) > r.Len() {
+			return nil, fmt.Errorf("expected %d bytes, but only %d bytes are left", size, r.Len())
+		}
+
+		buf := make([]byte, size)
+		_, err := r.Read(buf)
+
+		return buf, err
+	case wavelet.ABITypeAddress:
+		var v [32]byte
+		err := binary.Read(r, binary.LittleEndian, &v)
+
+		return v, err
+	}
+
+	return nil, fmt.Errorf("unknown type %q", t)
+}
@@ -3,6 +3,7 @@ package wctl
 import (
 	"encoding/hex"
 
+	"github.com/perlin-network/wavelet"
 	"github.com/valyala/fastjson"
 )
 
@@ -10,7 +11,7 @@ var _ UnmarshalableJSON = (*Account)(nil)
 
 // GetSelf gets the current account.
 func (c *Client) GetSelf() (*Account, error) {
-	return c.GetAccount(c.PublicKey)
+	return c.GetAccount(c.AccountID)
 }
 
 // GetAccount calls the /accounts endpoint of the API.
@@ -42,7 +43,29 @@ type Account struct {
 	Stake      uint64   `json:"stake"`
 	Reward     uint64   `json:"reward"`
 	IsContract bool     `json:"is_contract"`
+	CodeID     [32]byte `json:"code_id,omitempty"`
 	NumPages   uint64   `json:"num_mem_pages,omitempty"`
+
+	// SigningKey is the key that signs transactions on behalf of the account in place of the account ID. It is only
+	// set should the key of the account have been rotated.
+	SigningKey [32]byte `json:"signing_key,omitempty"`
+
+	// Multisig is set should the account be a multisig account.
+	Multisig *wavelet.Multisig `json:"multisig,omitempty"`
+
+	// Assets are the balances of the native assets held by the account.
+	Assets []AccountAsset `json:"assets,omitempty"`
+
+	// Allowances are the allowances the account approved to spenders.
+	Allowances []wavelet.Allowance `json:"allowances,omitempty"`
+}
+
+// AccountAsset is the balance of a native asset held by an account.
+type AccountAsset struct {
+	ID       [32]byte `json:"id"`
+	Symbol   string   `json:"symbol"`
+	Decimals uint8    `json:"decimals"`
+	Balance  uint64   `json:"balance"`
 }
 
 func (a *Account) UnmarshalJSON(b []byte) error {
@@ -62,7 +85,60 @@ func (a *Account) UnmarshalJSON(b []byte) error {
 	a.Stake = v.GetUint64("stake")
 	a.Reward = v.GetUint64("reward")
 	a.IsContract = v.GetBool("is_contract")
+
+	if a.IsContract {
+		if err := jsonHex(v, a.CodeID[:], "code_id"); err != nil {
+			return err
+		}
+	}
+
 	a.NumPages = v.GetUint64("num_mem_pages")
 
+	if v.Exists("signing_key") {
+		if err := jsonHex(v, a.SigningKey[:], "signing_key"); err != nil {
+			return err
+		}
+	}
+
+	if multisig := v.Get("multisig"); multisig != nil {
+		keys := multisig.GetArray("keys")
+
+		a.Multisig = &wavelet.Multisig{
+			Threshold: uint8(multisig.GetUint("threshold")),
+			Keys:      make([]wavelet.AccountID, len(keys)),
+		}
+
+		for i, key := range keys {
+			if err := jsonHex(key, a.Multisig.Keys[i][:]); err != nil {
+				return err
+			}
+		}
+	}
+
+	assets := v.GetArray("assets")
+	a.Assets = make([]AccountAsset, len(assets))
+
+	for i, asset := range assets {
+		if err := jsonHex(asset, a.Assets[i].ID[:], "id"); err != nil {
+			return err
+		}
+
+		a.Assets[i].Symbol = string(asset.GetStringBytes("symbol"))
+		a.Assets[i].Decimals = uint8(asset.GetUint("decimals"))
+		a.Assets[i].Balance = asset.GetUint64("balance")
+	}
+
+	allowances := v.GetArray("allowances")
+	a.Allowances = make([]wavelet.Allowance, len(allowances))
+
+	for i, allowance := range allowances {
+		if err := jsonHex(allowance, a.Allowances[i].Spender[:], "spender"); err != nil {
+			return err
+		}
+
+		a.Allowances[i].Amount = allowance.GetUint64("amount")
+		a.Allowances[i].Expiry = allowance.GetUint64("expiry")
+	}
+
 	return nil
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/perlin-network/noise/edwards25519"
+	"github.com/perlin-network/wavelet"
 	"github.com/valyala/fastjson"
 )
 
@@ -22,6 +23,12 @@ var (
 var (
 	// ErrInsufficientPerls is returned when you don't have enough PERLs.
 	ErrInsufficientPerls = errors.New("insufficient PERLs")
+
+	// ErrNoMultisigSignatures is returned when sending a transaction from a multisig account without signatures.
+	ErrNoMultisigSignatures = errors.New("transaction carries no multisig signatures")
+
+	// ErrNotFeePayer is returned when sponsoring a transaction whose fee payer is not the account of the client.
+	ErrNotFeePayer = errors.New("client is not the fee payer of the transaction")
 )
 
 type TransactionEvent struct {
@@ -82,6 +89,46 @@ func (c *Client) GetTransaction(txID [32]byte) (*Transaction, error) {
 func (c *Client) SendTransaction(tag byte, payload []byte) (*TxResponse, error) {
 	var res TxResponse
 
+	req := c.signTransaction(tag, payload)
+
+	if err := c.RequestJSON(RouteTxSend, ReqPost, &req, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// SimulateTransaction calls the /tx/simulate endpoint to apply a raw payload against the latest state of the
+// ledger, without it being sent.
+func (c *Client) SimulateTransaction(tag byte, payload []byte) (*wavelet.ContractTrace, error) {
+	req := c.signTransaction(tag, payload)
+
+	body, err := req.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.Request(RouteTxSimulate, ReqPost, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return wavelet.ParseContractTrace(res)
+}
+
+// TraceTransaction calls the /tx/:id/trace endpoint to replay a finalized transaction.
+func (c *Client) TraceTransaction(txID [32]byte) (*wavelet.ContractTrace, error) {
+	path := RouteTxList + "/" + hex.EncodeToString(txID[:]) + "/trace"
+
+	res, err := c.Request(path, ReqGet, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return wavelet.ParseContractTrace(res)
+}
+
+func (c *Client) signTransaction(tag byte, payload []byte) TxRequest {
 	nonce := uint64(time.Now().UnixNano())
 	block := c.Block.Load()
 
@@ -98,20 +145,14 @@ func (c *Client) SendTransaction(tag byte, payload []byte) (*TxResponse, error)
 		append(nonceBuf[:], append(blockBuf[:], append([]byte{tag}, payload...)...)...),
 	)
 
-	req := TxRequest{
-		Sender:    c.PublicKey,
+	return TxRequest{
+		Sender:    c.AccountID,
 		Nonce:     nonce,
 		Block:     block,
 		Tag:       tag,
 		Payload:   payload,
 		Signature: signature,
 	}
-
-	if err := c.RequestJSON(RouteTxSend, ReqPost, &req, &res); err != nil {
-		return nil, err
-	}
-
-	return &res, nil
 }
 
 // SendTransfer sends a wavelet.Transfer instead of a Payload.
@@ -208,6 +249,13 @@ type TxRequest struct {
 	Tag       byte     `json:"tag"`
 	Payload   []byte   `json:"payload"`
 	Signature [64]byte `json:"signature"`
+
+	// Signatures, should the sender be a multisig account, are sent in place of Signature.
+	Signatures []wavelet.MultisigSignature `json:"signatures,omitempty"`
+
+	// FeePayer and FeePayerSignature are only sent should the transaction be sponsored by a fee payer.
+	FeePayer          [32]byte `json:"fee_payer,omitempty"`
+	FeePayerSignature [64]byte `json:"fee_payer_signature,omitempty"`
 }
 
 func (s *TxRequest) MarshalJSON() ([]byte, error) {
@@ -219,7 +267,26 @@ func (s *TxRequest) MarshalJSON() ([]byte, error) {
 	o.Set("block", arena.NewNumberInt(int(s.Block)))
 	o.Set("tag", arena.NewNumberInt(int(s.Tag)))
 	o.Set("payload", arena.NewString(hex.EncodeToString(s.Payload)))
-	o.Set("signature", arena.NewString(hex.EncodeToString(s.Signature[:])))
+	if len(s.Signatures) > 0 {
+		signatures := arena.NewArray()
+
+		for i, signature := range s.Signatures {
+			v := arena.NewObject()
+			v.Set("key", arena.NewNumberInt(int(signature.Key)))
+			v.Set("signature", arena.NewString(hex.EncodeToString(signature.Signature[:])))
+
+			signatures.SetArrayItem(i, v)
+		}
+
+		o.Set("signatures", signatures)
+	} else {
+		o.Set("signature", arena.NewString(hex.EncodeToString(s.Signature[:])))
+	}
+
+	if s.FeePayer != [32]byte{} {
+		o.Set("fee_payer", arena.NewString(hex.EncodeToString(s.FeePayer[:])))
+		o.Set("fee_payer_signature", arena.NewString(hex.EncodeToString(s.FeePayerSignature[:])))
+	}
 
 	return o.MarshalTo(nil), nil
 }
@@ -0,0 +1,29 @@
+package wctl
+
+import (
+	"github.com/perlin-network/wavelet"
+	"github.com/perlin-network/wavelet/sys"
+)
+
+// ApproveAllowance approves spender to transfer up to amount PERLs out of the balance of the sender until the block
+// height expiry, or indefinitely should expiry be zero. Any allowance previously approved to spender is replaced, and
+// approving an amount of zero revokes it.
+func (c *Client) ApproveAllowance(spender [32]byte, amount uint64, expiry uint64) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagTransfer), wavelet.Transfer{
+		Opcode:    sys.ApproveAllowance,
+		Recipient: spender,
+		Amount:    amount,
+		Expiry:    expiry,
+	})
+}
+
+// TransferFrom transfers amount PERLs out of the allowance owner approved to the sender into the balance of
+// recipient.
+func (c *Client) TransferFrom(owner [32]byte, recipient [32]byte, amount uint64) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagTransfer), wavelet.Transfer{
+		Opcode:    sys.TransferFromAllowance,
+		Owner:     owner,
+		Recipient: recipient,
+		Amount:    amount,
+	})
+}
@@ -0,0 +1,46 @@
+package wctl
+
+import (
+	"github.com/perlin-network/wavelet"
+	"github.com/perlin-network/wavelet/sys"
+)
+
+// CreateAsset creates a native asset issued by the account of the client, whose initial supply is credited to the
+// account. The ID of the asset is the ID of the transaction creating it.
+func (c *Client) CreateAsset(symbol string, decimals uint8, supply uint64) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagAsset), wavelet.Asset{
+		Opcode:   sys.CreateAsset,
+		Symbol:   symbol,
+		Decimals: decimals,
+		Supply:   supply,
+	})
+}
+
+// TransferAsset transfers amount of a native asset to recipient.
+func (c *Client) TransferAsset(asset [32]byte, recipient [32]byte, amount uint64) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagAsset), wavelet.Asset{
+		Opcode:    sys.TransferAsset,
+		AssetID:   asset,
+		Recipient: recipient,
+		Amount:    amount,
+	})
+}
+
+// MintAsset mints amount of a native asset issued by the account of the client into the balance of recipient.
+func (c *Client) MintAsset(asset [32]byte, recipient [32]byte, amount uint64) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagAsset), wavelet.Asset{
+		Opcode:    sys.MintAsset,
+		AssetID:   asset,
+		Recipient: recipient,
+		Amount:    amount,
+	})
+}
+
+// BurnAsset burns amount of a native asset issued by the account of the client out of the balance of the account.
+func (c *Client) BurnAsset(asset [32]byte, amount uint64) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagAsset), wavelet.Asset{
+		Opcode:  sys.BurnAsset,
+		AssetID: asset,
+		Amount:  amount,
+	})
+}
@@ -24,3 +24,16 @@ func (c *Client) DepositGas(recipient [32]byte, gasAmount uint64) (*TxResponse,
 		GasDeposit: gasAmount,
 	})
 }
+
+func (c *Client) WithdrawGas(contract [32]byte, recipient [32]byte, gasAmount uint64) (*TxResponse, error) {
+	if !c.RecipientIsContract(contract) {
+		return nil, ErrNotContract
+	}
+
+	return c.sendTransfer(byte(sys.TagContractLifecycle), wavelet.ContractLifecycle{
+		Opcode:     sys.WithdrawContractGas,
+		ContractID: contract,
+		Amount:     gasAmount,
+		Recipient:  recipient,
+	})
+}
@@ -0,0 +1,106 @@
+package wctl
+
+import (
+	"encoding/hex"
+
+	"github.com/perlin-network/wavelet"
+	"github.com/perlin-network/wavelet/sys"
+	"github.com/valyala/fastjson"
+)
+
+var _ UnmarshalableJSON = (*HTLC)(nil)
+
+// LockHTLC locks amount PERLs in escrow, to be paid to recipient should a preimage whose SHA-256 hash is hashLock be
+// revealed before the block height expiry, and refunded otherwise. The ID of the hash time-locked transfer is the ID
+// of the transaction locking it.
+func (c *Client) LockHTLC(recipient [32]byte, amount uint64, hashLock [32]byte, expiry uint64) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagHTLC), wavelet.HTLC{
+		Opcode:    sys.LockHTLC,
+		Recipient: recipient,
+		Amount:    amount,
+		HashLock:  hashLock,
+		Expiry:    expiry,
+	})
+}
+
+// ClaimHTLC claims the amount locked by a hash time-locked transfer into the balance of its recipient by revealing
+// the preimage of its hash lock.
+func (c *Client) ClaimHTLC(id [32]byte, preimage []byte) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagHTLC), wavelet.HTLC{
+		Opcode:   sys.ClaimHTLC,
+		HTLCID:   id,
+		Preimage: preimage,
+	})
+}
+
+// RefundHTLC refunds the amount locked by an expired hash time-locked transfer to its sender.
+func (c *Client) RefundHTLC(id [32]byte) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagHTLC), wavelet.HTLC{
+		Opcode: sys.RefundHTLC,
+		HTLCID: id,
+	})
+}
+
+// GetHTLC calls the /htlc endpoint of the API.
+func (c *Client) GetHTLC(id [32]byte) (*HTLC, error) {
+	path := RouteHTLC + "/" + hex.EncodeToString(id[:])
+
+	var res HTLC
+	if err := c.RequestJSON(path, ReqGet, nil, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+type HTLC struct {
+	ID        [32]byte `json:"id"`
+	Sender    [32]byte `json:"sender"`
+	Recipient [32]byte `json:"recipient"`
+	Amount    uint64   `json:"amount"`
+	HashLock  [32]byte `json:"hash_lock"`
+	Expiry    uint64   `json:"expiry"`
+
+	// Status is either locked, claimed or refunded.
+	Status string `json:"status"`
+
+	// Preimage is the preimage revealed to claim the transfer, and is only set once the transfer is claimed.
+	Preimage []byte `json:"preimage,omitempty"`
+}
+
+func (h *HTLC) UnmarshalJSON(b []byte) error {
+	var parser fastjson.Parser
+
+	v, err := parser.ParseBytes(b)
+	if err != nil {
+		return err
+	}
+
+	if err := jsonHex(v, h.ID[:], "id"); err != nil {
+		return err
+	}
+
+	if err := jsonHex(v, h.Sender[:], "sender"); err != nil {
+		return err
+	}
+
+	if err := jsonHex(v, h.Recipient[:], "recipient"); err != nil {
+		return err
+	}
+
+	if err := jsonHex(v, h.HashLock[:], "hash_lock"); err != nil {
+		return err
+	}
+
+	h.Amount = v.GetUint64("amount")
+	h.Expiry = v.GetUint64("expiry")
+	h.Status = string(v.GetStringBytes("status"))
+
+	if v.Exists("preimage") {
+		if h.Preimage, err = hex.DecodeString(string(v.GetStringBytes("preimage"))); err != nil {
+			return errUnmarshalFail(v, "preimage", err)
+		}
+	}
+
+	return nil
+}
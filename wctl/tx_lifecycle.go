@@ -0,0 +1,30 @@
+package wctl
+
+import (
+	wasm "github.com/perlin-network/life/wasm-validation"
+	"github.com/perlin-network/wavelet"
+	"github.com/perlin-network/wavelet/sys"
+)
+
+func (c *Client) UpgradeContract(contractID [32]byte, code []byte, gasLimit uint64, params []byte) (*TxResponse, error) {
+	lc := wavelet.ContractLifecycle{
+		Opcode:     sys.UpgradeContract,
+		ContractID: contractID,
+		GasLimit:   gasLimit,
+		Params:     params,
+		Code:       code,
+	}
+
+	if err := wasm.GetValidator().ValidateWasm(code); err != nil {
+		return nil, err
+	}
+
+	return c.sendTransfer(byte(sys.TagContractLifecycle), lc)
+}
+
+func (c *Client) DestroyContract(contractID [32]byte) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagContractLifecycle), wavelet.ContractLifecycle{
+		Opcode:     sys.DestroyContract,
+		ContractID: contractID,
+	})
+}
@@ -0,0 +1,61 @@
+package wctl
+
+import (
+	"time"
+
+	"github.com/perlin-network/wavelet"
+	"github.com/perlin-network/wavelet/sys"
+)
+
+// RegisterMultisig registers a multisig account, from which transactions must be signed by exactly threshold of
+// keys. The ID of the account is the ID of the transaction registering it.
+func (c *Client) RegisterMultisig(threshold uint8, keys [][32]byte) (*TxResponse, error) {
+	multisig := wavelet.Multisig{Threshold: threshold, Keys: make([]wavelet.AccountID, len(keys))}
+	copy(multisig.Keys, keys)
+
+	return c.sendTransfer(byte(sys.TagMultisig), multisig)
+}
+
+// NewMultisigTransaction creates an unsigned transaction to be sent from a multisig account. The transaction may be
+// passed around offline, marshaled with its Marshal method, for the keys of the account to sign with SignMultisig.
+// Their signatures are then combined with wavelet.CombineMultisigSignatures, and sent with SendMultisigTransaction.
+func (c *Client) NewMultisigTransaction(multisig [32]byte, tag byte, payload []byte) wavelet.Transaction {
+	return wavelet.Transaction{
+		Sender:  multisig,
+		Nonce:   uint64(time.Now().UnixNano()),
+		Block:   c.Block.Load(),
+		Tag:     sys.Tag(tag),
+		Payload: payload,
+	}
+}
+
+// SignMultisig signs a transaction to be sent from a multisig account with the key of the client, which must be
+// found at index key amongst the keys of the account. No connection to a node is needed to sign a transaction.
+func (c *Client) SignMultisig(tx wavelet.Transaction, key uint8) wavelet.MultisigSignature {
+	return tx.SignMultisig(key, c.PrivateKey)
+}
+
+// SendMultisigTransaction calls the /tx/send endpoint to send a transaction from a multisig account, carrying the
+// signatures of the keys of the account combined with wavelet.CombineMultisigSignatures.
+func (c *Client) SendMultisigTransaction(tx wavelet.Transaction) (*TxResponse, error) {
+	if len(tx.Signatures) == 0 {
+		return nil, ErrNoMultisigSignatures
+	}
+
+	var res TxResponse
+
+	req := TxRequest{
+		Sender:     tx.Sender,
+		Nonce:      tx.Nonce,
+		Block:      tx.Block,
+		Tag:        byte(tx.Tag),
+		Payload:    tx.Payload,
+		Signatures: tx.Signatures,
+	}
+
+	if err := c.RequestJSON(RouteTxSend, ReqPost, &req, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
@@ -0,0 +1,13 @@
+package wctl
+
+import (
+	"github.com/perlin-network/wavelet"
+	"github.com/perlin-network/wavelet/sys"
+)
+
+// RotateKey binds key as the key that signs transactions on behalf of the account of the client. Once the
+// transaction is finalized, transactions are to be sent by a client configured with the private key of key and
+// with Config.AccountID set to the account.
+func (c *Client) RotateKey(key [32]byte) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagKeyRotation), wavelet.KeyRotation{Key: key})
+}
@@ -0,0 +1,110 @@
+package wctl
+
+import (
+	"encoding/hex"
+
+	"github.com/perlin-network/wavelet"
+	"github.com/perlin-network/wavelet/sys"
+	"github.com/valyala/fastjson"
+)
+
+var _ UnmarshalableJSON = (*ScheduleList)(nil)
+
+// CreateSchedule registers count transfers of amount PERLs to recipient, the first being paid at the block height
+// start and each following one interval blocks after the last. The PERLs of all transfers are escrowed from the
+// balance of the sender upfront. The ID of the scheduled transfer is the ID of the transaction registering it.
+func (c *Client) CreateSchedule(
+	recipient [32]byte, amount uint64, start uint64, interval uint64, count uint64,
+) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagSchedule), wavelet.Schedule{
+		Opcode:    sys.CreateSchedule,
+		Recipient: recipient,
+		Amount:    amount,
+		Start:     start,
+		Interval:  interval,
+		Count:     count,
+	})
+}
+
+// CancelSchedule cancels a scheduled transfer registered by the sender, refunding the PERLs escrowed for the
+// transfers not yet paid.
+func (c *Client) CancelSchedule(id [32]byte) (*TxResponse, error) {
+	return c.sendTransfer(byte(sys.TagSchedule), wavelet.Schedule{
+		Opcode:     sys.CancelSchedule,
+		ScheduleID: id,
+	})
+}
+
+// GetSchedules calls the /accounts/:id/schedules endpoint of the API to list the scheduled transfers registered by
+// an account.
+func (c *Client) GetSchedules(account [32]byte) ([]Schedule, error) {
+	path := RouteAccount + "/" + hex.EncodeToString(account[:]) + "/schedules"
+
+	var res ScheduleList
+	if err := c.RequestJSON(path, ReqGet, nil, &res); err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+type Schedule struct {
+	ID        [32]byte `json:"id"`
+	Recipient [32]byte `json:"recipient"`
+	Amount    uint64   `json:"amount"`
+
+	// Next is the block height at which the next transfer is paid.
+	Next     uint64 `json:"next"`
+	Interval uint64 `json:"interval"`
+
+	// Remaining is the number of transfers not yet paid.
+	Remaining uint64 `json:"remaining"`
+}
+
+func (s *Schedule) ParseJSON(v *fastjson.Value) error {
+	if err := jsonHex(v, s.ID[:], "id"); err != nil {
+		return err
+	}
+
+	if err := jsonHex(v, s.Recipient[:], "recipient"); err != nil {
+		return err
+	}
+
+	s.Amount = v.GetUint64("amount")
+	s.Next = v.GetUint64("next")
+	s.Interval = v.GetUint64("interval")
+	s.Remaining = v.GetUint64("remaining")
+
+	return nil
+}
+
+type ScheduleList []Schedule
+
+func (s *ScheduleList) UnmarshalJSON(b []byte) error {
+	var parser fastjson.Parser
+
+	v, err := parser.ParseBytes(b)
+	if err != nil {
+		return err
+	}
+
+	a, err := v.Array()
+	if err != nil {
+		return err
+	}
+
+	list := make([]Schedule, 0, len(a))
+
+	for _, v := range a {
+		var schedule Schedule
+		if err := schedule.ParseJSON(v); err != nil {
+			return err
+		}
+
+		list = append(list, schedule)
+	}
+
+	*s = list
+
+	return nil
+}
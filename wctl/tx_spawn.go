@@ -22,3 +22,18 @@ func (c *Client) Spawn(code []byte, gasLimit uint64) (*TxResponse, error) {
 
 	return c.sendTransfer(byte(sys.TagContract), ct)
 }
+
+// SpawnFromCodeID spawns a smart contract from code previously uploaded by the spawning of some other smart
+// contract, referenced by its code ID.
+func (c *Client) SpawnFromCodeID(codeID wavelet.ContractCodeID, gasLimit uint64) (*TxResponse, error) {
+	ct := wavelet.Contract{
+		GasLimit: 100000000,
+		CodeID:   codeID,
+	}
+
+	if gasLimit > 0 {
+		ct.GasLimit = gasLimit
+	}
+
+	return c.sendTransfer(byte(sys.TagContract), ct)
+}
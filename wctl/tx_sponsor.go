@@ -0,0 +1,55 @@
+package wctl
+
+import (
+	"time"
+
+	"github.com/perlin-network/wavelet"
+	"github.com/perlin-network/wavelet/sys"
+)
+
+// NewSponsoredTransaction creates a transaction sent from the account of the client whose fee, and the gas of any
+// smart contract function it invokes, is paid by feePayer. The transaction is signed by the client, and may be
+// passed around offline, marshaled with its Marshal method, for the fee payer to co-sign and send with
+// SponsorTransaction. No connection to a node is needed to create a sponsored transaction.
+func (c *Client) NewSponsoredTransaction(feePayer [32]byte, tag byte, payload []byte) wavelet.Transaction {
+	tx := wavelet.Transaction{
+		Sender:   c.AccountID,
+		Nonce:    uint64(time.Now().UnixNano()),
+		Block:    c.Block.Load(),
+		Tag:      sys.Tag(tag),
+		Payload:  payload,
+		FeePayer: feePayer,
+	}
+
+	tx.Signature = tx.SignSponsored(c.PrivateKey)
+
+	return tx
+}
+
+// SponsorTransaction co-signs a transaction created with NewSponsoredTransaction with the key of the client, which
+// must be the fee payer of the transaction, and calls the /tx/send endpoint to send it.
+func (c *Client) SponsorTransaction(tx wavelet.Transaction) (*TxResponse, error) {
+	if tx.FeePayer != c.AccountID {
+		return nil, ErrNotFeePayer
+	}
+
+	var res TxResponse
+
+	req := TxRequest{
+		Sender:            tx.Sender,
+		Nonce:             tx.Nonce,
+		Block:             tx.Block,
+		Tag:               byte(tx.Tag),
+		Payload:           tx.Payload,
+		Signature:         tx.Signature,
+		Signatures:        tx.Signatures,
+		FeePayer:          tx.FeePayer,
+		FeePayerSignature: tx.SignSponsored(c.PrivateKey),
+	}
+
+	if err := c.RequestJSON(RouteTxSend, ReqPost, &req, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
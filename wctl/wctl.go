@@ -22,6 +22,7 @@ package wctl
 
 import (
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log"
@@ -30,6 +31,7 @@ import (
 	"time"
 
 	"github.com/perlin-network/noise/edwards25519"
+	"github.com/perlin-network/wavelet"
 	"github.com/perlin-network/wavelet/cmd/wavelet/node"
 	"github.com/valyala/fastjson"
 	"go.uber.org/atomic"
@@ -39,9 +41,12 @@ const (
 	RouteLedger   = "/ledger"
 	RouteAccount  = "/accounts"
 	RouteContract = "/contract"
+	RouteHTLC     = "/htlc"
 	RouteTxList   = "/tx"
 	RouteTxSend   = "/tx/send"
 
+	RouteTxSimulate = "/tx/simulate"
+
 	RouteNode       = "/node"
 	RouteConnect    = RouteNode + "/connect"
 	RouteDisconnect = RouteNode + "/disconnect"
@@ -65,6 +70,10 @@ type Config struct {
 	UseHTTPS   bool
 	Timeout    time.Duration
 
+	// AccountID, if set, is the account transactions are sent from, should PrivateKey be the key bound to the
+	// account after its key was rotated. Otherwise, transactions are sent from the public key of PrivateKey.
+	AccountID [32]byte
+
 	// Optional
 	Server *node.Wavelet
 }
@@ -77,6 +86,9 @@ type Client struct {
 	edwards25519.PrivateKey
 	edwards25519.PublicKey
 
+	// AccountID is the account transactions are sent from.
+	AccountID [32]byte
+
 	jsonPool fastjson.ParserPool
 	url      string
 
@@ -116,6 +128,7 @@ type Client struct {
 	// Contract
 	OnContractGas
 	OnContractLog
+	OnContractResult
 
 	// PollTransactions
 	OnTxApplied
@@ -143,6 +156,7 @@ func NewClient(config Config) (*Client, error) {
 		Config:     config,
 		PrivateKey: config.PrivateKey,
 		PublicKey:  config.PrivateKey.Public(),
+		AccountID:  config.PrivateKey.Public(),
 		url: (&url.URL{
 			Scheme: protocol,
 			Host:   fmt.Sprintf("%s:%d", config.APIHost, config.APIPort),
@@ -156,6 +170,10 @@ func NewClient(config Config) (*Client, error) {
 		Block: atomic.NewUint64(0),
 	}
 
+	if config.AccountID != [32]byte{} {
+		c.AccountID = config.AccountID
+	}
+
 	ls, err := c.LedgerStatus()
 	if err != nil {
 		return c, err
@@ -191,6 +209,17 @@ func (c *Client) GetContractCode(contractID string) (string, error) {
 	return string(res), err
 }
 
+func (c *Client) GetContractABI(contractID string) (wavelet.ContractABI, error) {
+	path := fmt.Sprintf("%s/%s/abi", RouteContract, contractID)
+
+	res, err := c.Request(path, ReqGet, nil)
+	if err != nil {
+		return wavelet.ContractABI{}, err
+	}
+
+	return wavelet.ParseContractABI(res)
+}
+
 func (c *Client) GetContractPages(contractID string, index *uint64) (string, error) {
 	path := fmt.Sprintf("%s/%s/page", RouteContract, contractID)
 	if index != nil {
@@ -201,3 +230,11 @@ func (c *Client) GetContractPages(contractID string, index *uint64) (string, err
 
 	return base64.StdEncoding.EncodeToString(res), err
 }
+
+func (c *Client) GetContractStorage(contractID string, key []byte) (string, error) {
+	path := fmt.Sprintf("%s/%s/storage/%s", RouteContract, contractID, hex.EncodeToString(key))
+
+	res, err := c.Request(path, ReqGet, nil)
+
+	return base64.StdEncoding.EncodeToString(res), err
+}
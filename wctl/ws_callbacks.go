@@ -100,6 +100,15 @@ type (
 		Message    string    `json:"message"`
 	}
 	OnContractLog = func(ContractLog)
+
+	ContractResult struct {
+		ContractID [32]byte  `json:"contract_id"`
+		TxID       [32]byte  `json:"tx_id"`
+		Result     []byte    `json:"result"`
+		Time       time.Time `json:"time"`
+		Message    string    `json:"message"`
+	}
+	OnContractResult = func(ContractResult)
 )
 
 // Mod: tx
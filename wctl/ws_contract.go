@@ -1,6 +1,10 @@
 package wctl
 
-import "github.com/valyala/fastjson"
+import (
+	"encoding/hex"
+
+	"github.com/valyala/fastjson"
+)
 
 func (c *Client) PollContracts() (func(), error) {
 	return c.pollWS(RouteWSContracts, func(v *fastjson.Value) {
@@ -19,6 +23,8 @@ func (c *Client) PollContracts() (func(), error) {
 				err = parseContractGas(c, o)
 			case "log":
 				err = parseContractLog(c, o)
+			case "result":
+				err = parseContractResult(c, o)
 			default:
 				err = errInvalidEvent(o, ev)
 			}
@@ -77,3 +83,34 @@ func parseContractLog(c *Client, v *fastjson.Value) error {
 
 	return nil
 }
+
+func parseContractResult(c *Client, v *fastjson.Value) error {
+	var r ContractResult
+
+	if err := jsonHex(v, r.ContractID[:], "contract_id"); err != nil {
+		return err
+	}
+
+	if err := jsonHex(v, r.TxID[:], "tx_id"); err != nil {
+		return err
+	}
+
+	result, err := hex.DecodeString(jsonString(v, "result"))
+	if err != nil {
+		return errUnmarshalFail(v, "result", err)
+	}
+
+	r.Result = result
+
+	if err := jsonTime(v, &r.Time, "time"); err != nil {
+		return err
+	}
+
+	r.Message = string(v.GetStringBytes("message"))
+
+	if c.OnContractResult != nil {
+		c.OnContractResult(r)
+	}
+
+	return nil
+}